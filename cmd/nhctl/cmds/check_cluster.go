@@ -59,6 +59,7 @@ func checkClusterAvailable(kube string) error {
 	if err != nil {
 		return err
 	}
+	defer client.Close()
 
 	checkClusterStatus, err := client.SendCheckClusterStatusCommand(string(kubeContent))
 	if err != nil {
@@ -0,0 +1,130 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"path/filepath"
+)
+
+const convertFromDevContainer = "devcontainer"
+
+type ConfigConvertFlags struct {
+	CommonFlags
+	From       string
+	File       string
+	Container  string
+	LocalDir   string
+	SaveTo     string
+	Force      bool
+	BuildImage string
+}
+
+var configConvertFlags = ConfigConvertFlags{}
+
+func init() {
+	configConvertCmd.Flags().StringVar(&configConvertFlags.From, "from", "",
+		"format of the config to convert from, only devcontainer is supported")
+	configConvertCmd.Flags().StringVarP(&configConvertFlags.File, "file", "f", "",
+		"file to convert, default is "+app.DevContainerFile+" in the source directory")
+	configConvertCmd.Flags().StringVarP(&configConvertFlags.SvcName, "deployment", "d", "",
+		"k8s deployment which the config is applied to")
+	configConvertCmd.Flags().StringVarP(&serviceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet")
+	configConvertCmd.Flags().StringVarP(&configConvertFlags.Container, "container", "c", "",
+		"container which the config is applied to, default is the first container of the workload")
+	configConvertCmd.Flags().StringVar(&configConvertFlags.LocalDir, "local-dir", "",
+		"source directory of the service, default is the associated directory or current directory")
+	configConvertCmd.Flags().StringVar(&configConvertFlags.SaveTo, "save-to", "",
+		"where to save the converted config, annotation of the workload or local(.nocalhost/config.yaml "+
+			"in the source directory), print it if not specified")
+	configConvertCmd.Flags().BoolVar(&configConvertFlags.Force, "force", false,
+		"overwrite the existing .nocalhost/config.yaml while saving to local")
+	configConvertCmd.Flags().StringVar(&configConvertFlags.BuildImage, "build-image", "",
+		"repository which the dev image built from the Dockerfile of devcontainer.json is pushed to, "+
+			"required if the Dockerfile is used")
+	_ = configConvertCmd.MarkFlagRequired("from")
+	configCmd.AddCommand(configConvertCmd)
+}
+
+var configConvertCmd = &cobra.Command{
+	Use:   "convert [NAME]",
+	Short: "convert config of other tools to service config",
+	Long: `convert config of other tools to service config, image/build, containerEnv,
+forwardPorts, postCreateCommand, workspaceFolder and volume mounts of devcontainer.json
+are converted to image/build, env, portForward, command.build, workDir and
+persistentVolumeDirs of the dev config. The config is printed if NAME is not specified`,
+	Example: `  nhctl config convert --from devcontainer bookinfo -d reviews --save-to local`,
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if configConvertFlags.From != convertFromDevContainer {
+			log.Fatalf("Unsupported format %s, only %s is supported", configConvertFlags.From, convertFromDevContainer)
+		}
+
+		if configConvertFlags.SaveTo != "" && len(args) == 0 {
+			log.Fatal("NAME is required while --save-to is specified")
+		}
+
+		var localDir string
+		container := configConvertFlags.Container
+		if len(args) > 0 {
+			configConvertFlags.AppName = args[0]
+			if configConvertFlags.SvcName == "" {
+				log.Fatal("--deployment required")
+			}
+			initAppAndCheckIfSvcExist(configConvertFlags.AppName, configConvertFlags.SvcName, serviceType)
+			if container == "" {
+				podSpec, err := nocalhostSvc.GetPodSpec()
+				mustI(err, "Failed to get pod spec of "+nocalhostSvc.Name)
+				container = podSpec.Containers[0].Name
+			}
+			localDir = svcLocalDir(configConvertFlags.LocalDir, container)
+		} else {
+			var err error
+			localDir = configConvertFlags.LocalDir
+			if localDir == "" {
+				localDir, err = os.Getwd()
+				must(errors.Wrap(err, ""))
+			}
+			localDir, err = filepath.Abs(localDir)
+			must(errors.Wrap(err, ""))
+		}
+
+		file := configConvertFlags.File
+		if file == "" {
+			file = filepath.Join(localDir, filepath.FromSlash(app.DevContainerFile))
+		}
+		file, err := filepath.Abs(file)
+		must(errors.Wrap(err, ""))
+		dev, err := app.ConvertDevContainerFile(file, localDir, configConvertFlags.BuildImage)
+		mustI(err, "Failed to convert "+file)
+
+		svcConfig := &profile.ServiceConfigV2{
+			Name:             configConvertFlags.SvcName,
+			Type:             serviceType,
+			ContainerConfigs: []*profile.ContainerConfig{{Name: container, Dev: dev}},
+		}
+		if len(args) > 0 {
+			svcConfig.Type = nocalhostSvc.Type.String()
+		}
+		bys, err := yaml.Marshal(svcConfig)
+		must(errors.Wrap(err, "fail to marshal svc config"))
+
+		if configConvertFlags.SaveTo == "" {
+			fmt.Print(string(bys))
+			return
+		}
+		saveSvcConfig(bys, configConvertFlags.SaveTo, localDir, configConvertFlags.Force)
+		must(nocalhostApp.ReloadSvcCfg(nocalhostSvc.Name, nocalhostSvc.Type, false, false))
+	},
+}
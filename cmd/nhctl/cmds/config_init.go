@@ -0,0 +1,147 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/dev_dir"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	saveToAnnotation = "annotation"
+	saveToLocal      = "local"
+)
+
+type ConfigInitFlags struct {
+	CommonFlags
+	Container string
+	LocalDir  string
+	SaveTo    string
+	Force     bool
+}
+
+var configInitFlags = ConfigInitFlags{}
+
+func init() {
+	configInitCmd.Flags().StringVarP(&configInitFlags.SvcName, "deployment", "d", "",
+		"k8s deployment which your developing service exists")
+	configInitCmd.Flags().StringVarP(&serviceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet")
+	configInitCmd.Flags().StringVarP(&configInitFlags.Container, "container", "c", "",
+		"container to generate config for, default is the first container of the workload")
+	configInitCmd.Flags().StringVar(&configInitFlags.LocalDir, "local-dir", "",
+		"source directory of the service, default is the associated directory or current directory")
+	configInitCmd.Flags().StringVar(&configInitFlags.SaveTo, "save-to", "",
+		"where to save the generated config, annotation of the workload or local(.nocalhost/config.yaml "+
+			"in the source directory), print it if not specified")
+	configInitCmd.Flags().BoolVar(&configInitFlags.Force, "force", false,
+		"overwrite the existing .nocalhost/config.yaml while saving to local")
+	configCmd.AddCommand(configInitCmd)
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init [NAME]",
+	Short: "generate service config by inspecting the workload and its source",
+	Long: `generate service config by inspecting the workload and its source, dev image,
+workDir, commands, sync ignores and port-forwards are proposed by the pod spec of
+the workload and the go.mod, package.json, pom.xml, requirements.txt or Dockerfile
+in the source directory`,
+	Example: `  nhctl config init bookinfo -d productpage --local-dir ./productpage --save-to local`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		configInitFlags.AppName = args[0]
+		if configInitFlags.SvcName == "" {
+			log.Fatal("--deployment required")
+		}
+		switch configInitFlags.SaveTo {
+		case "", saveToAnnotation, saveToLocal:
+		default:
+			log.Fatalf("--save-to must be one of %s or %s", saveToAnnotation, saveToLocal)
+		}
+
+		initAppAndCheckIfSvcExist(configInitFlags.AppName, configInitFlags.SvcName, serviceType)
+
+		localDir := svcLocalDir(configInitFlags.LocalDir, configInitFlags.Container)
+
+		podSpec, err := nocalhostSvc.GetPodSpec()
+		mustI(err, "Failed to get pod spec of "+nocalhostSvc.Name)
+
+		svcConfig, err := app.GenerateSvcConfig(
+			nocalhostSvc.Name, nocalhostSvc.Type.String(), podSpec, configInitFlags.Container, localDir,
+		)
+		must(err)
+
+		bys, err := yaml.Marshal(svcConfig)
+		must(errors.Wrap(err, "fail to marshal svc config"))
+
+		if configInitFlags.SaveTo == "" {
+			fmt.Print(string(bys))
+			return
+		}
+		saveSvcConfig(bys, configInitFlags.SaveTo, localDir, configInitFlags.Force)
+		must(nocalhostApp.ReloadSvcCfg(nocalhostSvc.Name, nocalhostSvc.Type, false, false))
+	},
+}
+
+// saveSvcConfig saves config of nocalhostSvc to its annotation or .nocalhost/config.yaml under localDir
+func saveSvcConfig(bys []byte, saveTo, localDir string, force bool) {
+	switch saveTo {
+	case saveToAnnotation:
+		value, err := json.Marshal(string(bys))
+		must(errors.Wrap(err, ""))
+		must(
+			nocalhostSvc.Client.Patch(
+				nocalhostSvc.Type.String(), nocalhostSvc.Name,
+				fmt.Sprintf(`{"metadata":{"annotations":{"%s":%s}}}`, appmeta.AnnotationKey, value),
+			),
+		)
+		log.Infof("Config is saved to annotation %s of %s %s", appmeta.AnnotationKey, nocalhostSvc.Type, nocalhostSvc.Name)
+	case saveToLocal:
+		configFile := filepath.Join(localDir, app.DefaultGitNocalhostDir, "config.yaml")
+		if _, err := os.Stat(configFile); err == nil && !force {
+			log.Fatalf("%s already exists, use --force to overwrite it", configFile)
+		}
+		must(errors.Wrap(os.MkdirAll(filepath.Dir(configFile), 0755), ""))
+		must(errors.Wrap(ioutil.WriteFile(configFile, bys, 0644), ""))
+		log.Infof("Config is saved to %s", configFile)
+	default:
+		log.Fatalf("--save-to must be one of %s or %s", saveToAnnotation, saveToLocal)
+	}
+}
+
+// svcLocalDir returns localDir, or the associated directory of nocalhostSvc, or the current directory
+func svcLocalDir(localDir, container string) string {
+	if localDir == "" {
+		localDir = string(
+			dev_dir.NewSvcPack(
+				nocalhostSvc.NameSpace, nocalhostSvc.AppName, nocalhostSvc.Type, nocalhostSvc.Name, container,
+			).GetAssociatePath(),
+		)
+	}
+	if localDir == "" {
+		var err error
+		localDir, err = os.Getwd()
+		must(errors.Wrap(err, ""))
+	}
+	localDir, err := filepath.Abs(localDir)
+	must(errors.Wrap(err, ""))
+	return localDir
+}
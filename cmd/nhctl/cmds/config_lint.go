@@ -0,0 +1,75 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/output"
+	"os"
+	"path/filepath"
+)
+
+var lintResourceDir string
+
+func init() {
+	configLintCmd.Flags().StringVar(
+		&lintResourceDir, "resource-dir", "",
+		"dir which resourcePath of the config is relative to, default is the parent of .nocalhost dir",
+	)
+	configCmd.AddCommand(configLintCmd)
+}
+
+var configLintCmd = &cobra.Command{
+	Use:   "lint [FILE]",
+	Short: "Lint nocalhost config file",
+	Long: `Lint nocalhost config file, default is .nocalhost/config.yaml. The config is rendered with
+includes and env files, then validated against the schema of config, services and containers
+configured are checked against workloads in manifests of resourcePath as well`,
+	Example: `  nhctl config lint
+  nhctl config lint ./bookinfo/.nocalhost/config.yaml -o json`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := filepath.Join(app.DefaultGitNocalhostDir, app.DefaultConfigNameInGitNocalhostDir)
+		if len(args) > 0 {
+			file = args[0]
+		}
+		resourceDir := lintResourceDir
+		if resourceDir == "" {
+			resourceDir = filepath.Dir(filepath.Dir(file))
+		}
+
+		issues, err := app.LintConfig(file, resourceDir)
+		must(err)
+
+		errorCount := 0
+		report := &output.ConfigLintReport{File: file, Issues: make([]output.ConfigLintIssue, 0, len(issues))}
+		for _, issue := range issues {
+			if issue.Severity == app.LintError {
+				errorCount++
+			}
+			report.Issues = append(
+				report.Issues, output.ConfigLintIssue{
+					File: issue.File, Line: issue.Line, Column: issue.Column,
+					Path: issue.Path, Severity: issue.Severity, Message: issue.Message,
+				},
+			)
+		}
+
+		if outputFormat != "" {
+			printOutput(report)
+		} else {
+			for _, issue := range issues {
+				fmt.Println(issue.String())
+			}
+			fmt.Printf("%d errors, %d warnings\n", errorCount, len(issues)-errorCount)
+		}
+		if errorCount > 0 {
+			os.Exit(1)
+		}
+	},
+}
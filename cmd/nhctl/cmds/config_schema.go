@@ -0,0 +1,29 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/profile"
+)
+
+func init() {
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print json schema of nocalhost config",
+	Long: `Print json schema of nocalhost config, which can be used by editors to validate
+and complete .nocalhost/config.yaml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		bys, err := profile.ConfigSchemaJSON()
+		must(err)
+		fmt.Print(string(bys))
+	},
+}
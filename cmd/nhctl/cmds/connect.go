@@ -0,0 +1,156 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/syncthing/ports"
+	"nocalhost/internal/nhctl/tunnel"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+type connectFlags struct {
+	Listen string
+	Dns    string
+}
+
+var connectOptions = &connectFlags{}
+
+func init() {
+	connectCmd.Flags().StringVar(
+		&connectOptions.Listen, "listen", "127.0.0.1:1080", "address of the local SOCKS5 and HTTP proxy",
+	)
+	connectCmd.Flags().StringVar(
+		&connectOptions.Dns, "dns", "",
+		"address of the local dns resolver resolving names by the cluster dns, such as 127.0.0.1:5353, "+
+			"disabled if not specified",
+	)
+	rootCmd.AddCommand(connectCmd)
+}
+
+var connectCmd = &cobra.Command{
+	Use:   "connect",
+	Short: "Access the network of cluster from local",
+	Long: `Access the network of cluster from local.
+A SOCKS5 and HTTP proxy is started locally, connections are dialed by a helper pod
+in the namespace through the k8s api server, so cluster dns names such as
+mysql.dev-ns.svc.cluster.local are reachable without root`,
+	Example: `  nhctl connect -n dev-ns
+  export ALL_PROXY=socks5h://127.0.0.1:1080 HTTPS_PROXY=http://127.0.0.1:1080 HTTP_PROXY=http://127.0.0.1:1080`,
+	Run: func(cmd *cobra.Command, args []string) {
+		must(Prepare())
+		client, err := clientgoutils.NewClientGoUtils(kubeConfig, nameSpace)
+		must(err)
+
+		listener, err := net.Listen("tcp", connectOptions.Listen)
+		mustI(err, fmt.Sprintf("Failed to listen on %s", connectOptions.Listen))
+		tunnelPort, err := ports.GetAvailablePort()
+		must(err)
+
+		podName, err := tunnel.StartConnectHelper(client)
+		must(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+			<-signals
+			cancel()
+		}()
+
+		a := &app.Application{NameSpace: nameSpace, KubeConfig: kubeConfig}
+		helperDone := make(chan struct{})
+		go func() {
+			defer close(helperDone)
+			keepConnectHelper(ctx, client, a, podName, tunnelPort)
+		}()
+		// the helper is deleted by keepConnectHelper after ctx is done
+		defer func() {
+			cancel()
+			<-helperDone
+		}()
+
+		dialer := &tunnel.RemoteDialer{
+			DialTunnel: func() (net.Conn, error) {
+				conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", tunnelPort), 5*time.Second)
+				return conn, errors.Wrap(err, "")
+			},
+		}
+
+		go dialer.KeepAlive(ctx, tunnel.ConnectHelperKeepAlive)
+
+		errCh := make(chan error, 2)
+		if connectOptions.Dns != "" {
+			dnsServer := &tunnel.DNSServer{Address: connectOptions.Dns, Resolver: dialer}
+			go func() { errCh <- dnsServer.Serve(ctx) }()
+			coloredoutput.Information("DNS resolver is listening on %s (udp)", connectOptions.Dns)
+		}
+		proxy := &tunnel.ConnectProxy{Dialer: dialer}
+		go func() { errCh <- proxy.Serve(ctx, listener) }()
+
+		coloredoutput.Success("Connected to namespace %s, press Ctrl+C to disconnect", nameSpace)
+		coloredoutput.Information("SOCKS5 and HTTP proxy is listening on %s", connectOptions.Listen)
+		coloredoutput.Information("export ALL_PROXY=socks5h://%s", connectOptions.Listen)
+
+		select {
+		case <-ctx.Done():
+		case err = <-errCh:
+			utils.ShouldI(err, "Proxy exits")
+		}
+		log.Info("Disconnecting")
+	},
+}
+
+// keepConnectHelper forwards tunnelPort to the connect helper until ctx is done, the helper is
+// recreated if it terminated, since it's never restarted. The helper is deleted while returning
+func keepConnectHelper(
+	ctx context.Context, client *clientgoutils.ClientGoUtils, a *app.Application, podName string, tunnelPort int,
+) {
+	for {
+		forwardCtx, forwardCancel := context.WithCancel(ctx)
+		go forwardTunnel(forwardCtx, a, podName, tunnelPort, tunnel.DefaultDialPort)
+
+		for ctx.Err() == nil && !tunnel.IsHelperTerminated(client, podName) {
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Second):
+			}
+		}
+		forwardCancel()
+		utils.ShouldI(client.DeletePodByName(podName, 0), fmt.Sprintf("Failed to delete %s", podName))
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warnf("Connect helper %s terminated, recreating it", podName)
+		for {
+			newPod, err := tunnel.StartConnectHelper(client)
+			if err == nil {
+				podName = newPod
+				break
+			}
+			log.WarnE(err, "Failed to recreate connect helper, retrying")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/daemon_client"
+	"nocalhost/internal/nhctl/dbutils"
+	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"nocalhost/internal/nhctl/output"
+	"os"
+	"time"
+)
+
+func init() {
+	daemonCmd.AddCommand(daemonDoctorCmd)
+}
+
+var daemonDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose nhctl daemon",
+	Long: `Diagnose nhctl daemon, report goroutines, port-forward and sync routines supervised by the watchdog
+and informers opened by the daemon, as well as leveldb of applications held by processes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		report := &output.DaemonDoctorReport{
+			Daemons:  []output.DaemonHealth{diagnoseDaemon(false), diagnoseDaemon(true)},
+			LevelDBs: diagnoseLevelDBs(),
+		}
+
+		format := output.Table
+		if outputFormat != "" {
+			var err error
+			format, err = output.ParseFormat(outputFormat)
+			must(err)
+		}
+		must(output.Print(os.Stdout, format, report))
+	},
+}
+
+// diagnoseDaemon asks the daemon for its health, daemon is not started if it is not running
+func diagnoseDaemon(isSudo bool) output.DaemonHealth {
+	result := output.DaemonHealth{Sudo: isSudo}
+	if !daemon_client.CheckIfDaemonServerRunning(isSudo) {
+		return result
+	}
+	result.Running = true
+
+	client, err := daemon_client.NewDaemonClient(isSudo)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer client.Close()
+	health, err := client.SendGetDaemonHealthCommand()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Pid = health.Pid
+	result.Version = health.Version
+	result.ManagedBy = health.ManagedBy
+	result.StartTime = health.StartTime
+	result.Goroutines = health.Goroutines
+	for _, r := range health.Routines {
+		result.Routines = append(
+			result.Routines, output.DaemonRoutine{
+				Kind:          r.Kind,
+				Name:          r.Name,
+				State:         r.State,
+				Restarts:      r.Restarts,
+				LastError:     r.LastError,
+				LastErrorTime: r.LastErrorTime,
+				NextRestart:   r.NextRestart,
+			},
+		)
+	}
+	for _, i := range health.Informers {
+		result.Informers = append(
+			result.Informers, output.Informer{
+				Kind:            i.Kind,
+				Server:          i.Server,
+				Namespace:       i.Namespace,
+				ClusterScope:    i.ClusterScope,
+				Informers:       i.Informers,
+				SyncedInformers: i.SyncedInformers,
+				PodSubscribers:  i.PodSubscribers,
+				LastUsedTime:    i.LastUsedTime,
+			},
+		)
+	}
+	return result
+}
+
+// diagnoseLevelDBs finds out leveldb of applications locked for a while, which blocks nhctl
+func diagnoseLevelDBs() []output.LevelDBLock {
+	result := make([]output.LevelDBLock, 0)
+	appList, err := nocalhost.GetNsAndApplicationInfo()
+	if err != nil {
+		return append(result, output.LevelDBLock{Path: nocalhost_path.GetNhctlNameSpaceBaseDir(), Error: err.Error()})
+	}
+	for _, a := range appList {
+		db := output.LevelDBLock{Path: nocalhost_path.GetAppDbDir(a.Namespace, a.Name, a.Nid)}
+		if db.Locked, err = dbutils.IsLevelDBStuck(db.Path, 3, time.Second); err != nil {
+			db.Error = err.Error()
+		}
+		result = append(result, db)
+	}
+	return result
+}
@@ -24,6 +24,7 @@ var daemonInfoCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		client, err := daemon_client.NewDaemonClient(isSudoUser)
 		must(err)
+		defer client.Close()
 
 		daemonServerInfo, err := client.SendGetDaemonServerInfoCommand()
 		must(err)
@@ -0,0 +1,108 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/daemon_client"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var daemonInstallPrint bool
+
+func init() {
+	daemonInstallCmd.Flags().BoolVar(
+		&daemonInstallPrint, "print", false, "print the service definition instead of installing it",
+	)
+	daemonCmd.AddCommand(daemonInstallCmd)
+	daemonCmd.AddCommand(daemonUninstallCmd)
+}
+
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install nhctl daemon as a service of OS",
+	Long: `Install nhctl daemon as a systemd user service on linux or a launchd agent on macOS,
+the daemon is started on login and restarted while it crashes. Daemon run as sudo is still started on demand.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		service := newDaemonService()
+		if daemonInstallPrint {
+			fmt.Print(string(service.Content))
+			return
+		}
+
+		// the daemon started by nhctl occupies the port of the service
+		stopRunningDaemon()
+
+		must(errors.Wrap(os.MkdirAll(filepath.Dir(service.Path), 0755), ""))
+		must(errors.Wrap(ioutil.WriteFile(service.Path, service.Content, 0644), ""))
+		log.Infof("%s service is written to %s", service.Manager, service.Path)
+		for _, c := range service.RegisterCmds {
+			must(runServiceCommand(c))
+		}
+		log.Infof("nhctl daemon is installed as %s service", service.Manager)
+	},
+}
+
+var daemonUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Uninstall nhctl daemon service of OS",
+	Long:  `Uninstall nhctl daemon service of OS, the daemon is started by nhctl on demand again`,
+	Run: func(cmd *cobra.Command, args []string) {
+		service := newDaemonService()
+		if _, err := os.Stat(service.Path); os.IsNotExist(err) {
+			log.Infof("%s service %s is not installed", service.Manager, service.Path)
+			return
+		}
+		for _, c := range service.UnregisterCmds {
+			must(runServiceCommand(c))
+		}
+		must(errors.Wrap(os.Remove(service.Path), ""))
+		log.Infof("nhctl daemon %s service is uninstalled", service.Manager)
+	},
+}
+
+func newDaemonService() *daemon_common.DaemonService {
+	if utils.IsSudoUser() {
+		must(errors.New("Daemon service is installed for current user, please run it without sudo"))
+	}
+	nhctlPath, err := utils.GetNhctlPath()
+	must(err)
+	service, err := daemon_common.NewDaemonService(runtime.GOOS, nhctlPath, utils.GetHomePath())
+	must(err)
+	return service
+}
+
+func stopRunningDaemon() {
+	if !daemon_client.CheckIfDaemonServerRunning(false) {
+		return
+	}
+	log.Info("Stopping running nhctl daemon")
+	client, err := daemon_client.NewDaemonClient(false)
+	must(err)
+	defer client.Close()
+	must(client.SendStopDaemonServerCommand())
+	must(daemon_client.WaitForDaemonServerToBeDown(false, 10*time.Second))
+}
+
+func runServiceCommand(args []string) error {
+	log.Infof("Running %s", strings.Join(args, " "))
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to run %s: %s", strings.Join(args, " "), string(output)))
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ var daemonRestartCmd = &cobra.Command{
 		if isRunning {
 			client, err := daemon_client.NewDaemonClient(isSudoUser)
 			must(err)
+			defer client.Close()
 			must(client.SendRestartDaemonServerCommand())
 			log.Info("RestartDaemonServerCommand has been sent")
 		} else {
@@ -24,6 +24,7 @@ var daemonStatusCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		client, err := daemon_client.NewDaemonClient(isSudoUser)
 		must(err)
+		defer client.Close()
 
 		status, err := client.SendGetDaemonServerStatusCommand()
 		must(err)
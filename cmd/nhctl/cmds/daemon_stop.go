@@ -22,6 +22,7 @@ var daemonStopCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		client, err := daemon_client.NewDaemonClient(isSudoUser)
 		must(err)
+		defer client.Close()
 		must(client.SendStopDaemonServerCommand())
 	},
 }
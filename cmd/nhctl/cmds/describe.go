@@ -8,6 +8,9 @@ package cmds
 import (
 	"fmt"
 	"gopkg.in/yaml.v3"
+	"nocalhost/internal/nhctl/output"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/pkg/nhctl/log"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -36,7 +39,14 @@ var describeCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		applicationName := args[0]
 		initApp(applicationName)
-		if deploy == "" {
+		if outputFormat != "" {
+			if deploy == "" {
+				printOutput(applicationDescriptionOutput(nocalhostApp.GetDescription()))
+			} else {
+				checkIfSvcExist(deploy, serviceType)
+				printOutput(serviceDescriptionOutput(nocalhostApp.Name, nocalhostSvc.GetDescription()))
+			}
+		} else if deploy == "" {
 			appProfile := nocalhostApp.GetDescription()
 			bytes, err := yaml.Marshal(appProfile)
 			if err == nil {
@@ -52,3 +62,57 @@ var describeCmd = &cobra.Command{
 		}
 	},
 }
+
+func applicationDescriptionOutput(appProfile *profile.AppProfileV2) *output.ApplicationDescription {
+	if appProfile == nil {
+		log.Fatal("Failed to get description of application")
+	}
+	detail := output.ApplicationDetail{
+		Name:           appProfile.Name,
+		Namespace:      appProfile.Namespace,
+		Type:           appProfile.AppType,
+		Installed:      appProfile.Installed,
+		ConfigOverlays: appProfile.ConfigOverlays,
+		Services:       make([]output.ServiceDetail, 0, len(appProfile.SvcProfile)),
+	}
+	for _, svcProfile := range appProfile.SvcProfile {
+		detail.Services = append(detail.Services, serviceDetail(svcProfile))
+	}
+	return &output.ApplicationDescription{Application: detail}
+}
+
+func serviceDescriptionOutput(appName string, svcProfile *profile.SvcProfileV2) *output.ServiceDescription {
+	if svcProfile == nil {
+		log.Fatal("Failed to get description of service")
+	}
+	return &output.ServiceDescription{Application: appName, Service: serviceDetail(svcProfile)}
+}
+
+func serviceDetail(svcProfile *profile.SvcProfileV2) output.ServiceDetail {
+	portForwards := make([]string, 0, len(svcProfile.DevPortForwardList))
+	for _, pf := range svcProfile.DevPortForwardList {
+		portForward := fmt.Sprintf("%d:%d", pf.LocalPort, pf.RemotePort)
+		if pf.Protocol != "" && pf.Protocol != profile.PortForwardProtocolTCP {
+			portForward += "/" + pf.Protocol
+		}
+		portForwards = append(portForwards, portForward)
+	}
+	syncPaths := svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin
+	if syncPaths == nil {
+		syncPaths = []string{}
+	}
+	return output.ServiceDetail{
+		Name:                  svcProfile.GetName(),
+		Type:                  svcProfile.GetType(),
+		Developing:            svcProfile.Developing,
+		DevelopStatus:         svcProfile.DevelopStatus,
+		Possess:               svcProfile.Possess,
+		DevMode:               svcProfile.DevMode,
+		DevContainer:          svcProfile.DevContainer,
+		Syncing:               svcProfile.Syncing,
+		Associate:             svcProfile.Associate,
+		SyncPaths:             syncPaths,
+		DevPortForwards:       portForwards,
+		LocalSyncthingGUIPort: svcProfile.LocalSyncthingGUIPort,
+	}
+}
@@ -13,10 +13,14 @@ import (
 	"nocalhost/pkg/nhctl/log"
 )
 
+var devEndWithoutHook bool
+
 func init() {
 	devEndCmd.Flags().StringVarP(&deployment, "deployment", "d", "", "k8s deployment which your developing service exists")
 	devEndCmd.Flags().StringVarP(&serviceType, "controller-type", "t", "",
 		"kind of k8s controller,such as deployment,statefulSet")
+	devEndCmd.Flags().BoolVar(&devEndWithoutHook, "without-hook", false,
+		"do not run preEnd hook of dev.lifecycle before rolling back")
 	debugCmd.AddCommand(devEndCmd)
 }
 
@@ -35,9 +39,18 @@ var devEndCmd = &cobra.Command{
 		initAppAndCheckIfSvcExist(applicationName, deployment, serviceType)
 
 		if !nocalhostSvc.IsInDevMode() {
+			// intercept can be used without DevMode, restore the services only
+			if intercepted, err := nocalhostSvc.InterceptedServices(); err == nil && len(intercepted) > 0 {
+				must(nocalhostSvc.StopAllIntercepts())
+				coloredoutput.Success("Intercepted services have been restored")
+				return
+			}
 			log.Fatalf("Service %s is not in DevMode", deployment)
 		}
 
+		if !devEndWithoutHook {
+			runPreEndHook()
+		}
 		must(nocalhostSvc.DevEnd(false))
 
 		fmt.Println()
@@ -0,0 +1,175 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/coloredoutput"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/syncthing/daemon"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	// the first sync may take a while if there are lots of files
+	postStartSyncTimeout = 10 * time.Minute
+	postSyncWatchTimeout = 24 * time.Hour
+)
+
+var hookName string
+var hookWatch bool
+var hookSkipFirst bool
+
+func init() {
+	devHookCmd.Flags().StringVarP(&deployment, "deployment", "d", "",
+		"K8s deployment which your developing service exists")
+	devHookCmd.Flags().StringVarP(&serviceType, "controller-type", "t", "",
+		"kind of k8s controller,such as deployment,statefulSet")
+	devHookCmd.Flags().StringVarP(&container, "container", "c", "",
+		"container whose lifecycle hook to run, default is the one developing")
+	devHookCmd.Flags().StringVar(&hookName, "hook", "", fmt.Sprintf(
+		"lifecycle hook can be: %s", strings.Join(controller.LifecycleHooks, ", ")))
+	devHookCmd.Flags().BoolVar(&hookWatch, "watch", false,
+		"run postSync hook after every sync batch completes until file sync stops")
+	devHookCmd.Flags().BoolVar(&hookSkipFirst, "skip-first", false,
+		"do not run postSync hook after the first sync batch while watching, it's followed by postStart hook")
+	debugCmd.AddCommand(devHookCmd)
+}
+
+var devHookCmd = &cobra.Command{
+	Use:   "hook [NAME]",
+	Short: "Run lifecycle hook in dev container",
+	Long:  `Run lifecycle hook configured in dev.lifecycle in dev container`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		applicationName := args[0]
+		initAppAndCheckIfSvcExist(applicationName, deployment, serviceType)
+		if !nocalhostSvc.IsInDevMode() {
+			log.Fatalf("%s is not in DevMode", deployment)
+		}
+		if len(nocalhostSvc.LifecycleHook(container, hookName)) == 0 {
+			log.Fatalf("%s hook not defined", hookName)
+		}
+
+		if hookWatch {
+			if hookName != controller.PostSyncHook {
+				log.Fatalf("--watch is only supported by %s hook", controller.PostSyncHook)
+			}
+			watchPostSyncHook(container, hookSkipFirst)
+			return
+		}
+
+		podName, err := nocalhostSvc.BuildPodController().GetNocalhostDevContainerPod()
+		must(err)
+		must(nocalhostSvc.RunLifecycleHook(podName, container, hookName, os.Stdout, os.Stderr))
+	},
+}
+
+// startPostSyncHook runs postSync hook after every sync batch in a background process,
+// which is terminated while file sync stops. The process started before is replaced,
+// skipFirst if the first sync is followed by postStart hook
+func startPostSyncHook(container string, skipFirst bool) {
+	if len(nocalhostSvc.LifecycleHook(container, controller.PostSyncHook)) == 0 {
+		return
+	}
+	utils.ShouldI(nocalhostSvc.StopLifecycleProcess(), "Failed to stop previous postSync hook")
+	nhctlPath, err := utils.GetNhctlPath()
+	if err != nil {
+		log.WarnE(err, "Failed to start postSync hook")
+		return
+	}
+	args := []string{
+		nhctlPath, "dev", "hook", nocalhostSvc.AppName, "-d", nocalhostSvc.Name, "-t", string(nocalhostSvc.Type),
+		"--hook", controller.PostSyncHook, "--watch", "-n", nocalhostSvc.NameSpace,
+	}
+	if container != "" {
+		args = append(args, "-c", container)
+	}
+	if skipFirst {
+		args = append(args, "--skip-first")
+	}
+	if kubeConfig != "" {
+		args = append(args, "--kubeconfig", kubeConfig)
+	}
+	utils.ShouldI(daemon.RunSubProcess(args, nil, false), "Failed to start postSync hook")
+	coloredoutput.Information("postSync hook is running, output is written to %s", nocalhostSvc.GetLifecycleLogFile())
+}
+
+// watchPostSyncHook runs postSync hook after every sync batch, except the first one if skipFirst since
+// it's followed by postStart hook. Output of the hook is appended to the lifecycle log file
+func watchPostSyncHook(container string, skipFirst bool) {
+	must(nocalhostSvc.SaveLifecyclePid(os.Getpid()))
+	logFile, err := os.OpenFile(nocalhostSvc.GetLifecycleLogFile(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	must(errors.Wrap(err, ""))
+	defer logFile.Close()
+
+	must(nocalhostSvc.WaitForSync(context.TODO(), postSyncWatchTimeout, true, func() {
+		if skipFirst {
+			skipFirst = false
+			return
+		}
+		_, _ = fmt.Fprintf(logFile, "[%s] sync finished\n", time.Now().Format(time.RFC3339))
+		podName, err := nocalhostSvc.BuildPodController().GetNocalhostDevContainerPod()
+		if err == nil {
+			err = nocalhostSvc.RunLifecycleHook(podName, container, controller.PostSyncHook, logFile, logFile)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(logFile, "%s\n", err.Error())
+			log.WarnE(err, "Failed to run postSync hook")
+		}
+	}))
+}
+
+// runPostStartHook runs postStart hook once after the first sync completes, the dev mode is kept
+// even if the hook fails
+func runPostStartHook(podName, container string, waitForSync bool) {
+	if len(nocalhostSvc.LifecycleHook(container, controller.PostStartHook)) == 0 {
+		return
+	}
+	var err error
+	if waitForSync {
+		coloredoutput.Hint("Waiting for the first sync to complete before running postStart hook...")
+		err = nocalhostSvc.WaitForSync(context.TODO(), postStartSyncTimeout, false, func() {})
+	}
+	if err == nil {
+		err = nocalhostSvc.RunLifecycleHook(podName, container, controller.PostStartHook, os.Stdout, os.Stderr)
+	}
+	if err != nil {
+		log.LogE(err)
+		coloredoutput.Fail("postStart hook failed: %s, you can rerun it by `nhctl dev hook --hook postStart`", err)
+		return
+	}
+	coloredoutput.Success("postStart hook finished")
+}
+
+// runPreEndHook runs preEnd hook before the workload is rolled back, dev end is aborted if the hook fails
+func runPreEndHook() {
+	if len(nocalhostSvc.LifecycleHook("", controller.PreEndHook)) == 0 {
+		return
+	}
+	podName, err := nocalhostSvc.BuildPodController().GetNocalhostDevContainerPod()
+	if err != nil {
+		log.WarnE(err, "Dev container not found, skipping preEnd hook")
+		return
+	}
+	mustI(
+		nocalhostSvc.RunLifecycleHook(podName, "", controller.PreEndHook, os.Stdout, os.Stderr),
+		"DevMode is not ended, you can end it without the hook by --without-hook",
+	)
+}
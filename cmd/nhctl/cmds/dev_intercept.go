@@ -0,0 +1,155 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/coloredoutput"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/syncthing/ports"
+	"nocalhost/internal/nhctl/tunnel"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+type interceptFlags struct {
+	Service string
+	Ports   []string
+	Stop    bool
+}
+
+var interceptOptions = &interceptFlags{}
+
+func init() {
+	devInterceptCmd.Flags().StringVarP(
+		&deployment, "deployment", "d", "", "k8s workload which the intercepted service belongs to",
+	)
+	devInterceptCmd.Flags().StringVarP(
+		&serviceType, "controller-type", "t", "", "kind of k8s controller,such as deployment,statefulSet",
+	)
+	devInterceptCmd.Flags().StringVar(&interceptOptions.Service, "service", "", "k8s service to intercept")
+	devInterceptCmd.Flags().StringSliceVarP(
+		&interceptOptions.Ports, "port", "p", []string{},
+		"local port which traffic of the service port goes to, such as 8080:80, "+
+			"the same port is used if not specified",
+	)
+	devInterceptCmd.Flags().BoolVar(
+		&interceptOptions.Stop, "stop", false,
+		"restore the service, all services intercepted by the workload are restored if --service is not specified",
+	)
+	debugCmd.AddCommand(devInterceptCmd)
+}
+
+var devInterceptCmd = &cobra.Command{
+	Use:   "intercept [NAME]",
+	Short: "Route traffic of a k8s service to local",
+	Long: `Route traffic of a k8s service to local.
+The service is redirected to a proxy inside the cluster, which tunnels connections
+through the k8s api server back to the local ports, until interrupted or 'nhctl dev end'`,
+	Example: `  nhctl dev intercept bookinfo -d reviews --service reviews -p 9080:9080`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		applicationName := args[0]
+		initAppAndCheckIfSvcExist(applicationName, deployment, serviceType)
+
+		if interceptOptions.Stop {
+			if interceptOptions.Service == "" {
+				must(nocalhostSvc.StopAllIntercepts())
+			} else {
+				must(nocalhostSvc.StopIntercept(interceptOptions.Service))
+			}
+			coloredoutput.Success("Intercept has been stopped")
+			return
+		}
+
+		if interceptOptions.Service == "" {
+			log.Fatal("please use --service to specify the k8s service to intercept")
+		}
+		localPorts := map[int]int{}
+		for _, port := range interceptOptions.Ports {
+			localPort, servicePort, err := controller.GetPortForwardForString(port)
+			must(err)
+			localPorts[servicePort] = localPort
+		}
+
+		tunnelPort, err := ports.GetAvailablePort()
+		must(err)
+
+		podName, interceptPorts, err := nocalhostSvc.Intercept(interceptOptions.Service)
+		must(err)
+		defer func() {
+			utils.ShouldI(
+				nocalhostSvc.StopIntercept(interceptOptions.Service),
+				fmt.Sprintf("Failed to restore service %s", interceptOptions.Service),
+			)
+		}()
+
+		targets := map[int]string{}
+		for _, port := range interceptPorts {
+			localPort, ok := localPorts[port.ServicePort]
+			if !ok {
+				localPort = port.ServicePort
+			}
+			delete(localPorts, port.ServicePort)
+			targets[port.ProxyPort] = fmt.Sprintf("127.0.0.1:%d", localPort)
+			log.Infof("Intercepting %s:%d -> 127.0.0.1:%d", interceptOptions.Service, port.ServicePort, localPort)
+		}
+		for servicePort := range localPorts {
+			log.Warnf("Port %d is not a tcp port of service %s, ignored", servicePort, interceptOptions.Service)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+			<-signals
+			cancel()
+		}()
+
+		go forwardTunnel(ctx, nocalhostApp, podName, tunnelPort, tunnel.DefaultTunnelPort)
+
+		client := &tunnel.Client{
+			DialTunnel: func() (net.Conn, error) {
+				conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", tunnelPort))
+				return conn, errors.Wrap(err, "")
+			},
+			Targets: targets,
+			OnConnected: func() {
+				coloredoutput.Success("Service %s is intercepted, press Ctrl+C to stop", interceptOptions.Service)
+			},
+		}
+		must(client.Run(ctx))
+	},
+}
+
+// forwardTunnel keeps the port-forward to tunnel helper pod until ctx is done
+func forwardTunnel(ctx context.Context, a *app.Application, podName string, localPort, remotePort int) {
+	for ctx.Err() == nil {
+		err := a.PortForwardFollow(podName, _const.DefaultPortForwardBindAddress, localPort, remotePort, nil)
+		if ctx.Err() != nil {
+			return
+		}
+		log.WarnE(err, fmt.Sprintf("Port-forward to %s is broken, retrying", podName))
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"nocalhost/internal/nhctl/coloredoutput"
 	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/dev_dir"
 	"nocalhost/internal/nhctl/model"
 	"nocalhost/internal/nhctl/nocalhost"
@@ -25,6 +26,7 @@ import (
 	"nocalhost/pkg/nhctl/log"
 	utils2 "nocalhost/pkg/nhctl/utils"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -37,6 +39,9 @@ var (
 
 var devStartOps = &model.DevStartOptions{}
 
+// CONTAINER=LOCAL_DIR of --extra-container
+var extraContainers []string
+
 func init() {
 
 	devStartCmd.Flags().StringVarP(
@@ -55,6 +60,18 @@ func init() {
 		&devStartOps.Container, "container", "c", "",
 		"container to develop",
 	)
+	devStartCmd.Flags().StringArrayVar(
+		&extraContainers, "extra-container", []string{},
+		"another container to develop in the same session and the local directory to sync to it, "+
+			"such as worker=/path/to/worker, can be specified multiple times",
+	)
+	devStartCmd.Flags().StringVar(
+		&devStartOps.Mode, "mode", controller.ReplaceDevMode, fmt.Sprintf(
+			"dev mode can be: %s, ephemeral mode attaches an ephemeral dev container to a running pod "+
+				"without changing the workload, which requires ephemeral containers enabled by k8s",
+			strings.Join(controller.DevModes, ", "),
+		),
+	)
 	//devStartCmd.Flags().StringVar(&devStartOps.WorkDir, "work-dir", "", "container's work directory")
 	devStartCmd.Flags().StringVar(&devStartOps.StorageClass, "storage-class", "", "StorageClass used by PV")
 	devStartCmd.Flags().StringVar(
@@ -97,6 +114,9 @@ var devStartCmd = &cobra.Command{
 		if len(args) < 1 {
 			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
 		}
+		if devStartOps.Mode != controller.ReplaceDevMode && devStartOps.Mode != controller.EphemeralDevMode {
+			return errors.Errorf("unsupported dev mode %s", devStartOps.Mode)
+		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -127,22 +147,27 @@ var devStartCmd = &cobra.Command{
 
 		} else {
 
-			// 1) reload svc config from local if needed
-			// 2) stop previous syncthing
-			// 3) recording profile
-			// 4) mark app meta as developing
-			// 5) initial syncthing runtime env
-			// 6) stop port-forward
-			// 7) enter developing (replace image)
-			// 8) port forward for dev-container
-			// 9) start syncthing
-			// 10) entering dev container
+			// 1) parse extra containers developed in the same session
+			// 2) reload svc config from local if needed
+			// 3) stop previous syncthing
+			// 4) recording profile
+			// 5) build dev image in cluster if dev.build is configured
+			// 6) mark app meta as developing
+			// 7) initial syncthing runtime env
+			// 8) stop port-forward
+			// 9) enter developing (replace image)
+			// 10) port forward for dev-container
+			// 11) start syncthing
+			// 12) run postStart hook after the first sync
+			// 13) entering dev container
 
 			coloredoutput.Hint("Starting DevMode...")
 
+			parseExtraContainers()
 			loadLocalOrCmConfigIfValid()
 			stopPreviousSyncthing()
 			recordingProfile()
+			buildDevImage()
 			podName := enterDevMode()
 
 			if !devStartOps.NoSyncthing {
@@ -150,6 +175,7 @@ var devStartCmd = &cobra.Command{
 			} else {
 				coloredoutput.Success("File sync is not started caused by --without-sync flag..")
 			}
+			runPostStartHook(podName, devStartOps.Container, !devStartOps.NoSyncthing)
 
 			if !devStartOps.NoTerminal || shell != "" {
 				must(nocalhostSvc.EnterPodTerminal(podName, devStartOps.Container, shell))
@@ -180,6 +206,9 @@ func recordingProfile() {
 				} else {
 					return errors.New("Can not define multi 'local-sync(-s)'")
 				}
+				svcProfile.DevContainer = devStartOps.Container
+				svcProfile.ExtraDevContainers = devStartOps.ExtraContainers
+				svcProfile.DevMode = devStartOps.Mode
 
 				p.GenerateIdentifierIfNeeded()
 				return nil
@@ -188,6 +217,46 @@ func recordingProfile() {
 	)
 }
 
+// parseExtraContainers parses --extra-container, the container developed must be specified by --container
+// while there are extra containers
+func parseExtraContainers() {
+	devStartOps.ExtraContainers = make([]*profile.ExtraDevContainer, 0, len(extraContainers))
+	if len(extraContainers) > 0 && devStartOps.Container == "" {
+		log.Fatal("'container(-c)' should specify while there are extra containers")
+	}
+	for _, e := range extraContainers {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			log.Fatalf("Invalid extra container %s, it should be CONTAINER=LOCAL_DIR", e)
+		}
+		dir, err := filepath.Abs(kv[1])
+		must(errors.Wrap(err, ""))
+		if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+			log.Fatalf("Local directory %s of extra container %s not found", dir, kv[0])
+		}
+		devStartOps.ExtraContainers = append(
+			devStartOps.ExtraContainers, &profile.ExtraDevContainer{Container: kv[0], LocalSyncDir: dir},
+		)
+	}
+}
+
+// buildDevImage builds the dev image from the Dockerfile in the sync dir if dev.build is configured,
+// the built image is used by the dev container instead of dev.image, so are the extra containers
+func buildDevImage() {
+	image, err := nocalhostSvc.BuildDevImage(devStartOps.Container, devStartOps.LocalSyncDir[0])
+	mustI(err, "Failed to build dev image")
+	devStartOps.DevImage = image
+
+	devStartOps.ExtraDevImages = map[string]string{}
+	for _, extra := range devStartOps.ExtraContainers {
+		image, err = nocalhostSvc.BuildDevImage(extra.Container, extra.LocalSyncDir)
+		mustI(err, "Failed to build dev image of container "+extra.Container)
+		if image != "" {
+			devStartOps.ExtraDevImages[extra.Container] = image
+		}
+	}
+}
+
 // when re enter dev mode, nocalhost will check the associate dir
 // nocalhost will load svc config from associate dir if needed
 func loadLocalOrCmConfigIfValid() {
@@ -236,12 +305,15 @@ func stopPreviousSyncthing() {
 func startSyncthing(podName, container string, resume bool) {
 	if resume {
 		StartSyncthing(podName, true, false, container, nil, false)
+		startPostSyncHook(container, false)
 		defer func() {
 			fmt.Println()
 			coloredoutput.Success("File sync resumed")
 		}()
 	} else {
 		StartSyncthing(podName, false, false, container, nil, false)
+		// the first sync is followed by postStart hook instead
+		startPostSyncHook(container, len(nocalhostSvc.LifecycleHook(container, controller.PostStartHook)) > 0)
 		defer func() {
 			fmt.Println()
 			coloredoutput.Success("File sync started")
@@ -305,7 +377,7 @@ func enterDevMode() string {
 	pfList := appProfile.SvcProfileV2(deployment, string(nocalhostSvc.Type)).DevPortForwardList
 	for _, pf := range pfList {
 		log.Infof("Stopping %d:%d", pf.LocalPort, pf.RemotePort)
-		utils.Should(nocalhostSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort))
+		utils.Should(nocalhostSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort, pf.Protocol))
 	}
 
 	if err = nocalhostSvc.BuildPodController().ReplaceImage(context.TODO(), devStartOps); err != nil {
@@ -331,7 +403,12 @@ func enterDevMode() string {
 	must(err)
 
 	for _, pf := range pfList {
-		utils.Should(nocalhostSvc.PortForward(podName, pf.LocalPort, pf.RemotePort, pf.Role))
+		_, err = nocalhostSvc.StartPortForward(
+			podName, pf.LocalPort, pf.RemotePort, pf.Role, &controller.PortForwardOptions{
+				Protocol: pf.Protocol, BindAddress: pf.BindAddress, PortPolicy: pf.PortPolicy,
+			},
+		)
+		utils.Should(err)
 	}
 	must(nocalhostSvc.PortForwardAfterDevStart(podName, devStartOps.Container))
 
@@ -35,8 +35,9 @@ func init() {
 	getCmd.PersistentFlags().StringVarP(
 		&appName, "application", "a", "", "application name",
 	)
-	getCmd.PersistentFlags().StringVarP(
-		&outputType, "outputType", "o", "", "json or yaml",
+	// -o is provided by the global --output flag
+	getCmd.PersistentFlags().StringVar(
+		&outputType, "outputType", "", "json or yaml",
 	)
 	getCmd.PersistentFlags().StringToStringVarP(
 		&label, "selector", "l", map[string]string{}, "Selector (label query) to filter on, "+
@@ -94,6 +95,7 @@ nhctl get service serviceName [-n namespace] --kubeconfig=kubeconfigfile
 		if err != nil {
 			log.FatalE(err, "")
 		}
+		defer cli.Close()
 		data, err := cli.SendGetResourceInfoCommand(kubeConfig, nameSpace, appName, resourceType, resourceName, label)
 		if err != nil {
 			log.FatalE(err, "")
@@ -102,6 +104,9 @@ nhctl get service serviceName [-n namespace] --kubeconfig=kubeconfigfile
 			return
 		}
 
+		if outputType == "" {
+			outputType = outputFormat
+		}
 		switch outputType {
 		case JSON:
 			out(json.Marshal, data)
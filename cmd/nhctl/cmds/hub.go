@@ -0,0 +1,53 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/hub"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"os"
+)
+
+const (
+	hubUrlEnv   = "NOCALHOST_HUB"
+	hubTokenEnv = "NOCALHOST_HUB_TOKEN"
+)
+
+var (
+	hubUrl   string
+	hubToken string
+)
+
+func init() {
+	hubCmd.PersistentFlags().StringVar(&hubUrl, "hub", "",
+		"url of the hub, such as https://nocalhost-api.example.com/v1/hub, or a directory, default is $"+
+			hubUrlEnv+" or the local hub")
+	hubCmd.PersistentFlags().StringVar(&hubToken, "token", "",
+		"token of nocalhost-api serving the hub, default is $"+hubTokenEnv)
+	rootCmd.AddCommand(hubCmd)
+}
+
+var hubCmd = &cobra.Command{
+	Use:   "hub",
+	Short: "Search, pull and publish shareable service configs",
+	Long:  `Search, pull and publish shareable service configs`,
+}
+
+// hubRepository returns the repository specified by --hub
+func hubRepository() (string, hub.Repository) {
+	url, token := hubUrl, hubToken
+	if url == "" {
+		url = os.Getenv(hubUrlEnv)
+	}
+	if url == "" {
+		url = nocalhost_path.GetNocalhostHubDir()
+	}
+	if token == "" {
+		token = os.Getenv(hubTokenEnv)
+	}
+	return url, hub.NewRepository(url, token)
+}
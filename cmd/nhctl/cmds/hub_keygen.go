@@ -0,0 +1,61 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/hub"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	hubCmd.AddCommand(hubKeygenCmd)
+	hubCmd.AddCommand(hubTrustCmd)
+}
+
+var hubKeygenCmd = &cobra.Command{
+	Use:   "keygen NAME",
+	Short: "Generate a key pair to sign service configs",
+	Long: `Generate a key pair to sign service configs, the private key is written to NAME.key
+and the public key is written to NAME.pub, which should be trusted by the consumers
+of the configs with nhctl hub trust`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyId, err := hub.GenerateKey(args[0])
+		must(err)
+		log.Infof("Key %s is generated to %s%s and %s%s", keyId, args[0], hub.PrivateKeySuffix, args[0], hub.PublicKeySuffix)
+	},
+}
+
+var hubTrustCmd = &cobra.Command{
+	Use:   "trust PUBLIC_KEY_FILE",
+	Short: "Trust configs signed by the key",
+	Long:  `Trust configs signed by the key, configs signed by untrusted keys can not be pulled`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		bys, err := ioutil.ReadFile(args[0])
+		must(errors.Wrap(err, ""))
+
+		dir := nocalhost_path.GetNocalhostHubTrustedKeysDir()
+		must(errors.Wrap(os.MkdirAll(dir, 0700), ""))
+		name := strings.TrimSuffix(filepath.Base(args[0]), hub.PublicKeySuffix) + hub.PublicKeySuffix
+		file := filepath.Join(dir, name)
+		must(errors.Wrap(ioutil.WriteFile(file, bys, 0644), ""))
+
+		// make sure the key is valid
+		if _, err = hub.TrustedKeys(); err != nil {
+			_ = os.Remove(file)
+			must(err)
+		}
+		log.Infof("Configs signed by %s are trusted", args[0])
+	},
+}
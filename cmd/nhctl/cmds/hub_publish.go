@@ -0,0 +1,89 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/hub"
+	"nocalhost/pkg/nhctl/log"
+)
+
+type HubPublishFlags struct {
+	Name        string
+	Version     string
+	Description string
+	Keywords    []string
+	Maintainers []string
+	Images      []string
+	ServiceType string
+	Key         string
+}
+
+var hubPublishFlags = HubPublishFlags{}
+
+func init() {
+	hubPublishCmd.Flags().StringVar(&hubPublishFlags.Name, "name", "", "name of the config")
+	hubPublishCmd.Flags().StringVar(&hubPublishFlags.Version, "version", "", "semantic version of the config")
+	hubPublishCmd.Flags().StringVar(&hubPublishFlags.Description, "description", "", "description of the config")
+	hubPublishCmd.Flags().StringSliceVar(&hubPublishFlags.Keywords, "keyword", nil, "keywords of the config")
+	hubPublishCmd.Flags().StringSliceVar(&hubPublishFlags.Maintainers, "maintainer", nil, "maintainers of the config")
+	hubPublishCmd.Flags().StringSliceVar(&hubPublishFlags.Images, "image", nil,
+		"images which the config fits, a glob pattern such as */bookinfo/reviews* or a substring of the image")
+	hubPublishCmd.Flags().StringVarP(&hubPublishFlags.ServiceType, "controller-type", "t", "",
+		"kind of k8s controller which the config fits, default is the serviceType of the config")
+	hubPublishCmd.Flags().StringVar(&hubPublishFlags.Key, "key", "",
+		"private key generated by `nhctl hub keygen` to sign the config, the config is only checksummed if not specified")
+	_ = hubPublishCmd.MarkFlagRequired("name")
+	_ = hubPublishCmd.MarkFlagRequired("version")
+	hubCmd.AddCommand(hubPublishCmd)
+}
+
+var hubPublishCmd = &cobra.Command{
+	Use:   "publish FILE",
+	Short: "Publish a service config to hub",
+	Long: `Publish a service config to hub, FILE is the config of a service, the same as the
+config in the annotation of a workload. Versions in hub are immutable`,
+	Example: `  nhctl hub publish reviews.yaml --name bookinfo-reviews --version 1.0.0 --image "*/bookinfo/reviews*" \
+    --key ~/.nh/publisher.key --hub https://nocalhost-api.example.com/v1/hub`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		content, err := ioutil.ReadFile(args[0])
+		must(errors.Wrap(err, ""))
+		svcConfig, err := hub.ParseSvcConfig(content)
+		mustI(err, "Invalid service config "+args[0])
+
+		entry := &hub.Entry{
+			Name:        hubPublishFlags.Name,
+			Version:     hubPublishFlags.Version,
+			Description: hubPublishFlags.Description,
+			Keywords:    hubPublishFlags.Keywords,
+			Maintainers: hubPublishFlags.Maintainers,
+			Images:      hubPublishFlags.Images,
+			ServiceType: hubPublishFlags.ServiceType,
+		}
+		if entry.ServiceType == "" {
+			entry.ServiceType = svcConfig.Type
+		}
+		if len(entry.Images) == 0 {
+			for _, c := range svcConfig.ContainerConfigs {
+				if c.Hub != nil && c.Hub.Image != "" {
+					entry.Images = append(entry.Images, c.Hub.Image)
+				}
+			}
+		}
+		if len(entry.Images) == 0 {
+			log.Warn("No image rule is specified, the config can only be pulled by name")
+		}
+		must(entry.Validate())
+		must(hub.Sign(entry, content, hubPublishFlags.Key))
+
+		url, repo := hubRepository()
+		must(repo.Publish(entry, content))
+		log.Infof("%s is published to %s, digest %s", entry, url, entry.Digest)
+	},
+}
@@ -0,0 +1,97 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"nocalhost/internal/nhctl/hub"
+	"nocalhost/pkg/nhctl/log"
+)
+
+type HubPullFlags struct {
+	CommonFlags
+	Container          string
+	LocalDir           string
+	SaveTo             string
+	Force              bool
+	InsecureSkipVerify bool
+}
+
+var hubPullFlags = HubPullFlags{}
+
+func init() {
+	hubPullCmd.Flags().StringVarP(&hubPullFlags.SvcName, "deployment", "d", "",
+		"k8s deployment which the config is applied to")
+	hubPullCmd.Flags().StringVarP(&serviceType, "controller-type", "t", "deployment",
+		"kind of k8s controller,such as deployment,statefulSet")
+	hubPullCmd.Flags().StringVarP(&hubPullFlags.Container, "container", "c", "",
+		"container which the config is applied to, if the config has only one container")
+	hubPullCmd.Flags().StringVar(&hubPullFlags.LocalDir, "local-dir", "",
+		"source directory of the service while saving to local, default is the associated directory or current directory")
+	hubPullCmd.Flags().StringVar(&hubPullFlags.SaveTo, "save-to", "",
+		"where to save the config, annotation of the workload or local(.nocalhost/config.yaml in the source "+
+			"directory), the runtime config of the service is updated if not specified")
+	hubPullCmd.Flags().BoolVar(&hubPullFlags.Force, "force", false,
+		"overwrite the existing .nocalhost/config.yaml while saving to local")
+	hubPullCmd.Flags().BoolVar(&hubPullFlags.InsecureSkipVerify, "insecure-skip-verify", false,
+		"skip verifying signature of the config, checksum is always verified")
+	hubCmd.AddCommand(hubPullCmd)
+}
+
+var hubPullCmd = &cobra.Command{
+	Use:   "pull NAME[@VERSION] [APP]",
+	Short: "Pull a service config from hub",
+	Long: `Pull a service config from hub, the checksum and signature of it are verified,
+it is printed if APP is not specified, VERSION is either an exact version or a
+constraint such as ^1.2, the latest version is pulled by default`,
+	Example: `  nhctl hub pull bookinfo-reviews@^1.0 bookinfo -d reviews --save-to local`,
+	Args:    cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, version := hub.ParseReference(args[0])
+		_, repo := hubRepository()
+		entry, svcConfig, err := hub.Pull(repo, name, version, hubPullFlags.InsecureSkipVerify)
+		must(err)
+		log.Infof("%s is pulled, digest %s", entry, entry.Digest)
+
+		if len(args) < 2 {
+			bys, err := yaml.Marshal(svcConfig)
+			must(errors.Wrap(err, "fail to marshal svc config"))
+			fmt.Print(string(bys))
+			return
+		}
+
+		hubPullFlags.AppName = args[1]
+		if hubPullFlags.SvcName == "" {
+			log.Fatal("--deployment required")
+		}
+		initAppAndCheckIfSvcExist(hubPullFlags.AppName, hubPullFlags.SvcName, serviceType)
+
+		if hubPullFlags.Container != "" && svcConfig.GetContainerConfig(hubPullFlags.Container) == nil {
+			if len(svcConfig.ContainerConfigs) != 1 {
+				log.Fatalf("container %s not found in %s", hubPullFlags.Container, entry)
+			}
+			svcConfig.ContainerConfigs[0].Name = hubPullFlags.Container
+		}
+		svcConfig.Name = nocalhostSvc.Name
+		svcConfig.Type = nocalhostSvc.Type.String()
+
+		if hubPullFlags.SaveTo == "" {
+			must(nocalhostSvc.UpdateConfig(*svcConfig))
+			log.Infof("Config of %s %s is updated by %s", nocalhostSvc.Type, nocalhostSvc.Name, entry)
+			return
+		}
+
+		bys, err := yaml.Marshal(svcConfig)
+		must(errors.Wrap(err, "fail to marshal svc config"))
+		saveSvcConfig(
+			bys, hubPullFlags.SaveTo, svcLocalDir(hubPullFlags.LocalDir, hubPullFlags.Container), hubPullFlags.Force,
+		)
+		must(nocalhostApp.ReloadSvcCfg(nocalhostSvc.Name, nocalhostSvc.Type, false, false))
+	},
+}
@@ -0,0 +1,50 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/output"
+)
+
+var hubSearchImage string
+
+func init() {
+	hubSearchCmd.Flags().StringVar(&hubSearchImage, "image", "", "search configs matching the image")
+	hubCmd.AddCommand(hubSearchCmd)
+}
+
+var hubSearchCmd = &cobra.Command{
+	Use:     "search [KEYWORD]",
+	Short:   "Search service configs in hub",
+	Long:    `Search service configs in hub by keyword in name, description and keywords, or by image`,
+	Example: `  nhctl hub search --image nocalhost-docker.pkg.coding.net/nocalhost/bookinfo/reviews:latest`,
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyword := ""
+		if len(args) > 0 {
+			keyword = args[0]
+		}
+
+		url, repo := hubRepository()
+		index, err := repo.Index()
+		must(err)
+
+		result := &output.HubConfigList{Items: make([]output.HubConfig, 0)}
+		for _, e := range index.Search(keyword, hubSearchImage) {
+			result.Items = append(
+				result.Items, output.HubConfig{
+					Name: e.Name, Version: e.Version, ServiceType: e.ServiceType, Images: e.Images,
+					Description: e.Description, KeyId: e.KeyId, Hub: url,
+				},
+			)
+		}
+		if outputFormat == "" {
+			outputFormat = string(output.Table)
+		}
+		printOutput(result)
+	},
+}
@@ -105,7 +105,7 @@ var InitCommand = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		kubectl, err := tools.CheckThirdPartyCLI()
-		mustI(err, "you should install them first(helm3 and kubectl)")
+		mustI(err, "you should install kubectl first")
 		must(Prepare())
 
 		// init api and web
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"nocalhost/internal/nhctl/app_flags"
+	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
 
 	"github.com/pkg/errors"
@@ -51,20 +52,34 @@ func init() {
 		&installFlags.Config, "config", "",
 		"specify a config relative to .nocalhost dir",
 	)
+	installCmd.Flags().StringSliceVar(
+		&installFlags.ConfigOverlays, "config-overlay", []string{},
+		"overlays merged into the config in order, such as staging for config.staging.yaml next to the config",
+	)
 	installCmd.Flags().StringArrayVarP(
 		&installFlags.HelmValueFile, "helm-values", "f", []string{},
 		"helm's Value.yaml",
 	)
 	installCmd.Flags().StringVarP(
 		&installFlags.AppType, "type", "t", "", fmt.Sprintf(
-			"nocalhost application type: %s, %s, %s, %s, %s, %s or %s",
+			"nocalhost application type: %s, %s, %s, %s, %s, %s, %s, %s, %s or %s",
 			appmeta.HelmRepo, appmeta.Helm, appmeta.HelmLocal,
 			appmeta.Manifest, appmeta.ManifestGit, appmeta.ManifestLocal, appmeta.KustomizeGit,
+			appmeta.JsonnetGit, appmeta.JsonnetLocal, appmeta.CueLocal,
 		),
 	)
 	installCmd.Flags().BoolVar(
 		&installFlags.HelmWait, "wait", installFlags.HelmWait,
-		"wait for completion",
+		"wait for Deployments, StatefulSets, DaemonSets and Jobs to be ready, "+
+			"failing pods will be reported on timeout",
+	)
+	installCmd.Flags().DurationVar(
+		&installFlags.Timeout, "timeout", clientgoutils.DefaultWaitReadyTimeout,
+		"time to wait for workloads to be ready, used with --wait",
+	)
+	installCmd.Flags().BoolVar(
+		&installFlags.HelmAtomic, "atomic", installFlags.HelmAtomic,
+		"if set, helm application will be rolled back on failure, --wait will be set automatically",
 	)
 	installCmd.Flags().BoolVar(
 		&installFlags.IgnorePreInstall, "ignore-pre-install", installFlags.IgnorePreInstall,
@@ -95,6 +110,10 @@ func init() {
 		&installFlags.LocalPath, "local-path", "",
 		"local path for application",
 	)
+	installCmd.Flags().BoolVar(
+		&installFlags.WithDeps, "with-deps", installFlags.WithDeps,
+		"install the applications specified in dependsOn which are not installed yet",
+	)
 	rootCmd.AddCommand(installCmd)
 }
 
@@ -113,6 +132,7 @@ var installCmd = &cobra.Command{
 			err             error
 			applicationName = args[0]
 		)
+		installFlags.TimeoutSet = cmd.Flags().Changed("timeout")
 
 		must(Prepare())
 
@@ -124,7 +144,9 @@ var installCmd = &cobra.Command{
 		if installFlags.GitUrl == "" && (installFlags.AppType != string(appmeta.HelmRepo) &&
 			installFlags.AppType != string(appmeta.ManifestLocal) &&
 			installFlags.AppType != string(appmeta.HelmLocal) &&
-			installFlags.AppType != string(appmeta.KustomizeLocal)) {
+			installFlags.AppType != string(appmeta.KustomizeLocal) &&
+			installFlags.AppType != string(appmeta.JsonnetLocal) &&
+			installFlags.AppType != string(appmeta.CueLocal)) {
 			log.Fatalf("If app type is not %s , --git-url must be specified", appmeta.HelmRepo)
 		}
 		if installFlags.AppType == string(appmeta.HelmRepo) {
@@ -140,7 +162,11 @@ var installCmd = &cobra.Command{
 		}
 
 		log.Info("Installing application...")
-		nocalhostApp, err = common.InstallApplication(installFlags, applicationName, kubeConfig, nameSpace)
+		if installFlags.WithDeps {
+			nocalhostApp, err = common.InstallApplicationWithDeps(installFlags, applicationName, kubeConfig, nameSpace)
+		} else {
+			nocalhostApp, err = common.InstallApplication(installFlags, applicationName, kubeConfig, nameSpace)
+		}
 		must(err)
 		log.Infof("Application %s installed", applicationName)
 
@@ -190,13 +216,16 @@ var installCmd = &cobra.Command{
 				}
 
 				for _, pf := range cc.Install.PortForward {
-					lPort, rPort, err := controller.GetPortForwardForString(pf)
+					lPort, rPort, protocol, err := controller.ParsePortForward(pf)
 					if err != nil {
 						log.WarnE(err, "")
 						continue
 					}
-					log.Infof("Port forward %d:%d", lPort, rPort)
-					utils.Should(nhSvc.PortForward(podName, lPort, rPort, ""))
+					log.Infof("Port forward %s", pf)
+					_, err = nhSvc.StartPortForward(
+						podName, lPort, rPort, "", &controller.PortForwardOptions{Protocol: protocol},
+					)
+					utils.Should(err)
 				}
 			}
 		}
@@ -28,6 +28,7 @@ var kubeconfigAddCmd = &cobra.Command{
 		if err != nil {
 			log.FatalE(err, "")
 		}
+		defer daemonClient.Close()
 		if err := Prepare(); err != nil {
 			return
 		}
@@ -29,6 +29,7 @@ var kubeconfigRemoveCmd = &cobra.Command{
 		if err != nil {
 			log.FatalE(err, "")
 		}
+		defer daemonClient.Close()
 		// can set kubeConfig default value, but effect too much
 		if len(kubeConfig) == 0 {
 			kubeConfig = clientcmd.RecommendedHomeFile
@@ -15,6 +15,7 @@ import (
 	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/daemon_handler"
 	"nocalhost/internal/nhctl/model"
+	"nocalhost/internal/nhctl/output"
 	"nocalhost/pkg/nhctl/log"
 	"os"
 	"strconv"
@@ -45,11 +46,17 @@ var listCmd = &cobra.Command{
 		if len(args) > 0 { // list application detail
 			applicationName := args[0]
 			initApp(applicationName)
-			ListApplicationSvc(nocalhostApp)
+			if outputFormat != "" {
+				printOutput(applicationSvcOutput(nocalhostApp))
+			} else {
+				ListApplicationSvc(nocalhostApp)
+			}
 			os.Exit(0)
 		}
 
-		if listFlags.Yaml {
+		if outputFormat != "" {
+			printOutput(applicationsOutput())
+		} else if listFlags.Yaml {
 			ListApplicationsYaml()
 		} else if listFlags.Json {
 			ListApplicationsJson()
@@ -82,6 +89,53 @@ func ListApplicationSvc(napp *app.Application) {
 	table.Render() // Send output
 }
 
+func applicationSvcOutput(napp *app.Application) *output.ServiceList {
+	appProfile, err := napp.GetProfile()
+	must(err)
+
+	result := &output.ServiceList{Application: napp.Name, Items: make([]output.Service, 0)}
+	for _, svcProfile := range appProfile.SvcProfile {
+		portForwards := make([]string, 0)
+		for _, pf := range svcProfile.DevPortForwardList {
+			portForwards = append(portForwards, fmt.Sprintf("%d:%d", pf.LocalPort, pf.RemotePort))
+		}
+		syncPaths := svcProfile.LocalAbsoluteSyncDirFromDevStartPlugin
+		if syncPaths == nil {
+			syncPaths = []string{}
+		}
+		result.Items = append(
+			result.Items, output.Service{
+				Name:                  svcProfile.GetName(),
+				Type:                  svcProfile.GetType(),
+				Developing:            svcProfile.Developing,
+				Syncing:               svcProfile.Syncing,
+				DevPortForwards:       portForwards,
+				SyncPaths:             syncPaths,
+				LocalSyncthingGUIPort: svcProfile.LocalSyncthingGUIPort,
+			},
+		)
+	}
+	return result
+}
+
+func applicationsOutput() *output.ApplicationList {
+	metas, err := DoGetApplicationMetas()
+	must(err)
+
+	result := &output.ApplicationList{Items: make([]output.Application, 0)}
+	for _, meta := range metas {
+		result.Items = append(
+			result.Items, output.Application{
+				Name:      meta.Application,
+				Namespace: meta.Ns,
+				State:     string(meta.ApplicationState),
+				Type:      string(meta.ApplicationType),
+			},
+		)
+	}
+	return result
+}
+
 func ListApplicationsResult() []*model.Namespace {
 	metas, err := DoGetApplicationMetas()
 	must(err)
@@ -0,0 +1,22 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"os"
+
+	"nocalhost/internal/nhctl/output"
+)
+
+// outputFormat is specified by the global -o flag, read commands print
+// versioned schemas defined in package output while it is not empty
+var outputFormat string
+
+func printOutput(obj output.Object) {
+	format, err := output.ParseFormat(outputFormat)
+	must(err)
+	must(output.Print(os.Stdout, format, obj))
+}
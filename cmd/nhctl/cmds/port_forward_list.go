@@ -11,11 +11,21 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"nocalhost/internal/nhctl/daemon_client"
+	"nocalhost/internal/nhctl/output"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
 )
 
+var portForwardListStats bool
+
 func init() {
 	portForwardListCmd.Flags().BoolVar(&listFlags.Yaml, "yaml", false, "use yaml as out put")
 	portForwardListCmd.Flags().BoolVar(&listFlags.Json, "json", false, "use json as out put")
+	portForwardListCmd.Flags().BoolVar(
+		&portForwardListStats, "stats", false, "show traffic stats of running port-forward from daemon",
+	)
 	PortForwardCmd.AddCommand(portForwardListCmd)
 }
 
@@ -29,6 +39,48 @@ type PortForwardItem struct {
 	DaemonServerPid int    `json:"daemonserverpid" yaml:"daemonserverpid"`
 	Updated         string `json:"updated" yaml:"updated"`
 	Reason          string `json:"reason" yaml:"reason"`
+
+	Stats *clientgoutils.PortForwardStats `json:"stats,omitempty" yaml:"stats,omitempty"`
+}
+
+func portForwardStatsKey(svcType, svcName string, localPort, remotePort int, protocol string) string {
+	if protocol != profile.PortForwardProtocolUDP {
+		protocol = profile.PortForwardProtocolTCP
+	}
+	return fmt.Sprintf("%s/%s/%d:%d/%s", svcType, svcName, localPort, remotePort, protocol)
+}
+
+// getPortForwardStats queries traffic stats of the application's port-forwards from daemon,
+// sudo daemon is only asked while some port-forward is running in it
+func getPortForwardStats(p *profile.AppProfileV2) map[string]*clientgoutils.PortForwardStats {
+	stats := make(map[string]*clientgoutils.PortForwardStats)
+	sudo := false
+	for _, sp := range p.SvcProfile {
+		for _, pf := range sp.DevPortForwardList {
+			sudo = sudo || pf.Sudo
+		}
+	}
+	isSudoList := []bool{false}
+	if sudo {
+		isSudoList = append(isSudoList, true)
+	}
+	for _, isSudo := range isSudoList {
+		client, err := daemon_client.NewDaemonClient(isSudo)
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to connect to daemon(sudo:%t)", isSudo))
+			continue
+		}
+		pfList, err := client.SendGetPortForwardStatsCommand(nocalhostApp.NameSpace, nocalhostApp.Name)
+		_ = client.Close()
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to get port-forward stats from daemon(sudo:%t)", isSudo))
+			continue
+		}
+		for _, pf := range pfList {
+			stats[portForwardStatsKey(pf.SvcType, pf.SvcName, pf.LocalPort, pf.RemotePort, pf.Protocol)] = pf.Stats
+		}
+	}
+	return stats
 }
 
 var portForwardListCmd = &cobra.Command{
@@ -48,19 +100,68 @@ var portForwardListCmd = &cobra.Command{
 		p, err := nocalhostApp.GetProfile()
 		must(err)
 
+		var stats map[string]*clientgoutils.PortForwardStats
+		if portForwardListStats {
+			stats = getPortForwardStats(p)
+		}
+		statsOf := func(sp *profile.SvcProfileV2, pf *profile.DevPortForward) *clientgoutils.PortForwardStats {
+			return stats[portForwardStatsKey(sp.GetType(), sp.GetName(), pf.LocalPort, pf.RemotePort, pf.Protocol)]
+		}
+
+		if outputFormat != "" {
+			result := &output.PortForwardList{Application: applicationName, Items: make([]output.PortForward, 0)}
+			for _, sp := range p.SvcProfile {
+				for _, pf := range sp.DevPortForwardList {
+					item := output.PortForward{
+						Service:         sp.GetName(),
+						ServiceType:     sp.GetType(),
+						LocalPort:       pf.LocalPort,
+						RemotePort:      pf.RemotePort,
+						Protocol:        pf.Protocol,
+						BindAddress:     pf.BindAddress,
+						Status:          pf.Status,
+						Role:            pf.Role,
+						Sudo:            pf.Sudo,
+						DaemonServerPid: pf.DaemonServerPid,
+						Updated:         pf.Updated,
+						Reason:          pf.Reason,
+					}
+					if s := statsOf(sp, pf); s != nil {
+						item.Stats = &output.PortForwardStats{
+							BytesIn:           s.BytesIn,
+							BytesOut:          s.BytesOut,
+							ActiveConnections: s.ActiveConnections,
+							TotalConnections:  s.TotalConnections,
+							Reconnects:        s.Reconnects,
+							LastError:         s.LastError,
+							LastErrorTime:     s.LastErrorTime,
+						}
+					}
+					result.Items = append(result.Items, item)
+				}
+			}
+			printOutput(result)
+			return
+		}
+
 		pfList := make([]PortForwardItem, 0)
 		for _, sp := range p.SvcProfile {
 			for _, pf := range sp.DevPortForwardList {
+				port := fmt.Sprintf("%d:%d", pf.LocalPort, pf.RemotePort)
+				if pf.IsUDP() {
+					port += "/" + profile.PortForwardProtocolUDP
+				}
 				pfList = append(pfList, PortForwardItem{
 					SvcName:         sp.GetName(),
 					ServiceType:     sp.GetType(),
-					Port:            fmt.Sprintf("%d:%d", pf.LocalPort, pf.RemotePort),
+					Port:            port,
 					Status:          pf.Status,
 					Role:            pf.Role,
 					Sudo:            pf.Sudo,
 					DaemonServerPid: pf.DaemonServerPid,
 					Updated:         pf.Updated,
 					Reason:          pf.Reason,
+					Stats:           statsOf(sp, pf),
 				})
 			}
 		}
@@ -6,10 +6,15 @@
 package cmds
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"nocalhost/internal/nhctl/app"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/profile"
 	"nocalhost/pkg/nhctl/log"
 )
 
@@ -21,7 +26,7 @@ func init() {
 	)
 	portForwardStartCmd.Flags().StringSliceVarP(
 		&portForwardOptions.DevPort, "dev-port", "p", []string{},
-		"port-forward between pod and local, such 8080:8080 or :8080(random localPort)",
+		"port-forward between pod and local, such 8080:8080, :8080(random localPort) or 8125:8125/udp",
 	)
 	//portForwardStartCmd.Flags().BoolVarP(&portForwardOptions.RunAsDaemon,
 	// "daemon", "m", true, "if port-forward run as daemon")
@@ -33,6 +38,11 @@ func init() {
 		&portForwardOptions.PodName, "pod", "", "",
 		"specify pod name",
 	)
+	portForwardStartCmd.Flags().StringVar(
+		&portForwardOptions.Service, "service", "",
+		"forward to a k8s Service, remote port is the port of service, "+
+			"the local port keeps listening while pods behind the service change",
+	)
 	portForwardStartCmd.Flags().StringVarP(
 		&container, "container", "c", "",
 		"which container of pod to run command",
@@ -49,6 +59,15 @@ func init() {
 		&portForwardOptions.Follow, "follow", "", false,
 		"stock here waiting for disconnect or return immediately",
 	)
+	portForwardStartCmd.Flags().StringVar(
+		&portForwardOptions.BindAddress, "address", _const.DefaultPortForwardBindAddress,
+		"local address to listen on, 0.0.0.0 exposes the port to other hosts",
+	)
+	portForwardStartCmd.Flags().StringVar(
+		&portForwardOptions.PortPolicy, "port-policy", profile.PortPolicyFixed,
+		"policy while local port is unavailable, fixed fails and next-free listens on the next free port, "+
+			"only works without --follow",
+	)
 	PortForwardCmd.AddCommand(portForwardStartCmd)
 }
 
@@ -65,6 +84,11 @@ var portForwardStartCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 
 		applicationName := args[0]
+		if portForwardOptions.Service != "" {
+			startServicePortForward(applicationName)
+			return
+		}
+		checkPortPolicy()
 		initAppAndCheckIfSvcExist(applicationName, deployment, serviceType)
 
 		log.Info("Starting port-forwarding")
@@ -78,22 +102,93 @@ var portForwardStartCmd = &cobra.Command{
 		}
 
 		var localPorts, remotePorts []int
+		var protocols []string
 		for _, port := range portForwardOptions.DevPort {
-			localPort, remotePort, err := controller.GetPortForwardForString(port)
+			localPort, remotePort, protocol, err := controller.ParsePortForward(port)
 			if err != nil {
 				log.WarnE(err, "")
 				continue
 			}
 			localPorts = append(localPorts, localPort)
 			remotePorts = append(remotePorts, remotePort)
+			protocols = append(protocols, protocol)
 		}
 
 		for index, localPort := range localPorts {
 			if portForwardOptions.Follow {
-				must(nocalhostApp.PortForwardFollow(podName, localPort, remotePorts[index], nil))
+				if protocols[index] == profile.PortForwardProtocolUDP {
+					must(
+						nocalhostApp.UDPPortForwardFollow(
+							context.Background(), podName, portForwardOptions.BindAddress,
+							localPort, remotePorts[index], nil,
+						),
+					)
+				} else {
+					must(
+						nocalhostApp.PortForwardFollow(
+							podName, portForwardOptions.BindAddress, localPort, remotePorts[index], nil,
+						),
+					)
+				}
 			} else {
-				must(nocalhostSvc.PortForward(podName, localPort, remotePorts[index], ""))
+				_, err = nocalhostSvc.StartPortForward(
+					podName, localPort, remotePorts[index], "", &controller.PortForwardOptions{
+						Protocol:    protocols[index],
+						BindAddress: portForwardOptions.BindAddress,
+						PortPolicy:  portForwardOptions.PortPolicy,
+					},
+				)
+				must(err)
 			}
 		}
 	},
 }
+
+func checkPortPolicy() {
+	if portForwardOptions.PortPolicy != profile.PortPolicyFixed &&
+		portForwardOptions.PortPolicy != profile.PortPolicyNextFree {
+		log.Fatalf(
+			"Unsupported port policy %s, should be %s or %s",
+			portForwardOptions.PortPolicy, profile.PortPolicyFixed, profile.PortPolicyNextFree,
+		)
+	}
+}
+
+func startServicePortForward(applicationName string) {
+	checkPortPolicy()
+	initApp(applicationName)
+
+	// port-forward is recorded in profile of the workload, use the service name if no workload specified
+	if deployment == "" {
+		deployment = portForwardOptions.Service
+	}
+	nocalhostSvc = initService(deployment, serviceType)
+
+	_, err := nocalhostApp.GetClient().GetService(portForwardOptions.Service)
+	mustI(err, fmt.Sprintf("Service %s not found", portForwardOptions.Service))
+
+	log.Infof("Starting port-forwarding to service %s", portForwardOptions.Service)
+	for _, port := range portForwardOptions.DevPort {
+		localPort, servicePort, err := controller.GetPortForwardForString(port)
+		if err != nil {
+			log.WarnE(err, "")
+			continue
+		}
+		if portForwardOptions.Follow {
+			must(
+				nocalhostApp.ServicePortForwardFollow(
+					context.Background(), portForwardOptions.Service, portForwardOptions.BindAddress,
+					localPort, servicePort, nil,
+				),
+			)
+		} else {
+			_, err = nocalhostSvc.PortForwardService(
+				portForwardOptions.Service, localPort, servicePort, "", &controller.PortForwardOptions{
+					BindAddress: portForwardOptions.BindAddress,
+					PortPolicy:  portForwardOptions.PortPolicy,
+				},
+			)
+			must(err)
+		}
+	}
+}
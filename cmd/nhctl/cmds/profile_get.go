@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/output"
 	"nocalhost/internal/nhctl/profile"
 	"nocalhost/pkg/nhctl/log"
 )
@@ -52,7 +53,7 @@ var profileGetCmd = &cobra.Command{
 			for _, c := range p.ContainerConfigs {
 				if c.Name == container {
 					if c.Dev != nil && c.Dev.Image != "" {
-						fmt.Printf(`{"image": "%s"}`, c.Dev.Image)
+						printProfileValue(configKey, c.Dev.Image)
 					}
 					return
 				} else if c.Name == "" {
@@ -72,10 +73,18 @@ var profileGetCmd = &cobra.Command{
 					defaultContainerConfig.Name = container // setting container name
 				}
 				must(nocalhostSvc.UpdateConfig(*p))
-				fmt.Printf(`{"image": "%s"}`, defaultContainerConfig.Dev.Image)
+				printProfileValue(configKey, defaultContainerConfig.Dev.Image)
 			}
 		default:
 			log.Fatalf("Getting config key %s is unsupported", configKey)
 		}
 	},
 }
+
+func printProfileValue(key, value string) {
+	if outputFormat != "" {
+		printOutput(&output.ProfileValue{Service: deployment, Container: container, Key: key, Value: value})
+		return
+	}
+	fmt.Printf(`{"%s": "%s"}`, key, value)
+}
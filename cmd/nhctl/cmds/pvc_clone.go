@@ -0,0 +1,65 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/pvc"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+type PVCCloneFlags struct {
+	From           string
+	FromNamespace  string
+	FromKubeConfig string
+	App            string
+	Svc            string
+	StorageClass   string
+}
+
+var pvcCloneFlags = PVCCloneFlags{}
+
+func init() {
+	pvcCloneCmd.Flags().StringVar(&pvcCloneFlags.From, "from", "", "PVC to clone from")
+	pvcCloneCmd.Flags().StringVar(&pvcCloneFlags.FromNamespace, "from-namespace", "",
+		"namespace of the PVC to clone from, default is the namespace of --from-kubeconfig")
+	pvcCloneCmd.Flags().StringVar(&pvcCloneFlags.FromKubeConfig, "from-kubeconfig", "",
+		"kubeconfig of the dev space to clone from, default is --kubeconfig")
+	pvcCloneCmd.Flags().StringVar(&pvcCloneFlags.App, "app", "",
+		"application of the cloned PVC, default is the application of the source PVC")
+	pvcCloneCmd.Flags().StringVar(&pvcCloneFlags.Svc, "controller", "",
+		"service of the cloned PVC, default is the service of the source PVC")
+	pvcCloneCmd.Flags().StringVar(&pvcCloneFlags.StorageClass, "storage-class", "",
+		"storage class of the cloned PVC if it's created, default is the default storage class")
+	_ = pvcCloneCmd.MarkFlagRequired("from")
+	pvcCmd.AddCommand(pvcCloneCmd)
+}
+
+var pvcCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone a PersistVolumeClaim from another dev space",
+	Long: `Clone a persistent volume dir of a service from another dev space, so dev mode starts
+with a warm cache. The content is copied to the PVC of the same persistent volume dir, which
+is created if not exists. The existing PVC must not be used by any pod`,
+	Example: `  nhctl pvc clone --from bookinfo-1624867534181409000 --from-namespace alice-dev -n bob-dev`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := clientgoutils.NewClientGoUtils(kubeConfig, nameSpace)
+		must(err)
+		fromKubeConfig := pvcCloneFlags.FromKubeConfig
+		if fromKubeConfig == "" {
+			fromKubeConfig = kubeConfig
+		}
+		fromClient, err := clientgoutils.NewClientGoUtils(fromKubeConfig, pvcCloneFlags.FromNamespace)
+		must(err)
+
+		cloned, err := pvc.Clone(
+			fromClient, pvcCloneFlags.From, client, pvcCloneFlags.App, pvcCloneFlags.Svc, pvcCloneFlags.StorageClass,
+		)
+		mustI(err, "Failed to clone pvc "+pvcCloneFlags.From)
+		log.Infof("Pvc %s is cloned to %s", pvcCloneFlags.From, cloned)
+	},
+}
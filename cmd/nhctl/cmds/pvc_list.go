@@ -12,6 +12,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"nocalhost/internal/nhctl/common/base"
 	"nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/output"
 	"nocalhost/pkg/nhctl/clientgoutils"
 
 	"github.com/spf13/cobra"
@@ -69,7 +70,13 @@ var pvcListCmd = &cobra.Command{
 			must(err)
 		}
 
-		if pvcFlags.Yaml {
+		if outputFormat != "" {
+			result := &output.PVCList{Items: make([]output.PVC, 0)}
+			for _, pvc := range makePVCObjectList(pvcList) {
+				result.Items = append(result.Items, output.PVC(*pvc))
+			}
+			printOutput(result)
+		} else if pvcFlags.Yaml {
 			DisplayPVCsByYaml(pvcList)
 		} else if pvcFlags.Json {
 			DisplayPVCsByJson(pvcList)
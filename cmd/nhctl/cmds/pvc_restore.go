@@ -0,0 +1,41 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/pvc"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+func init() {
+	pvcRestoreCmd.Flags().StringVar(&pvcSnapshotFlags.Name, "name", "", "PVC to restore")
+	pvcRestoreCmd.Flags().StringVar(&pvcSnapshotFlags.Snapshot, "snapshot", "", "snapshot to restore from")
+	_ = pvcRestoreCmd.MarkFlagRequired("name")
+	_ = pvcRestoreCmd.MarkFlagRequired("snapshot")
+	pvcCmd.AddCommand(pvcRestoreCmd)
+}
+
+var pvcRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a PersistVolumeClaim from a snapshot",
+	Long: `Restore a PersistVolumeClaim from a snapshot, the PVC must not be used by any pod.
+The PVC is replaced by a new one with the same labels if the snapshot is a CSI VolumeSnapshot,
+otherwise the content of the PVC is replaced in place`,
+	Example: `  nhctl pvc restore --name bookinfo-1624867534181409000 --snapshot reviews-m2-cache`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := clientgoutils.NewClientGoUtils(kubeConfig, nameSpace)
+		must(err)
+		restored, err := pvc.RestoreSnapshot(client, pvcSnapshotFlags.Snapshot, pvcSnapshotFlags.Name)
+		mustI(err, "Failed to restore pvc "+pvcSnapshotFlags.Name)
+		if restored != pvcSnapshotFlags.Name {
+			log.Infof("Pvc %s is replaced by %s restored from %s", pvcSnapshotFlags.Name, restored, pvcSnapshotFlags.Snapshot)
+			return
+		}
+		log.Infof("Pvc %s is restored from %s", restored, pvcSnapshotFlags.Snapshot)
+	},
+}
@@ -0,0 +1,50 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/pvc"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+type PVCSnapshotFlags struct {
+	Name      string
+	Snapshot  string
+	ForceCopy bool
+}
+
+var pvcSnapshotFlags = PVCSnapshotFlags{}
+
+func init() {
+	pvcSnapshotCmd.Flags().StringVar(&pvcSnapshotFlags.Name, "name", "", "PVC to snapshot")
+	pvcSnapshotCmd.Flags().StringVar(&pvcSnapshotFlags.Snapshot, "snapshot", "",
+		"name of the snapshot, default is <PVC>-snapshot-<timestamp>")
+	pvcSnapshotCmd.Flags().BoolVar(&pvcSnapshotFlags.ForceCopy, "copy", false,
+		"copy the content to a snapshot PVC even if CSI snapshot is supported")
+	_ = pvcSnapshotCmd.MarkFlagRequired("name")
+	pvcCmd.AddCommand(pvcSnapshotCmd)
+}
+
+var pvcSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Snapshot a PersistVolumeClaim",
+	Long: `Snapshot a PersistVolumeClaim. A CSI VolumeSnapshot is created if the storage class
+of the PVC supports, otherwise the content is copied to a snapshot PVC through helper pods`,
+	Example: `  nhctl pvc snapshot --name bookinfo-1624867534181409000 --snapshot reviews-m2-cache`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := clientgoutils.NewClientGoUtils(kubeConfig, nameSpace)
+		must(err)
+		snapshot, err := pvc.CreateSnapshot(client, pvcSnapshotFlags.Name, pvcSnapshotFlags.Snapshot, pvcSnapshotFlags.ForceCopy)
+		mustI(err, "Failed to snapshot pvc "+pvcSnapshotFlags.Name)
+		if snapshot.CSI {
+			log.Infof("VolumeSnapshot %s of pvc %s is ready", snapshot.Name, snapshot.Source)
+		} else {
+			log.Infof("Snapshot pvc %s of pvc %s is ready", snapshot.Name, snapshot.Source)
+		}
+	},
+}
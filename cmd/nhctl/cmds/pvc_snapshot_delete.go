@@ -0,0 +1,40 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/pvc"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+func init() {
+	pvcSnapshotCmd.AddCommand(pvcSnapshotDeleteCmd)
+}
+
+var pvcSnapshotDeleteCmd = &cobra.Command{
+	Use:     "delete [SNAPSHOT]",
+	Aliases: []string{"rm"},
+	Short:   "Delete a snapshot of PersistVolumeClaim",
+	Long:    `Delete a VolumeSnapshot or a snapshot PVC created by nhctl`,
+	Example: `  nhctl pvc snapshot delete reviews-m2-cache`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.Errorf("%q requires at least 1 argument\n", cmd.CommandPath())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := clientgoutils.NewClientGoUtils(kubeConfig, nameSpace)
+		must(err)
+		for _, name := range args {
+			mustI(pvc.DeleteSnapshot(client, name), "Failed to delete snapshot "+name)
+			log.Infof("Snapshot %s has been deleted", name)
+		}
+	},
+}
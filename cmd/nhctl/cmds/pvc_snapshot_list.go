@@ -0,0 +1,38 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/pvc"
+	"nocalhost/pkg/nhctl/clientgoutils"
+)
+
+func init() {
+	pvcSnapshotCmd.AddCommand(pvcSnapshotListCmd)
+}
+
+var pvcSnapshotListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List snapshots of PersistVolumeClaims",
+	Long:    `List VolumeSnapshots and snapshot PVCs created by nhctl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := clientgoutils.NewClientGoUtils(kubeConfig, nameSpace)
+		must(err)
+		snapshots, err := pvc.ListSnapshots(client)
+		mustI(err, "Failed to list snapshots")
+		fmt.Println("Snapshot Source Type")
+		for _, snapshot := range snapshots {
+			snapshotType := "PVC"
+			if snapshot.CSI {
+				snapshotType = "VolumeSnapshot"
+			}
+			fmt.Printf("%s %s %s\n", snapshot.Name, snapshot.Source, snapshotType)
+		}
+	},
+}
@@ -17,6 +17,7 @@ import (
 	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/nocalhost"
 	"nocalhost/internal/nhctl/nocalhost_path"
+	"nocalhost/internal/nhctl/output"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
 	"os"
@@ -58,6 +59,10 @@ func init() {
 		&kubeConfig, "kubeconfig", "",
 		"the path of the kubeconfig file",
 	)
+	rootCmd.PersistentFlags().StringVarP(
+		&outputFormat, "output", "o", "",
+		"output format of read commands: json, yaml or table",
+	)
 
 }
 
@@ -104,6 +109,12 @@ var rootCmd = &cobra.Command{
 		}
 		serviceType = strings.ToLower(serviceType)
 
+		if outputFormat != "" {
+			if _, err = output.ParseFormat(outputFormat); err != nil {
+				log.FatalE(err, "")
+			}
+		}
+
 		if authCheck {
 
 			must(Prepare())
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"nocalhost/internal/nhctl/app"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/syncthing/ports"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
@@ -43,7 +44,7 @@ var sshRCmd = &cobra.Command{
 		}
 		okchan := make(chan struct{})
 		go func() {
-			if err := app.PortForwardFollow(podName, port, podSshPort, okchan); err != nil {
+			if err := app.PortForwardFollow(podName, _const.DefaultPortForwardBindAddress, port, podSshPort, okchan); err != nil {
 				log.Fatal(err)
 			}
 		}()
@@ -76,6 +76,9 @@ var fileSyncCmd = &cobra.Command{
 			"", fileSyncOps.Resume, fileSyncOps.Stop, fileSyncOps.Container,
 			&fileSyncOps.SyncDouble, fileSyncOps.Override,
 		)
+		if !fileSyncOps.Stop {
+			startPostSyncHook(fileSyncOps.Container, false)
+		}
 	},
 }
 
@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/util/retry"
 	"nocalhost/internal/nhctl/app"
 	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/output"
 	"nocalhost/internal/nhctl/syncthing/network/req"
 	"nocalhost/pkg/nhctl/log"
 	"time"
@@ -64,7 +65,15 @@ var syncStatusCmd = &cobra.Command{
 		}
 
 		if data := SyncStatus(syncStatusOps, nameSpace, args[0], deployment, serviceType, kubeConfig); data != nil {
-			display(data)
+			if outputFormat != "" {
+				printOutput(
+					&output.SyncStatus{
+						Status: string(data.Status), Msg: data.Msg, Tips: data.Tips, OutOfSync: data.OutOfSync,
+					},
+				)
+			} else {
+				display(data)
+			}
 		}
 	},
 }
@@ -0,0 +1,94 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package cmds
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"nocalhost/internal/nhctl/tunnel"
+	"nocalhost/pkg/nhctl/log"
+)
+
+var (
+	tunnelProxyPort        int
+	tunnelProxyPorts       []int
+	tunnelProxyDialPort    int
+	tunnelProxyUDPPort     int
+	tunnelProxyUDPPorts    []int
+	tunnelProxyToken       string
+	tunnelProxyIdleTimeout time.Duration
+	tunnelProxyProbePort   int
+)
+
+func init() {
+	tunnelProxyCmd.Flags().IntVar(
+		&tunnelProxyPort, "tunnel-port", tunnel.DefaultTunnelPort, "port which the tunnel client connects to",
+	)
+	tunnelProxyCmd.Flags().IntSliceVar(&tunnelProxyPorts, "ports", []int{}, "ports to intercept")
+	tunnelProxyCmd.Flags().IntVar(
+		&tunnelProxyDialPort, "dial-port", 0, "port serving dial requests of 'nhctl connect', 0 means disabled",
+	)
+	tunnelProxyCmd.Flags().IntVar(
+		&tunnelProxyUDPPort, "udp-relay-port", 0, "port relaying udp datagrams of port-forward, 0 means disabled",
+	)
+	tunnelProxyCmd.Flags().IntSliceVar(&tunnelProxyUDPPorts, "udp-ports", []int{}, "udp ports allowed to relay")
+	tunnelProxyCmd.Flags().StringVar(&tunnelProxyToken, "token", "", "token required by the udp relay")
+	tunnelProxyCmd.Flags().DurationVar(
+		&tunnelProxyIdleTimeout, "idle-timeout", 0, "exit after no dial request for the duration",
+	)
+	tunnelProxyCmd.Flags().IntVar(
+		&tunnelProxyProbePort, "probe-port", 0, "check if the port of loopback is listening and exit, used by readiness probe",
+	)
+	rootCmd.AddCommand(tunnelProxyCmd)
+}
+
+// tunnelProxyCmd is the entrypoint of intercept proxy running inside the cluster
+var tunnelProxyCmd = &cobra.Command{
+	Use:    "tunnel-proxy",
+	Short:  "Run the in-cluster proxy of intercept",
+	Long:   `Run the in-cluster proxy of intercept`,
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if tunnelProxyProbePort > 0 {
+			must(tunnel.Probe(tunnelProxyProbePort))
+			return
+		}
+		if len(tunnelProxyPorts) == 0 && tunnelProxyDialPort == 0 && tunnelProxyUDPPort == 0 {
+			log.Fatal("--ports, --dial-port or --udp-relay-port must be specified")
+		}
+		if tunnelProxyUDPPort > 0 && (tunnelProxyToken == "" || len(tunnelProxyUDPPorts) == 0) {
+			log.Fatal("--udp-ports and --token must be specified for --udp-relay-port")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+			<-signals
+			cancel()
+		}()
+
+		// returns while any of the servers exits
+		errCh := make(chan error, 3)
+		if len(tunnelProxyPorts) > 0 {
+			go func() { errCh <- tunnel.NewProxy(tunnelProxyPort, tunnelProxyPorts).Serve(ctx) }()
+		}
+		if tunnelProxyDialPort > 0 {
+			go func() { errCh <- tunnel.NewDialServer(tunnelProxyDialPort, tunnelProxyIdleTimeout).Serve(ctx) }()
+		}
+		if tunnelProxyUDPPort > 0 {
+			go func() {
+				errCh <- tunnel.NewUDPRelay(tunnelProxyUDPPort, tunnelProxyToken, tunnelProxyUDPPorts).Serve(ctx)
+			}()
+		}
+		must(<-errCh)
+	},
+}
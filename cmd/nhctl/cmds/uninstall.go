@@ -6,6 +6,8 @@
 package cmds
 
 import (
+	"strings"
+
 	"nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/nocalhost"
@@ -16,11 +18,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var force bool
+var (
+	force   bool
+	cascade bool
+)
 
 func init() {
 	uninstallCmd.Flags().StringVarP(&nameSpace, "namespace", "n", "", "kubernetes namespace")
 	uninstallCmd.Flags().BoolVar(&force, "force", false, "force to uninstall anyway")
+	uninstallCmd.Flags().BoolVar(
+		&cascade, "cascade", false, "also uninstall the applications depend on this application",
+	)
 	rootCmd.AddCommand(uninstallCmd)
 }
 
@@ -44,34 +52,55 @@ var uninstallCmd = &cobra.Command{
 
 		must(Prepare())
 
-		appMeta, err := nocalhost.GetApplicationMeta(applicationName, nameSpace, kubeConfig)
+		metas, err := nocalhost.GetApplicationMetas(nameSpace, kubeConfig)
 		must(err)
 
-		nid = appMeta.NamespaceId
-
-		if appMeta.IsNotInstall() {
-			log.Fatal(appMeta.NotInstallTips())
+		dependents, err := metas.Dependents(applicationName)
+		must(err)
+		if len(dependents) > 0 && !cascade {
+			log.Fatalf(
+				"Application %s is depended on by %s, use --cascade to uninstall them together",
+				applicationName, strings.Join(dependents, ", "),
+			)
+		}
+		for _, dependent := range dependents {
+			log.Infof("Uninstalling dependent application %s...", dependent)
+			uninstallApplication(dependent)
 		}
 
-		log.Info("Uninstalling application...")
+		uninstallApplication(applicationName)
+	},
+}
 
-		//goland:noinspection ALL
-		mustI(appMeta.Uninstall(true), "Error while uninstall application")
+func uninstallApplication(applicationName string) {
+	var err error
+	appMeta, err := nocalhost.GetApplicationMeta(applicationName, nameSpace, kubeConfig)
+	must(err)
 
-		p, _ := nocalhost.GetProfileV2(nameSpace, applicationName, nid)
-		if p != nil {
-			for _, sv := range p.SvcProfile {
-				for _, pf := range sv.DevPortForwardList {
-					log.Infof("Stopping %s-%s's port-forward %d:%d", nameSpace, applicationName, pf.LocalPort, pf.RemotePort)
-					utils.Should(controller.StopPortForward(nameSpace, nid, applicationName, sv.Name, pf))
-				}
+	nid = appMeta.NamespaceId
+
+	if appMeta.IsNotInstall() {
+		log.Fatal(appMeta.NotInstallTips())
+	}
+
+	log.Info("Uninstalling application...")
+
+	//goland:noinspection ALL
+	mustI(appMeta.Uninstall(true), "Error while uninstall application")
+
+	p, _ := nocalhost.GetProfileV2(nameSpace, applicationName, nid)
+	if p != nil {
+		for _, sv := range p.SvcProfile {
+			for _, pf := range sv.DevPortForwardList {
+				log.Infof("Stopping %s-%s's port-forward %d:%d", nameSpace, applicationName, pf.LocalPort, pf.RemotePort)
+				utils.Should(controller.StopPortForward(nameSpace, nid, applicationName, sv.Name, pf))
 			}
 		}
+	}
 
-		if err = nocalhost.CleanupAppFilesUnderNs(nameSpace, nid); err != nil {
-			log.WarnE(err, "")
-		}
+	if err = nocalhost.CleanupAppFilesUnderNs(nameSpace, nid); err != nil {
+		log.WarnE(err, "")
+	}
 
-		log.Infof("Application \"%s\" is uninstalled", applicationName)
-	},
+	log.Infof("Application \"%s\" is uninstalled", applicationName)
 }
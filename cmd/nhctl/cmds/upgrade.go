@@ -12,6 +12,7 @@ import (
 	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/profile"
 	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
 	"time"
 )
@@ -24,6 +25,8 @@ func init() {
 	upgradeCmd.Flags().StringVar(&installFlags.Config, "config", "", "specify a config relative to .nocalhost dir")
 	upgradeCmd.Flags().StringVarP(&installFlags.OuterConfig, "outer-config", "c", "",
 		"specify a config.yaml in local path")
+	upgradeCmd.Flags().StringSliceVar(&installFlags.ConfigOverlays, "config-overlay", []string{},
+		"overlays merged into the config in order, overlays specified while installing are used if not set")
 	upgradeCmd.Flags().StringArrayVarP(&installFlags.HelmValueFile, "helm-values", "f", []string{}, "helm's Value.yaml")
 	//installCmd.Flags().StringVarP(&installFlags.AppType, "type", "t", "",
 	//fmt.Sprintf("nocalhost application type: %s or %s or %s", app.HelmRepo, app.Helm, app.Manifest))
@@ -35,6 +38,12 @@ func init() {
 	upgradeCmd.Flags().StringVar(&installFlags.HelmRepoVersion, "helm-repo-version", "", "chart repository version")
 	upgradeCmd.Flags().StringVar(&installFlags.HelmChartName, "helm-chart-name", "", "chart name")
 	upgradeCmd.Flags().StringVar(&installFlags.LocalPath, "local-path", "", "local path for application")
+	upgradeCmd.Flags().BoolVar(&installFlags.HelmWait, "wait", installFlags.HelmWait,
+		"wait for Deployments, StatefulSets, DaemonSets and Jobs to be ready, failing pods will be reported on timeout")
+	upgradeCmd.Flags().DurationVar(&installFlags.Timeout, "timeout", clientgoutils.DefaultWaitReadyTimeout,
+		"time to wait for workloads to be ready, used with --wait")
+	upgradeCmd.Flags().BoolVar(&installFlags.HelmAtomic, "atomic", installFlags.HelmAtomic,
+		"if set, helm application will be rolled back on failure, --wait will be set automatically")
 	rootCmd.AddCommand(upgradeCmd)
 }
 
@@ -50,6 +59,7 @@ var upgradeCmd = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 
+		installFlags.TimeoutSet = cmd.Flags().Changed("timeout")
 		initApp(args[0])
 
 		// Check if there are services in developing
@@ -71,7 +81,7 @@ var upgradeCmd = &cobra.Command{
 				}
 				pfList = append(pfList, pf)
 				log.Infof("Stopping pf: %d:%d", pf.LocalPort, pf.RemotePort)
-				utils.Should(nhSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort))
+				utils.Should(nhSvc.EndDevPortForward(pf.LocalPort, pf.RemotePort, pf.Protocol))
 			}
 			if len(pfList) > 0 {
 				pfListMap[svcProfile.GetName()] = pfList
@@ -96,7 +106,12 @@ var upgradeCmd = &cobra.Command{
 					continue
 				}
 				log.Infof("Starting pf %d:%d for %s", pf.LocalPort, pf.RemotePort, svcName)
-				utils.Should(nhSvc.PortForward(podName, pf.LocalPort, pf.RemotePort, pf.Role))
+				_, err = nhSvc.StartPortForward(
+					podName, pf.LocalPort, pf.RemotePort, pf.Role, &controller.PortForwardOptions{
+						Protocol: pf.Protocol, BindAddress: pf.BindAddress, PortPolicy: pf.PortPolicy,
+					},
+				)
+				utils.Should(err)
 			}
 		}
 	},
@@ -132,6 +132,35 @@ CREATE TABLE `clusters_users` (
 
 
 
+# Dump of table hub_configs
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `hub_configs`;
+
+CREATE TABLE `hub_configs` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL DEFAULT '',
+  `version` varchar(64) NOT NULL DEFAULT '',
+  `description` varchar(1000) DEFAULT NULL,
+  `keywords` varchar(1000) DEFAULT NULL,
+  `maintainers` varchar(1000) DEFAULT NULL,
+  `images` varchar(1000) DEFAULT NULL,
+  `service_type` varchar(32) DEFAULT NULL,
+  `digest` varchar(100) NOT NULL DEFAULT '',
+  `signature` varchar(255) DEFAULT NULL,
+  `key_id` varchar(32) DEFAULT NULL,
+  `content` text NOT NULL,
+  `user_id` int(11) NOT NULL,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `deleted_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uidx_name_version` (`name`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+
+
+
 # Dump of table pre_pull
 # ------------------------------------------------------------
 
@@ -6,6 +6,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -24,6 +25,7 @@ import (
 	"nocalhost/internal/nhctl/nocalhost"
 	nocalhostDb "nocalhost/internal/nhctl/nocalhost/db"
 	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/tunnel"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
@@ -31,6 +33,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"time"
 )
 
 var (
@@ -354,6 +357,8 @@ func (a *Application) generateSecretForEarlierVer() bool {
 			_ = a.InstallManifest(false)
 		case string(appmeta.KustomizeGit):
 			_ = a.InstallKustomize(false)
+		case string(appmeta.JsonnetGit), string(appmeta.JsonnetLocal), string(appmeta.CueLocal):
+			_ = a.InstallRendered(false)
 		default:
 		}
 
@@ -611,6 +616,7 @@ func (a *Application) loadSvcCfgFromLocalIfValid(svcName string, svcType base.Sv
 		if _, // local config should not contain app config
 			svcCfg, _ = doLoadProfileFromAppConfig(
 			envsubst.LocalFileRenderItem{FilePathEnhance: configFile}, svcName, svcType,
+			a.localConfigOverlays(configFile)...,
 		); svcCfg == nil {
 			if err != nil {
 
@@ -687,10 +693,10 @@ func doLoadProfileFromSvcConfig(renderItem envsubst.RenderItem, svcName string,
 	return nil, errors.New("Local config loaded, but no valid config found")
 }
 
-func doLoadProfileFromAppConfig(configFile envsubst.RenderItem, svcName string, svcType base.SvcType) (
-	*profile.NocalHostAppConfigV2, *profile.ServiceConfigV2, error,
-) {
-	appConfig, err := RenderConfig(configFile)
+func doLoadProfileFromAppConfig(
+	configFile envsubst.RenderItem, svcName string, svcType base.SvcType, overlays ...*fp.FilePathEnhance,
+) (*profile.NocalHostAppConfigV2, *profile.ServiceConfigV2, error) {
+	appConfig, err := RenderConfig(configFile, overlays...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -698,6 +704,23 @@ func doLoadProfileFromAppConfig(configFile envsubst.RenderItem, svcName string,
 	return appConfig, appConfig.GetSvcConfigV2(svcName, svcType), nil
 }
 
+// localConfigOverlays returns overlays of the local config specified while installing,
+// overlays not found in the associated dir are ignored
+func (a *Application) localConfigOverlays(configFile *fp.FilePathEnhance) []*fp.FilePathEnhance {
+	result := make([]*fp.FilePathEnhance, 0)
+	p, err := a.GetProfile()
+	if err != nil {
+		return result
+	}
+	for _, overlay := range p.ConfigOverlays {
+		file := fp.NewFilePath(OverlayConfigPath(configFile.Abs(), overlay))
+		if file.CheckExist() == nil {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
 func (a *Application) newConfigFromProfile() *profile.NocalHostAppConfigV2 {
 
 	profileV2, _ := a.GetProfile()
@@ -823,12 +846,17 @@ func (a *Application) LoadConfigFromLocalV2() (*profile.NocalHostAppConfigV2, er
 type HelmFlags struct {
 	Debug    bool
 	Wait     bool
+	Atomic   bool
+	Timeout  time.Duration
 	Set      []string
 	Values   []string
 	Chart    string
 	RepoName string
 	RepoUrl  string
 	Version  string
+
+	// Timeout is passed to helm only if TimeoutSet or Wait, otherwise helm uses its own default
+	TimeoutSet bool
 }
 
 func (a *Application) GetApplicationConfigV2() *profile.ApplicationConfig {
@@ -863,11 +891,14 @@ type PortForwardOptions struct {
 	Pid     int      `json:"pid" yaml:"pid"`
 	DevPort []string // 8080:8080 or :8080 means random localPort
 	PodName string   // directly port-forward pod
+	Service string   // port-forward k8s Service, re-targeting while pods change
 	//ServiceType string   // service type such deployment
 	Way         string // port-forward way, value is manual or devPorts
 	RunAsDaemon bool
 	Forward     bool
-	Follow      bool // will stock until send ctrl+c or occurs error
+	Follow      bool   // will stock until send ctrl+c or occurs error
+	BindAddress string // local address to listen on
+	PortPolicy  string // fixed or next-free, next-free listens on the next free port if local port is unavailable
 }
 
 type PortForwardEndOptions struct {
@@ -953,8 +984,8 @@ func (a *Application) PortForwardAPod(req clientgoutils.PortForwardAPodRequest)
 	return a.client.PortForwardAPod(req)
 }
 
-func (a *Application) PortForward(pod string, localPort, remotePort int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams) error {
-	return a.client.Forward(pod, localPort, remotePort, readyChan, stopChan, g)
+func (a *Application) PortForward(pod, address string, localPort, remotePort int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams, metrics *clientgoutils.PortForwardMetrics) error {
+	return a.client.Forward(pod, address, localPort, remotePort, readyChan, stopChan, g, metrics)
 }
 
 // set pid file empty
@@ -992,13 +1023,14 @@ func (a *Application) IsAnyServiceInDevMode() bool {
 	return false
 }
 
-func (a *Application) PortForwardFollow(podName string, localPort int, remotePort int, okChan chan struct{}) error {
+// PortForwardFollow forward localPort listened on address to remotePort of the pod until error occurs
+func (a *Application) PortForwardFollow(podName, address string, localPort int, remotePort int, okChan chan struct{}) error {
 	client, err := clientgoutils.NewClientGoUtils(a.KubeConfig, a.NameSpace)
 	if err != nil {
 		return err
 	}
 	fps := []*clientgoutils.ForwardPort{{LocalPort: localPort, RemotePort: remotePort}}
-	pf, err := client.CreatePortForwarder(podName, fps, nil, nil, genericclioptions.IOStreams{})
+	pf, err := client.CreatePortForwarderOnAddress(podName, address, fps, nil, nil, genericclioptions.IOStreams{})
 	if err != nil {
 		return err
 	}
@@ -1012,8 +1044,7 @@ func (a *Application) PortForwardFollow(podName string, localPort int, remotePor
 		for {
 			select {
 			case <-pf.Ready:
-				fmt.Printf("Forwarding from 127.0.0.1:%d -> %d\n", localPort, remotePort)
-				fmt.Printf("Forwarding from [::1]:%d -> %d\n", localPort, remotePort)
+				fmt.Printf("Forwarding from %s -> %d\n", net.JoinHostPort(address, strconv.Itoa(localPort)), remotePort)
 				if okChan != nil {
 					okChan <- struct{}{}
 				}
@@ -1023,3 +1054,56 @@ func (a *Application) PortForwardFollow(podName string, localPort int, remotePor
 	}()
 	return <-errChan
 }
+
+// ServicePortForwardFollow forward localPort to servicePort of k8s Service until ctx is done,
+// re-targeting to another ready pod of the service while the current one is gone
+func (a *Application) ServicePortForwardFollow(
+	ctx context.Context, k8sService, address string, localPort, servicePort int, okChan chan struct{},
+) error {
+	forwarder := a.client.NewServicePortForwarder(k8sService, address, localPort, servicePort)
+	readyCh := make(chan struct{})
+	go func() {
+		select {
+		case <-readyCh:
+			fmt.Printf(
+				"Forwarding from %s -> service %s:%d\n",
+				net.JoinHostPort(address, strconv.Itoa(localPort)), k8sService, servicePort,
+			)
+			if okChan != nil {
+				okChan <- struct{}{}
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return forwarder.ForwardPorts(ctx, readyCh)
+}
+
+// UDPPortForwardFollow forward datagrams of local udp port to remotePort of the pod until ctx is done,
+// datagrams are relayed by an udp relay added to the pod as an ephemeral container
+func (a *Application) UDPPortForwardFollow(
+	ctx context.Context, podName, address string, localPort, remotePort int, okChan chan struct{},
+) error {
+	relay, err := tunnel.EnsureUDPRelay(a.client, podName, remotePort)
+	if err != nil {
+		return err
+	}
+	dialer := a.client.NewPodDialer(podName)
+	defer dialer.Close()
+
+	forwarder := tunnel.NewUDPForwarder(
+		net.JoinHostPort(address, strconv.Itoa(localPort)), remotePort, relay.Token,
+		func() (net.Conn, error) { return dialer.Dial(relay.Port) },
+	)
+	readyCh := make(chan struct{})
+	go func() {
+		select {
+		case <-readyCh:
+			fmt.Printf("Forwarding from %s/udp -> %d/udp\n", net.JoinHostPort(address, strconv.Itoa(localPort)), remotePort)
+			if okChan != nil {
+				okChan <- struct{}{}
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return forwarder.Serve(ctx, readyCh)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"regexp"
 	"sync"
 	"testing"
@@ -39,8 +41,13 @@ func TestIoUtilWriteFile(t *testing.T) {
 	context := `
 aaaa
 `
-	err := ioutil.WriteFile("aa.txt", []byte(context), 0755)
+	dir, err := ioutil.TempDir("", "nhctl-app-test")
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "aa.txt"), []byte(context), 0644)
+	if err != nil {
+		t.Fatal(err)
 	}
 }
@@ -114,7 +114,9 @@ func BuildApplication(name string, flags *app_flags.InstallFlags, kubeconfig str
 	}
 
 	// load nocalhost config from dir
-	config, err := app.loadOrGenerateConfig(flags.OuterConfig, flags.Config, flags.ResourcePath, flags.AppType)
+	config, err := app.loadOrGenerateConfig(
+		flags.OuterConfig, flags.Config, flags.ResourcePath, flags.AppType, flags.ConfigOverlays,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +139,7 @@ func BuildApplication(name string, flags *app_flags.InstallFlags, kubeconfig str
 	appProfileV2.Secreted = true
 	appProfileV2.Namespace = namespace
 	appProfileV2.Kubeconfig = kubeconfig
+	appProfileV2.ConfigOverlays = flags.ConfigOverlays
 	//appProfileV2.ConfigMigrated = true
 
 	app.AppType = appProfileV2.AppType
@@ -145,7 +148,7 @@ func BuildApplication(name string, flags *app_flags.InstallFlags, kubeconfig str
 }
 
 func (a *Application) loadOrGenerateConfig(
-	outerConfig, config string, resourcePath []string, appType string,
+	outerConfig, config string, resourcePath []string, appType string, overlays []string,
 ) (*profile.NocalHostAppConfigV2, error) {
 	var nocalhostConfig *profile.NocalHostAppConfigV2
 	var err error
@@ -158,6 +161,9 @@ func (a *Application) loadOrGenerateConfig(
 				return nil, errors.Wrap(err, "")
 			}
 			// no config.yaml
+			if len(overlays) > 0 {
+				return nil, errors.New("Config overlays are specified, but no config is found")
+			}
 			renderedConfig := &profile.NocalHostAppConfigV2{
 				ConfigProperties: profile.ConfigProperties{Version: "v2"},
 				ApplicationConfig: profile.ApplicationConfig{
@@ -180,8 +186,12 @@ func (a *Application) loadOrGenerateConfig(
 
 	// config.yaml found
 	if configFilePath != "" {
+		var files []*fp.FilePathEnhance
+		if files, err = overlayFiles(configFilePath, overlays); err != nil {
+			return nil, err
+		}
 		if nocalhostConfig, err = RenderConfig(
-			envsubst.LocalFileRenderItem{FilePathEnhance: fp.NewFilePath(configFilePath)},
+			envsubst.LocalFileRenderItem{FilePathEnhance: fp.NewFilePath(configFilePath)}, files...,
 		); err != nil {
 			return nil, err
 		}
@@ -260,7 +270,10 @@ func RenderConfigForSvc(renderItem envsubst.RenderItem) ([]*profile.ServiceConfi
 }
 
 // V2
-func RenderConfig(renderItem envsubst.RenderItem) (*profile.NocalHostAppConfigV2, error) {
+// RenderConfig renders the config, overlays are merged into it in order
+func RenderConfig(renderItem envsubst.RenderItem, overlays ...*fp.FilePathEnhance) (
+	*profile.NocalHostAppConfigV2, error,
+) {
 	configFileLocation := renderItem.GetLocation()
 
 	var envFile *fp.FilePathEnhance
@@ -308,6 +321,9 @@ func RenderConfig(renderItem envsubst.RenderItem) (*profile.NocalHostAppConfigV2
 		}
 
 		if configVersion == "v1" {
+			if len(overlays) > 0 {
+				return nil, errors.New(fmt.Sprintf("Overlays are not supported by config v1 %s", configFile.Abs()))
+			}
 			v2TmpDir, _ := ioutil.TempDir("", "")
 			if err = os.MkdirAll(v2TmpDir, DefaultNewFilePermission); err != nil {
 				return nil, errors.Wrap(err, "Fail to create tmp dir")
@@ -335,6 +351,16 @@ func RenderConfig(renderItem envsubst.RenderItem) (*profile.NocalHostAppConfigV2
 		}
 	}
 
+	overlayNodes := make([]*customyaml3.Node, 0, len(overlays))
+	for _, overlay := range overlays {
+		node, err := renderOverlay(overlay, envFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Logf("Render %s Nocalhost config with overlay %s", configFileLocation, overlay.Abs())
+		overlayNodes = append(overlayNodes, node)
+	}
+
 	// ------
 	// Render end, start to unmarshal the config
 	// ------
@@ -343,6 +369,9 @@ func RenderConfig(renderItem envsubst.RenderItem) (*profile.NocalHostAppConfigV2
 	renderedConfig := &profile.NocalHostAppConfigV2{}
 	if err := parseNocalhostConfigEnvFile(
 		renderedStr, fp.NewFilePath(configFileLocation), func(node *customyaml3.Node) error {
+			for _, overlay := range overlayNodes {
+				mergeConfigNode(node, overlay)
+			}
 			_ = node.Decode(renderedConfig)
 
 			parseEnvFromIntoEnv(renderedConfig)
@@ -367,7 +396,7 @@ func RenderConfig(renderItem envsubst.RenderItem) (*profile.NocalHostAppConfigV2
 
 		for i, config := range renderedConfig.ApplicationConfig.ServiceConfigs {
 			if _, ok := maps[config.Name]; ok {
-				log.Log(
+				log.Logf(
 					"Duplicate service %s found, Nocalhost will "+
 						"keep the last one according to the sequence",
 					config.Name,
@@ -0,0 +1,509 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"nocalhost/internal/nhctl/profile"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const devImageRepo = "nocalhost-docker.pkg.coding.net/nocalhost/dev-images/"
+
+// defaultIgnoreFilePattern is always ignored by sync of generated configs
+var defaultIgnoreFilePattern = []string{".git", ".idea", ".vscode", ".nocalhost"}
+
+// devLanguage describes how a kind of source is developed in the dev container
+type devLanguage struct {
+	name          string
+	markerFile    string
+	imageKeywords []string
+	// envNames are env set only by images of the language
+	envNames       []string
+	image          string
+	debugPort      int
+	ignores        []string
+	persistentDirs []string
+	commands       func(dir string, appPort int) *profile.DevCommands
+}
+
+// devLanguages are detected in order by their marker files in the source directory,
+// or by keywords in the image and names of env of the container if no source is found
+var devLanguages = []*devLanguage{
+	{
+		name:          "go",
+		markerFile:    "go.mod",
+		imageKeywords: []string{"golang", "go"},
+		envNames:      []string{"GOPATH"},
+		image:         devImageRepo + "golang:latest",
+		debugPort:     9009,
+		ignores:       []string{"vendor"},
+		commands: func(string, int) *profile.DevCommands {
+			return &profile.DevCommands{
+				Build: []string{"go", "build", "."},
+				Run:   []string{"go", "run", "."},
+				Debug: []string{
+					"dlv", "debug", ".", "--headless", "--listen=:9009", "--api-version=2", "--accept-multiclient",
+				},
+			}
+		},
+	},
+	{
+		name:          "node",
+		markerFile:    "package.json",
+		imageKeywords: []string{"node", "npm"},
+		envNames:      []string{"NODE_ENV"},
+		image:         devImageRepo + "node:12.18.1-slim",
+		debugPort:     9229,
+		ignores:       []string{"node_modules"},
+		commands:      nodeCommands,
+	},
+	{
+		name:           "java",
+		markerFile:     "pom.xml",
+		imageKeywords:  []string{"openjdk", "java", "jdk", "jre", "maven", "tomcat"},
+		envNames:       []string{"JAVA_HOME"},
+		image:          devImageRepo + "java:latest",
+		debugPort:      5005,
+		ignores:        []string{"target"},
+		persistentDirs: []string{"/root/.m2"},
+		commands:       mavenCommands,
+	},
+	{
+		name:          "python",
+		markerFile:    "requirements.txt",
+		imageKeywords: []string{"python", "pip"},
+		envNames:      []string{"PYTHONPATH"},
+		image:         devImageRepo + "python:3.7.7-slim",
+		debugPort:     5678,
+		ignores:       []string{"__pycache__", "*.pyc", "venv", ".venv"},
+		commands:      pythonCommands,
+	},
+}
+
+func nodeCommands(dir string, _ int) *profile.DevCommands {
+	pkg := struct {
+		Main    string            `json:"main"`
+		Scripts map[string]string `json:"scripts"`
+	}{}
+	if bys, err := ioutil.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		_ = json.Unmarshal(bys, &pkg)
+	}
+	main := pkg.Main
+	if main == "" {
+		main = "index.js"
+	}
+
+	commands := &profile.DevCommands{
+		Build: []string{"npm", "install"},
+		Run:   []string{"node", main},
+		Debug: []string{"node", "--inspect=0.0.0.0:9229", main},
+	}
+	if _, ok := pkg.Scripts["start"]; ok {
+		commands.Run = []string{"npm", "start"}
+	}
+	return commands
+}
+
+func mavenCommands(dir string, _ int) *profile.DevCommands {
+	jdwp := "-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=*:5005"
+	commands := &profile.DevCommands{
+		Build: []string{"mvn", "package", "-DskipTests"},
+		Run:   []string{"mvn", "compile", "exec:java"},
+		Debug: []string{"bash", "-c", fmt.Sprintf("MAVEN_OPTS=%s mvn compile exec:java", jdwp)},
+	}
+	if bys, err := ioutil.ReadFile(filepath.Join(dir, "pom.xml")); err == nil &&
+		strings.Contains(string(bys), "spring-boot") {
+		commands.Run = []string{"mvn", "spring-boot:run"}
+		commands.Debug = []string{"mvn", "spring-boot:run", "-Dspring-boot.run.jvmArguments=" + jdwp}
+	}
+	return commands
+}
+
+func pythonCommands(dir string, appPort int) *profile.DevCommands {
+	debugpy := []string{"python", "-m", "debugpy", "--listen", "0.0.0.0:5678"}
+	commands := &profile.DevCommands{
+		Build: []string{"pip", "install", "-r", "requirements.txt"},
+	}
+	if fileExist(filepath.Join(dir, "manage.py")) {
+		if appPort == 0 {
+			appPort = 8000
+		}
+		args := []string{"manage.py", "runserver", fmt.Sprintf("0.0.0.0:%d", appPort)}
+		commands.Run = append([]string{"python"}, args...)
+		commands.Debug = append(append(debugpy, args...), "--noreload")
+		return commands
+	}
+
+	entry := "main.py"
+	for _, e := range []string{"app.py", "main.py", "server.py", "run.py"} {
+		if fileExist(filepath.Join(dir, e)) {
+			entry = e
+			break
+		}
+	}
+	commands.Run = []string{"python", entry}
+	commands.Debug = append(debugpy, entry)
+	return commands
+}
+
+// dockerfileInfo is what generating config concerns in a Dockerfile
+type dockerfileInfo struct {
+	// baseImage and workDir are of the first stage, which sources are usually built in
+	baseImage string
+	workDir   string
+	// command is of the final stage, entrypoint and cmd are joined
+	command []string
+}
+
+var dockerfileInstruction = regexp.MustCompile(`^\s*([A-Za-z]+)\s+(.*)$`)
+
+// parseDockerfile parses instructions of the Dockerfile, nil is returned if it does not exist
+func parseDockerfile(file string) *dockerfileInfo {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info := &dockerfileInfo{}
+	var entrypoint, cmd []string
+	stage, line := 0, ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if line == "" && strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSpace(strings.TrimSuffix(text, "\\")) + " "
+			continue
+		}
+		line += text
+
+		match := dockerfileInstruction.FindStringSubmatch(line)
+		line = ""
+		if match == nil {
+			continue
+		}
+		args := strings.TrimSpace(match[2])
+		switch strings.ToUpper(match[1]) {
+		case "FROM":
+			stage++
+			entrypoint, cmd = nil, nil
+			if stage == 1 {
+				for _, field := range strings.Fields(args) {
+					if !strings.HasPrefix(field, "--") {
+						info.baseImage = field
+						break
+					}
+				}
+			}
+		case "WORKDIR":
+			if stage == 1 {
+				if strings.HasPrefix(args, "/") || info.workDir == "" {
+					info.workDir = args
+				} else {
+					info.workDir = filepath.ToSlash(filepath.Join(info.workDir, args))
+				}
+			}
+		case "ENTRYPOINT":
+			entrypoint = dockerfileCommand(args)
+		case "CMD":
+			cmd = dockerfileCommand(args)
+		}
+	}
+	info.command = append(entrypoint, cmd...)
+	return info
+}
+
+// dockerfileCommand parses both exec form and shell form of CMD and ENTRYPOINT
+func dockerfileCommand(args string) []string {
+	if strings.HasPrefix(args, "[") {
+		var command []string
+		if err := json.Unmarshal([]byte(args), &command); err == nil {
+			return command
+		}
+	}
+	return []string{"sh", "-c", args}
+}
+
+// GenerateSvcConfig proposes the config of container of the workload, by inspecting the
+// pod spec of it in cluster and the source in localDir, localDir is skipped if it is empty
+func GenerateSvcConfig(
+	svcName, svcType string, podSpec *corev1.PodSpec, container, localDir string,
+) (*profile.ServiceConfigV2, error) {
+	if podSpec == nil || len(podSpec.Containers) == 0 {
+		return nil, errors.New(fmt.Sprintf("No container found in %s %s", svcType, svcName))
+	}
+
+	var c *corev1.Container
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == container {
+			c = &podSpec.Containers[i]
+			break
+		}
+	}
+	if c == nil {
+		if container != "" {
+			return nil, errors.New(fmt.Sprintf("Container %s not found in %s %s", container, svcType, svcName))
+		}
+		c = &podSpec.Containers[0]
+	}
+
+	return &profile.ServiceConfigV2{
+		Name: svcName,
+		Type: svcType,
+		ContainerConfigs: []*profile.ContainerConfig{
+			{
+				Name: c.Name,
+				Dev:  GenerateContainerDevConfig(c, podSpec.Volumes, localDir),
+			},
+		},
+	}, nil
+}
+
+// GenerateContainerDevConfig proposes the dev config of the container, the language of the
+// source in localDir decides the dev image and commands, the ports, probes, volumes and
+// resources of the container decide the port-forwards, persistent volumes and resources
+func GenerateContainerDevConfig(c *corev1.Container, volumes []corev1.Volume, localDir string) *profile.ContainerDevConfig {
+	ports := containerPorts(c)
+	appPort := 0
+	if len(ports) > 0 {
+		appPort = ports[0]
+	}
+
+	dev := &profile.ContainerDevConfig{
+		Image:   c.Image,
+		Shell:   "bash",
+		WorkDir: profile.DefaultWorkDir,
+		Sync: &profile.SyncConfig{
+			Type:              "send",
+			FilePattern:       []string{"."},
+			IgnoreFilePattern: append([]string{}, defaultIgnoreFilePattern...),
+		},
+		DevContainerResources: containerResources(c),
+	}
+
+	var dockerfile *dockerfileInfo
+	if localDir != "" {
+		dev.GitUrl = gitRemoteUrl(localDir)
+		dockerfile = parseDockerfile(filepath.Join(localDir, "Dockerfile"))
+		dev.Sync.IgnoreFilePattern = appendUnique(dev.Sync.IgnoreFilePattern, gitIgnores(localDir)...)
+	}
+	if dockerfile != nil {
+		if dockerfile.workDir != "" {
+			dev.WorkDir = dockerfile.workDir
+		}
+		if dockerfile.baseImage != "" {
+			dev.Image = dockerfile.baseImage
+		}
+	}
+
+	if lang := detectLanguage(c, localDir); lang != nil {
+		dev.Image = lang.image
+		dev.Command = lang.commands(localDir, appPort)
+		dev.DebugConfig = &profile.DebugConfig{RemoteDebugPort: lang.debugPort}
+		dev.Sync.IgnoreFilePattern = appendUnique(dev.Sync.IgnoreFilePattern, lang.ignores...)
+		for _, dir := range lang.persistentDirs {
+			dev.PersistentVolumeDirs = append(dev.PersistentVolumeDirs, &profile.PersistentVolumeDir{Path: dir})
+		}
+	} else if dockerfile != nil && len(dockerfile.command) > 0 {
+		dev.Command = &profile.DevCommands{Run: dockerfile.command}
+	} else if len(c.Command)+len(c.Args) > 0 {
+		dev.Command = &profile.DevCommands{Run: append(append([]string{}, c.Command...), c.Args...)}
+	}
+
+	for _, path := range emptyDirMounts(c, volumes) {
+		if path != dev.WorkDir {
+			dev.PersistentVolumeDirs = append(dev.PersistentVolumeDirs, &profile.PersistentVolumeDir{Path: path})
+		}
+	}
+
+	for _, port := range ports {
+		dev.PortForward = append(dev.PortForward, fmt.Sprintf("%d:%d", port, port))
+	}
+	return dev
+}
+
+// detectLanguage detects the language by marker files in localDir first,
+// then by the image and env of the container
+func detectLanguage(c *corev1.Container, localDir string) *devLanguage {
+	if localDir != "" {
+		for _, lang := range devLanguages {
+			if fileExist(filepath.Join(localDir, lang.markerFile)) {
+				return lang
+			}
+		}
+	}
+
+	image := c.Image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	imageName := strings.ToLower(strings.SplitN(image, ":", 2)[0])
+	for _, lang := range devLanguages {
+		for _, keyword := range lang.imageKeywords {
+			if imageName == keyword || strings.HasPrefix(imageName, keyword+"-") {
+				return lang
+			}
+		}
+	}
+	for _, lang := range devLanguages {
+		for _, env := range c.Env {
+			for _, name := range lang.envNames {
+				if env.Name == name {
+					return lang
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// containerPorts returns the container ports and the ports of probes of the container
+func containerPorts(c *corev1.Container) []int {
+	named := map[string]int{}
+	result := make([]int, 0)
+	add := func(port int) {
+		if port <= 0 {
+			return
+		}
+		for _, p := range result {
+			if p == port {
+				return
+			}
+		}
+		result = append(result, port)
+	}
+
+	for _, p := range c.Ports {
+		if p.Name != "" {
+			named[p.Name] = int(p.ContainerPort)
+		}
+		add(int(p.ContainerPort))
+	}
+
+	probePort := func(port intstr.IntOrString) int {
+		if port.Type == intstr.String {
+			if p, ok := named[port.StrVal]; ok {
+				return p
+			}
+			p, _ := strconv.Atoi(port.StrVal)
+			return p
+		}
+		return port.IntValue()
+	}
+	for _, probe := range []*corev1.Probe{c.LivenessProbe, c.ReadinessProbe, c.StartupProbe} {
+		if probe == nil {
+			continue
+		}
+		if probe.HTTPGet != nil {
+			add(probePort(probe.HTTPGet.Port))
+		}
+		if probe.TCPSocket != nil {
+			add(probePort(probe.TCPSocket.Port))
+		}
+	}
+	return result
+}
+
+// emptyDirMounts returns the mount paths of the emptyDir volumes of the container,
+// data in them is usually worth persisting across dev sessions
+func emptyDirMounts(c *corev1.Container, volumes []corev1.Volume) []string {
+	emptyDirs := map[string]bool{}
+	for _, v := range volumes {
+		if v.EmptyDir != nil {
+			emptyDirs[v.Name] = true
+		}
+	}
+	result := make([]string, 0)
+	for _, m := range c.VolumeMounts {
+		if emptyDirs[m.Name] && !m.ReadOnly {
+			result = append(result, m.MountPath)
+		}
+	}
+	return result
+}
+
+func containerResources(c *corev1.Container) *profile.ResourceQuota {
+	quota := func(list corev1.ResourceList) *profile.QuotaList {
+		if len(list) == 0 {
+			return nil
+		}
+		q := &profile.QuotaList{}
+		if cpu, ok := list[corev1.ResourceCPU]; ok {
+			q.Cpu = cpu.String()
+		}
+		if memory, ok := list[corev1.ResourceMemory]; ok {
+			q.Memory = memory.String()
+		}
+		return q
+	}
+	limits, requests := quota(c.Resources.Limits), quota(c.Resources.Requests)
+	if limits == nil && requests == nil {
+		return nil
+	}
+	return &profile.ResourceQuota{Limits: limits, Requests: requests}
+}
+
+var gitRemoteUrlRegexp = regexp.MustCompile(`(?m)^\[remote "origin"\][^\[]*?^\s*url\s*=\s*(\S+)`)
+
+// gitRemoteUrl returns url of the origin remote of the git repo in dir
+func gitRemoteUrl(dir string) string {
+	bys, err := ioutil.ReadFile(filepath.Join(dir, ".git", "config"))
+	if err != nil {
+		return ""
+	}
+	if match := gitRemoteUrlRegexp.FindStringSubmatch(string(bys)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// gitIgnores returns patterns in .gitignore of dir, negations are skipped
+// since sync can not express them
+func gitIgnores(dir string) []string {
+	bys, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	result := make([]string, 0)
+	for _, line := range strings.Split(string(bys), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		result = append(result, strings.TrimSuffix(strings.TrimPrefix(line, "/"), "/"))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if item != "" && !containsString(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func fileExist(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
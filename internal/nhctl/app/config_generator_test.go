@@ -0,0 +1,185 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func generatorTestPodSpec() *corev1.PodSpec {
+	return &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name:  "istio-proxy",
+				Image: "istio/proxyv2",
+			},
+			{
+				Name:  "reviews",
+				Image: "docker.io/istio/examples-bookinfo-reviews-v1:1.16.2",
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 9080}},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromString("http")},
+					},
+				},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9090)},
+					},
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "tmp", MountPath: "/opt/ibm/wlp/output"},
+					{Name: "config", MountPath: "/etc/config"},
+				},
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{
+			{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}},
+		},
+	}
+}
+
+func TestGenerateSvcConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"pom.xml":    "<project><parent><artifactId>spring-boot-starter-parent</artifactId></parent></project>",
+		"Dockerfile": "FROM maven:3 AS builder\nWORKDIR /src\nRUN mvn package\n\nFROM openjdk:11\nCMD [\"java\", \"-jar\", \"app.jar\"]\n",
+		".gitignore": "# build\n/logs/\n!keep.log\n*.log\n",
+		".git/config": "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = https://github.com/nocalhost/bookinfo-reviews.git\n" +
+			"\tfetch = +refs/heads/*:refs/remotes/origin/*\n",
+	} {
+		file := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svcConfig, err := GenerateSvcConfig("reviews", "deployment", generatorTestPodSpec(), "reviews", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svcConfig.ContainerConfigs) != 1 || svcConfig.ContainerConfigs[0].Name != "reviews" {
+		t.Fatalf("config of container reviews should be generated")
+	}
+
+	dev := svcConfig.ContainerConfigs[0].Dev
+	if dev.Image != devImageRepo+"java:latest" {
+		t.Errorf("java dev image should be used, got %s", dev.Image)
+	}
+	if dev.WorkDir != "/src" {
+		t.Errorf("workDir should be of the build stage of Dockerfile, got %s", dev.WorkDir)
+	}
+	if dev.GitUrl != "https://github.com/nocalhost/bookinfo-reviews.git" {
+		t.Errorf("unexpected gitUrl %s", dev.GitUrl)
+	}
+	if !reflect.DeepEqual(dev.Command.Run, []string{"mvn", "spring-boot:run"}) {
+		t.Errorf("unexpected run command %v", dev.Command.Run)
+	}
+	if dev.DebugConfig == nil || dev.DebugConfig.RemoteDebugPort != 5005 {
+		t.Errorf("remote debug port should be 5005")
+	}
+	if !reflect.DeepEqual(dev.PortForward, []string{"9080:9080", "9090:9090"}) {
+		t.Errorf("unexpected port-forwards %v", dev.PortForward)
+	}
+	if !reflect.DeepEqual(
+		dev.Sync.IgnoreFilePattern, []string{".git", ".idea", ".vscode", ".nocalhost", "*.log", "logs", "target"},
+	) {
+		t.Errorf("unexpected ignoreFilePattern %v", dev.Sync.IgnoreFilePattern)
+	}
+	paths := make([]string, 0)
+	for _, p := range dev.PersistentVolumeDirs {
+		paths = append(paths, p.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"/root/.m2", "/opt/ibm/wlp/output"}) {
+		t.Errorf("unexpected persistentVolumeDirs %v", paths)
+	}
+	if dev.DevContainerResources == nil || dev.DevContainerResources.Limits.Memory != "1Gi" {
+		t.Errorf("resources of the container should be kept")
+	}
+}
+
+func TestGenerateSvcConfigWithoutSource(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name:    "ratings",
+				Image:   "node:12",
+				Command: []string{"node", "ratings.js"},
+			},
+		},
+	}
+	svcConfig, err := GenerateSvcConfig("ratings", "deployment", podSpec, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := svcConfig.ContainerConfigs[0].Dev
+	if dev.Image != devImageRepo+"node:12.18.1-slim" {
+		t.Errorf("language should be detected by image, got %s", dev.Image)
+	}
+
+	if _, err = GenerateSvcConfig("ratings", "deployment", podSpec, "sidecar", ""); err == nil {
+		t.Errorf("error should be returned if container is not found")
+	}
+}
+
+func TestDetectLanguageByEnv(t *testing.T) {
+	c := &corev1.Container{
+		Image: "registry.example.com/ratings:v1",
+		Env:   []corev1.EnvVar{{Name: "NODE_NAME"}, {Name: "NODE_IP"}},
+	}
+	if lang := detectLanguage(c, ""); lang != nil {
+		t.Errorf("language should not be detected by env of downward api, got %s", lang.name)
+	}
+	c.Env = append(c.Env, corev1.EnvVar{Name: "JAVA_HOME"})
+	if lang := detectLanguage(c, ""); lang == nil || lang.name != "java" {
+		t.Errorf("java should be detected by JAVA_HOME, got %v", lang)
+	}
+}
+
+func TestParseDockerfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "Dockerfile")
+	content := "# syntax=docker/dockerfile:1\nFROM --platform=linux/amd64 ruby:2.7 \nWORKDIR /app\nWORKDIR src\n" +
+		"ENTRYPOINT [\"bundle\", \"exec\"]\nCMD ruby \\\n  details.rb 9080\n"
+	if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := parseDockerfile(file)
+	if info.baseImage != "ruby:2.7" || info.workDir != "/app/src" {
+		t.Errorf("unexpected base image %s, workDir %s", info.baseImage, info.workDir)
+	}
+	if !reflect.DeepEqual(info.command, []string{"bundle", "exec", "sh", "-c", "ruby details.rb 9080"}) {
+		t.Errorf("unexpected command %q", info.command)
+	}
+	if parseDockerfile(filepath.Join(dir, "NotExist")) != nil {
+		t.Errorf("nil should be returned if Dockerfile not exist")
+	}
+}
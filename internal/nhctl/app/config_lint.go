@@ -0,0 +1,552 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"io"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/envsubst"
+	"nocalhost/internal/nhctl/envsubst/parse"
+	"nocalhost/internal/nhctl/fp"
+	"nocalhost/internal/nhctl/profile"
+	customyaml3 "nocalhost/pkg/nhctl/utils/custom_yaml_v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+
+	includeWarnSign = "#  ======    WARN    ======"
+)
+
+var lintSvcTypes = []string{
+	string(base.Deployment), string(base.StatefulSet), string(base.DaemonSet),
+	string(base.Job), string(base.CronJob), string(base.Pod),
+}
+
+// LintIssue is a problem found in config file, File, Line and Column point to
+// the source of the problem, which may be a file included by the config file
+type LintIssue struct {
+	File     string
+	Line     int
+	Column   int
+	Path     string
+	Severity string
+	Message  string
+}
+
+func (l *LintIssue) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s: %s", l.File, l.Line, l.Column, l.Severity, l.Message)
+	if l.Path != "" {
+		s += fmt.Sprintf(" (%s)", l.Path)
+	}
+	return s
+}
+
+// LintConfig validates the nocalhost config file against the schema of config after
+// rendering it, services and containers configured are also checked against the
+// workloads in manifests under resourceDir if the application is made of manifests
+func LintConfig(configFile, resourceDir string) ([]*LintIssue, error) {
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if _, err = os.Stat(configFile); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var envFile *fp.FilePathEnhance
+	if relPath := gettingRenderEnvFile(configFile); relPath != "" {
+		envFile = fp.NewFilePath(configFile).RelOrAbs("../").RelOrAbs(relPath)
+	}
+	rendered, err := envsubst.Render(envsubst.LocalFileRenderItem{FilePathEnhance: fp.NewFilePath(configFile)}, envFile)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &configLinter{file: configFile, sourceMap: newSourceMap(rendered)}
+	l.issues = append(l.issues, l.sourceMap.includeErrors...)
+
+	doc := customyaml3.Node{}
+	if err = customyaml3.Unmarshal([]byte(rendered), &doc); err != nil {
+		l.addYamlError(err)
+		return l.issues, nil
+	}
+	if len(doc.Content) == 0 {
+		l.add(nil, "", LintWarning, "config is empty")
+		return l.issues, nil
+	}
+	l.root = doc.Content[0]
+
+	var schema map[string]interface{}
+	switch {
+	case l.root.Kind == customyaml3.SequenceNode:
+		schema = profile.ServiceConfigSchema(true)
+	case l.root.Kind == customyaml3.MappingNode && isServiceConfigNode(l.root):
+		schema = profile.ServiceConfigSchema(false)
+	default:
+		if version, _ := checkConfigVersion(rendered); version == "v1" {
+			l.add(
+				l.root, "", LintWarning, fmt.Sprintf(
+					"config v1 is deprecated and not linted, convert it by "+
+						"`nhctl convert -f %s --from-version v1 --to-version v2`", configFile,
+				),
+			)
+			return l.issues, nil
+		}
+		schema = profile.ConfigSchema()
+	}
+
+	var value interface{}
+	if err = l.root.Decode(&value); err != nil {
+		l.addYamlError(err)
+		return l.issues, nil
+	}
+	if err = l.validate(schema, value); err != nil {
+		return nil, err
+	}
+
+	if schema["$ref"] == "#/definitions/NocalHostAppConfigV2" {
+		config := &profile.NocalHostAppConfigV2{}
+		_ = l.root.Decode(config)
+		l.checkWorkloads(config, resourceDir)
+	}
+
+	sort.SliceStable(
+		l.issues, func(i, j int) bool {
+			a, b := l.issues[i], l.issues[j]
+			if a.File != b.File {
+				if a.File == configFile || b.File == configFile {
+					return a.File == configFile
+				}
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		},
+	)
+	return l.issues, nil
+}
+
+// isServiceConfigNode tells if the config file contains a single service config,
+// as the one in the repo of a service
+func isServiceConfigNode(node *customyaml3.Node) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "application", "configProperties":
+			return false
+		case "containers", "dependLabelSelector":
+			return true
+		}
+	}
+	return false
+}
+
+type configLinter struct {
+	file      string
+	root      *customyaml3.Node
+	sourceMap *sourceMap
+	issues    []*LintIssue
+}
+
+func (l *configLinter) add(node *customyaml3.Node, path, severity, message string) {
+	issue := &LintIssue{File: l.file, Path: path, Severity: severity, Message: message}
+	if node != nil {
+		issue.File, issue.Line, issue.Column = l.sourceMap.position(node.Line, node.Column)
+		if issue.File == "" {
+			issue.File = l.file
+		}
+	}
+	l.issues = append(l.issues, issue)
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+func (l *configLinter) addYamlError(err error) {
+	issue := &LintIssue{File: l.file, Severity: LintError, Message: err.Error()}
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); len(match) > 1 {
+		line, _ := strconv.Atoi(match[1])
+		issue.File, issue.Line, issue.Column = l.sourceMap.position(line, 1)
+		if issue.File == "" {
+			issue.File = l.file
+		}
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *configLinter) validate(schema map[string]interface{}, value interface{}) error {
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(value))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	for _, e := range result.Errors() {
+		path := e.Field()
+		if path == gojsonschema.STRING_CONTEXT_ROOT {
+			path = ""
+		}
+		// null is allowed everywhere as empty values in yaml, it is noise in messages
+		message := strings.TrimPrefix(e.Description(), e.Field()+" ")
+		message = strings.NewReplacer(",null]", "]", ", null", "").Replace(message)
+
+		switch e.Type() {
+		case "additional_property_not_allowed":
+			property, _ := e.Details()["property"].(string)
+			if name := closestProperty(propertiesAt(schema, path), property); name != "" {
+				message += fmt.Sprintf(", did you mean %s?", name)
+			}
+			if path == "" {
+				path = property
+			} else {
+				path += "." + property
+			}
+			key, _ := nodeAt(l.root, path)
+			l.add(key, path, LintError, message)
+			continue
+		case "number_one_of", "number_any_of", "condition_then", "condition_else":
+			// reported by the errors of sub schemas
+			continue
+		}
+		_, node := nodeAt(l.root, path)
+		l.add(node, path, LintError, message)
+	}
+	return nil
+}
+
+// checkWorkloads checks if services and containers configured exist in the manifests
+func (l *configLinter) checkWorkloads(config *profile.NocalHostAppConfigV2, resourceDir string) {
+	if len(config.ApplicationConfig.ServiceConfigs) == 0 {
+		return
+	}
+
+	switch appmeta.AppType(config.ApplicationConfig.Type) {
+	case "", appmeta.Manifest, appmeta.ManifestGit, appmeta.ManifestLocal:
+	default:
+		_, node := nodeAt(l.root, "application.manifestType")
+		l.add(
+			node, "application.manifestType", LintWarning, fmt.Sprintf(
+				"services are only checked against raw manifests, skipped for %s application",
+				config.ApplicationConfig.Type,
+			),
+		)
+		return
+	}
+
+	manifests := config.ApplicationConfig.LoadManifests(fp.NewFilePath(resourceDir))
+	if len(manifests) == 0 {
+		_, node := nodeAt(l.root, "application.resourcePath")
+		l.add(
+			node, "application.resourcePath", LintWarning,
+			fmt.Sprintf("no manifests found in %s, services are not checked", resourceDir),
+		)
+		return
+	}
+
+	workloads := map[string][]string{}
+	for _, manifest := range manifests {
+		if err := loadWorkloadContainers(manifest, workloads); err != nil {
+			l.issues = append(
+				l.issues, &LintIssue{File: manifest, Severity: LintWarning, Message: err.Error()},
+			)
+		}
+	}
+
+	for i, svc := range config.ApplicationConfig.ServiceConfigs {
+		if svc == nil || svc.Name == "" {
+			continue
+		}
+		svcType := strings.ToLower(svc.Type)
+		if svcType == "" {
+			svcType = string(base.Deployment)
+		}
+		if !containsString(lintSvcTypes, svcType) {
+			// reported by schema
+			continue
+		}
+		path := fmt.Sprintf("application.services.%d", i)
+		containers, ok := workloads[svcType+"/"+svc.Name]
+		if !ok {
+			_, node := nodeAt(l.root, path+".name")
+			l.add(
+				node, path+".name", LintError,
+				fmt.Sprintf("%s %s is not found in manifests", svcType, svc.Name),
+			)
+			continue
+		}
+
+		for j, c := range svc.ContainerConfigs {
+			if c == nil || c.Name == "" || containsString(containers, c.Name) {
+				continue
+			}
+			cPath := fmt.Sprintf("%s.containers.%d.name", path, j)
+			_, node := nodeAt(l.root, cPath)
+			l.add(
+				node, cPath, LintError, fmt.Sprintf(
+					"container %s is not found in %s %s, available containers: %s",
+					c.Name, svcType, svc.Name, strings.Join(containers, ", "),
+				),
+			)
+		}
+	}
+}
+
+// loadWorkloadContainers reads workloads in the manifest into workloads, keyed by `kind/name`
+func loadWorkloadContainers(manifest string, workloads map[string][]string) error {
+	f, err := os.Open(manifest)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer f.Close()
+
+	decoder := k8syaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := map[string]interface{}{}
+		if err = decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "Fail to parse manifest")
+		}
+		if len(obj) == 0 {
+			continue
+		}
+
+		u := unstructured.Unstructured{Object: obj}
+		var podSpec []string
+		switch kind := strings.ToLower(u.GetKind()); kind {
+		case "deployment", "statefulset", "daemonset", "job":
+			podSpec = []string{"spec", "template", "spec"}
+		case "cronjob":
+			podSpec = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+		case "pod":
+			podSpec = []string{"spec"}
+		default:
+			continue
+		}
+
+		names := make([]string, 0)
+		containers, _, _ := unstructured.NestedSlice(obj, append(podSpec, "containers")...)
+		for _, c := range containers {
+			if m, ok := c.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+		workloads[strings.ToLower(u.GetKind())+"/"+u.GetName()] = names
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closestProperty returns the property which is most likely to be mistyped as s
+func closestProperty(properties map[string]interface{}, s string) string {
+	closest, best := "", 3
+	for name := range properties {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(s)); d < best || d == best && name < closest {
+			closest, best = name, d
+		}
+	}
+	return closest
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// nodeAt returns the key and value node of path, such as `application.services.0.name`,
+// the closest parent is returned if path is not found
+func nodeAt(root *customyaml3.Node, path string) (*customyaml3.Node, *customyaml3.Node) {
+	key, node := root, root
+	if path == "" {
+		return key, node
+	}
+	for _, seg := range strings.Split(path, ".") {
+		switch node.Kind {
+		case customyaml3.MappingNode:
+			found := false
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == seg {
+					key, node = node.Content[i], node.Content[i+1]
+					found = true
+					break
+				}
+			}
+			if !found {
+				return key, node
+			}
+		case customyaml3.SequenceNode:
+			index, err := strconv.Atoi(seg)
+			if err != nil || index >= len(node.Content) {
+				return key, node
+			}
+			key, node = node.Content[index], node.Content[index]
+		default:
+			return key, node
+		}
+	}
+	return key, node
+}
+
+// propertiesAt returns the properties allowed by the schema at path
+func propertiesAt(schema map[string]interface{}, path string) map[string]interface{} {
+	definitions, _ := schema["definitions"].(map[string]interface{})
+	resolve := func(s map[string]interface{}) map[string]interface{} {
+		if ref, ok := s["$ref"].(string); ok {
+			d, _ := definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+			return d
+		}
+		return s
+	}
+
+	current := resolve(schema)
+	for _, seg := range strings.Split(path, ".") {
+		if seg == "" || current == nil {
+			continue
+		}
+		if items, ok := current["items"].(map[string]interface{}); ok {
+			current = resolve(items)
+			continue
+		}
+		properties, _ := current["properties"].(map[string]interface{})
+		next, _ := properties[seg].(map[string]interface{})
+		current = resolve(next)
+	}
+	properties, _ := current["properties"].(map[string]interface{})
+	return properties
+}
+
+// sourceMap maps lines of the rendered config back to the files they come from,
+// rendered config is marked by parse.AbsSign where a file starts or an include ends
+type sourceMap struct {
+	lines         []sourcePosition
+	includeErrors []*LintIssue
+}
+
+type sourcePosition struct {
+	file   string
+	line   int
+	indent int
+}
+
+func newSourceMap(rendered string) *sourceMap {
+	m := &sourceMap{}
+	stack := make([]*sourcePosition, 0)
+	// the rest of the line where a file is included
+	continuation := false
+	inWarn := false
+	var warn []string
+
+	for _, text := range strings.Split(rendered, "\n") {
+		trimmed := strings.TrimSpace(text)
+		position := sourcePosition{}
+		if len(stack) > 0 {
+			position = *stack[len(stack)-1]
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, parse.AbsSign):
+			file := strings.TrimSpace(strings.TrimPrefix(trimmed, parse.AbsSign))
+			returned := false
+			for i := len(stack) - 2; i >= 0; i-- {
+				if stack[i].file == file {
+					stack = stack[:i+1]
+					returned = true
+					break
+				}
+			}
+			if !returned {
+				stack = append(stack, &sourcePosition{file: file, indent: len(text) - len(strings.TrimLeft(text, " "))})
+			}
+			continuation = returned
+		case trimmed == includeWarnSign:
+			if inWarn {
+				m.includeErrors = append(
+					m.includeErrors, &LintIssue{
+						File: position.file, Line: position.line, Column: 1, Severity: LintError,
+						Message: "Fail to include: " + strings.Join(warn, ", "),
+					},
+				)
+				warn = nil
+				continuation = true
+			}
+			inWarn = !inWarn
+		case inWarn:
+			for _, prefix := range []string{"#  Err", "#  Statement"} {
+				if strings.HasPrefix(trimmed, prefix) {
+					if i := strings.Index(trimmed, ":"); i > 0 {
+						warn = append(warn, strings.TrimSpace(trimmed[i+1:]))
+					}
+				}
+			}
+		case len(stack) > 0:
+			top := stack[len(stack)-1]
+			if !continuation {
+				top.line++
+			}
+			continuation = false
+			position = *top
+		}
+		m.lines = append(m.lines, position)
+	}
+	return m
+}
+
+// position returns file, line and column in source of the line and column of rendered config
+func (m *sourceMap) position(line, column int) (string, int, int) {
+	if line < 1 || line > len(m.lines) {
+		return "", line, column
+	}
+	p := m.lines[line-1]
+	if column -= p.indent; column < 1 {
+		column = 1
+	}
+	return p.file, p.line, column
+}
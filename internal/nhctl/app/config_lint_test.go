@@ -0,0 +1,150 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintTestConfig = `configProperties:
+  version: v2
+
+application:
+  name: bookinfo
+  manifestType: rawManifestLocal
+  resourcePath: ["manifests"]
+  services:
+    - name: productpage
+      serviceType: deployment
+      containers:
+        - name: productpage
+          dev:
+            workdir: /home/nocalhost-dev
+            debug:
+              remoteDebugPort: "5005"
+        - name: sidecar
+    - name: details
+      serviceType: deploy
+    - ${_INCLUDE_:- ./ratings.yaml | nindent 6}
+    - name: reviews
+      serviceType: deployment
+`
+
+const lintTestInclude = `name: ratings
+serviceType: deployment
+containers:
+  - name: ratings
+    dev:
+      shel: bash
+`
+
+const lintTestManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: productpage
+spec:
+  template:
+    spec:
+      containers:
+        - name: productpage
+          image: productpage
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ratings
+spec:
+  template:
+    spec:
+      containers:
+        - name: ratings
+          image: ratings
+`
+
+func writeLintTestFiles(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		".nocalhost/config.yaml":  lintTestConfig,
+		".nocalhost/ratings.yaml": lintTestInclude,
+		"manifests/app.yaml":      lintTestManifest,
+	} {
+		file := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLintConfig(t *testing.T) {
+	dir := writeLintTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	issues, err := LintConfig(filepath.Join(dir, ".nocalhost/config.yaml"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		file    string
+		line    int
+		column  int
+		message string
+	}{
+		{"config.yaml", 14, 13, "did you mean workDir?"},
+		{"config.yaml", 16, 32, "Invalid type. Expected: [integer], given: string"},
+		{"config.yaml", 17, 17, "container sidecar is not found in deployment productpage"},
+		{"config.yaml", 19, 20, "must be one of the following"},
+		{"config.yaml", 21, 13, "deployment reviews is not found"},
+		{"ratings.yaml", 6, 7, "did you mean shell?"},
+	}
+
+	for _, e := range expected {
+		found := false
+		for _, issue := range issues {
+			if strings.HasSuffix(issue.File, e.file) && issue.Line == e.line && issue.Column == e.column &&
+				strings.Contains(issue.Message, e.message) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("issue %s:%d:%d %s not found", e.file, e.line, e.column, e.message)
+		}
+	}
+	if len(issues) != len(expected) {
+		for _, issue := range issues {
+			t.Log(issue.String())
+		}
+		t.Errorf("expected %d issues, got %d", len(expected), len(issues))
+	}
+}
+
+func TestSourceMapOfInclude(t *testing.T) {
+	dir := writeLintTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	issues, err := LintConfig(filepath.Join(dir, ".nocalhost/config.yaml"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if issue.Path == "application.services.2.containers.0.dev.shel" &&
+			issue.File != filepath.Join(dir, ".nocalhost/ratings.yaml") {
+			t.Errorf("issue in included file is reported in %s", issue.File)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/envsubst"
+	"nocalhost/internal/nhctl/fp"
+	customyaml3 "nocalhost/pkg/nhctl/utils/custom_yaml_v3"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// overlayPatchKey is the directive of strategic merge in overlays, `_patch: delete`
+	// removes the item from the list, `_patch: replace` replaces the item instead of merging it,
+	// it is not `$patch` as kubernetes since `$` is substituted by envsubst
+	overlayPatchKey     = "_patch"
+	overlayPatchDelete  = "delete"
+	overlayPatchReplace = "replace"
+)
+
+// overlayMergeKeys are keys identifying items of lists which are merged by overlays,
+// the other lists are replaced by the ones in overlays
+var overlayMergeKeys = map[string][]string{
+	"services":             {"name", "serviceType"},
+	"containers":           {"name"},
+	"env":                  {"name"},
+	"dependsOn":            {"name"},
+	"helmValues":           {"key"},
+	"persistentVolumeDirs": {"path"},
+}
+
+// OverlayConfigPath returns the overlay file of configFile, overlay is either a name such as
+// `staging` for config.staging.yaml next to configFile, or a path of the overlay file
+func OverlayConfigPath(configFile, overlay string) string {
+	if strings.HasSuffix(overlay, ".yaml") || strings.HasSuffix(overlay, ".yml") {
+		if filepath.IsAbs(overlay) {
+			return overlay
+		}
+		return filepath.Join(filepath.Dir(configFile), overlay)
+	}
+	ext := filepath.Ext(configFile)
+	return strings.TrimSuffix(configFile, ext) + "." + overlay + ext
+}
+
+// overlayFiles returns overlay files of configFile, error is returned if any of them not found
+func overlayFiles(configFile string, overlays []string) ([]*fp.FilePathEnhance, error) {
+	result := make([]*fp.FilePathEnhance, 0, len(overlays))
+	for _, overlay := range overlays {
+		file := fp.NewFilePath(OverlayConfigPath(configFile, overlay))
+		if err := file.CheckExist(); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("overlay %s of config %s not found", overlay, configFile))
+		}
+		result = append(result, file)
+	}
+	return result, nil
+}
+
+// renderOverlay renders the overlay with the env file of the config it overlays,
+// paths of env files in the overlay is relative to the overlay
+func renderOverlay(overlay, envFile *fp.FilePathEnhance) (*customyaml3.Node, error) {
+	renderedStr, err := envsubst.Render(envsubst.LocalFileRenderItem{FilePathEnhance: overlay}, envFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var node *customyaml3.Node
+	if err = parseNocalhostConfigEnvFile(
+		renderedStr, overlay, func(n *customyaml3.Node) error {
+			node = n
+			return nil
+		},
+	); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Fail to parse overlay %s", overlay.Abs()))
+	}
+	return node, nil
+}
+
+// mergeConfigNode merges overlay into config with strategic merge semantics: maps are merged
+// recursively, services are merged by their names and types, containers and some other lists
+// are merged by their names, a null
+// value in overlay removes the key from config
+func mergeConfigNode(config, overlay *customyaml3.Node) {
+	merged := mergeNode(documentContent(config), documentContent(overlay), "")
+	if merged == nil || config.Kind != customyaml3.DocumentNode {
+		return
+	}
+	if len(config.Content) == 0 {
+		config.Content = []*customyaml3.Node{merged}
+	} else {
+		config.Content[0] = merged
+	}
+}
+
+func documentContent(node *customyaml3.Node) *customyaml3.Node {
+	if node != nil && node.Kind == customyaml3.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node.Content[0]
+	}
+	return node
+}
+
+func mergeNode(base, overlay *customyaml3.Node, key string) *customyaml3.Node {
+	if overlay == nil {
+		return base
+	}
+	if base == nil || base.Kind != overlay.Kind || patchOf(overlay) == overlayPatchReplace {
+		return withoutPatch(overlay)
+	}
+
+	switch overlay.Kind {
+	case customyaml3.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			k, v := overlay.Content[i], overlay.Content[i+1]
+			if k.Value == overlayPatchKey {
+				continue
+			}
+			index := mappingIndex(base, k.Value)
+			switch {
+			case v.Tag == "!!null" || patchOf(v) == overlayPatchDelete:
+				if index >= 0 {
+					base.Content = append(base.Content[:index], base.Content[index+2:]...)
+				}
+			case index >= 0:
+				base.Content[index+1] = mergeNode(base.Content[index+1], v, k.Value)
+			default:
+				base.Content = append(base.Content, k, withoutPatch(v))
+			}
+		}
+		return base
+	case customyaml3.SequenceNode:
+		mergeKeys, ok := overlayMergeKeys[key]
+		if !ok {
+			return overlay
+		}
+		for _, item := range overlay.Content {
+			name := mergeKeyOf(item, mergeKeys)
+			index := -1
+			for i, b := range base.Content {
+				if name != "" && mergeKeyOf(b, mergeKeys) == name {
+					index = i
+					break
+				}
+			}
+			switch {
+			case index >= 0 && patchOf(item) == overlayPatchDelete:
+				base.Content = append(base.Content[:index], base.Content[index+1:]...)
+			case patchOf(item) == overlayPatchDelete:
+			case index >= 0:
+				base.Content[index] = mergeNode(base.Content[index], item, "")
+			default:
+				base.Content = append(base.Content, withoutPatch(item))
+			}
+		}
+		return base
+	}
+	return overlay
+}
+
+// mergeKeyOf returns the identity of item in a merged list, it is empty if the first key is missing,
+// serviceType is deployment if not specified
+func mergeKeyOf(item *customyaml3.Node, keys []string) string {
+	if mappingValue(item, keys[0]) == "" {
+		return ""
+	}
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := mappingValue(item, key)
+		// compared case-insensitively, an unknown type just never matches other types
+		if key == "serviceType" {
+			if value = strings.ToLower(value); value == "" {
+				value = string(base.Deployment)
+			}
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, "/")
+}
+
+func mappingIndex(node *customyaml3.Node, key string) int {
+	if node == nil || node.Kind != customyaml3.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingValue(node *customyaml3.Node, key string) string {
+	if i := mappingIndex(node, key); i >= 0 {
+		return node.Content[i+1].Value
+	}
+	return ""
+}
+
+func patchOf(node *customyaml3.Node) string {
+	return mappingValue(node, overlayPatchKey)
+}
+
+func withoutPatch(node *customyaml3.Node) *customyaml3.Node {
+	if i := mappingIndex(node, overlayPatchKey); i >= 0 {
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+	}
+	return node
+}
@@ -0,0 +1,193 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	"nocalhost/internal/nhctl/envsubst"
+	"nocalhost/internal/nhctl/fp"
+	customyaml3 "nocalhost/pkg/nhctl/utils/custom_yaml_v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const overlayTestBase = `configProperties:
+  version: v2
+
+application:
+  name: bookinfo
+  manifestType: rawManifestLocal
+  resourcePath: ["manifests"]
+  env:
+    - name: DEBUG
+      value: "false"
+    - name: REGION
+      value: ap-guangzhou
+  services:
+    - name: productpage
+      serviceType: deployment
+      containers:
+        - name: productpage
+          dev:
+            image: python:3.7
+            workDir: /home/nocalhost-dev
+            portForward: ["9080:9080"]
+          hub:
+            image: productpage
+    - name: ratings
+      serviceType: deployment
+      containers:
+        - name: ratings
+          dev:
+            image: node:12
+`
+
+const overlayTestStaging = `application:
+  env:
+    - name: DEBUG
+      value: "true"
+  services:
+    - name: productpage
+      containers:
+        - name: productpage
+          dev:
+            image: python:3.8
+            portForward: ["19080:9080"]
+          hub:
+    - name: ratings
+      _patch: delete
+    - name: reviews
+      serviceType: deployment
+      containers:
+        - name: reviews
+          dev:
+            image: maven:3
+`
+
+func TestRenderConfigWithOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	if err = ioutil.WriteFile(configFile, []byte(overlayTestBase), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "config.staging.yaml"), []byte(overlayTestStaging), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlays, err := overlayFiles(configFile, []string{"staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := RenderConfig(envsubst.LocalFileRenderItem{FilePathEnhance: fp.NewFilePath(configFile)}, overlays...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envs := map[string]string{}
+	for _, env := range config.ApplicationConfig.Env {
+		envs[env.Name] = env.Value
+	}
+	if envs["DEBUG"] != "true" || envs["REGION"] != "ap-guangzhou" {
+		t.Errorf("env should be merged by name, got %v", envs)
+	}
+
+	services := map[string]bool{}
+	for _, svc := range config.ApplicationConfig.ServiceConfigs {
+		services[svc.Name] = true
+	}
+	if len(services) != 2 || !services["productpage"] || !services["reviews"] {
+		t.Fatalf("ratings should be deleted and reviews added, got %v", services)
+	}
+
+	productpage := config.GetSvcConfigV2("productpage", "deployment").GetContainerConfig("productpage")
+	if productpage == nil || productpage.Dev == nil {
+		t.Fatal("container productpage not found")
+	}
+	if productpage.Dev.Image != "python:3.8" || productpage.Dev.WorkDir != "/home/nocalhost-dev" {
+		t.Errorf("dev config should be merged, got image %s, workDir %s", productpage.Dev.Image, productpage.Dev.WorkDir)
+	}
+	if len(productpage.Dev.PortForward) != 1 || productpage.Dev.PortForward[0] != "19080:9080" {
+		t.Errorf("portForward should be replaced, got %v", productpage.Dev.PortForward)
+	}
+	if productpage.Hub != nil {
+		t.Errorf("hub should be removed by null")
+	}
+}
+
+func TestOverlayConfigPath(t *testing.T) {
+	for _, c := range []struct {
+		overlay  string
+		expected string
+	}{
+		{"staging", "/repo/.nocalhost/config.staging.yaml"},
+		{"overlays/prod.yaml", "/repo/.nocalhost/overlays/prod.yaml"},
+		{"/tmp/prod.yaml", "/tmp/prod.yaml"},
+	} {
+		if path := OverlayConfigPath("/repo/.nocalhost/config.yaml", c.overlay); path != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, path)
+		}
+	}
+}
+
+func TestMergeConfigNodeServicesByType(t *testing.T) {
+	var config, overlay customyaml3.Node
+	if err := customyaml3.Unmarshal([]byte(overlayTestBase), &config); err != nil {
+		t.Fatal(err)
+	}
+	if err := customyaml3.Unmarshal(
+		[]byte(`application:
+  services:
+    - name: productpage
+      serviceType: statefulset
+      containers:
+        - name: productpage
+          dev:
+            image: python:3.9
+`), &overlay,
+	); err != nil {
+		t.Fatal(err)
+	}
+	mergeConfigNode(&config, &overlay)
+
+	root := documentContent(&config)
+	application := root.Content[mappingIndex(root, "application")+1]
+	list := application.Content[mappingIndex(application, "services")+1].Content
+	if len(list) != 3 {
+		t.Fatalf("statefulset productpage should be added, got %d services", len(list))
+	}
+	if mergeKeyOf(list[0], overlayMergeKeys["services"]) != "productpage/deployment" {
+		t.Errorf("deployment productpage should be kept, got %s", mergeKeyOf(list[0], overlayMergeKeys["services"]))
+	}
+	if mergeKeyOf(list[2], overlayMergeKeys["services"]) != "productpage/statefulset" {
+		t.Errorf("statefulset productpage should be appended, got %s", mergeKeyOf(list[2], overlayMergeKeys["services"]))
+	}
+}
+
+func TestMergeKeyOfServiceType(t *testing.T) {
+	for _, c := range []struct {
+		service  string
+		expected string
+	}{
+		{"name: ratings", "ratings/deployment"},
+		{"name: ratings\nserviceType: StatefulSet", "ratings/statefulset"},
+		{"name: ratings\nserviceType: rollout", "ratings/rollout"},
+		{"serviceType: statefulset", ""},
+	} {
+		var node customyaml3.Node
+		if err := customyaml3.Unmarshal([]byte(c.service), &node); err != nil {
+			t.Fatal(err)
+		}
+		if key := mergeKeyOf(documentContent(&node), overlayMergeKeys["services"]); key != c.expected {
+			t.Errorf("expect %q, got %q", c.expected, key)
+		}
+	}
+}
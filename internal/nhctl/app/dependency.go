@@ -0,0 +1,162 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"nocalhost/internal/nhctl/app_flags"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/utils"
+)
+
+// DefaultDependencyReadyTimeout is how long to wait for a dependency to be ready
+// before installing the applications depend on it
+const DefaultDependencyReadyTimeout = 10 * time.Minute
+
+// Dependency is an application not installed yet which must be installed before the requested one
+type Dependency struct {
+	Name  string
+	Flags *app_flags.InstallFlags
+}
+
+// ResolveDependencies load the config of application and its dependencies from the same source
+// as flags, return the dependencies not installed yet, sorted in the order they should be installed
+func ResolveDependencies(
+	name string, flags *app_flags.InstallFlags, installed appmeta.ApplicationMetas,
+) ([]*Dependency, error) {
+	sourceDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer os.RemoveAll(sourceDir)
+
+	if flags.GitUrl != "" {
+		if err = downloadResourcesFromGit(flags.GitUrl, flags.GitRef, sourceDir); err != nil {
+			return nil, err
+		}
+	} else if flags.LocalPath != "" {
+		if err = utils.CopyDir(
+			filepath.Join(flags.LocalPath, DefaultGitNocalhostDir),
+			filepath.Join(sourceDir, DefaultGitNocalhostDir),
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	source := &Application{Name: name, ResourceTmpDir: sourceDir}
+	rootConfig, err := source.loadOrGenerateConfig(
+		flags.OuterConfig, flags.Config, flags.ResourcePath, flags.AppType, flags.ConfigOverlays,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := map[string][]string{}
+	configs := map[string]*profile.NocalHostAppConfigV2{}
+	dependencies := map[string]*profile.AppDependency{}
+
+	var resolve func(appName string, config *profile.NocalHostAppConfigV2) error
+	resolve = func(appName string, config *profile.NocalHostAppConfigV2) error {
+		graph[appName] = []string{}
+		for _, dep := range config.ApplicationConfig.DependsOn {
+			if dep == nil || dep.Name == "" {
+				return errors.New(fmt.Sprintf("dependency of application %s must have a name", appName))
+			}
+			graph[appName] = append(graph[appName], dep.Name)
+
+			if _, ok := graph[dep.Name]; ok {
+				continue
+			}
+			if meta := installed.Get(dep.Name); meta != nil && meta.IsInstalled() {
+				graph[dep.Name] = []string{}
+				continue
+			}
+			if dep.Config == "" {
+				return errors.New(
+					fmt.Sprintf(
+						"dependency %s of application %s is not installed and no config is specified to install it",
+						dep.Name, appName,
+					),
+				)
+			}
+
+			depConfigPath := source.getConfigPathInGitResourcesDir(dep.Config)
+			if _, err := os.Stat(depConfigPath); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("config %s of dependency %s not found", dep.Config, dep.Name))
+			}
+			depConfig, err := source.loadOrGenerateConfig(depConfigPath, "", nil, "", nil)
+			if err != nil {
+				return err
+			}
+			if depConfig.ApplicationConfig.Type == "" {
+				return errors.New(
+					fmt.Sprintf("manifestType of dependency %s must be specified in %s", dep.Name, dep.Config),
+				)
+			}
+			configs[dep.Name] = depConfig
+			dependencies[dep.Name] = dep
+			if err := resolve(dep.Name, depConfig); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err = resolve(name, rootConfig); err != nil {
+		return nil, err
+	}
+
+	sorted, err := appmeta.TopologicalSort(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Dependency, 0)
+	for _, appName := range sorted {
+		config, ok := configs[appName]
+		if !ok {
+			// the requested application or dependencies already installed
+			continue
+		}
+		depFlags := &app_flags.InstallFlags{
+			GitUrl:       flags.GitUrl,
+			GitRef:       flags.GitRef,
+			LocalPath:    flags.LocalPath,
+			AppType:      config.ApplicationConfig.Type,
+			Config:       dependencies[appName].Config,
+			ResourcePath: config.ApplicationConfig.ResourcePath,
+			HelmRepoName: flags.HelmRepoName,
+			HelmRepoUrl:  flags.HelmRepoUrl,
+			HelmWait:     true,
+			Timeout:      DefaultDependencyReadyTimeout,
+			WithDeps:     true,
+		}
+		result = append(result, &Dependency{Name: appName, Flags: depFlags})
+	}
+	return result, nil
+}
+
+// CheckDependencies return error if any dependency of application is not installed
+func (a *Application) CheckDependencies(installed appmeta.ApplicationMetas) error {
+	for _, dep := range a.GetApplicationConfigV2().DependsOn {
+		if meta := installed.Get(dep.Name); meta == nil || !meta.IsInstalled() {
+			return errors.New(
+				fmt.Sprintf(
+					"Application %s depends on %s which is not installed, use --with-deps to install it first",
+					a.Name, dep.Name,
+				),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/pkg/nhctl/log"
+)
+
+// DevContainerFile is the default location of devcontainer.json in the source directory
+const DevContainerFile = ".devcontainer/devcontainer.json"
+
+// DevContainer is the part of devcontainer.json which can be mapped to the dev config,
+// see https://containers.dev/implementors/json_reference/
+type DevContainer struct {
+	Name  string             `json:"name"`
+	Image string             `json:"image"`
+	Build *DevContainerBuild `json:"build"`
+	// DockerFile and Context are the legacy properties of build
+	DockerFile        string            `json:"dockerFile"`
+	Context           string            `json:"context"`
+	DockerComposeFile interface{}       `json:"dockerComposeFile"`
+	ContainerEnv      map[string]string `json:"containerEnv"`
+	ForwardPorts      []interface{}     `json:"forwardPorts"`
+	PostCreateCommand interface{}       `json:"postCreateCommand"`
+	WorkspaceFolder   string            `json:"workspaceFolder"`
+	Mounts            []interface{}     `json:"mounts"`
+}
+
+type DevContainerBuild struct {
+	Dockerfile string            `json:"dockerfile"`
+	Context    string            `json:"context"`
+	Args       map[string]string `json:"args"`
+	Target     string            `json:"target"`
+}
+
+// ParseDevContainer parses devcontainer.json, which is json with comments and trailing commas
+func ParseDevContainer(bys []byte) (*DevContainer, error) {
+	dc := &DevContainer{}
+	if err := json.Unmarshal(stripJSONC(bys), dc); err != nil {
+		return nil, errors.Wrap(err, "invalid devcontainer.json")
+	}
+	return dc, nil
+}
+
+// ConvertDevContainerFile converts the devcontainer.json in the source directory localDir to the dev config,
+// buildImage is the repository which the image built from the Dockerfile of devcontainer.json is pushed to
+func ConvertDevContainerFile(file, localDir, buildImage string) (*profile.ContainerDevConfig, error) {
+	bys, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	dc, err := ParseDevContainer(bys)
+	if err != nil {
+		return nil, err
+	}
+	// paths in devcontainer.json are relative to the dir of it
+	dcDir, err := filepath.Rel(localDir, filepath.Dir(file))
+	if err != nil || strings.HasPrefix(dcDir, "..") {
+		return nil, errors.New(fmt.Sprintf("%s is not in the source directory %s", file, localDir))
+	}
+	return ConvertDevContainer(dc, filepath.ToSlash(dcDir), filepath.Base(localDir), buildImage)
+}
+
+// ConvertDevContainer maps image/build, containerEnv, forwardPorts, postCreateCommand, workspaceFolder
+// and volume mounts of the devcontainer to image, env, portForward, command, workDir and persistentVolumeDirs.
+// dcDir is the dir of devcontainer.json relative to the source directory, and workspace is the name of the
+// source directory
+func ConvertDevContainer(dc *DevContainer, dcDir, workspace, buildImage string) (*profile.ContainerDevConfig, error) {
+	if dc.DockerComposeFile != nil {
+		return nil, errors.New("devcontainer based on docker compose is not supported, services are deployed to k8s")
+	}
+
+	dev := &profile.ContainerDevConfig{
+		Image:   dc.Image,
+		WorkDir: dc.WorkspaceFolder,
+		Sync: &profile.SyncConfig{
+			Type:              "send",
+			FilePattern:       []string{"."},
+			IgnoreFilePattern: append([]string{}, defaultIgnoreFilePattern...),
+		},
+	}
+	if dev.WorkDir == "" {
+		dev.WorkDir = "/workspaces/" + workspace
+	}
+	vars := strings.NewReplacer(
+		"${localWorkspaceFolderBasename}", workspace,
+		"${containerWorkspaceFolder}", dev.WorkDir,
+		"${containerWorkspaceFolderBasename}", path.Base(dev.WorkDir),
+	)
+	dev.WorkDir = vars.Replace(dev.WorkDir)
+
+	build := dc.Build
+	if build == nil && dc.DockerFile != "" {
+		build = &DevContainerBuild{Dockerfile: dc.DockerFile, Context: dc.Context}
+	}
+	if build != nil && build.Dockerfile != "" {
+		if buildImage == "" {
+			return nil, errors.New(
+				"repository of the dev image built from the Dockerfile must be specified, please set --build-image",
+			)
+		}
+		context := path.Join(dcDir, build.Context)
+		dockerfile, err := filepath.Rel(filepath.FromSlash(context), filepath.FromSlash(path.Join(dcDir, build.Dockerfile)))
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		dev.Image = ""
+		dev.Build = &profile.DevBuildConfig{
+			Image:      buildImage,
+			Context:    context,
+			Dockerfile: filepath.ToSlash(dockerfile),
+			Target:     build.Target,
+			Args:       build.Args,
+		}
+	}
+	if dev.Image == "" && dev.Build == nil {
+		return nil, errors.New("neither image nor build is specified in devcontainer.json")
+	}
+
+	names := make([]string, 0, len(dc.ContainerEnv))
+	for name := range dc.ContainerEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dev.Env = append(dev.Env, &profile.Env{Name: name, Value: vars.Replace(dc.ContainerEnv[name])})
+	}
+
+	for _, p := range dc.ForwardPorts {
+		port, ok := devContainerPort(p)
+		if !ok {
+			log.Warnf("Port %v forwarded from another container is not supported, skipping", p)
+			continue
+		}
+		dev.PortForward = append(dev.PortForward, fmt.Sprintf("%d:%d", port, port))
+	}
+
+	if cmd := devContainerCommand(dc.PostCreateCommand); len(cmd) > 0 {
+		dev.Command = &profile.DevCommands{Build: cmd}
+	}
+
+	for _, m := range dc.Mounts {
+		mount := devContainerMount(m)
+		target := vars.Replace(mount["target"])
+		if mount["type"] != "volume" || target == "" {
+			log.Warnf("Mount %v is not a volume, skipping", m)
+			continue
+		}
+		dev.PersistentVolumeDirs = append(dev.PersistentVolumeDirs, &profile.PersistentVolumeDir{Path: target})
+	}
+	return dev, nil
+}
+
+// devContainerPort returns the port of a forwardPorts item, which is either a port or `host:port`
+func devContainerPort(p interface{}) (int, bool) {
+	switch v := p.(type) {
+	case float64:
+		return int(v), v > 0
+	case string:
+		host, port := "localhost", v
+		if i := strings.LastIndex(v, ":"); i >= 0 {
+			host, port = v[:i], v[i+1:]
+		}
+		n, err := strconv.Atoi(port)
+		return n, err == nil && n > 0 && (host == "localhost" || host == "127.0.0.1")
+	}
+	return 0, false
+}
+
+// devContainerCommand converts a lifecycle command, which is either a shell command, a command
+// with args or an object of commands running in parallel
+func devContainerCommand(c interface{}) []string {
+	switch v := c.(type) {
+	case string:
+		if v != "" {
+			return []string{"sh", "-c", v}
+		}
+	case []interface{}:
+		cmd := make([]string, 0, len(v))
+		for _, arg := range v {
+			cmd = append(cmd, fmt.Sprint(arg))
+		}
+		return cmd
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		scripts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			cmd := devContainerCommand(v[k])
+			if len(cmd) == 3 && cmd[0] == "sh" && cmd[1] == "-c" {
+				scripts = append(scripts, cmd[2])
+			} else if len(cmd) > 0 {
+				scripts = append(scripts, strings.Join(cmd, " "))
+			}
+		}
+		if len(scripts) > 0 {
+			return []string{"sh", "-c", strings.Join(scripts, " && ")}
+		}
+	}
+	return nil
+}
+
+// devContainerMount converts a mount, which is either `type=volume,source=name,target=/path`
+// or an object, to a map of type, source and target
+func devContainerMount(m interface{}) map[string]string {
+	mount := map[string]string{}
+	switch v := m.(type) {
+	case string:
+		for _, kv := range strings.Split(v, ",") {
+			if i := strings.Index(kv, "="); i > 0 {
+				mount[strings.TrimSpace(kv[:i])] = strings.TrimSpace(kv[i+1:])
+			}
+		}
+	case map[string]interface{}:
+		for k, val := range v {
+			mount[k] = fmt.Sprint(val)
+		}
+	}
+	for _, alias := range []string{"destination", "dst"} {
+		if mount["target"] == "" && mount[alias] != "" {
+			mount["target"] = mount[alias]
+		}
+	}
+	return mount
+}
+
+// stripJSONC removes comments and trailing commas of json with comments
+func stripJSONC(bys []byte) []byte {
+	out := make([]byte, 0, len(bys))
+	inString := false
+	for i := 0; i < len(bys); i++ {
+		c := bys[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(bys) {
+				i++
+				out = append(out, bys[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(bys) && bys[i+1] == '/':
+			for i < len(bys) && bys[i] != '\n' {
+				i++
+			}
+			if i < len(bys) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(bys) && bys[i+1] == '*':
+			i += 2
+			for i+1 < len(bys) && !(bys[i] == '*' && bys[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == ']' || c == '}':
+			// drop the trailing comma
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testDevContainer = `// the dev container of reviews
+{
+	"name": "reviews", /* inline comment */
+	"build": {
+		"dockerfile": "Dockerfile",
+		"context": "..",
+		"args": {"JAVA_VERSION": "11"},
+	},
+	"containerEnv": {"SPRING_PROFILES_ACTIVE": "dev", "WORKSPACE": "${containerWorkspaceFolder}"},
+	"forwardPorts": [9080, "localhost:5005", "db:5432"],
+	"postCreateCommand": "mvn -q dependency:go-offline // not a comment",
+	"mounts": [
+		"source=reviews-m2,target=/root/.m2,type=volume",
+		{"source": "${localWorkspaceFolder}/logs", "target": "/logs", "type": "bind"},
+	],
+}
+`
+
+func TestConvertDevContainerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	localDir := filepath.Join(dir, "reviews")
+	file := filepath.Join(localDir, filepath.FromSlash(DevContainerFile))
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(file, []byte(testDevContainer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dev, err := ConvertDevContainerFile(file, localDir, "registry.example.com/dev/reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.Image != "" || dev.Build == nil || dev.Build.Context != "." ||
+		dev.Build.Dockerfile != ".devcontainer/Dockerfile" || dev.Build.Args["JAVA_VERSION"] != "11" ||
+		dev.Build.Image != "registry.example.com/dev/reviews" {
+		t.Errorf("unexpected build %+v", dev.Build)
+	}
+	if dev.WorkDir != "/workspaces/reviews" {
+		t.Errorf("unexpected workDir %s", dev.WorkDir)
+	}
+	if len(dev.Env) != 2 || dev.Env[0].Name != "SPRING_PROFILES_ACTIVE" || dev.Env[1].Value != "/workspaces/reviews" {
+		t.Errorf("unexpected env %v", dev.Env)
+	}
+	if !reflect.DeepEqual(dev.PortForward, []string{"9080:9080", "5005:5005"}) {
+		t.Errorf("unexpected port-forwards %v", dev.PortForward)
+	}
+	if dev.Command == nil ||
+		!reflect.DeepEqual(dev.Command.Build, []string{"sh", "-c", "mvn -q dependency:go-offline // not a comment"}) {
+		t.Errorf("unexpected command %+v", dev.Command)
+	}
+	if len(dev.PersistentVolumeDirs) != 1 || dev.PersistentVolumeDirs[0].Path != "/root/.m2" {
+		t.Errorf("only volume mounts should be persistent volume dirs, got %v", dev.PersistentVolumeDirs)
+	}
+}
+
+func TestConvertDevContainer(t *testing.T) {
+	dc, err := ParseDevContainer(
+		[]byte(`{"image": "mcr.microsoft.com/vscode/devcontainers/go:1.16", "workspaceFolder": "/go/src/app",
+			"postCreateCommand": {"deps": "go mod download", "tools": ["go", "install", "./tools/..."]}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := ConvertDevContainer(dc, ".devcontainer", "app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.Image != "mcr.microsoft.com/vscode/devcontainers/go:1.16" || dev.Build != nil || dev.WorkDir != "/go/src/app" {
+		t.Errorf("unexpected dev config %+v", dev)
+	}
+	expected := []string{"sh", "-c", "go mod download && go install ./tools/..."}
+	if dev.Command == nil || !reflect.DeepEqual(dev.Command.Build, expected) {
+		t.Errorf("expected %v, got %+v", expected, dev.Command)
+	}
+
+	if _, err = ConvertDevContainer(&DevContainer{DockerComposeFile: "docker-compose.yml"}, ".", "app", ""); err == nil {
+		t.Errorf("docker compose should not be supported")
+	}
+	if _, err = ConvertDevContainer(
+		&DevContainer{Build: &DevContainerBuild{Dockerfile: "Dockerfile"}}, ".devcontainer", "app", "",
+	); err == nil {
+		t.Errorf("repository of the image built should be required")
+	}
+}
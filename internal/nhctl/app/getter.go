@@ -24,6 +24,10 @@ func (a *Application) IsManifest() bool {
 	return t == appmeta.Manifest || t == appmeta.ManifestLocal || t == appmeta.ManifestGit
 }
 
+func (a *Application) IsRendered() bool {
+	return a.GetType().IsRendered()
+}
+
 func (a *Application) IsKustomize() bool {
 	t := a.GetType()
 	return t == appmeta.KustomizeGit
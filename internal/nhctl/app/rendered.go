@@ -0,0 +1,59 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/pkg/nhctl/clientgoutils"
+)
+
+// Install different type of Application: Jsonnet, Cue
+// manifests are rendered in-process, then go through the same way as Manifest
+func (a *Application) InstallRendered(doApply bool) error {
+	reader, err := a.newRenderedResourceReader()
+	if err != nil {
+		return err
+	}
+
+	return a.client.ApplyReader(
+		reader, true,
+		StandardNocalhostMetas(a.Name, a.NameSpace).
+			SetDoApply(doApply).
+			SetBeforeApply(
+				func(manifest string) error {
+					a.GetAppMeta().Manifest = a.GetAppMeta().Manifest + manifest
+					return a.GetAppMeta().Update()
+				},
+			),
+	)
+}
+
+func (a *Application) newRenderedResourceReader() (clientgoutils.ResourceReader, error) {
+	resourcesPath := a.GetResourceDir(a.ResourceTmpDir)
+
+	switch a.GetType() {
+	case appmeta.JsonnetGit, appmeta.JsonnetLocal:
+		return clientgoutils.NewJsonnetResourceReader(resourcesPath, a.renderVars(), a.ResourceTmpDir), nil
+	case appmeta.CueLocal:
+		return clientgoutils.NewCueResourceReader(resourcesPath, a.renderVars()), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("application type %s can not be rendered", a.GetType()))
+	}
+}
+
+// renderVars are fed to jsonnet as external variables and to cue as tags,
+// envFrom is already merged into env while loading the config
+func (a *Application) renderVars() map[string]string {
+	vars := make(map[string]string)
+	for _, env := range a.GetApplicationConfigV2().Env {
+		vars[env.Name] = env.Value
+	}
+	return vars
+}
@@ -60,6 +60,7 @@ func (a *Application) getResource(resourceName, resourceType string) (interface{
 	if err != nil {
 		return nil, err
 	}
+	defer cli.Close()
 
 	data, err := cli.SendGetResourceInfoCommand(
 		a.KubeConfig, a.NameSpace, "", resourceType, resourceName, nil,
@@ -13,10 +13,10 @@ import (
 	flag "nocalhost/internal/nhctl/app_flags"
 	"nocalhost/internal/nhctl/appmeta"
 	"nocalhost/internal/nhctl/fp"
+	"nocalhost/internal/nhctl/profile"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
-	"nocalhost/pkg/nhctl/tools"
 	"os"
 )
 
@@ -41,14 +41,34 @@ func (a *Application) PrepareForUpgrade(flags *flag.InstallFlags) error {
 		return nil
 	}
 
-	config, err := a.loadOrGenerateConfig(flags.OuterConfig, flags.Config, flags.ResourcePath, flags.AppType)
+	overlays := flags.ConfigOverlays
+	if len(overlays) == 0 {
+		if p, err := a.GetProfile(); err == nil {
+			overlays = p.ConfigOverlays
+		}
+	}
+
+	config, err := a.loadOrGenerateConfig(flags.OuterConfig, flags.Config, flags.ResourcePath, flags.AppType, overlays)
 	if err != nil {
 		return err
 	}
 
 	a.appMeta.Config = config
 	a.appMeta.Config.Migrated = true
-	return a.appMeta.Update()
+	if err = a.appMeta.Update(); err != nil {
+		return err
+	}
+
+	// overlays specified are used by later upgrades only if the config is loaded with them
+	if len(flags.ConfigOverlays) == 0 {
+		return nil
+	}
+	return a.UpdateProfile(
+		func(p *profile.AppProfileV2) error {
+			p.ConfigOverlays = overlays
+			return nil
+		},
+	)
 }
 
 func (a *Application) Upgrade(installFlags *flag.InstallFlags) error {
@@ -87,10 +107,27 @@ func (a *Application) Upgrade(installFlags *flag.InstallFlags) error {
 		if err := a.PostUpgradeHook(); err != nil {
 			return err
 		}
+	case appmeta.JsonnetGit, appmeta.JsonnetLocal, appmeta.CueLocal:
+
+		if err := a.PreUpgradeHook(); err != nil {
+			return err
+		}
+		if err := a.upgradeForRendered(); err != nil {
+			return err
+		}
+		if err := a.PostUpgradeHook(); err != nil {
+			return err
+		}
 	default:
 		return errors.New("Unsupported app type")
 	}
 
+	if installFlags.HelmWait && !a.IsHelm() {
+		if err := a.WaitUntilReady(installFlags.Timeout); err != nil {
+			return err
+		}
+	}
+
 	// prepare and store the delete hook while delete is trigger
 	if err := a.PrepareForPreDeleteHook(); err != nil {
 		return err
@@ -123,9 +160,20 @@ func (a *Application) upgradeForKustomize() error {
 
 func (a *Application) upgradeForManifest() error {
 	manifests := a.GetAppMeta().GetApplicationConfig().LoadManifests(fp.NewFilePath(a.ResourceTmpDir))
+	return a.upgradeByReader(clientgoutils.NewManifestResourceReader(manifests))
+}
 
+func (a *Application) upgradeForRendered() error {
+	reader, err := a.newRenderedResourceReader()
+	if err != nil {
+		return err
+	}
+	return a.upgradeByReader(reader)
+}
+
+func (a *Application) upgradeByReader(reader clientgoutils.ResourceReader) error {
 	// Read upgrade resource obj
-	updateResource, err := clientgoutils.NewManifestResourceReader(manifests).LoadResource()
+	updateResource, err := reader.LoadResource()
 	if err != nil {
 		return err
 	}
@@ -223,8 +271,7 @@ func isContainsInfo(info *resource.Info, infos []*resource.Info) bool {
 
 func (a *Application) upgradeForHelm(installFlags *flag.InstallFlags, fromRepo bool) error {
 
-	_, err := tools.ExecCommand(nil, true, false, false, "helm", "repo", "update")
-	if err != nil {
+	if err := a.client.HelmRepoUpdate(); err != nil {
 		log.Info(err.Error())
 	}
 
@@ -242,56 +289,48 @@ func (a *Application) upgradeForHelm(installFlags *flag.InstallFlags, fromRepo b
 		releaseName = a.appMeta.Application
 	}
 
-	commonParams := make([]string, 0)
-	if a.NameSpace != "" {
-		commonParams = append(commonParams, "--namespace", a.NameSpace)
+	helmFlags := &clientgoutils.HelmFlags{
+		ReleaseName: releaseName,
+		ValueFiles:  installFlags.HelmValueFile,
+		Set:         installFlags.HelmSet,
+		Atomic:      installFlags.HelmAtomic,
 	}
-	if a.KubeConfig != "" {
-		commonParams = append(commonParams, "--kubeconfig", a.KubeConfig)
+	// the default of --timeout is for waiting workloads, which is too short for helm without --wait
+	if installFlags.HelmWait || installFlags.TimeoutSet {
+		helmFlags.Timeout = installFlags.Timeout
 	}
 
-	params := []string{"upgrade", releaseName}
-
 	if fromRepo {
 		chartName := installFlags.HelmChartName
 		if a.appMeta.Config != nil && a.appMeta.Config.ApplicationConfig.Name != "" {
 			chartName = a.appMeta.Config.ApplicationConfig.Name
 		}
 		if installFlags.HelmRepoUrl != "" {
-			params = append(params, chartName, "--repo", installFlags.HelmRepoUrl)
+			helmFlags.Chart = chartName
+			helmFlags.RepoUrl = installFlags.HelmRepoUrl
+			helmFlags.Username, helmFlags.Password = credentialFromRepoUrl(installFlags.HelmRepoUrl)
 		} else if installFlags.HelmRepoName != "" {
-			params = append(params, fmt.Sprintf("%s/%s", installFlags.HelmRepoName, chartName))
-		}
-		if installFlags.HelmRepoVersion != "" {
-			params = append(params, "--version", installFlags.HelmRepoVersion)
+			helmFlags.Chart = fmt.Sprintf("%s/%s", installFlags.HelmRepoName, chartName)
 		}
+		helmFlags.Version = installFlags.HelmRepoVersion
 	} else {
 		resourcesPath := a.GetResourceDir(resourceDir)
-		params = append(params, resourcesPath[0])
+		helmFlags.Chart = resourcesPath[0]
 		log.Info("building dependency...")
-		depParams := []string{"dependency", "build", resourcesPath[0]}
-		depParams = append(depParams, commonParams...)
-		if _, err = tools.ExecCommand(nil, true, false, false, "helm", depParams...); err != nil {
+		if err = a.client.HelmDependencyBuild(resourcesPath[0], false); err != nil {
 			return errors.Wrap(err, "fail to build dependency for helm app")
 		}
 	}
 
-	if installFlags.HelmWait {
-		params = append(params, "--wait")
-	}
-	if len(installFlags.HelmValueFile) > 0 {
-		for _, values := range installFlags.HelmValueFile {
-			params = append(params, "-f", values)
-		}
-	}
-	for _, set := range installFlags.HelmSet {
-		params = append(params, "--set", set)
-	}
-	params = append(params, "--timeout", "60m")
-	params = append(params, commonParams...)
-
 	log.Info("Upgrade helm application, this may take several minutes, please waiting...")
 
-	_, err = tools.ExecCommand(nil, true, false, false, "helm", params...)
-	return errors.Wrap(err, "")
+	rel, err := a.client.HelmUpgrade(helmFlags)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if installFlags.HelmWait && !installFlags.HelmAtomic {
+		return a.client.WaitManifestReady(rel.Manifest, installFlags.Timeout)
+	}
+	return nil
 }
@@ -5,6 +5,8 @@
 
 package app_flags
 
+import "time"
+
 type InstallFlags struct {
 	GitUrl           string // resource url
 	GitRef           string
@@ -18,11 +20,16 @@ type InstallFlags struct {
 	HelmRepoVersion  string
 	HelmChartName    string
 	HelmWait         bool
+	Timeout          time.Duration
+	TimeoutSet       bool // --timeout is specified explicitly
+	HelmAtomic       bool
 	OuterConfig      string
 	Config           string
+	ConfigOverlays   []string
 	ResourcePath     []string
 	//Namespace        string
 	LocalPath string
+	WithDeps  bool
 }
 
 type ListFlags struct {
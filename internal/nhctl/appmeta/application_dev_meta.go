@@ -15,6 +15,11 @@ const (
 	DEV_STA EVENT = "DEV_STA"
 	DEV_END EVENT = "DEV_END"
 
+	// events of application meta itself, ResourceName of them is empty
+	APP_ADD EVENT = "APP_ADD"
+	APP_UPD EVENT = "APP_UPD"
+	APP_DEL EVENT = "APP_DEL"
+
 	STARTING DevStartStatus = "STARTING"
 	STARTED  DevStartStatus = "STARTED"
 	NONE     DevStartStatus = "NONE"
@@ -26,6 +31,11 @@ type DevStartStatus string
 
 //type ApplicationDevType string
 type EVENT string
+
+// IsDevEvent reports whether the event is about dev mode of a workload
+func (e EVENT) IsDevEvent() bool {
+	return e == DEV_STA || e == DEV_END
+}
 type ApplicationEvent struct {
 	Identifier   string
 	ResourceName string
@@ -52,11 +62,11 @@ func (from *ApplicationDevMeta) Events(to ApplicationDevMeta) *[]*ApplicationEve
 
 	marshalFrom, err := json.Marshal(from)
 	if err != nil {
-		log.Error("Error while marshal 'From ApplicationDevMeta': %s", err.Error())
+		log.Errorf("Error while marshal 'From ApplicationDevMeta': %s", err.Error())
 	}
 	marshalTo, err := json.Marshal(to)
 	if err != nil {
-		log.Error("Error while marshal 'To ApplicationDevMeta': %s", err.Error())
+		log.Errorf("Error while marshal 'To ApplicationDevMeta': %s", err.Error())
 	}
 
 	if string(marshalTo) == string(marshalFrom) {
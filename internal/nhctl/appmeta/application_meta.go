@@ -26,7 +26,6 @@ import (
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
-	"nocalhost/pkg/nhctl/tools"
 	"regexp"
 	"strings"
 	"sync"
@@ -62,6 +61,9 @@ const (
 	HelmLocal      AppType = "helmLocal"
 	KustomizeGit   AppType = "kustomizeGit"
 	KustomizeLocal AppType = "kustomizeLocal"
+	JsonnetGit     AppType = "jsonnetGit"
+	JsonnetLocal   AppType = "jsonnetLocal"
+	CueLocal       AppType = "cueLocal"
 
 	UNINSTALLED ApplicationState = "UNINSTALLED"
 	INSTALLING  ApplicationState = "INSTALLING"
@@ -125,6 +127,12 @@ func AppTypeOf(s string) AppType {
 		return ManifestLocal
 	case string(KustomizeGit):
 		return KustomizeGit
+	case string(JsonnetGit):
+		return JsonnetGit
+	case string(JsonnetLocal):
+		return JsonnetLocal
+	case string(CueLocal):
+		return CueLocal
 	default:
 		return Manifest
 	}
@@ -134,6 +142,11 @@ func (a AppType) IsHelm() bool {
 	return a == Helm || a == HelmRepo || a == HelmLocal
 }
 
+// IsRendered means the manifests of application are rendered in-process, such as jsonnet and cue
+func (a AppType) IsRendered() bool {
+	return a == JsonnetGit || a == JsonnetLocal || a == CueLocal
+}
+
 type ApplicationState string
 
 func ApplicationStateOf(s string) ApplicationState {
@@ -583,6 +596,7 @@ func (a *ApplicationMeta) Update() error {
 				_, _ = client.SendUpdateApplicationMetaCommand(
 					string(a.operator.GetKubeconfigBytes()), a.Ns, a.Secret.Name, a.Secret,
 				)
+				_ = client.Close()
 			}
 			return nil
 		},
@@ -649,7 +663,7 @@ func (a *ApplicationMeta) Uninstall(force bool) error {
 	}
 
 	if e := a.cleanUpDepConfigMap(); e != nil {
-		log.Error("Error while clean up dep config map %s ", e.Error())
+		log.Errorf("Error while clean up dep config map %s ", e.Error())
 	}
 
 	// remove hook
@@ -663,29 +677,11 @@ func (a *ApplicationMeta) Uninstall(force bool) error {
 	a.cleanManifest()
 
 	if a.IsHelm() {
-		commonParams := make([]string, 0)
-		if a.Ns != "" {
-			commonParams = append(commonParams, "--namespace", a.Ns)
-		}
-
-		//appProfile, _ := a.GetProfile()
-		if a.operator.ClientInner.KubeConfigFilePath() != "" {
-			commonParams = append(commonParams, "--kubeconfig", a.operator.ClientInner.KubeConfigFilePath())
+		releaseName := a.HelmReleaseName
+		if releaseName == "" {
+			releaseName = a.Application
 		}
-
-		uninstallParams := []string{"uninstall"}
-
-		if a.HelmReleaseName != "" {
-			uninstallParams = append(uninstallParams, a.HelmReleaseName)
-		} else {
-			uninstallParams = append(uninstallParams, a.Application)
-		}
-
-		uninstallParams = append(uninstallParams, commonParams...)
-		if _, err := tools.ExecCommand(
-			nil, true, true,
-			true, "helm", uninstallParams...,
-		); err != nil && !force {
+		if err := a.operator.ClientInner.HelmUninstall(releaseName, false); err != nil && !force {
 			return err
 		}
 	}
@@ -713,7 +709,7 @@ func (a *ApplicationMeta) cleanManifest() {
 	//goland:noinspection GoNilness
 	infos, err := resource.GetResourceInfo(operator.ClientInner, true)
 	if err != nil {
-		log.Error("Error while loading manifest %s, err: %s ", a.Manifest, err)
+		log.Errorf("Error while loading manifest %s, err: %s ", a.Manifest, err)
 	}
 	for _, info := range infos {
 		utils.ShouldI(clientgoutils.DeleteResourceInfo(info), "Failed to delete resource "+info.Name)
@@ -768,6 +764,7 @@ func (a *ApplicationMeta) Delete() error {
 		_, _ = client.SendUpdateApplicationMetaCommand(
 			string(a.operator.GetKubeconfigBytes()), a.Ns, name, nil,
 		)
+		_ = client.Close()
 	}
 	return nil
 }
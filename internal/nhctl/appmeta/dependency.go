@@ -0,0 +1,139 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package appmeta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Get return the meta of application, nil if not found
+func (as ApplicationMetas) Get(appName string) *ApplicationMeta {
+	for _, meta := range as {
+		if meta.Application == appName {
+			return meta
+		}
+	}
+	return nil
+}
+
+// DependencyGraph describe the applications each installed application depends on
+func (as ApplicationMetas) DependencyGraph() map[string][]string {
+	graph := make(map[string][]string)
+	for _, meta := range as {
+		if !meta.IsInstalled() {
+			continue
+		}
+		deps := make([]string, 0)
+		for _, dep := range meta.GetApplicationConfig().DependsOn {
+			deps = append(deps, dep.Name)
+		}
+		graph[meta.Application] = deps
+	}
+	return graph
+}
+
+// Dependents return all installed applications that depend on appName directly or indirectly,
+// sorted in the order they should be uninstalled
+func (as ApplicationMetas) Dependents(appName string) ([]string, error) {
+	graph := as.DependencyGraph()
+
+	dependents := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		for app, deps := range graph {
+			if dependents[app] {
+				continue
+			}
+			for _, dep := range deps {
+				if dep == name {
+					dependents[app] = true
+					visit(app)
+					break
+				}
+			}
+		}
+	}
+	visit(appName)
+
+	if len(dependents) == 0 {
+		return []string{}, nil
+	}
+
+	sorted, err := TopologicalSort(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	// dependents should be uninstalled before its dependencies, so reverse the install order
+	result := make([]string, 0, len(dependents))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if dependents[sorted[i]] {
+			result = append(result, sorted[i])
+		}
+	}
+	return result, nil
+}
+
+// TopologicalSort sort applications by dependency graph, dependencies always come before
+// the applications depend on them, application with the same depth is sorted by name
+func TopologicalSort(graph map[string][]string) ([]string, error) {
+	inDegree := make(map[string]int)
+	dependents := make(map[string][]string)
+
+	for app, deps := range graph {
+		if _, ok := inDegree[app]; !ok {
+			inDegree[app] = 0
+		}
+		for _, dep := range deps {
+			if _, ok := inDegree[dep]; !ok {
+				inDegree[dep] = 0
+			}
+			inDegree[app]++
+			dependents[dep] = append(dependents[dep], app)
+		}
+	}
+
+	ready := make([]string, 0)
+	for app, degree := range inDegree {
+		if degree == 0 {
+			ready = append(ready, app)
+		}
+	}
+
+	result := make([]string, 0, len(inDegree))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		next := make([]string, 0)
+		for _, app := range ready {
+			result = append(result, app)
+			for _, dependent := range dependents[app] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ready = next
+	}
+
+	if len(result) != len(inDegree) {
+		cycle := make([]string, 0)
+		for app, degree := range inDegree {
+			if degree > 0 {
+				cycle = append(cycle, app)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, errors.New(
+			fmt.Sprintf("circular dependency found between applications: %s", strings.Join(cycle, ", ")),
+		)
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package appmeta
+
+import (
+	"reflect"
+	"testing"
+
+	"nocalhost/internal/nhctl/profile"
+)
+
+func TestTopologicalSort(t *testing.T) {
+	sorted, err := TopologicalSort(
+		map[string][]string{
+			"web":      {"api"},
+			"api":      {"mysql", "redis"},
+			"worker":   {"redis"},
+			"mysql":    {},
+			"frontend": {"web"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"mysql", "redis", "api", "worker", "web", "frontend"}
+	if !reflect.DeepEqual(sorted, expected) {
+		t.Fatalf("expected %v, got %v", expected, sorted)
+	}
+
+	if _, err = TopologicalSort(map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}); err == nil {
+		t.Fatal("circular dependency should fail")
+	}
+}
+
+func metaDependsOn(name string, deps ...string) *ApplicationMeta {
+	meta := &ApplicationMeta{
+		Application:      name,
+		ApplicationState: INSTALLED,
+		Config:           &profile.NocalHostAppConfigV2{},
+	}
+	for _, dep := range deps {
+		meta.Config.ApplicationConfig.DependsOn = append(
+			meta.Config.ApplicationConfig.DependsOn, &profile.AppDependency{Name: dep},
+		)
+	}
+	return meta
+}
+
+func TestDependents(t *testing.T) {
+	metas := ApplicationMetas{
+		metaDependsOn("mysql"),
+		metaDependsOn("api", "mysql"),
+		metaDependsOn("web", "api"),
+		metaDependsOn("other"),
+	}
+
+	dependents, err := metas.Dependents("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"web", "api"}; !reflect.DeepEqual(dependents, expected) {
+		t.Fatalf("expected %v, got %v", expected, dependents)
+	}
+
+	if dependents, _ = metas.Dependents("other"); len(dependents) != 0 {
+		t.Fatalf("expected no dependents, got %v", dependents)
+	}
+}
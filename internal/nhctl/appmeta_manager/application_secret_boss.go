@@ -18,6 +18,7 @@ import (
 	"k8s.io/client-go/util/flowcontrol"
 	"nocalhost/internal/nhctl/appmeta"
 	"nocalhost/pkg/nhctl/log"
+	"sort"
 	"sync"
 )
 
@@ -228,3 +229,15 @@ func (s *Supervisor) key(ns string, configBytes []byte) string {
 
 	return fmt.Sprintf("%s[%s]", ns, string(state))
 }
+
+// WatchedNamespaces returns namespaces whose application secrets are being watched
+func WatchedNamespaces() []string {
+	supervisor.lock.Lock()
+	defer supervisor.lock.Unlock()
+	result := make([]string, 0, len(supervisor.deck))
+	for _, asw := range supervisor.deck {
+		result = append(result, asw.ns)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -17,6 +17,10 @@ import (
 var (
 	eventListener = []func(*ApplicationEventPack) error{
 		func(pack *ApplicationEventPack) error {
+			if !pack.Event.EventType.IsDevEvent() {
+				log.Infof("Application(%s - %s) %s", pack.Ns, pack.AppName, pack.Event.EventType)
+				return nil
+			}
 			if pack.Event.EventType == appmeta.DEV_STA {
 				log.Infof(
 					"Resource(%s - %s - %s), Name(%s) Start DevMode ",
@@ -44,6 +48,8 @@ type ApplicationEventPack struct {
 	Event           *appmeta.ApplicationEvent
 	Ns, AppName     string
 	KubeConfigBytes []byte
+	// current meta of the application, nil for APP_DEL
+	Meta *appmeta.ApplicationMeta
 }
 
 func RegisterListener(fun func(*ApplicationEventPack) error) {
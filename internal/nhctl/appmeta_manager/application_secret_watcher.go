@@ -75,13 +75,31 @@ func (asw *applicationSecretWatcher) join(secret *v1.Secret) error {
 	}
 	appName := current.Application
 
+	appEvent := appmeta.APP_ADD
 	if before, ok := asw.applicationMetas[appName]; ok && before != nil {
 		devMetaBefore = before.GetApplicationDevMeta()
+		appEvent = appmeta.APP_UPD
+		// informer resyncs the same secret periodically
+		if before.Secret != nil && before.Secret.ResourceVersion == secret.ResourceVersion {
+			appEvent = ""
+		}
 	}
 
 	devMetaCurrent = current.DevMeta
 	asw.applicationMetas[appName] = current
 
+	if appEvent != "" {
+		EventPush(
+			&ApplicationEventPack{
+				Event:           &appmeta.ApplicationEvent{EventType: appEvent},
+				Ns:              asw.ns,
+				AppName:         appName,
+				KubeConfigBytes: asw.configBytes,
+				Meta:            current,
+			},
+		)
+	}
+
 	for _, event := range *devMetaBefore.Events(devMetaCurrent) {
 		EventPush(
 			&ApplicationEventPack{
@@ -90,6 +108,7 @@ func (asw *applicationSecretWatcher) join(secret *v1.Secret) error {
 				Ns:              asw.ns,
 				AppName:         appName,
 				KubeConfigBytes: asw.configBytes,
+				Meta:            current,
 			},
 		)
 	}
@@ -104,7 +123,8 @@ func (asw *applicationSecretWatcher) left(appName string) {
 	asw.lock.Lock()
 	defer asw.lock.Unlock()
 
-	if before, ok := asw.applicationMetas[appName]; ok {
+	before, ok := asw.applicationMetas[appName]
+	if ok {
 		devMetaBefore = before.GetApplicationDevMeta()
 	}
 	//m := asw.applicationMetas[appName]
@@ -122,6 +142,17 @@ func (asw *applicationSecretWatcher) left(appName string) {
 		)
 	}
 
+	if ok {
+		EventPush(
+			&ApplicationEventPack{
+				Event:           &appmeta.ApplicationEvent{EventType: appmeta.APP_DEL},
+				Ns:              asw.ns,
+				AppName:         appName,
+				KubeConfigBytes: asw.configBytes,
+			},
+		)
+	}
+
 	// todo stop all port forward
 	//go func() {
 	//
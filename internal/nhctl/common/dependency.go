@@ -0,0 +1,40 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package common
+
+import (
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/app_flags"
+	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/pkg/nhctl/log"
+)
+
+// InstallApplicationWithDeps install the dependencies of application which are not installed yet
+// in dependency order, waiting for each of them to be ready, then install the application itself
+func InstallApplicationWithDeps(
+	flags *app_flags.InstallFlags, applicationName, kubeconfig, namespace string,
+) (*app.Application, error) {
+	metas, err := nocalhost.GetApplicationMetas(namespace, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := app.ResolveDependencies(applicationName, flags, metas)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dep := range deps {
+		log.Infof("Installing dependency %s of application %s...", dep.Name, applicationName)
+		// dependency is installed with --wait, so it is ready once installed
+		if _, err = InstallApplication(dep.Flags, dep.Name, kubeconfig, namespace); err != nil {
+			return nil, err
+		}
+		log.Infof("Dependency %s is ready", dep.Name)
+	}
+
+	return InstallApplication(flags, applicationName, kubeconfig, namespace)
+}
@@ -17,6 +17,7 @@ import (
 	"nocalhost/internal/nhctl/coloredoutput"
 	"nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/fp"
+	"nocalhost/internal/nhctl/nocalhost"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
 	"os"
@@ -92,6 +93,16 @@ func InstallApplication(flags *app_flags.InstallFlags, applicationName, kubeconf
 		return nil, errors2.New("--type must be specified")
 	}
 
+	if len(nocalhostApp.GetApplicationConfigV2().DependsOn) > 0 {
+		var metas appmeta.ApplicationMetas
+		if metas, err = nocalhost.GetApplicationMetas(namespace, kubeconfig); err != nil {
+			return nil, err
+		}
+		if err = nocalhostApp.CheckDependencies(metas); err != nil {
+			return nil, err
+		}
+	}
+
 	// add helmValue in config
 	helmValue := nocalhostApp.GetApplicationConfigV2().HelmValues
 	for _, v := range helmValue {
@@ -118,10 +129,14 @@ func InstallApplication(flags *app_flags.InstallFlags, applicationName, kubeconf
 		Values:   flags.HelmValueFile,
 		Set:      flags.HelmSet,
 		Wait:     flags.HelmWait,
+		Timeout:  flags.Timeout,
+		Atomic:   flags.HelmAtomic,
 		Chart:    flags.HelmChartName,
 		RepoUrl:  flags.HelmRepoUrl,
 		RepoName: flags.HelmRepoName,
 		Version:  flags.HelmRepoVersion,
+
+		TimeoutSet: flags.TimeoutSet,
 	}
 
 	err = nocalhostApp.Install(flag)
@@ -22,6 +22,9 @@ const (
 	DefaultNocalhostSideCarName   = "nocalhost-sidecar"
 	DefaultSidecarImagePullPolicy = "Always"
 
+	// the container developed is replaced by the dev container
+	DefaultNocalhostDevContainerName = "nocalhost-dev"
+
 	DevImageRevisionAnnotationKey            = "nhctl.dev.image.revision"
 	DevImageOriginalPodReplicasAnnotationKey = "nhctl.dev.image.original.pod.replicas"
 	DevImageRevisionAnnotationValue          = "first"
@@ -31,11 +34,41 @@ const (
 	AppLabel                 = "nocalhost.dev/app"
 
 	DefaultSideCarImage = "nocalhost-docker.pkg.coding.net/nocalhost/public/nocalhost-sidecar:sshversion"
+	DefaultTunnelImage  = "nocalhost-docker.pkg.coding.net/nocalhost/public/nocalhost-tunnel:latest"
+
+	// intercept replaces the selector of a service with the label of tunnel proxy pod
+	InterceptLabel                      = "nocalhost.dev/intercept"
+	InterceptedByAnnotation             = "nocalhost.dev/intercepted-by"
+	InterceptOriginalSelectorAnnotation = "nocalhost.dev/intercept-original-selector"
+	// label of helper pods created by `nhctl connect`
+	ConnectLabel = "nocalhost.dev/connect"
+	// snapshots of persistent volume dirs, the snapshot is either a VolumeSnapshot or a pvc copied by a helper pod
+	SnapshotLabel            = "nocalhost.dev/snapshot"
+	SnapshotSourceAnnotation = "nocalhost.dev/snapshot-source"
+	PvcHelperLabel           = "nocalhost.dev/pvc-helper"
+	// jobs building dev images by dev.build, images built are cached in the configmap keyed by hash of the context
+	DevBuildLabel          = "nocalhost.dev/dev-build"
+	DevBuildCacheConfigMap = "nocalhost-dev-build-cache"
+
+	// port-forward listens on loopback unless another address is specified
+	DefaultPortForwardBindAddress = "127.0.0.1"
 
 	DefaultApplicationSyncPidFile = "syncthing.pid"
+	// process running postSync hook of dev.lifecycle and its output
+	DefaultLifecyclePidFile = "lifecycle.pid"
+	DefaultLifecycleLogFile = "lifecycle.log"
 
 	EnableFullLogEnvKey = "NH_FULL_LOG"
 
+	// daemon serves port-forward metrics in prometheus text format on http server while it is set
+	EnableDaemonMetricsEnvKey = "NH_DAEMON_METRICS"
+
+	// comma separated origins besides loopback and IDE webviews allowed to request daemon http server
+	DaemonAllowedOriginsEnvKey = "NH_DAEMON_ALLOWED_ORIGINS"
+
+	// service manager running the daemon, set by the service installed by `nhctl daemon install`
+	DaemonManagedEnvKey = "NH_DAEMON_MANAGED"
+
 	// default is a special app type, it can be uninstalled neither installed
 	// it's a virtual application to managed that those manifest out of Nocalhost management
 	DefaultNocalhostApplication           = "default.application"
@@ -104,11 +104,18 @@ func (d *DaemonSetController) ReplaceImage(ctx context.Context, ops *model.DevSt
 	}
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		d.genContainersAndVolumes(devContainer, ops.Container, ops.StorageClass)
+		d.genContainersAndVolumes(devContainer, ops.Container, ops.DevImage, ops.StorageClass)
 	if err != nil {
 		return err
 	}
 
+	extraContainers, extraVolumes, err := d.genExtraDevContainers(&generatedDeployment.Spec.Template.Spec, sideCarContainer, ops)
+	if err != nil {
+		return err
+	}
+	devModeVolumes = append(devModeVolumes, extraVolumes...)
+	replaceContainers(&generatedDeployment.Spec.Template.Spec, extraContainers)
+
 	if ops.Container != "" {
 		for index, c := range generatedDeployment.Spec.Template.Spec.Containers {
 			if c.Name == ops.Container {
@@ -66,11 +66,17 @@ func (d *DeploymentController) ReplaceImage(ctx context.Context, ops *model.DevS
 	}
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		d.genContainersAndVolumes(devContainer, ops.Container, ops.StorageClass)
+		d.genContainersAndVolumes(devContainer, ops.Container, ops.DevImage, ops.StorageClass)
 	if err != nil {
 		return err
 	}
 
+	extraContainers, extraVolumes, err := d.genExtraDevContainers(&dep.Spec.Template.Spec, sideCarContainer, ops)
+	if err != nil {
+		return err
+	}
+	devModeVolumes = append(devModeVolumes, extraVolumes...)
+
 	for i := 0; i < 10; i++ {
 		// Get latest deployment
 		dep, err := d.Client.GetDeployment(d.Name())
@@ -88,6 +94,7 @@ func (d *DeploymentController) ReplaceImage(ctx context.Context, ops *model.DevS
 		} else {
 			dep.Spec.Template.Spec.Containers[0] = *devContainer
 		}
+		replaceContainers(&dep.Spec.Template.Spec, extraContainers)
 
 		// Add volumes to deployment spec
 		if dep.Spec.Template.Spec.Volumes == nil {
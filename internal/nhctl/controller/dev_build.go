@@ -0,0 +1,560 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	DevBuilderKaniko   = "kaniko"
+	DevBuilderBuildkit = "buildkit"
+
+	kanikoImage   = "gcr.io/kaniko-project/executor:v1.6.0"
+	buildkitImage = "moby/buildkit:v0.9.0"
+
+	devBuildTimeout          = 30 * time.Minute
+	devBuildPodReadyTimeout  = 5 * time.Minute
+	devBuildContextDir       = "/workspace"
+	devBuildContextReadyFile = ".nocalhost-context-ready"
+	devBuildContextContainer = "context"
+	devBuildBuilderContainer = "builder"
+	devBuildDockerConfigDir  = "/docker-config"
+)
+
+// BuildDevImage builds the dev image of the container by dev.build in the cluster, localDir is the
+// source directory of the service. Returns the built image, or empty if dev.build is not configured
+func (c *Controller) BuildDevImage(container, localDir string) (string, error) {
+	svcConfig, _ := c.GetConfig()
+	devConfig := svcConfig.GetContainerDevConfigOrDefault(container)
+	if devConfig == nil || devConfig.Build == nil {
+		return "", nil
+	}
+	build := devConfig.Build
+	if build.Image == "" {
+		return "", errors.New("dev.build.image must be specified")
+	}
+	if build.Builder != "" && build.Builder != DevBuilderKaniko && build.Builder != DevBuilderBuildkit {
+		return "", errors.New(fmt.Sprintf("Unsupported builder %s, should be kaniko or buildkit", build.Builder))
+	}
+
+	contextDir := filepath.Join(localDir, build.Context)
+	dockerfile := devBuildDockerfile(build)
+	if _, err := os.Stat(filepath.Join(contextDir, filepath.FromSlash(dockerfile))); err != nil {
+		return "", errors.Wrap(err, "Dockerfile not found in the build context")
+	}
+	ignores, err := readDockerIgnore(contextDir)
+	if err != nil {
+		return "", err
+	}
+	ignores.keep = dockerfile
+
+	hash, err := devBuildHash(contextDir, ignores, build)
+	if err != nil {
+		return "", err
+	}
+	image := fmt.Sprintf("%s:nh-%s", build.Image, hash[:16])
+	if c.cachedDevImage(hash) == image {
+		log.Infof("Dev image %s is up to date, skipping build", image)
+		return image, nil
+	}
+
+	log.Infof("Building dev image %s in cluster...", image)
+	job, err := c.Client.CreateJob(devBuildJob(c.Name, image, build))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		utils.ShouldI(c.Client.DeleteJob(job.Name), fmt.Sprintf("Failed to delete build job %s", job.Name))
+	}()
+
+	podName, err := c.waitDevBuildPod(job.Name)
+	if err != nil {
+		return "", err
+	}
+	if err = c.uploadBuildContext(podName, contextDir, ignores); err != nil {
+		return "", err
+	}
+
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		if c.waitBuilderStarted(podName) == nil {
+			_ = c.Client.FollowContainerLogs(podName, devBuildBuilderContainer, os.Stdout)
+		}
+	}()
+	if err = c.Client.WaitJobComplete(job.Name, devBuildTimeout); err != nil {
+		return "", errors.Wrap(err, "Failed to build dev image")
+	}
+	select {
+	case <-logsDone:
+	case <-time.After(5 * time.Second):
+	}
+
+	utils.ShouldI(c.cacheDevImage(hash, image), "Failed to cache dev image")
+	log.Infof("Dev image %s is built", image)
+	return image, nil
+}
+
+func devBuildDockerfile(build *profile.DevBuildConfig) string {
+	if build.Dockerfile == "" {
+		return "Dockerfile"
+	}
+	return path.Clean(filepath.ToSlash(build.Dockerfile))
+}
+
+// devBuildJob returns the job building the image, the context is uploaded to the init container
+// and the builder starts once the context is ready
+func devBuildJob(svcName, image string, build *profile.DevBuildConfig) *batchv1.Job {
+	dockerfile := devBuildDockerfile(build)
+	args := make([]string, 0, len(build.Args))
+	for k := range build.Args {
+		args = append(args, k)
+	}
+	sort.Strings(args)
+
+	var builder corev1.Container
+	if build.Builder == DevBuilderBuildkit {
+		output := fmt.Sprintf("type=image,name=%s,push=true", image)
+		if build.Insecure {
+			output += ",registry.insecure=true"
+		}
+		privileged := true
+		builder = corev1.Container{
+			Image:   buildkitImage,
+			Command: []string{"buildctl-daemonless.sh"},
+			Args: []string{
+				"build", "--frontend", "dockerfile.v0",
+				"--local", "context=" + devBuildContextDir,
+				"--local", "dockerfile=" + path.Join(devBuildContextDir, path.Dir(dockerfile)),
+				"--opt", "filename=" + path.Base(dockerfile),
+				"--output", output,
+			},
+			Env:             []corev1.EnvVar{{Name: "DOCKER_CONFIG", Value: devBuildDockerConfigDir}},
+			SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+		}
+		for _, k := range args {
+			builder.Args = append(builder.Args, "--opt", fmt.Sprintf("build-arg:%s=%s", k, build.Args[k]))
+		}
+		if build.Target != "" {
+			builder.Args = append(builder.Args, "--opt", "target="+build.Target)
+		}
+	} else {
+		builder = corev1.Container{
+			Image: kanikoImage,
+			Args: []string{
+				"--context=dir://" + devBuildContextDir,
+				"--dockerfile=" + path.Join(devBuildContextDir, dockerfile),
+				"--destination=" + image,
+				"--cache=true",
+			},
+			Env: []corev1.EnvVar{{Name: "DOCKER_CONFIG", Value: devBuildDockerConfigDir}},
+		}
+		for _, k := range args {
+			builder.Args = append(builder.Args, fmt.Sprintf("--build-arg=%s=%s", k, build.Args[k]))
+		}
+		if build.Target != "" {
+			builder.Args = append(builder.Args, "--target="+build.Target)
+		}
+		if build.Insecure {
+			builder.Args = append(builder.Args, "--insecure", "--skip-tls-verify")
+		}
+	}
+	builder.Name = devBuildBuilderContainer
+	builder.VolumeMounts = []corev1.VolumeMount{{Name: "context", MountPath: devBuildContextDir}}
+
+	volumes := []corev1.Volume{
+		{Name: "context", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+	if build.PushSecret != "" {
+		volumes = append(
+			volumes, corev1.Volume{
+				Name: "docker-config",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: build.PushSecret,
+						Items:      []corev1.KeyToPath{{Key: corev1.DockerConfigJsonKey, Path: "config.json"}},
+					},
+				},
+			},
+		)
+		builder.VolumeMounts = append(
+			builder.VolumeMounts, corev1.VolumeMount{Name: "docker-config", MountPath: devBuildDockerConfigDir},
+		)
+	}
+
+	labels := map[string]string{
+		_const.DevBuildLabel:     svcName,
+		_const.AppManagedByLabel: _const.AppManagedByNocalhost,
+	}
+	backoffLimit := int32(0)
+	deadline := int64(devBuildTimeout.Seconds())
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: svcName + "-dev-build-", Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					InitContainers: []corev1.Container{
+						{
+							Name:            devBuildContextContainer,
+							Image:           _const.DefaultSideCarImage,
+							ImagePullPolicy: _const.DefaultSidecarImagePullPolicy,
+							Command: []string{
+								"sh", "-c",
+								fmt.Sprintf(
+									"until [ -f %s ]; do sleep 1; done",
+									path.Join(devBuildContextDir, devBuildContextReadyFile),
+								),
+							},
+							VolumeMounts: []corev1.VolumeMount{{Name: "context", MountPath: devBuildContextDir}},
+						},
+					},
+					Containers: []corev1.Container{builder},
+					Volumes:    volumes,
+				},
+			},
+		},
+	}
+}
+
+// waitDevBuildPod waits until the context container of the build job is running, returns name of the pod
+func (c *Controller) waitDevBuildPod(jobName string) (string, error) {
+	var podName string
+	err := wait.PollImmediate(
+		time.Second, devBuildPodReadyTimeout, func() (bool, error) {
+			pods, err := c.Client.ListPodsByJob(jobName)
+			if err != nil {
+				return false, err
+			}
+			for _, pod := range pods.Items {
+				if pod.Status.Phase == corev1.PodFailed {
+					return false, errors.New(fmt.Sprintf("build pod %s failed", pod.Name))
+				}
+				for _, status := range pod.Status.InitContainerStatuses {
+					if status.Name == devBuildContextContainer && status.State.Running != nil {
+						podName = pod.Name
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		},
+	)
+	return podName, errors.Wrap(err, fmt.Sprintf("build job %s is not started", jobName))
+}
+
+func (c *Controller) waitBuilderStarted(podName string) error {
+	return wait.PollImmediate(
+		time.Second, devBuildTimeout, func() (bool, error) {
+			pod, err := c.Client.GetPod(podName)
+			if err != nil {
+				return false, err
+			}
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name == devBuildBuilderContainer {
+					return status.State.Running != nil || status.State.Terminated != nil, nil
+				}
+			}
+			return false, nil
+		},
+	)
+}
+
+// uploadBuildContext streams the context as a tar archive to the context container of the build pod,
+// the builder waits for the ready file which is only created after the whole context is archived
+func (c *Controller) uploadBuildContext(podName, contextDir string, ignores *dockerIgnore) error {
+	reader, writer := io.Pipe()
+	archived := make(chan error, 1)
+	go func() {
+		err := writeContextTar(writer, contextDir, ignores)
+		_ = writer.CloseWithError(err)
+		archived <- err
+	}()
+
+	log.Infof("Uploading build context %s", contextDir)
+	stderr := &bytes.Buffer{}
+	err := c.Client.ExecStream(
+		podName, devBuildContextContainer,
+		[]string{"sh", "-c", fmt.Sprintf("tar xf - -C %s", devBuildContextDir)},
+		reader, nil, stderr,
+	)
+	// unblock the archiving while the extraction fails
+	_ = reader.Close()
+	archiveErr := <-archived
+	if archiveErr != nil && !errors.Is(archiveErr, io.ErrClosedPipe) {
+		return errors.Wrap(archiveErr, "Failed to archive build context "+contextDir)
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to upload build context: "+strings.TrimSpace(stderr.String()))
+	}
+	if archiveErr != nil {
+		return errors.Wrap(archiveErr, "Failed to archive build context "+contextDir)
+	}
+
+	stderr.Reset()
+	if err = c.Client.ExecStream(
+		podName, devBuildContextContainer,
+		[]string{"touch", path.Join(devBuildContextDir, devBuildContextReadyFile)},
+		nil, nil, stderr,
+	); err != nil {
+		return errors.Wrap(err, "Failed to mark build context ready: "+strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (c *Controller) cachedDevImage(hash string) string {
+	cm, err := c.Client.GetConfigMaps(_const.DevBuildCacheConfigMap)
+	if err != nil {
+		return ""
+	}
+	return cm.Data[hash]
+}
+
+// cacheDevImage records the image built from the context of hash, so it's shared by the namespace
+func (c *Controller) cacheDevImage(hash, image string) error {
+	cm, err := c.Client.GetConfigMaps(_const.DevBuildCacheConfigMap)
+	if k8serrors.IsNotFound(err) {
+		_, err = c.Client.CreateConfigMap(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   _const.DevBuildCacheConfigMap,
+					Labels: map[string]string{_const.AppManagedByLabel: _const.AppManagedByNocalhost},
+				},
+				Data: map[string]string{hash: image},
+			},
+		)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[hash] = image
+	_, err = c.Client.UpdateConfigMaps(cm)
+	return err
+}
+
+// devBuildHash is the hash of files in the context and the build options
+func devBuildHash(contextDir string, ignores *dockerIgnore, build *profile.DevBuildConfig) (string, error) {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "image=%s\nbuilder=%s\ndockerfile=%s\ntarget=%s\n",
+		build.Image, build.Builder, devBuildDockerfile(build), build.Target)
+	keys := make([]string, 0, len(build.Args))
+	for k := range build.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(h, "arg:%s=%s\n", k, build.Args[k])
+	}
+
+	err := walkContext(
+		contextDir, ignores, func(rel string, info os.FileInfo, file string) error {
+			_, _ = fmt.Fprintf(h, "%s %o\n", rel, info.Mode())
+			switch {
+			case info.Mode().IsRegular():
+				f, err := os.Open(file)
+				if err != nil {
+					return errors.Wrap(err, "")
+				}
+				defer f.Close()
+				_, err = io.Copy(h, f)
+				return errors.Wrap(err, "")
+			case info.Mode()&os.ModeSymlink != 0:
+				target, err := os.Readlink(file)
+				if err != nil {
+					return errors.Wrap(err, "")
+				}
+				_, _ = fmt.Fprintln(h, target)
+			}
+			return nil
+		},
+	)
+	return hex.EncodeToString(h.Sum(nil)), err
+}
+
+// writeContextTar writes files in the context to w as a tar archive
+func writeContextTar(w io.Writer, contextDir string, ignores *dockerIgnore) error {
+	tw := tar.NewWriter(w)
+	err := walkContext(
+		contextDir, ignores, func(rel string, info os.FileInfo, file string) error {
+			var link string
+			if info.Mode()&os.ModeSymlink != 0 {
+				var err error
+				if link, err = os.Readlink(file); err != nil {
+					return errors.Wrap(err, "")
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			header.Name = rel
+			if err = tw.WriteHeader(header); err != nil {
+				return errors.Wrap(err, "")
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(file)
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return errors.Wrap(err, "")
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(tw.Close(), "")
+}
+
+// walkContext walks files not ignored in the context in lexical order, rel is the slash separated
+// path relative to the context
+func walkContext(
+	contextDir string, ignores *dockerIgnore, fn func(rel string, info os.FileInfo, file string) error,
+) error {
+	return filepath.Walk(
+		contextDir, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			rel, err := filepath.Rel(contextDir, file)
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			if rel == "." {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if ignores.ignored(rel) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				rel += "/"
+			}
+			return fn(rel, info, file)
+		},
+	)
+}
+
+// dockerIgnore is the patterns of .dockerignore, a pattern starting with `!` excludes files from
+// being ignored, the last matched pattern wins
+type dockerIgnore struct {
+	patterns []string
+	// keep is never ignored, such as the Dockerfile
+	keep string
+}
+
+func readDockerIgnore(contextDir string) (*dockerIgnore, error) {
+	ignores := &dockerIgnore{}
+	bys, err := ioutil.ReadFile(filepath.Join(contextDir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return ignores, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	for _, line := range strings.Split(string(bys), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negative := strings.HasPrefix(line, "!")
+		pattern := path.Clean(strings.TrimPrefix(strings.TrimPrefix(line, "!"), "/"))
+		if negative {
+			pattern = "!" + pattern
+		}
+		ignores.patterns = append(ignores.patterns, pattern)
+	}
+	return ignores, nil
+}
+
+func (d *dockerIgnore) ignored(rel string) bool {
+	if rel == d.keep {
+		return false
+	}
+	ignored := false
+	for _, pattern := range d.patterns {
+		negative := strings.HasPrefix(pattern, "!")
+		if matchPathOrParent(strings.TrimPrefix(pattern, "!"), rel) {
+			ignored = !negative
+		}
+	}
+	return ignored
+}
+
+// matchPathOrParent returns true if the pattern matches the path or any of its parent dirs
+func matchPathOrParent(pattern, rel string) bool {
+	patternParts := strings.Split(pattern, "/")
+	for p := rel; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if matchParts(patternParts, strings.Split(p, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchParts matches the path by parts separated by `/`, `**` matches any number of
+// directories as docker does, the other parts are matched by path.Match
+func matchParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		// trailing `**` matches files in the directory but not the directory itself
+		if len(pattern) == 1 {
+			return len(parts) > 0
+		}
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], parts[0]); !matched {
+		return false
+	}
+	return matchParts(pattern[1:], parts[1:])
+}
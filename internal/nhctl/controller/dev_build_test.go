@@ -0,0 +1,155 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nocalhost/internal/nhctl/profile"
+)
+
+func writeBuildContext(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDevBuildContext(t *testing.T) {
+	dir := writeBuildContext(
+		t, map[string]string{
+			"Dockerfile":         "FROM golang:1.16\n",
+			".dockerignore":      "# comment\nnode_modules\n*.log\n!keep.log\npkg/*/*.log\n",
+			"main.go":            "package main\n",
+			"debug.log":          "ignored",
+			"keep.log":           "kept",
+			"node_modules/a.js":  "ignored",
+			"pkg/util/util.go":   "package util\n",
+			"pkg/util/trace.log": "ignored",
+		},
+	)
+	defer os.RemoveAll(dir)
+	build := &profile.DevBuildConfig{Image: "registry.example.com/dev/reviews"}
+
+	ignores, err := readDockerIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err = writeContextTar(buf, dir, ignores); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	tr := tar.NewReader(buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	expected := []string{".dockerignore", "Dockerfile", "keep.log", "main.go", "pkg/", "pkg/util/", "pkg/util/util.go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v in context, got %v", expected, names)
+	}
+
+	hash, err := devBuildHash(dir, ignores, build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "debug.log"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := devBuildHash(dir, ignores, build); h != hash {
+		t.Errorf("hash should not be changed by ignored files")
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := devBuildHash(dir, ignores, build); h == hash {
+		t.Errorf("hash should be changed by files in context")
+	}
+	build.Args = map[string]string{"GOPROXY": "https://goproxy.cn"}
+	if h, _ := devBuildHash(dir, ignores, build); h == hash {
+		t.Errorf("hash should be changed by build args")
+	}
+}
+
+func TestDevBuildJob(t *testing.T) {
+	build := &profile.DevBuildConfig{
+		Image:      "registry.example.com/dev/reviews",
+		Dockerfile: "docker/Dockerfile.dev",
+		Args:       map[string]string{"B": "2", "A": "1"},
+		PushSecret: "registry",
+	}
+	job := devBuildJob("reviews", "registry.example.com/dev/reviews:nh-0123", build)
+	builder := job.Spec.Template.Spec.Containers[0]
+	expected := []string{
+		"--context=dir:///workspace", "--dockerfile=/workspace/docker/Dockerfile.dev",
+		"--destination=registry.example.com/dev/reviews:nh-0123", "--cache=true", "--build-arg=A=1", "--build-arg=B=2",
+	}
+	if builder.Image != kanikoImage || !reflect.DeepEqual(builder.Args, expected) {
+		t.Errorf("unexpected kaniko builder %s %v", builder.Image, builder.Args)
+	}
+	if len(builder.VolumeMounts) != 2 || len(job.Spec.Template.Spec.Volumes) != 2 {
+		t.Errorf("push secret should be mounted")
+	}
+
+	build.Builder = DevBuilderBuildkit
+	build.Insecure = true
+	builder = devBuildJob("reviews", "registry.example.com/dev/reviews:nh-0123", build).Spec.Template.Spec.Containers[0]
+	expected = []string{
+		"build", "--frontend", "dockerfile.v0", "--local", "context=/workspace",
+		"--local", "dockerfile=/workspace/docker", "--opt", "filename=Dockerfile.dev",
+		"--output", "type=image,name=registry.example.com/dev/reviews:nh-0123,push=true,registry.insecure=true",
+		"--opt", "build-arg:A=1", "--opt", "build-arg:B=2",
+	}
+	if builder.Image != buildkitImage || !reflect.DeepEqual(builder.Args, expected) {
+		t.Errorf("unexpected buildkit builder %s %v", builder.Image, builder.Args)
+	}
+}
+
+func TestMatchPathOrParent(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		rel     string
+		matched bool
+	}{
+		{"*.log", "debug.log", true},
+		{"*.log", "pkg/trace.log", false},
+		{"**/*.log", "debug.log", true},
+		{"**/*.log", "pkg/util/trace.log", true},
+		{"pkg/**/*.log", "pkg/trace.log", true},
+		{"pkg/**/*.log", "cmd/trace.log", false},
+		{"**/node_modules", "web/node_modules/a.js", true},
+		{"pkg/**", "pkg/util/util.go", true},
+		{"pkg/**", "pkg", false},
+	} {
+		if matched := matchPathOrParent(c.pattern, c.rel); matched != c.matched {
+			t.Errorf("%s matching %s should be %v", c.pattern, c.rel, c.matched)
+		}
+	}
+}
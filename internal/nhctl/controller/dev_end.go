@@ -26,6 +26,8 @@ func (c *Controller) DevEnd(reset bool) error {
 		log.WarnE(err, "something incorrect occurs when rolling back")
 	}
 
+	utils.ShouldI(c.StopAllIntercepts(), "something incorrect occurs when restoring intercepted services")
+
 	utils.ShouldI(c.AppMeta.SvcDevEnd(c.Name, c.Type), "something incorrect occurs when updating secret")
 	return nil
 }
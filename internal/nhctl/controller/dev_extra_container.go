@@ -0,0 +1,126 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/model"
+)
+
+// extraSyncHome is where workDirs of extra dev containers are mounted in the sidecar, so that
+// all of them are synced by the syncthing of the sidecar without conflicting with the workDir
+const extraSyncHome = "/nocalhost-sync"
+
+// ExtraSyncPath is the remote path of the sync folder of an extra dev container
+func ExtraSyncPath(container string) string {
+	return path.Join(extraSyncHome, container)
+}
+
+// genExtraDevContainers generates dev containers replacing ops.ExtraContainers in the pod spec, mounts
+// workDirs of them to the sidecar and returns volumes of them. Extra containers keep their names, and
+// are rolled back together with the one developed since the whole original spec is restored while dev end
+func (c *Controller) genExtraDevContainers(
+	spec *corev1.PodSpec, sideCarContainer *corev1.Container, ops *model.DevStartOptions,
+) ([]corev1.Container, []corev1.Volume, error) {
+
+	containers := make([]corev1.Container, 0, len(ops.ExtraContainers))
+	volumes := make([]corev1.Volume, 0)
+	svcConfig, _ := c.GetConfig()
+
+	for index, extra := range ops.ExtraContainers {
+		if extra.Container == ops.Container {
+			return nil, nil, errors.New(fmt.Sprintf("container %s is already developed", extra.Container))
+		}
+		original, err := findContainerInPodSpec(&corev1.Pod{Spec: *spec}, extra.Container)
+		if err != nil {
+			return nil, nil, err
+		}
+		if svcConfig == nil || svcConfig.GetContainerDevConfig(extra.Container) == nil {
+			return nil, nil, errors.New(fmt.Sprintf("dev config of container %s not found", extra.Container))
+		}
+
+		vols, mounts, err := c.genWorkDirAndPVAndMounts(extra.Container, ops.StorageClass)
+		if err != nil {
+			return nil, nil, err
+		}
+		// volume names are generated per container, prefix them to be unique in the pod
+		prefixVolumes(fmt.Sprintf("nh-extra-%d-", index+1), vols, mounts)
+
+		workDir := c.GetWorkDir(extra.Container)
+		sideCarMount, err := syncMountOf(workDir, mounts)
+		if err != nil {
+			return nil, nil, err
+		}
+		sideCarMount.MountPath = ExtraSyncPath(extra.Container)
+		sideCarContainer.VolumeMounts = append(sideCarContainer.VolumeMounts, *sideCarMount)
+
+		// the image built by dev.build comes first, GetDevImage falls back to the default dev image
+		devImage := ops.ExtraDevImages[extra.Container]
+		if devImage == "" {
+			devImage = c.GetDevImage(extra.Container)
+		}
+
+		devContainer := original.DeepCopy()
+		devContainer.Image = devImage
+		devContainer.Command = []string{"/bin/sh", "-c", "tail -f /dev/null"}
+		devContainer.Args = nil
+		devContainer.WorkingDir = workDir
+		devContainer.ImagePullPolicy = _const.DefaultSidecarImagePullPolicy
+		for _, v := range c.GetDevContainerEnv(extra.Container).DevEnv {
+			devContainer.Env = append(devContainer.Env, corev1.EnvVar{Name: v.Name, Value: v.Value})
+		}
+		devContainer.VolumeMounts = append(devContainer.VolumeMounts, mounts...)
+		if requirements := c.genResourceReq(extra.Container); requirements != nil {
+			devContainer.Resources = *requirements
+		}
+
+		containers = append(containers, *devContainer)
+		volumes = append(volumes, vols...)
+	}
+	return containers, volumes, nil
+}
+
+// replaceContainers replaces containers of the pod spec with the ones of the same names
+func replaceContainers(spec *corev1.PodSpec, containers []corev1.Container) {
+	for _, container := range containers {
+		for index, c := range spec.Containers {
+			if c.Name == container.Name {
+				spec.Containers[index] = container
+				break
+			}
+		}
+	}
+}
+
+func prefixVolumes(prefix string, volumes []corev1.Volume, mounts []corev1.VolumeMount) {
+	for i := range volumes {
+		volumes[i].Name = prefix + volumes[i].Name
+	}
+	for i := range mounts {
+		mounts[i].Name = prefix + mounts[i].Name
+	}
+}
+
+// syncMountOf returns a mount of the volume which workDir resides in
+func syncMountOf(workDir string, mounts []corev1.VolumeMount) (*corev1.VolumeMount, error) {
+	for _, m := range mounts {
+		if m.MountPath == workDir {
+			return &corev1.VolumeMount{Name: m.Name}, nil
+		}
+		if strings.HasPrefix(workDir, strings.TrimSuffix(m.MountPath, "/")+"/") {
+			return &corev1.VolumeMount{
+				Name: m.Name, SubPath: strings.TrimPrefix(workDir, strings.TrimSuffix(m.MountPath, "/")+"/"),
+			}, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("volume of workDir %s not found", workDir))
+}
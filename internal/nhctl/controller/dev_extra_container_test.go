@@ -0,0 +1,59 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSyncMountOf(t *testing.T) {
+	mounts := []corev1.VolumeMount{
+		{Name: "nh-extra-1-persist-volume-0", MountPath: "/data"},
+		{Name: "nh-extra-1-nocalhost-shared-volume", MountPath: "/home/worker"},
+	}
+	cases := []struct {
+		workDir string
+		name    string
+		subPath string
+	}{
+		{"/home/worker", "nh-extra-1-nocalhost-shared-volume", ""},
+		{"/data/src/worker", "nh-extra-1-persist-volume-0", "src/worker"},
+	}
+	for _, c := range cases {
+		m, err := syncMountOf(c.workDir, mounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Name != c.name || m.SubPath != c.subPath {
+			t.Errorf("syncMountOf(%s) = %s:%s, want %s:%s", c.workDir, m.Name, m.SubPath, c.name, c.subPath)
+		}
+	}
+	if _, err := syncMountOf("/database", mounts); err == nil {
+		t.Error("workDir not in any volume should fail")
+	}
+}
+
+func TestReplaceExtraContainers(t *testing.T) {
+	volumes := []corev1.Volume{{Name: "nocalhost-shared-volume"}}
+	mounts := []corev1.VolumeMount{{Name: "nocalhost-shared-volume", MountPath: "/home/worker"}}
+	prefixVolumes("nh-extra-1-", volumes, mounts)
+	if volumes[0].Name != "nh-extra-1-nocalhost-shared-volume" || mounts[0].Name != volumes[0].Name {
+		t.Errorf("volume is not prefixed: %s, %s", volumes[0].Name, mounts[0].Name)
+	}
+
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "app", Image: "app"}, {Name: "worker", Image: "worker"}},
+	}
+	replaceContainers(spec, []corev1.Container{{Name: "worker", Image: "dev"}})
+	if spec.Containers[0].Image != "app" || spec.Containers[1].Image != "dev" {
+		t.Errorf("unexpected containers %v", spec.Containers)
+	}
+	if ExtraSyncPath("worker") != "/nocalhost-sync/worker" {
+		t.Errorf("unexpected sync path %s", ExtraSyncPath("worker"))
+	}
+}
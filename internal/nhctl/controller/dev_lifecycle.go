@@ -0,0 +1,166 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-ps"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/retry"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	"nocalhost/internal/nhctl/syncthing/terminate"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	PostStartHook = "postStart"
+	PostSyncHook  = "postSync"
+	PreEndHook    = "preEnd"
+)
+
+// LifecycleHooks are hooks can be configured in dev.lifecycle
+var LifecycleHooks = []string{PostStartHook, PostSyncHook, PreEndHook}
+
+// LifecycleHook returns the command of the hook configured in dev config of the container,
+// container is the one developed by dev start if not specified
+func (c *Controller) LifecycleHook(container, hook string) []string {
+	if container == "" {
+		if svcProfile, err := c.GetProfile(); err == nil && svcProfile != nil {
+			container = svcProfile.DevContainer
+		}
+	}
+	config, err := c.GetConfig()
+	if err != nil || config == nil {
+		return nil
+	}
+	return lifecycleHook(config.GetContainerDevConfigOrDefault(container), hook)
+}
+
+func lifecycleHook(devConfig *profile.ContainerDevConfig, hook string) []string {
+	if devConfig == nil || devConfig.Lifecycle == nil {
+		return nil
+	}
+	switch hook {
+	case PostStartHook:
+		return devConfig.Lifecycle.PostStart
+	case PostSyncHook:
+		return devConfig.Lifecycle.PostSync
+	case PreEndHook:
+		return devConfig.Lifecycle.PreEnd
+	}
+	return nil
+}
+
+// RunLifecycleHook executes the hook in the dev container of the pod, output of the hook is
+// streamed to out and errOut. Nothing is done if the hook is not configured
+func (c *Controller) RunLifecycleHook(podName, container, hook string, out, errOut io.Writer) error {
+	cmd := c.LifecycleHook(container, hook)
+	if len(cmd) == 0 {
+		return nil
+	}
+	log.Infof("Running %s hook: %s", hook, strings.Join(cmd, " "))
+	if err := c.Client.ExecStream(podName, c.devContainerName(), cmd, nil, out, errOut); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s hook failed", hook))
+	}
+	return nil
+}
+
+// WaitForSync calls onSync after the first sync completes, then after every sync batch completes
+// if watch, until ctx is done. The first sync must complete in timeout
+func (c *Controller) WaitForSync(ctx context.Context, timeout time.Duration, watch bool, onSync func()) error {
+	client := c.NewSyncthingHttpClient(2)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if connected, err := client.SystemConnections(); err == nil && connected {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.New("wait for sync connect timeout")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+
+	// scan folder
+	if err := retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return err != nil
+	}, func() error {
+		return client.Scan()
+	}); err != nil {
+		log.Logf("scan folder manually error, err: %v", err)
+	}
+
+	lastId := int64(0)
+	synced := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second * 2):
+		}
+
+		found := false
+		if status := client.GetSyncthingStatus(); status != nil && status.Status == req.Idle {
+			if events, err := client.Events(lastId); err == nil {
+				for _, event := range events {
+					if event.EventType == req.EventFolderCompletion && event.Data.Completion == 100 {
+						found = true
+					}
+					lastId = event.Id
+				}
+			}
+		}
+		if found {
+			synced = true
+			onSync()
+			if !watch {
+				return nil
+			}
+		} else if !synced && time.Now().After(deadline) {
+			return errors.New("wait for sync finished timeout")
+		}
+	}
+}
+
+// SaveLifecyclePid records the process running postSync hook, it's terminated while file sync stops
+func (c *Controller) SaveLifecyclePid(pid int) error {
+	return errors.Wrap(ioutil.WriteFile(c.GetLifecyclePidFile(), []byte(strconv.Itoa(pid)), 0644), "")
+}
+
+// StopLifecycleProcess terminates the process running postSync hook if exists
+func (c *Controller) StopLifecycleProcess() error {
+	pidFile := c.GetLifecyclePidFile()
+	bys, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "")
+	}
+	_ = os.Remove(pidFile)
+
+	pid, err := strconv.Atoi(string(bys))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if pro, err := ps.FindProcess(pid); err != nil || pro == nil {
+		return nil
+	}
+	return terminate.Terminate(pid, false)
+}
@@ -0,0 +1,39 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"nocalhost/internal/nhctl/profile"
+)
+
+func TestLifecycleHook(t *testing.T) {
+	devConfig := &profile.ContainerDevConfig{
+		Lifecycle: &profile.DevLifecycle{
+			PostStart: []string{"go", "mod", "download"},
+			PreEnd:    []string{"sh", "-c", "pg_dump > /data/dump.sql"},
+		},
+	}
+	cases := []struct {
+		config *profile.ContainerDevConfig
+		hook   string
+		want   []string
+	}{
+		{devConfig, PostStartHook, []string{"go", "mod", "download"}},
+		{devConfig, PostSyncHook, nil},
+		{devConfig, PreEndHook, []string{"sh", "-c", "pg_dump > /data/dump.sql"}},
+		{devConfig, "unknown", nil},
+		{&profile.ContainerDevConfig{}, PostStartHook, nil},
+		{nil, PreEndHook, nil},
+	}
+	for _, c := range cases {
+		if got := lifecycleHook(c.config, c.hook); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("lifecycleHook(%s) = %v, want %v", c.hook, got, c.want)
+		}
+	}
+}
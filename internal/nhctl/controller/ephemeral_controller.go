@@ -0,0 +1,316 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/model"
+	"nocalhost/internal/nhctl/pod_controller"
+	"nocalhost/internal/nhctl/profile"
+	secret_config "nocalhost/internal/nhctl/syncthing/secret-config"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	ReplaceDevMode   = "replace"
+	EphemeralDevMode = "ephemeral"
+
+	// the ephemeral dev container exits once the file is created by dev end
+	ephemeralEndMarker  = "/tmp/.nocalhost-dev-end"
+	ephemeralWorkDirEnv = "NOCALHOST_WORKDIR"
+)
+
+// DevModes are modes supported by `dev start --mode`
+var DevModes = []string{ReplaceDevMode, EphemeralDevMode}
+
+// the ephemeral sync container finds the dev container by the marker in its filesystem, since they share the
+// process namespace of the target container, and links the workDir to the filesystem of the dev container
+const ephemeralSyncScript = `unset STGUIADDRESS
+mkdir -p ` + secret_config.DefaultSyncthingHome + `
+printf '%s' "$ST_CONFIG" > ` + secret_config.DefaultSyncthingHome + `/config.xml
+printf '%s' "$ST_CERT" > ` + secret_config.DefaultSyncthingHome + `/cert.pem
+printf '%s' "$ST_KEY" > ` + secret_config.DefaultSyncthingHome + `/key.pem
+pid=""
+while [ -z "$pid" ]; do
+  for p in $(ls /proc | grep -E '^[0-9]+$' | sort -n); do
+    if [ -f "/proc/$p/root$NOCALHOST_DEV_MARKER" ]; then pid=$p; break; fi
+  done
+  sleep 1
+done
+mkdir -p "$(dirname "$NOCALHOST_WORKDIR")" && rm -rf "$NOCALHOST_WORKDIR"
+ln -s "/proc/$pid/root$NOCALHOST_WORKDIR" "$NOCALHOST_WORKDIR"
+/bin/entrypoint.sh
+/bin/syncthing -home ` + secret_config.DefaultSyncthingHome + ` &
+while [ -d "/proc/$pid" ]; do sleep 2; done
+kill $!`
+
+// EphemeralController attaches an ephemeral dev container and an ephemeral sync container to a running pod
+// of the workload instead of replacing the pod template, the workload spec is not changed. The containers
+// can not be removed from the pod, they exit while dev end
+type EphemeralController struct {
+	*Controller
+	workload pod_controller.PodController
+}
+
+func (e *EphemeralController) Name() string {
+	return e.workload.Name()
+}
+
+func (e *EphemeralController) GetPodList() ([]corev1.Pod, error) {
+	return e.workload.GetPodList()
+}
+
+// ReplaceImage attaches ephemeral containers targeting the container developed to a running pod,
+// the dev container shares the process namespace, environments and volumes of the target
+func (e *EphemeralController) ReplaceImage(ctx context.Context, ops *model.DevStartOptions) error {
+	e.Client.Context(ctx)
+
+	if len(ops.ExtraContainers) > 0 {
+		return errors.New("extra containers are not supported in ephemeral dev mode")
+	}
+	if len(e.GetPersistentVolumeDirs(ops.Container)) > 0 {
+		log.Warn("PersistentVolumeDirs are ignored in ephemeral dev mode")
+	}
+
+	pods, err := e.workload.GetPodList()
+	if err != nil {
+		return err
+	}
+	var pod *corev1.Pod
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning && pods[i].DeletionTimestamp == nil {
+			pod = &pods[i]
+			break
+		}
+	}
+	if pod == nil {
+		return errors.New(fmt.Sprintf("no running pod of %s found", e.Name()))
+	}
+
+	target := &pod.Spec.Containers[0]
+	if ops.Container != "" {
+		if target, err = findContainerInPodSpec(pod, ops.Container); err != nil {
+			return err
+		}
+	}
+	devImage := ops.DevImage
+	if devImage == "" {
+		devImage = e.GetDevImage(ops.Container)
+	}
+	sidecarImage := e.GetDevSidecarImage(ops.Container)
+	if sidecarImage == "" {
+		sidecarImage = _const.DefaultSideCarImage
+	}
+	devEnv := make([]corev1.EnvVar, 0)
+	for _, v := range e.GetDevContainerEnv(ops.Container).DevEnv {
+		devEnv = append(devEnv, corev1.EnvVar{Name: v.Name, Value: v.Value})
+	}
+
+	devName := ephemeralContainerName(pod, _const.DefaultNocalhostDevContainerName)
+	dev, sync := ephemeralDevContainers(
+		target, devName, ephemeralContainerName(pod, _const.DefaultNocalhostSideCarName),
+		devImage, sidecarImage, e.GetWorkDir(ops.Container), e.GetSyncThingSecretName(), devEnv,
+	)
+
+	// record the pod before adding containers, so that they can be cleaned up if failed
+	if err = e.UpdateSvcProfile(
+		func(svcProfile *profile.SvcProfileV2) error {
+			svcProfile.EphemeralPod = pod.Name
+			svcProfile.EphemeralDevContainer = devName
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+
+	log.Infof("Attaching ephemeral dev container %s to pod %s", devName, pod.Name)
+	if err = e.Client.AddEphemeralContainer(pod.Name, dev); err != nil {
+		return errors.Wrap(err, "ephemeral containers may be not supported or enabled by the cluster")
+	}
+	return e.Client.AddEphemeralContainer(pod.Name, sync)
+}
+
+// GetNocalhostDevContainerPod returns the pod which the ephemeral dev container is attached to
+func (e *EphemeralController) GetNocalhostDevContainerPod() (string, error) {
+	svcProfile, err := e.GetProfile()
+	if err != nil {
+		return "", err
+	}
+	if svcProfile.EphemeralPod == "" {
+		return "", errors.New("dev container not found")
+	}
+	pod, err := e.Client.GetPod(svcProfile.EphemeralPod)
+	if err != nil {
+		return "", err
+	}
+	if !isEphemeralContainerRunning(pod, svcProfile.EphemeralDevContainer) {
+		return "", errors.New(fmt.Sprintf("dev container %s of pod %s is not running", svcProfile.EphemeralDevContainer, pod.Name))
+	}
+	return pod.Name, nil
+}
+
+// RollBack stops the ephemeral dev container, the sync container exits with it
+func (e *EphemeralController) RollBack(reset bool) error {
+	svcProfile, err := e.GetProfile()
+	if err != nil {
+		return err
+	}
+	if svcProfile.EphemeralPod != "" && svcProfile.EphemeralDevContainer != "" {
+		if err = e.stopEphemeralDev(svcProfile.EphemeralPod, svcProfile.EphemeralDevContainer); err != nil {
+			if !reset {
+				return err
+			}
+			log.WarnE(err, "Failed to stop ephemeral dev container")
+		}
+	}
+	return e.UpdateSvcProfile(
+		func(svcProfile *profile.SvcProfileV2) error {
+			svcProfile.DevMode = ""
+			svcProfile.EphemeralPod = ""
+			svcProfile.EphemeralDevContainer = ""
+			return nil
+		},
+	)
+}
+
+func (e *EphemeralController) stopEphemeralDev(podName, container string) error {
+	pod, err := e.Client.GetPod(podName)
+	if err != nil {
+		if k8serrors.IsNotFound(errors.Cause(err)) {
+			return nil
+		}
+		return err
+	}
+	if !isEphemeralContainerRunning(pod, container) {
+		return nil
+	}
+	log.Infof("Stopping ephemeral dev container %s of pod %s", container, podName)
+	stderr := &bytes.Buffer{}
+	if err = e.Client.ExecStream(
+		podName, container, []string{"touch", ephemeralEndMarker}, nil, nil, stderr,
+	); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// devContainerName returns name of the container to exec in dev mode, which is the ephemeral
+// dev container in ephemeral dev mode
+func (c *Controller) devContainerName() string {
+	if svcProfile, err := c.GetProfile(); err == nil && svcProfile != nil &&
+		svcProfile.DevMode == EphemeralDevMode && svcProfile.EphemeralDevContainer != "" {
+		return svcProfile.EphemeralDevContainer
+	}
+	return _const.DefaultNocalhostDevContainerName
+}
+
+// ephemeralContainerName returns an unused name of ephemeral containers of the pod,
+// since ephemeral containers can neither be removed nor restarted
+func ephemeralContainerName(pod *corev1.Pod, base string) string {
+	used := make(map[string]bool, len(pod.Spec.EphemeralContainers))
+	for _, ec := range pod.Spec.EphemeralContainers {
+		used[ec.Name] = true
+	}
+	name := base
+	for i := 1; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+func isEphemeralContainerRunning(pod *corev1.Pod, container string) bool {
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name == container {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
+
+// ephemeralVolumeMounts returns volume mounts of the target shared with the dev container,
+// mounts with subPath are skipped since they are not allowed in ephemeral containers
+func ephemeralVolumeMounts(target *corev1.Container) []corev1.VolumeMount {
+	mounts := make([]corev1.VolumeMount, 0, len(target.VolumeMounts))
+	for _, m := range target.VolumeMounts {
+		if m.SubPath != "" || m.SubPathExpr != "" {
+			log.Warnf("Volume %s mounted with subPath at %s is not available in the ephemeral container", m.Name, m.MountPath)
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// ephemeralDevContainers generates the dev container and the sync container, both of them target the
+// container developed. The sync container runs syncthing configured by the syncthing secret
+func ephemeralDevContainers(
+	target *corev1.Container, devName, syncName, devImage, sidecarImage, workDir, secretName string,
+	devEnv []corev1.EnvVar,
+) (corev1.EphemeralContainer, corev1.EphemeralContainer) {
+
+	marker := "/tmp/.nocalhost-" + devName
+	dev := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:            devName,
+			Image:           devImage,
+			ImagePullPolicy: _const.DefaultSidecarImagePullPolicy,
+			WorkingDir:      workDir,
+			Command: []string{
+				"/bin/sh", "-c",
+				fmt.Sprintf(
+					`mkdir -p "$%s" && touch %s && while [ ! -f %s ]; do sleep 2; done`,
+					ephemeralWorkDirEnv, marker, ephemeralEndMarker,
+				),
+			},
+			Env: append(
+				append(append([]corev1.EnvVar{}, target.Env...), devEnv...),
+				corev1.EnvVar{Name: ephemeralWorkDirEnv, Value: workDir},
+			),
+			EnvFrom:      target.EnvFrom,
+			VolumeMounts: ephemeralVolumeMounts(target),
+			Stdin:        true,
+			TTY:          true,
+		},
+		TargetContainerName: target.Name,
+	}
+
+	secretEnv := func(name, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  key,
+				},
+			},
+		}
+	}
+	sync := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:            syncName,
+			Image:           sidecarImage,
+			ImagePullPolicy: _const.DefaultSidecarImagePullPolicy,
+			Command:         []string{"/bin/sh", "-c", ephemeralSyncScript},
+			Env: []corev1.EnvVar{
+				secretEnv("ST_CONFIG", "config.xml"),
+				secretEnv("ST_CERT", "cert.pem"),
+				secretEnv("ST_KEY", "key.pem"),
+				{Name: ephemeralWorkDirEnv, Value: workDir},
+				{Name: "NOCALHOST_DEV_MARKER", Value: marker},
+			},
+		},
+		TargetContainerName: target.Name,
+	}
+	return dev, sync
+}
@@ -0,0 +1,71 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestEphemeralContainerName(t *testing.T) {
+	pod := &corev1.Pod{}
+	if name := ephemeralContainerName(pod, "nocalhost-dev"); name != "nocalhost-dev" {
+		t.Errorf("ephemeralContainerName = %s, want nocalhost-dev", name)
+	}
+	for _, n := range []string{"nocalhost-dev", "nocalhost-dev-1"} {
+		pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: n},
+		})
+	}
+	if name := ephemeralContainerName(pod, "nocalhost-dev"); name != "nocalhost-dev-2" {
+		t.Errorf("ephemeralContainerName = %s, want nocalhost-dev-2", name)
+	}
+}
+
+func TestEphemeralDevContainers(t *testing.T) {
+	target := &corev1.Container{
+		Name: "app",
+		Env:  []corev1.EnvVar{{Name: "PROFILE", Value: "prod"}},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "config", MountPath: "/etc/app"},
+			{Name: "config", MountPath: "/etc/app.yaml", SubPath: "app.yaml"},
+			{Name: "data", MountPath: "/var/log/app", SubPathExpr: "$(POD_NAME)"},
+		},
+	}
+	dev, sync := ephemeralDevContainers(
+		target, "nocalhost-dev", "nocalhost-sidecar", "golang:1.16", "sidecar:latest", "/home/nocalhost-dev",
+		"nocalhost-syncthing-secret", []corev1.EnvVar{{Name: "PROFILE", Value: "dev"}},
+	)
+
+	if dev.TargetContainerName != "app" || sync.TargetContainerName != "app" {
+		t.Errorf("target container = %s/%s, want app", dev.TargetContainerName, sync.TargetContainerName)
+	}
+	if dev.Image != "golang:1.16" || dev.WorkingDir != "/home/nocalhost-dev" {
+		t.Errorf("dev container = %s in %s", dev.Image, dev.WorkingDir)
+	}
+	if len(dev.VolumeMounts) != 1 || dev.VolumeMounts[0].MountPath != "/etc/app" {
+		t.Errorf("volume mounts of the target without subPath should be shared, got %v", dev.VolumeMounts)
+	}
+	// dev env overrides the env of the target since the latter one wins
+	if len(dev.Env) != 3 || dev.Env[1].Value != "dev" || dev.Env[2].Name != ephemeralWorkDirEnv {
+		t.Errorf("env of the dev container = %v", dev.Env)
+	}
+	if len(target.Env) != 1 {
+		t.Error("env of the target should not be changed")
+	}
+	if !strings.Contains(dev.Command[2], ephemeralEndMarker) {
+		t.Errorf("dev container should exit by %s, got %s", ephemeralEndMarker, dev.Command[2])
+	}
+
+	if sync.Image != "sidecar:latest" || len(sync.Env) != 5 {
+		t.Fatalf("sync container = %s with env %v", sync.Image, sync.Env)
+	}
+	if ref := sync.Env[0].ValueFrom.SecretKeyRef; ref.Name != "nocalhost-syncthing-secret" || ref.Key != "config.xml" {
+		t.Errorf("syncthing config should be read from the secret, got %s/%s", ref.Name, ref.Key)
+	}
+}
@@ -29,5 +29,10 @@ func (c *Controller) EnterPodTerminal(podName, container string, shell string) e
 	if shell != "" {
 		cmd = fmt.Sprintf("(%s || zsh || bash || sh)", shell)
 	}
+	// the dev container is attached to the pod as an ephemeral container in ephemeral dev mode
+	if svcProfile, _ := c.GetProfile(); svcProfile != nil && svcProfile.DevMode == EphemeralDevMode &&
+		svcProfile.EphemeralPod == pod {
+		container = svcProfile.EphemeralDevContainer
+	}
 	return c.Client.ExecShell(pod, container, cmd)
 }
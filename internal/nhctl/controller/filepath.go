@@ -18,13 +18,29 @@ func (c *Controller) GetSyncThingPidFile() string {
 	return filepath.Join(c.GetApplicationSyncDir(), _const.DefaultApplicationSyncPidFile)
 }
 
-func (c *Controller) GetApplicationSyncDir() string {
-	dirPath := ""
-	if c.Type == base.Deployment {
-		dirPath = filepath.Join(c.getAppHomeDir(), _const.DefaultBinSyncThingDirName, c.Name)
-	} else {
-		dirPath = filepath.Join(c.getAppHomeDir(), _const.DefaultBinSyncThingDirName, string(c.Type)+"-"+c.Name)
+func (c *Controller) GetLifecyclePidFile() string {
+	return filepath.Join(c.GetApplicationSyncDir(), _const.DefaultLifecyclePidFile)
+}
+
+func (c *Controller) GetLifecycleLogFile() string {
+	return filepath.Join(c.GetApplicationSyncDir(), _const.DefaultLifecycleLogFile)
+}
+
+// GetSyncThingPidFileOf returns the syncthing pid file of a service without initializing its controller
+func GetSyncThingPidFileOf(ns, appName, nid string, svcType base.SvcType, svcName string) string {
+	appHomeDir := nocalhost_path.GetAppDirUnderNs(appName, ns, nid)
+	return filepath.Join(applicationSyncDir(appHomeDir, svcType, svcName), _const.DefaultApplicationSyncPidFile)
+}
+
+func applicationSyncDir(appHomeDir string, svcType base.SvcType, svcName string) string {
+	if svcType == base.Deployment {
+		return filepath.Join(appHomeDir, _const.DefaultBinSyncThingDirName, svcName)
 	}
+	return filepath.Join(appHomeDir, _const.DefaultBinSyncThingDirName, string(svcType)+"-"+svcName)
+}
+
+func (c *Controller) GetApplicationSyncDir() string {
+	dirPath := applicationSyncDir(c.getAppHomeDir(), c.Type, c.Name)
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		utils.Should(os.MkdirAll(dirPath, 0700))
 	}
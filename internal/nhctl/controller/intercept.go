@@ -0,0 +1,215 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/tunnel"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const interceptProxyReadyTimeout = 3 * time.Minute
+
+// InterceptPort maps a port of the intercepted service to the port the tunnel proxy listens on
+type InterceptPort struct {
+	ServicePort int
+	ProxyPort   int
+}
+
+func InterceptProxyPodName(service string) string {
+	return "nocalhost-intercept-" + service
+}
+
+// interceptor identifies the workload which intercepts a service
+func (c *Controller) interceptor() string {
+	return fmt.Sprintf("%s/%s/%s", c.AppName, c.Type, c.Name)
+}
+
+// Intercept redirects traffic of the k8s service to a tunnel proxy pod, the proxy pod
+// hands the connections over to the tunnel client on the developer's machine.
+// Returns name of the proxy pod and ports it listens on
+func (c *Controller) Intercept(service string) (string, []InterceptPort, error) {
+	svc, err := c.Client.GetService(service)
+	if err != nil {
+		return "", nil, err
+	}
+	if by := svc.Annotations[_const.InterceptedByAnnotation]; by != "" && by != c.interceptor() {
+		return "", nil, errors.New(fmt.Sprintf("service %s is already intercepted by %s", service, by))
+	}
+
+	ports, err := interceptPorts(svc)
+	if err != nil {
+		return "", nil, err
+	}
+
+	podName := InterceptProxyPodName(service)
+	if _, err = c.Client.GetPod(podName); err == nil {
+		log.Infof("Deleting previous intercept proxy %s", podName)
+		if err = c.Client.DeletePod(podName, true, time.Minute); err != nil {
+			return "", nil, errors.Wrap(err, "")
+		}
+	}
+
+	log.Infof("Creating intercept proxy %s", podName)
+	if _, err = c.Client.CreatePod(c.interceptProxyPod(svc, ports)); err != nil {
+		return "", nil, err
+	}
+	if err = c.Client.WaitPodReady(podName, interceptProxyReadyTimeout); err != nil {
+		_ = c.Client.DeletePodByName(podName, 0)
+		return "", nil, err
+	}
+
+	// original selector is kept while re-intercepting a service intercepted by this workload
+	if svc.Annotations[_const.InterceptedByAnnotation] == "" {
+		selector, err := json.Marshal(svc.Spec.Selector)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "")
+		}
+		if svc.Annotations == nil {
+			svc.Annotations = map[string]string{}
+		}
+		svc.Annotations[_const.InterceptOriginalSelectorAnnotation] = string(selector)
+		svc.Annotations[_const.InterceptedByAnnotation] = c.interceptor()
+	}
+	svc.Spec.Selector = map[string]string{_const.InterceptLabel: service}
+	if _, err = c.Client.UpdateService(svc); err != nil {
+		_ = c.Client.DeletePodByName(podName, 0)
+		return "", nil, err
+	}
+	log.Infof("Service %s is intercepted", service)
+	return podName, ports, nil
+}
+
+// StopIntercept restores the selector of the service and deletes the tunnel proxy
+func (c *Controller) StopIntercept(service string) error {
+	svc, err := c.Client.GetService(service)
+	if err != nil {
+		return err
+	}
+	if svc.Annotations[_const.InterceptedByAnnotation] != "" {
+		selector := map[string]string{}
+		if err = json.Unmarshal(
+			[]byte(svc.Annotations[_const.InterceptOriginalSelectorAnnotation]), &selector,
+		); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("fail to restore selector of service %s", service))
+		}
+		svc.Spec.Selector = selector
+		delete(svc.Annotations, _const.InterceptOriginalSelectorAnnotation)
+		delete(svc.Annotations, _const.InterceptedByAnnotation)
+		if _, err = c.Client.UpdateService(svc); err != nil {
+			return err
+		}
+		log.Infof("Service %s is restored", service)
+	}
+
+	if err = c.Client.DeletePodByName(InterceptProxyPodName(service), 0); err != nil &&
+		!k8serrors.IsNotFound(errors.Cause(err)) {
+		return err
+	}
+	return nil
+}
+
+// InterceptedServices returns services intercepted by this workload
+func (c *Controller) InterceptedServices() ([]string, error) {
+	services, err := c.Client.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0)
+	for _, svc := range services {
+		if svc.Annotations[_const.InterceptedByAnnotation] == c.interceptor() {
+			result = append(result, svc.Name)
+		}
+	}
+	return result, nil
+}
+
+// StopAllIntercepts restores all services intercepted by this workload
+func (c *Controller) StopAllIntercepts() error {
+	services, err := c.InterceptedServices()
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		if err = c.StopIntercept(service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interceptPorts returns the ports proxy should listen on, proxy listens on the target port
+// of service port, or the service port itself if target port is a name of container port
+func interceptPorts(svc *corev1.Service) ([]InterceptPort, error) {
+	ports := make([]InterceptPort, 0)
+	used := map[int]bool{}
+	for _, port := range svc.Spec.Ports {
+		if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+			log.Warnf("Port %d/%s of service %s can not be intercepted", port.Port, port.Protocol, svc.Name)
+			continue
+		}
+		proxyPort := int(port.Port)
+		if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal > 0 {
+			proxyPort = int(port.TargetPort.IntVal)
+		}
+		if proxyPort == tunnel.DefaultTunnelPort {
+			return nil, errors.New(
+				fmt.Sprintf("port %d of service %s conflicts with the tunnel port", proxyPort, svc.Name),
+			)
+		}
+		if used[proxyPort] {
+			continue
+		}
+		used[proxyPort] = true
+		ports = append(ports, InterceptPort{ServicePort: int(port.Port), ProxyPort: proxyPort})
+	}
+	if len(ports) == 0 {
+		return nil, errors.New(fmt.Sprintf("service %s has no tcp port to intercept", svc.Name))
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].ServicePort < ports[j].ServicePort })
+	return ports, nil
+}
+
+func (c *Controller) interceptProxyPod(svc *corev1.Service, ports []InterceptPort) *corev1.Pod {
+	proxyPorts := make([]string, 0, len(ports))
+	for _, port := range ports {
+		proxyPorts = append(proxyPorts, strconv.Itoa(port.ProxyPort))
+	}
+
+	// named target ports are resolved by container ports of the proxy, names of container ports must be unique
+	containerPorts := []corev1.ContainerPort{{Name: "tunnel", ContainerPort: tunnel.DefaultTunnelPort}}
+	named := map[string]bool{"tunnel": true}
+	for _, port := range svc.Spec.Ports {
+		if port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "" && !named[port.TargetPort.StrVal] {
+			named[port.TargetPort.StrVal] = true
+			containerPorts = append(
+				containerPorts, corev1.ContainerPort{Name: port.TargetPort.StrVal, ContainerPort: port.Port},
+			)
+		}
+	}
+
+	args := []string{
+		"--tunnel-port", strconv.Itoa(tunnel.DefaultTunnelPort),
+		"--ports", strings.Join(proxyPorts, ","),
+	}
+	return tunnel.HelperPod(
+		InterceptProxyPodName(svc.Name),
+		map[string]string{_const.InterceptLabel: svc.Name},
+		map[string]string{_const.InterceptedByAnnotation: c.interceptor()},
+		args, containerPorts, tunnel.DefaultTunnelPort,
+	)
+}
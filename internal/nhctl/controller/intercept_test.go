@@ -0,0 +1,68 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestInterceptPorts(t *testing.T) {
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Port: 8080, TargetPort: intstr.FromString("http")},
+				{Port: 80, TargetPort: intstr.FromInt(9080)},
+				{Port: 53, Protocol: corev1.ProtocolUDP},
+				{Port: 443},
+			},
+		},
+	}
+	ports, err := interceptPorts(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []InterceptPort{
+		{ServicePort: 80, ProxyPort: 9080},
+		{ServicePort: 443, ProxyPort: 443},
+		{ServicePort: 8080, ProxyPort: 8080},
+	}
+	if !reflect.DeepEqual(ports, expected) {
+		t.Fatalf("expect %v, got %v", expected, ports)
+	}
+
+	svc.Spec.Ports = []corev1.ServicePort{{Port: 53, Protocol: corev1.ProtocolUDP}}
+	if _, err = interceptPorts(svc); err == nil {
+		t.Fatal("expect error while no tcp port")
+	}
+}
+
+func TestInterceptProxyPodPortNames(t *testing.T) {
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 8080, TargetPort: intstr.FromString("http")},
+				{Name: "http-alt", Port: 8081, TargetPort: intstr.FromString("http")},
+			},
+		},
+	}
+	svc.Name = "productpage"
+	ports, err := interceptPorts(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := (&Controller{}).interceptProxyPod(svc, ports)
+	names := make([]string, 0)
+	for _, port := range pod.Spec.Containers[0].Ports {
+		names = append(names, port.Name)
+	}
+	if expected := []string{"tunnel", "http"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expect %v, got %v", expected, names)
+	}
+}
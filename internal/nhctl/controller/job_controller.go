@@ -70,11 +70,18 @@ func (j *JobController) ReplaceImage(ctx context.Context, ops *model.DevStartOpt
 	}
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		j.genContainersAndVolumes(devContainer, ops.Container, ops.StorageClass)
+		j.genContainersAndVolumes(devContainer, ops.Container, ops.DevImage, ops.StorageClass)
 	if err != nil {
 		return err
 	}
 
+	extraContainers, extraVolumes, err := j.genExtraDevContainers(&generatedJob.Spec.Template.Spec, sideCarContainer, ops)
+	if err != nil {
+		return err
+	}
+	devModeVolumes = append(devModeVolumes, extraVolumes...)
+	replaceContainers(&generatedJob.Spec.Template.Spec, extraContainers)
+
 	if ops.Container != "" {
 		for index, c := range generatedJob.Spec.Template.Spec.Containers {
 			if c.Name == ops.Container {
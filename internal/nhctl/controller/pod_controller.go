@@ -11,6 +11,17 @@ import (
 )
 
 func (c *Controller) BuildPodController() pod_controller.PodController {
+	workload := c.buildWorkloadController()
+	if workload == nil {
+		return nil
+	}
+	if svcProfile, err := c.GetProfile(); err == nil && svcProfile != nil && svcProfile.DevMode == EphemeralDevMode {
+		return &EphemeralController{Controller: c, workload: workload}
+	}
+	return workload
+}
+
+func (c *Controller) buildWorkloadController() pod_controller.PodController {
 	switch c.Type {
 	case base.Deployment:
 		return &DeploymentController{Controller: c}
@@ -19,7 +19,8 @@ import (
 	"time"
 )
 
-func (c *Controller) EndDevPortForward(localPort int, remotePort int) error {
+// EndDevPortForward stops the port-forward of localPort:remotePort by protocol, empty protocol means tcp
+func (c *Controller) EndDevPortForward(localPort int, remotePort int, protocol string) error {
 
 	svcProfile, err := c.GetProfile()
 	if err != nil {
@@ -27,11 +28,12 @@ func (c *Controller) EndDevPortForward(localPort int, remotePort int) error {
 	}
 
 	for _, portForward := range svcProfile.DevPortForwardList {
-		if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+		if portForward.Matches(localPort, remotePort, protocol) {
 			client, err := daemon_client.NewDaemonClient(portForward.Sudo)
 			if err != nil {
 				return err
 			}
+			defer client.Close()
 			return client.SendStopPortForwardCommand(
 				&model.NocalHostResource{
 					NameSpace:   c.NameSpace,
@@ -40,6 +42,7 @@ func (c *Controller) EndDevPortForward(localPort int, remotePort int) error {
 					Service:     c.Name,
 					ServiceType: string(c.Type),
 					PodName:     "",
+					Protocol:    portForward.Protocol,
 				}, localPort, remotePort,
 			)
 		}
@@ -52,6 +55,7 @@ func StopPortForward(ns, nid, app, svc string, portForward *profile.DevPortForwa
 	if err != nil {
 		return err
 	}
+	defer client.Close()
 	return client.SendStopPortForwardCommand(
 		&model.NocalHostResource{
 			NameSpace:   ns,
@@ -60,6 +64,7 @@ func StopPortForward(ns, nid, app, svc string, portForward *profile.DevPortForwa
 			Service:     svc,
 			ServiceType: portForward.ServiceType,
 			PodName:     portForward.PodName,
+			Protocol:    portForward.Protocol,
 		}, portForward.LocalPort, portForward.RemotePort,
 	)
 }
@@ -71,28 +76,24 @@ func (c *Controller) StopAllPortForward() error {
 	}
 
 	for _, portForward := range svcProfile.DevPortForwardList {
-		utils.Should(c.EndDevPortForward(portForward.LocalPort, portForward.RemotePort))
+		utils.Should(c.EndDevPortForward(portForward.LocalPort, portForward.RemotePort, portForward.Protocol))
 	}
 	return nil
 }
 
-// StopPortForwardByPort port format 8080:80
+// StopPortForwardByPort port format 8080:80 or 8125:8125/udp
 func (c *Controller) StopPortForwardByPort(port string) error {
-
-	ports := strings.Split(port, ":")
-	localPort, err := strconv.Atoi(ports[0])
-	if err != nil {
-		return errors.Wrap(err, "")
-	}
-	remotePort, err := strconv.Atoi(ports[1])
+	localPort, remotePort, protocol, err := ParsePortForward(port)
 	if err != nil {
-		return errors.Wrap(err, "")
+		return err
 	}
-	return c.EndDevPortForward(localPort, remotePort)
+	return c.EndDevPortForward(localPort, remotePort, protocol)
 }
 
-func (c *Controller) UpdatePortForwardStatus(localPort int, remotePort int, portStatus string, reason string) error {
-	pf, err := c.GetPortForward(localPort, remotePort)
+func (c *Controller) UpdatePortForwardStatus(
+	localPort int, remotePort int, protocol string, portStatus string, reason string,
+) error {
+	pf, err := c.GetPortForward(localPort, remotePort, protocol)
 	if err != nil {
 		return err
 	}
@@ -112,7 +113,7 @@ func (c *Controller) UpdatePortForwardStatus(localPort int, remotePort int, port
 			}
 
 			for _, portForward := range svcProfile.DevPortForwardList {
-				if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+				if portForward.Matches(localPort, remotePort, protocol) {
 					portForward.Status = portStatus
 					portForward.Reason = reason
 					//portForward.Pid = os.Getpid()
@@ -126,14 +127,14 @@ func (c *Controller) UpdatePortForwardStatus(localPort int, remotePort int, port
 }
 
 // GetPortForward If not found return err
-func (c *Controller) GetPortForward(localPort, remotePort int) (*profile.DevPortForward, error) {
+func (c *Controller) GetPortForward(localPort, remotePort int, protocol string) (*profile.DevPortForward, error) {
 	var err error
 	svcProfile, err := c.GetProfile()
 	if err != nil {
 		return nil, err
 	}
 	for _, pf := range svcProfile.DevPortForwardList {
-		if pf.LocalPort == localPort && pf.RemotePort == remotePort {
+		if pf.Matches(localPort, remotePort, protocol) {
 			return pf, nil
 		}
 	}
@@ -175,71 +176,146 @@ func (c *Controller) PortForwardAfterDevStart(podName, containerName string) err
 	}
 
 	for _, pf := range cc.PortForward {
-		lPort, rPort, err := GetPortForwardForString(pf)
+		lPort, rPort, protocol, err := ParsePortForward(pf)
 		if err != nil {
 			log.WarnE(err, "")
 			continue
 		}
-		log.Infof("Forwarding %d:%d", lPort, rPort)
-		utils.Should(c.PortForward(podName, lPort, rPort, ""))
+		log.Infof("Forwarding %s", pf)
+		_, err = c.StartPortForward(podName, lPort, rPort, "", &PortForwardOptions{Protocol: protocol})
+		utils.Should(err)
 	}
 	return nil
 }
 
 // PortForward Role: If set to "SYNC", means it is a pf used for syncthing
 func (c *Controller) PortForward(podName string, localPort, remotePort int, role string) error {
+	_, err := c.StartPortForward(podName, localPort, remotePort, role, nil)
+	return err
+}
+
+// PortForwardOptions of port-forward started by daemon server
+type PortForwardOptions struct {
+	// tcp or udp, udp datagrams are relayed by an udp relay running in the pod
+	Protocol string
+	// local address to listen on, loopback if empty
+	BindAddress string
+	// profile.PortPolicyFixed or profile.PortPolicyNextFree
+	PortPolicy string
+}
+
+// StartPortForward returns the local port actually listened on, which is different from localPort
+// if the port is unavailable and the port policy is next-free
+func (c *Controller) StartPortForward(
+	podName string, localPort, remotePort int, role string, opts *PortForwardOptions,
+) (int, error) {
+	return c.sendPortForward(
+		&model.NocalHostResource{
+			NameSpace:   c.NameSpace,
+			Application: c.AppName,
+			Service:     c.Name,
+			ServiceType: c.Type.String(),
+			PodName:     podName,
+		}, localPort, remotePort, role, opts,
+	)
+}
+
+// PortForwardService forward localPort to servicePort of k8s Service, daemon server
+// re-targets to another ready pod of the service while the current one is gone
+func (c *Controller) PortForwardService(
+	k8sService string, localPort, servicePort int, role string, opts *PortForwardOptions,
+) (int, error) {
+	return c.sendPortForward(
+		&model.NocalHostResource{
+			NameSpace:   c.NameSpace,
+			Application: c.AppName,
+			Service:     c.Name,
+			ServiceType: c.Type.String(),
+			K8sService:  k8sService,
+		}, localPort, servicePort, role, opts,
+	)
+}
 
+func (c *Controller) sendPortForward(
+	nhResource *model.NocalHostResource, localPort, remotePort int, role string, opts *PortForwardOptions,
+) (int, error) {
+	if opts != nil {
+		nhResource.Protocol = opts.Protocol
+		nhResource.BindAddress = opts.BindAddress
+		nhResource.PortPolicy = opts.PortPolicy
+	}
 	isAdmin := utils.IsSudoUser()
 	client, err := daemon_client.NewDaemonClient(isAdmin)
 	if err != nil {
-		return err
-	}
-	nhResource := &model.NocalHostResource{
-		NameSpace:   c.NameSpace,
-		Application: c.AppName,
-		Service:     c.Name,
-		ServiceType: c.Type.String(),
-		PodName:     podName,
+		return 0, err
 	}
+	defer client.Close()
 
-	if err = client.SendStartPortForwardCommand(nhResource, localPort, remotePort, role, c.AppMeta.NamespaceId); err != nil {
-		return err
+	resp, err := client.SendStartPortForwardCommand(nhResource, localPort, remotePort, role, c.AppMeta.NamespaceId)
+	if err != nil {
+		return 0, err
+	}
+	if resp.LocalPort != localPort {
+		log.Infof(
+			"Port %d is unavailable, port-forward %d:%d has been started on %s instead",
+			localPort, resp.LocalPort, remotePort, resp.BindAddress,
+		)
 	} else {
 		log.Infof("Port-forward %d:%d has been started", localPort, remotePort)
-		return c.SetPortForwardedStatus(true) //  todo: move port-forward start
 	}
+	return resp.LocalPort, c.SetPortForwardedStatus(true) //  todo: move port-forward start
 }
 
-// portStr is like 8080:80 or :80
+// portStr is like 8080:80 or :80, udp is not supported
 func GetPortForwardForString(portStr string) (int, int, error) {
+	localPort, remotePort, protocol, err := ParsePortForward(portStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if protocol == profile.PortForwardProtocolUDP {
+		return 0, 0, errors.New(fmt.Sprintf("udp is not supported: %s", portStr))
+	}
+	return localPort, remotePort, nil
+}
+
+// ParsePortForward parses portStr like 8080:80, :80 or 8125:8125/udp, protocol is tcp if not specified
+func ParsePortForward(portStr string) (int, int, string, error) {
 	var err error
+	protocol := profile.PortForwardProtocolTCP
+	if i := strings.LastIndex(portStr, "/"); i >= 0 {
+		protocol = strings.ToLower(portStr[i+1:])
+		portStr = portStr[:i]
+		if protocol != profile.PortForwardProtocolTCP && protocol != profile.PortForwardProtocolUDP {
+			return 0, 0, "", errors.New(fmt.Sprintf("Unsupported protocol of port: %s", protocol))
+		}
+	}
 	s := strings.Split(portStr, ":")
 	if len(s) < 2 {
-		return 0, 0, errors.New(fmt.Sprintf("Wrong format of port: %s", portStr))
+		return 0, 0, "", errors.New(fmt.Sprintf("Wrong format of port: %s", portStr))
 	}
 	var localPort, remotePort int
 	sLocalPort := s[0]
 	if sLocalPort == "" {
 		// get random port in local
 		if localPort, err = ports.GetAvailablePort(); err != nil {
-			return 0, 0, err
+			return 0, 0, "", err
 		}
 	} else if localPort, err = strconv.Atoi(sLocalPort); err != nil {
-		return 0, 0, errors.Wrap(err, fmt.Sprintf("Wrong format of local port: %s.", sLocalPort))
+		return 0, 0, "", errors.Wrap(err, fmt.Sprintf("Wrong format of local port: %s.", sLocalPort))
 	}
 	if remotePort, err = strconv.Atoi(s[1]); err != nil {
-		return 0, 0, errors.Wrap(err, fmt.Sprintf("wrong format of remote port: %s, skipped", s[1]))
+		return 0, 0, "", errors.Wrap(err, fmt.Sprintf("wrong format of remote port: %s, skipped", s[1]))
 	}
-	return localPort, remotePort, nil
+	return localPort, remotePort, protocol, nil
 }
 
-func (c *Controller) CheckIfPortForwardExists(localPort, remotePort int) (bool, error) {
+func (c *Controller) CheckIfPortForwardExists(localPort, remotePort int, protocol string) (bool, error) {
 	svcProfile, err := c.GetProfile()
 	if err != nil {
 		return false, err
 	}
 	for _, portForward := range svcProfile.DevPortForwardList {
-		if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+		if portForward.Matches(localPort, remotePort, protocol) {
 			return true, nil
 		}
 	}
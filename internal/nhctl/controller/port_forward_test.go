@@ -0,0 +1,59 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package controller
+
+import (
+	"testing"
+
+	"nocalhost/internal/nhctl/profile"
+)
+
+func TestParsePortForward(t *testing.T) {
+	cases := []struct {
+		port     string
+		local    int
+		remote   int
+		protocol string
+	}{
+		{"8080:80", 8080, 80, "tcp"},
+		{"8125:8125/udp", 8125, 8125, "udp"},
+		{"53:53/UDP", 53, 53, "udp"},
+		{"9090:90/tcp", 9090, 90, "tcp"},
+	}
+	for _, c := range cases {
+		local, remote, protocol, err := ParsePortForward(c.port)
+		if err != nil {
+			t.Fatalf("%s: %v", c.port, err)
+		}
+		if local != c.local || remote != c.remote || protocol != c.protocol {
+			t.Fatalf("%s: got %d:%d/%s", c.port, local, remote, protocol)
+		}
+	}
+
+	for _, port := range []string{"8080", "8080:80/sctp", "a:80"} {
+		if _, _, _, err := ParsePortForward(port); err == nil {
+			t.Fatalf("expect error for %s", port)
+		}
+	}
+	if _, _, err := GetPortForwardForString("8125:8125/udp"); err == nil {
+		t.Fatal("expect error for udp")
+	}
+}
+
+func TestDevPortForwardMatches(t *testing.T) {
+	tcp := &profile.DevPortForward{LocalPort: 8125, RemotePort: 8125}
+	udp := &profile.DevPortForward{LocalPort: 8125, RemotePort: 8125, Protocol: profile.PortForwardProtocolUDP}
+
+	if !tcp.Matches(8125, 8125, "") || !tcp.Matches(8125, 8125, profile.PortForwardProtocolTCP) {
+		t.Error("tcp port-forward should match tcp")
+	}
+	if tcp.Matches(8125, 8125, profile.PortForwardProtocolUDP) || udp.Matches(8125, 8125, "") {
+		t.Error("tcp and udp port-forwards of the same ports should not match each other")
+	}
+	if !udp.Matches(8125, 8125, profile.PortForwardProtocolUDP) || udp.Matches(8125, 8126, profile.PortForwardProtocolUDP) {
+		t.Error("udp port-forward should match its ports only")
+	}
+}
@@ -147,7 +147,7 @@ func (c *Controller) AddPortForwardToDB(port *profile.DevPortForward) error {
 	)
 }
 
-func (c *Controller) DeletePortForwardFromDB(localPort, remotePort int) error {
+func (c *Controller) DeletePortForwardFromDB(localPort, remotePort int, protocol string) error {
 
 	return c.UpdateSvcProfile(
 		func(svcProfile *profile.SvcProfileV2) error {
@@ -157,7 +157,7 @@ func (c *Controller) DeletePortForwardFromDB(localPort, remotePort int) error {
 
 			indexToDelete := -1
 			for index, portForward := range svcProfile.DevPortForwardList {
-				if portForward.LocalPort == localPort && portForward.RemotePort == remotePort {
+				if portForward.Matches(localPort, remotePort, protocol) {
 					indexToDelete = index
 					break
 				}
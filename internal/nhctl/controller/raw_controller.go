@@ -64,11 +64,18 @@ func (r *RawPodController) ReplaceImage(ctx context.Context, ops *model.DevStart
 	}
 
 	devContainer, sideCarContainer, devModeVolumes, err :=
-		r.genContainersAndVolumes(devContainer, ops.Container, ops.StorageClass)
+		r.genContainersAndVolumes(devContainer, ops.Container, ops.DevImage, ops.StorageClass)
 	if err != nil {
 		return err
 	}
 
+	extraContainers, extraVolumes, err := r.genExtraDevContainers(&originalPod.Spec, sideCarContainer, ops)
+	if err != nil {
+		return err
+	}
+	devModeVolumes = append(devModeVolumes, extraVolumes...)
+	replaceContainers(&originalPod.Spec, extraContainers)
+
 	if ops.Container != "" {
 		for index, c := range originalPod.Spec.Containers {
 			if c.Name == ops.Container {
@@ -73,44 +73,9 @@ func (c *Controller) CheckIfExist() (bool, error) {
 }
 
 func (c *Controller) GetContainerImage(container string) (string, error) {
-	var podSpec v1.PodSpec
-	switch c.Type {
-	case base.Deployment:
-		d, err := c.Client.GetDeployment(c.Name)
-		if err != nil {
-			return "", err
-		}
-		podSpec = d.Spec.Template.Spec
-	case base.StatefulSet:
-		s, err := c.Client.GetStatefulSet(c.Name)
-		if err != nil {
-			return "", err
-		}
-		podSpec = s.Spec.Template.Spec
-	case base.DaemonSet:
-		d, err := c.Client.GetDaemonSet(c.Name)
-		if err != nil {
-			return "", err
-		}
-		podSpec = d.Spec.Template.Spec
-	case base.Job:
-		j, err := c.Client.GetJobs(c.Name)
-		if err != nil {
-			return "", err
-		}
-		podSpec = j.Spec.Template.Spec
-	case base.CronJob:
-		j, err := c.Client.GetCronJobs(c.Name)
-		if err != nil {
-			return "", err
-		}
-		podSpec = j.Spec.JobTemplate.Spec.Template.Spec
-	case base.Pod:
-		p, err := c.Client.GetPod(c.Name)
-		if err != nil {
-			return "", err
-		}
-		podSpec = p.Spec
+	podSpec, err := c.GetPodSpec()
+	if err != nil {
+		return "", err
 	}
 
 	for _, c := range podSpec.Containers {
@@ -122,6 +87,15 @@ func (c *Controller) GetContainerImage(container string) (string, error) {
 }
 
 func (c *Controller) GetContainers() ([]v1.Container, error) {
+	podSpec, err := c.GetPodSpec()
+	if err != nil {
+		return nil, err
+	}
+	return podSpec.Containers, nil
+}
+
+// GetPodSpec returns the pod template spec of the workload in cluster
+func (c *Controller) GetPodSpec() (*v1.PodSpec, error) {
 	var podSpec v1.PodSpec
 	switch c.Type {
 	case base.Deployment:
@@ -162,7 +136,7 @@ func (c *Controller) GetContainers() ([]v1.Container, error) {
 		podSpec = p.Spec
 	}
 
-	return podSpec.Containers, nil
+	return &podSpec, nil
 }
 
 func (c *Controller) GetDescription() *profile.SvcProfileV2 {
@@ -88,7 +88,10 @@ func (s *StatefulSetController) ReplaceImage(ctx context.Context, ops *model.Dev
 	devModeMounts = append(devModeMounts, workDirAndPersistVolumeMounts...)
 
 	workDir := s.GetWorkDir(ops.Container)
-	devImage := s.GetDevImage(ops.Container) // Default : replace the first container
+	devImage := ops.DevImage
+	if devImage == "" {
+		devImage = s.GetDevImage(ops.Container) // Default : replace the first container
+	}
 	if devImage == "" {
 		return errors.New("Dev image must be specified")
 	}
@@ -126,6 +129,12 @@ func (s *StatefulSetController) ReplaceImage(ctx context.Context, ops *model.Dev
 	rq, _ := convertResourceQuota(r)
 	sideCarContainer.Resources = *rq
 
+	extraContainers, extraVolumes, err := s.genExtraDevContainers(&dep.Spec.Template.Spec, &sideCarContainer, ops)
+	if err != nil {
+		return err
+	}
+	devModeVolumes = append(devModeVolumes, extraVolumes...)
+
 	needToRemovePriorityClass := false
 	for i := 0; i < 10; i++ {
 		events, err := s.Client.ListEventsByStatefulSet(s.Name())
@@ -148,6 +157,7 @@ func (s *StatefulSetController) ReplaceImage(ctx context.Context, ops *model.Dev
 		} else {
 			dep.Spec.Template.Spec.Containers[0] = *devContainer
 		}
+		replaceContainers(&dep.Spec.Template.Spec, extraContainers)
 
 		// Add volumes to deployment spec
 		if dep.Spec.Template.Spec.Volumes == nil {
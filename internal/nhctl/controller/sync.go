@@ -20,10 +20,12 @@ import (
 
 func (c *Controller) StopFileSyncOnly() error {
 
+	utils.ShouldI(c.StopLifecycleProcess(), "Failed to stop postSync hook")
+
 	pf, err := c.GetPortForwardForSync()
 	utils.Should(err)
 	if pf != nil {
-		utils.Should(c.EndDevPortForward(pf.LocalPort, pf.RemotePort))
+		utils.Should(c.EndDevPortForward(pf.LocalPort, pf.RemotePort, pf.Protocol))
 	}
 
 	// read and clean up pid file
@@ -140,6 +140,18 @@ func (c *Controller) NewSyncthing(container string, localSyncDir []string, syncD
 			index++
 		}
 	}
+	// containers developed in the same dev session are synced from their own local dirs by the same syncthing
+	for _, extra := range svcProfile.ExtraDevContainers {
+		s.Folders = append(
+			s.Folders,
+			&syncthing.Folder{
+				Name:       strconv.Itoa(index),
+				LocalPath:  extra.LocalSyncDir,
+				RemotePath: ExtraSyncPath(extra.Container),
+			},
+		)
+		index++
+	}
 	_ = appProfile.Save()
 	return s, nil
 }
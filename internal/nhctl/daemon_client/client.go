@@ -10,23 +10,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 	"io/ioutil"
 	v1 "k8s.io/api/core/v1"
 	"net"
 	"nocalhost/internal/nhctl/daemon_common"
 	"nocalhost/internal/nhctl/daemon_server/command"
+	rpcv1 "nocalhost/internal/nhctl/daemon_server/rpc/v1"
 	"nocalhost/internal/nhctl/model"
 	"nocalhost/internal/nhctl/syncthing/ports"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
 	"os"
 	"runtime/debug"
+	"sync"
 	"time"
 )
 
 type DaemonClient struct {
 	isSudo                 bool
 	daemonServerListenPort int
+	token                  string
+
+	// commands are sent by grpc if daemon serves it
+	grpcPort int
+	rpcOnce  sync.Once
+	conn     *grpc.ClientConn
+	rpc      rpcv1.DaemonServiceClient
 }
 
 func waitForTCPPortToBeReady(port int, timeout time.Duration) error {
@@ -63,6 +73,14 @@ func CheckIfDaemonServerRunning(isSudoUser bool) bool {
 	return !ports.IsTCP4PortAvailable("0.0.0.0", listenPort)
 }
 
+// WaitForDaemonServerToBeDown waits for the daemon to release its port after it is stopped
+func WaitForDaemonServerToBeDown(isSudoUser bool, timeout time.Duration) error {
+	if isSudoUser {
+		return waitForTCPPortToBeDown(daemon_common.SudoDaemonPort, timeout)
+	}
+	return waitForTCPPortToBeDown(daemon_common.DefaultDaemonPort, timeout)
+}
+
 func NewDaemonClient(isSudoUser bool) (*DaemonClient, error) {
 	var err error
 	client := &DaemonClient{
@@ -74,6 +92,11 @@ func NewDaemonClient(isSudoUser bool) (*DaemonClient, error) {
 		client.daemonServerListenPort = daemon_common.DefaultDaemonPort
 	}
 
+	// token must exist before the daemon starts, so that it is owned by current user
+	if client.token, err = daemon_common.GetOrCreateDaemonToken(isSudoUser); err != nil {
+		return nil, err
+	}
+
 	if err = startDaemonServerIfNotRunning(isSudoUser, client.daemonServerListenPort); err != nil {
 		return nil, err
 	}
@@ -87,12 +110,14 @@ func NewDaemonClient(isSudoUser bool) (*DaemonClient, error) {
 	if daemonServerInfo.Upgrading {
 		return nil, errors.New("DaemonServer is upgrading. Please Try it later.")
 	}
+	client.grpcPort = daemonServerInfo.GrpcPort
 
 	nhctlPath, _ := utils.GetNhctlPath()
 
 	// force update earlier version
 	if daemonServerInfo.NhctlPath == "" {
 		log.Log("Daemon server need to stop and restart")
+		client.grpcPort = 0
 		utils.Should(client.SendStopDaemonServerCommand())
 		utils.Should(waitForTCPPortToBeDown(client.daemonServerListenPort, 10*time.Second))
 		if err = startDaemonServerIfNotRunning(isSudoUser, client.daemonServerListenPort); err != nil {
@@ -103,7 +128,17 @@ func NewDaemonClient(isSudoUser bool) (*DaemonClient, error) {
 		// if from same nhctl
 		if nhctlPath == daemonServerInfo.NhctlPath || utils.IsWindows() {
 			log.Logf("Daemon server [%s] need to upgrade", daemonServerInfo.NhctlPath)
+			client.grpcPort = 0
 			utils.Should(client.SendRestartDaemonServerCommand())
+		} else if daemonServerInfo.ProtocolVersion != 0 && daemonServerInfo.ProtocolVersion != command.ProtocolVersion {
+			return nil, errors.New(
+				fmt.Sprintf(
+					"Daemon server [%s] speaks protocol v%d but current nhctl [%s] speaks v%d, "+
+						"please stop the daemon server with `%s daemon stop` and try again",
+					daemonServerInfo.NhctlPath, daemonServerInfo.ProtocolVersion,
+					nhctlPath, command.ProtocolVersion, daemonServerInfo.NhctlPath,
+				),
+			)
 		} else {
 			// else do not update the daemon
 			log.Logf(
@@ -144,6 +179,21 @@ func (d *DaemonClient) SendGetDaemonServerInfoCommand() (*daemon_common.DaemonSe
 	return daemonServerInfo, err
 }
 
+// SendGetDaemonHealthCommand asks daemon for the health of its routines and informers
+func (d *DaemonClient) SendGetDaemonHealthCommand() (*daemon_common.DaemonHealth, error) {
+	cmd := &command.BaseCommand{CommandType: command.GetDaemonHealth, ClientStack: string(debug.Stack())}
+	bys, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	health := &daemon_common.DaemonHealth{}
+	if err := d.sendAndWaitForResponse(bys, health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
 func (d *DaemonClient) SendCheckClusterStatusCommand(kubeContent string) (*daemon_common.CheckClusterStatus, error) {
 	cmd := &command.CheckClusterStatusCommand{
 		CommandType:       command.CheckClusterStatus,
@@ -151,6 +201,18 @@ func (d *DaemonClient) SendCheckClusterStatusCommand(kubeContent string) (*daemo
 		KubeConfigContent: kubeContent,
 	}
 
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		reply, err := c.CheckClusterStatus(ctx, &rpcv1.CheckClusterStatusRequest{Kubeconfig: kubeContent})
+		if err == nil {
+			return &daemon_common.CheckClusterStatus{Available: reply.GetAvailable(), Info: reply.GetInfo()}, nil
+		}
+		if !fallbackToJson("CheckClusterStatus", err) {
+			return nil, rpcError("CheckClusterStatus", err)
+		}
+	}
+
 	bys, err := json.Marshal(cmd)
 	if err != nil {
 		return nil, errors.Wrap(err, "")
@@ -204,6 +266,43 @@ func (d *DaemonClient) SendGetDaemonServerStatusCommand() (*daemon_common.Daemon
 	return status, nil
 }
 
+// SendGetPortForwardStatsCommand returns running port-forwards of the application with their traffic stats
+func (d *DaemonClient) SendGetPortForwardStatsCommand(ns, appName string) ([]*daemon_common.PortForwardProfile, error) {
+	cmd := &command.GetPortForwardStatsCommand{
+		CommandType: command.GetPortForwardStats,
+		ClientStack: string(debug.Stack()),
+		NameSpace:   ns,
+		AppName:     appName,
+	}
+
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		reply, err := c.ListPortForwards(ctx, &rpcv1.ListPortForwardsRequest{Namespace: ns, AppName: appName})
+		if err == nil {
+			result := make([]*daemon_common.PortForwardProfile, 0, len(reply.GetItems()))
+			for _, item := range reply.GetItems() {
+				result = append(result, item.Profile())
+			}
+			return result, nil
+		}
+		if !fallbackToJson("ListPortForwards", err) {
+			return nil, rpcError("ListPortForwards", err)
+		}
+	}
+
+	bys, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	result := make([]*daemon_common.PortForwardProfile, 0)
+	if err = d.sendAndWaitForResponse(bys, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // the reason why return a interface is applicationMeta needs to using this client,
 // otherwise it will cause cycle import
 func (d *DaemonClient) SendGetApplicationMetaCommand(ns, appName, kubeConfigContent string) (interface{}, error) {
@@ -216,9 +315,23 @@ func (d *DaemonClient) SendGetApplicationMetaCommand(ns, appName, kubeConfigCont
 		KubeConfigContent: kubeConfigContent,
 	}
 
+	var meta interface{}
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		reply, err := c.GetApplicationMeta(
+			ctx, &rpcv1.GetApplicationMetaRequest{Namespace: ns, AppName: appName, Kubeconfig: kubeConfigContent},
+		)
+		if err == nil {
+			return meta, errors.Wrap(json.Unmarshal(reply.GetJson(), &meta), "")
+		}
+		if !fallbackToJson("GetApplicationMeta", err) {
+			return nil, rpcError("GetApplicationMeta", err)
+		}
+	}
+
 	bys, err := json.Marshal(gamCmd)
 
-	var meta interface{}
 	if err = d.sendAndWaitForResponse(bys, &meta); err != nil {
 		return nil, err
 	}
@@ -236,18 +349,34 @@ func (d *DaemonClient) SendGetApplicationMetasCommand(ns, kubeConfig string) ([]
 		KubeConfigContent: kubeConfig,
 	}
 
+	var meta []interface{}
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		reply, err := c.GetApplicationMetas(
+			ctx, &rpcv1.GetApplicationMetasRequest{Namespace: ns, Kubeconfig: kubeConfig},
+		)
+		if err == nil {
+			return meta, errors.Wrap(json.Unmarshal(reply.GetJson(), &meta), "")
+		}
+		if !fallbackToJson("GetApplicationMetas", err) {
+			return nil, rpcError("GetApplicationMetas", err)
+		}
+	}
+
 	bys, err := json.Marshal(gamCmd)
 
-	var meta []interface{}
 	if err = d.sendAndWaitForResponse(bys, &meta); err != nil {
 		return nil, err
 	}
 	return meta, nil
 }
 
+// SendStartPortForwardCommand returns the address port-forward listens on, the local port
+// may differ from localPort according to the port policy
 func (d *DaemonClient) SendStartPortForwardCommand(
 	nhSvc *model.NocalHostResource, localPort, remotePort int, role, nid string,
-) error {
+) (*command.PortForwardResponse, error) {
 
 	startPFCmd := &command.PortForwardCommand{
 		CommandType: command.StartPortForward,
@@ -262,14 +391,40 @@ func (d *DaemonClient) SendStartPortForwardCommand(
 		RemotePort:  remotePort,
 		Role:        role,
 		Nid:         nid,
+		K8sService:  nhSvc.K8sService,
+		Protocol:    nhSvc.Protocol,
+		BindAddress: nhSvc.BindAddress,
+		PortPolicy:  nhSvc.PortPolicy,
+	}
+
+	resp := &command.PortForwardResponse{}
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		reply, err := c.StartPortForward(ctx, rpcv1.NewPortForwardRequest(startPFCmd))
+		if err == nil {
+			resp.BindAddress, resp.LocalPort = reply.GetBindAddress(), int(reply.GetLocalPort())
+			return resp, nil
+		}
+		if !fallbackToJson("StartPortForward", err) {
+			return nil, rpcError("StartPortForward", err)
+		}
 	}
 
 	bys, err := json.Marshal(startPFCmd)
 	if err != nil {
-		return errors.Wrap(err, "")
+		return nil, errors.Wrap(err, "")
 	}
 
-	return d.sendAndWaitForResponse(bys, nil)
+	if err = d.sendAndWaitForResponse(bys, resp); err != nil {
+		return nil, err
+	}
+	// elder daemon responses nothing
+	if resp.LocalPort == 0 {
+		resp.LocalPort = localPort
+		resp.BindAddress = startPFCmd.BindAddress
+	}
+	return resp, nil
 }
 
 // SendStopPortForwardCommand send port forward to daemon
@@ -287,6 +442,19 @@ func (d *DaemonClient) SendStopPortForwardCommand(nhSvc *model.NocalHostResource
 		PodName:     nhSvc.PodName,
 		LocalPort:   localPort,
 		RemotePort:  remotePort,
+		Protocol:    nhSvc.Protocol,
+	}
+
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		_, err := c.StopPortForward(ctx, rpcv1.NewPortForwardRequest(startPFCmd))
+		if err == nil {
+			return nil
+		}
+		if !fallbackToJson("StopPortForward", err) {
+			return rpcError("StopPortForward", err)
+		}
 	}
 
 	bys, err := json.Marshal(startPFCmd)
@@ -318,12 +486,29 @@ func (d *DaemonClient) SendGetResourceInfoCommand(
 		Label:        label,
 	}
 
+	var result interface{}
+	if c := d.RpcClient(); c != nil {
+		ctx, cancel := rpcContext()
+		defer cancel()
+		reply, err := c.GetResourceInfo(
+			ctx, &rpcv1.GetResourceInfoRequest{
+				Kubeconfig: kubeconfig, Namespace: ns, AppName: appName,
+				Resource: resource, ResourceName: resourceName, Label: label,
+			},
+		)
+		if err == nil {
+			return result, errors.Wrap(json.Unmarshal(reply.GetJson(), &result), "")
+		}
+		if !fallbackToJson("GetResourceInfo", err) {
+			return nil, rpcError("GetResourceInfo", err)
+		}
+	}
+
 	bys, err := json.Marshal(cmd)
 	if err != nil {
 		return nil, errors.Wrap(err, "")
 	}
 
-	var result interface{}
 	if err := d.sendAndWaitForResponse(bys, &result); err != nil {
 		return nil, err
 	}
@@ -359,7 +544,8 @@ func (d *DaemonClient) SendUpdateApplicationMetaCommand(
 	return true, nil
 }
 
-// SendKubeconfigOperationCommand send add/remove kubeconfig request to daemon
+// SendKubeconfigOperationCommand send add/remove kubeconfig request to daemon,
+// it is still sent as json command since it does not wait for the daemon to finish
 func (d *DaemonClient) SendKubeconfigOperationCommand(kubeconfigBytes []byte, ns string, operation command.Operation) error {
 	cmd := &command.KubeconfigOperationCommand{
 		CommandType: command.KubeconfigOperation,
@@ -383,6 +569,9 @@ func (d *DaemonClient) sendDataToDaemonServer(data []byte) error {
 	if err != nil {
 		return errors.Wrap(err, "Failed to unmarshal command")
 	}
+	if data, err = command.SignCommand(data, d.token); err != nil {
+		return err
+	}
 	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", "127.0.0.1", d.daemonServerListenPort), time.Second*30)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("%s failed to dial to daemon", baseCmd.CommandType))
@@ -415,6 +604,9 @@ func (d *DaemonClient) sendAndWaitForResponse(req []byte, resp interface{}) erro
 	if err != nil {
 		return errors.Wrap(err, "Failed to unmarshal command")
 	}
+	if req, err = command.SignCommand(req, d.token); err != nil {
+		return err
+	}
 	conn, err = net.DialTimeout("tcp", fmt.Sprintf("%s:%d", "127.0.0.1", d.daemonServerListenPort), time.Second*30)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("%s failed to dial to daemon", baseCmd.CommandType))
@@ -0,0 +1,90 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_client
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	rpcv1 "nocalhost/internal/nhctl/daemon_server/rpc/v1"
+	"nocalhost/pkg/nhctl/log"
+	"strconv"
+	"time"
+)
+
+// tokenCredentials attaches daemon token and protocol version to every call
+type tokenCredentials struct {
+	token string
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		rpcv1.TokenMetadataKey:           t.token,
+		rpcv1.ProtocolVersionMetadataKey: strconv.Itoa(command.ProtocolVersion),
+	}, nil
+}
+
+// RequireTransportSecurity is false since daemon only listens on loopback
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// RpcClient returns the client of daemon's grpc service,
+// nil if the daemon serves json commands only
+func (d *DaemonClient) RpcClient() rpcv1.DaemonServiceClient {
+	if d.grpcPort == 0 {
+		return nil
+	}
+	d.rpcOnce.Do(
+		func() {
+			conn, err := grpc.Dial(
+				fmt.Sprintf("127.0.0.1:%d", d.grpcPort),
+				grpc.WithInsecure(), grpc.WithPerRPCCredentials(&tokenCredentials{token: d.token}),
+			)
+			if err != nil {
+				log.LogE(errors.Wrap(err, "Failed to dial grpc of daemon"))
+				return
+			}
+			d.conn = conn
+			d.rpc = rpcv1.NewDaemonServiceClient(conn)
+		},
+	)
+	return d.rpc
+}
+
+// Close closes the grpc connection to daemon if it's dialed
+func (d *DaemonClient) Close() error {
+	if d.conn == nil {
+		return nil
+	}
+	return errors.Wrap(d.conn.Close(), "")
+}
+
+func rpcContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 30*time.Second)
+}
+
+// fallbackToJson reports whether the call should be sent again as json command,
+// which happens while grpc of daemon is unreachable
+func fallbackToJson(method string, err error) bool {
+	if status.Code(err) == codes.Unavailable {
+		log.Logf("Grpc of daemon is unavailable for %s, fallback to json command: %v", method, err)
+		return true
+	}
+	return false
+}
+
+// rpcError formats errors of grpc calls the same as errors of json commands
+func rpcError(method string, err error) error {
+	s := status.Convert(err)
+	return errors.New(
+		fmt.Sprintf("Error occur from daemon, method [%s], code [%s], msg [%s].", method, s.Code(), s.Message()),
+	)
+}
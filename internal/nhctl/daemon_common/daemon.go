@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"nocalhost/internal/nhctl/syncthing/daemon"
 	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/clientgoutils"
 	"path/filepath"
 )
 
@@ -18,6 +19,9 @@ const (
 	DefaultDaemonPort = 30123
 	SudoDaemonPort    = 30124
 	DaemonHttpPort    = 30125
+
+	DaemonGrpcPort     = 30126
+	SudoDaemonGrpcPort = 30127
 )
 
 var (
@@ -30,6 +34,10 @@ type DaemonServerInfo struct {
 	CommitId  string
 	NhctlPath string
 	Upgrading bool
+	// zero for daemon server not checking protocol version and token
+	ProtocolVersion int `json:",omitempty"`
+	// zero for daemon server only serving json commands
+	GrpcPort int `json:",omitempty"`
 }
 
 type CheckClusterStatus struct {
@@ -39,15 +47,21 @@ type CheckClusterStatus struct {
 }
 
 type PortForwardProfile struct {
-	Cancel     context.CancelFunc `json:"-"` // For canceling a port forward
-	StopCh     chan error         `json:"-"`
-	NameSpace  string             `json:"nameSpace"`
-	AppName    string             `json:"appName"`
-	SvcName    string             `json:"svcName"`
-	SvcType    string             `json:"svcType"`
-	Role       string             `json:"role"`
-	LocalPort  int                `json:"localPort"`
-	RemotePort int                `json:"remotePort"`
+	Cancel      context.CancelFunc `json:"-"` // For canceling a port forward
+	StopCh      chan error         `json:"-"`
+	NameSpace   string             `json:"nameSpace"`
+	AppName     string             `json:"appName"`
+	SvcName     string             `json:"svcName"`
+	SvcType     string             `json:"svcType"`
+	Role        string             `json:"role"`
+	LocalPort   int                `json:"localPort"`
+	RemotePort  int                `json:"remotePort"`
+	BindAddress string             `json:"bindAddress,omitempty"`
+	Protocol    string             `json:"protocol,omitempty"`
+
+	Metrics *clientgoutils.PortForwardMetrics `json:"-"`
+	// snapshot of Metrics, only filled in response of GetPortForwardStats
+	Stats *clientgoutils.PortForwardStats `json:"stats,omitempty"`
 }
 
 type DaemonServerStatusResponse struct {
@@ -0,0 +1,55 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_common
+
+const (
+	RoutineKindPortForward = "port-forward"
+	RoutineKindSync        = "sync"
+
+	RoutineStateRunning = "Running"
+	// crashed and waiting for restarting by watchdog
+	RoutineStateBackoff = "BackOff"
+	// watchdog gives up restarting
+	RoutineStateFailed = "Failed"
+)
+
+// DaemonHealth is reported by daemon for `nhctl daemon doctor`
+type DaemonHealth struct {
+	Pid        int    `json:"pid"`
+	Sudo       bool   `json:"sudo"`
+	Version    string `json:"version"`
+	CommitId   string `json:"commitId"`
+	NhctlPath  string `json:"nhctlPath"`
+	ManagedBy  string `json:"managedBy,omitempty"` // service manager running the daemon, e.g. systemd
+	StartTime  string `json:"startTime"`
+	Goroutines int    `json:"goroutines"`
+
+	Routines  []*RoutineHealth  `json:"routines"`
+	Informers []*InformerHealth `json:"informers"`
+}
+
+// RoutineHealth is the state of a port-forward or sync routine supervised by the watchdog
+type RoutineHealth struct {
+	Kind          string `json:"kind"`
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	Restarts      int    `json:"restarts"`
+	LastError     string `json:"lastError,omitempty"`
+	LastErrorTime string `json:"lastErrorTime,omitempty"`
+	NextRestart   string `json:"nextRestart,omitempty"`
+}
+
+// InformerHealth describes informers opened by daemon for caching resources or watching application secrets
+type InformerHealth struct {
+	Kind            string `json:"kind"`
+	Server          string `json:"server,omitempty"`
+	Namespace       string `json:"namespace"`
+	ClusterScope    bool   `json:"clusterScope,omitempty"`
+	Informers       int    `json:"informers"`
+	SyncedInformers int    `json:"syncedInformers"`
+	PodSubscribers  int    `json:"podSubscribers,omitempty"`
+	LastUsedTime    string `json:"lastUsedTime,omitempty"`
+}
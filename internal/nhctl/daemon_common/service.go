@@ -0,0 +1,172 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_common
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	ServiceManagerSystemd = "systemd"
+	ServiceManagerLaunchd = "launchd"
+
+	DaemonSystemdUnit  = "nhctl-daemon.service"
+	DaemonLaunchdLabel = "dev.nocalhost.nhctl-daemon"
+)
+
+// the daemon exits with failure while it is asked to restart, so that systemd restarts it,
+// KillMode=process keeps syncthing started by the daemon running while the daemon restarts
+const systemdUnitTemplate = `[Unit]
+Description=Nocalhost nhctl daemon
+Documentation=https://nocalhost.dev
+After=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{ systemdQuote .NhctlPath }} daemon start
+Environment={{ .ManagedEnvKey }}=systemd
+Restart=on-failure
+RestartSec=5
+KillMode=process
+
+[Install]
+WantedBy=default.target
+`
+
+const launchdPlistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{ .Label }}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{ xmlEscape .NhctlPath }}</string>
+		<string>daemon</string>
+		<string>start</string>
+	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>{{ .ManagedEnvKey }}</key>
+		<string>launchd</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>5</integer>
+	<key>AbandonProcessGroup</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>{{ xmlEscape .LogFile }}</string>
+	<key>StandardErrorPath</key>
+	<string>{{ xmlEscape .LogFile }}</string>
+</dict>
+</plist>
+`
+
+var serviceTemplateFuncs = template.FuncMap{
+	"systemdQuote": systemdQuote,
+	"xmlEscape":    xmlEscape,
+}
+
+// DaemonService is the definition of daemon registered to the service manager of OS
+type DaemonService struct {
+	Manager string
+	// the unit or plist file
+	Path    string
+	Content []byte
+	// commands run after writing the file and before removing it
+	RegisterCmds   [][]string
+	UnregisterCmds [][]string
+}
+
+// NewDaemonService renders the daemon service of goos, a systemd user unit for linux
+// and a launchd agent for darwin
+func NewDaemonService(goos, nhctlPath, homeDir string) (*DaemonService, error) {
+	values := map[string]string{
+		"NhctlPath":     nhctlPath,
+		"ManagedEnvKey": _const.DaemonManagedEnvKey,
+		"Label":         DaemonLaunchdLabel,
+		"LogFile": filepath.Join(
+			homeDir, nocalhost_path.DefaultNhctlHomeDirName, _const.DefaultLogDirName, "daemon-service.log",
+		),
+	}
+
+	switch goos {
+	case "linux":
+		content, err := renderServiceTemplate(systemdUnitTemplate, values)
+		if err != nil {
+			return nil, err
+		}
+		return &DaemonService{
+			Manager: ServiceManagerSystemd,
+			Path:    filepath.Join(homeDir, ".config", "systemd", "user", DaemonSystemdUnit),
+			Content: content,
+			RegisterCmds: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+				{"systemctl", "--user", "enable", "--now", DaemonSystemdUnit},
+			},
+			UnregisterCmds: [][]string{
+				{"systemctl", "--user", "disable", "--now", DaemonSystemdUnit},
+			},
+		}, nil
+	case "darwin":
+		content, err := renderServiceTemplate(launchdPlistTemplate, values)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(homeDir, "Library", "LaunchAgents", DaemonLaunchdLabel+".plist")
+		return &DaemonService{
+			Manager:        ServiceManagerLaunchd,
+			Path:           path,
+			Content:        content,
+			RegisterCmds:   [][]string{{"launchctl", "load", "-w", path}},
+			UnregisterCmds: [][]string{{"launchctl", "unload", "-w", path}},
+		}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Running daemon as a service is not supported on %s", goos))
+}
+
+func renderServiceTemplate(tpl string, values map[string]string) ([]byte, error) {
+	t, err := template.New("daemonService").Funcs(serviceTemplateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	buf := new(bytes.Buffer)
+	if err = t.Execute(buf, values); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return buf.Bytes(), nil
+}
+
+// systemdQuote quotes a word of ExecStart, '%' is the prefix of specifiers in systemd
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "%", "%%")
+	return `"` + s + `"`
+}
+
+func xmlEscape(s string) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := xml.EscapeText(buf, []byte(s)); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	return buf.String(), nil
+}
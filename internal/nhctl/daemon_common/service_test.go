@@ -0,0 +1,60 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_common
+
+import (
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewDaemonService(t *testing.T) {
+	s, err := NewDaemonService("linux", "/opt/my tools/nhctl%1", "/home/dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Path != filepath.Join("/home/dev", ".config", "systemd", "user", DaemonSystemdUnit) {
+		t.Fatalf("unexpected unit path %s", s.Path)
+	}
+	unit := string(s.Content)
+	for _, line := range []string{
+		`ExecStart="/opt/my tools/nhctl%%1" daemon start`,
+		"Environment=NH_DAEMON_MANAGED=systemd",
+		"Restart=on-failure",
+		"KillMode=process",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Fatalf("%q not found in unit:\n%s", line, unit)
+		}
+	}
+
+	s, err = NewDaemonService("darwin", "/Users/dev/R&D/nhctl", "/Users/dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Manager != ServiceManagerLaunchd || s.RegisterCmds[0][len(s.RegisterCmds[0])-1] != s.Path {
+		t.Fatalf("unexpected launchd service %v", s)
+	}
+	if !strings.Contains(string(s.Content), "<string>/Users/dev/R&amp;D/nhctl</string>") {
+		t.Fatalf("nhctl path is not escaped:\n%s", s.Content)
+	}
+	// plist must be a well-formed xml
+	d := xml.NewDecoder(strings.NewReader(string(s.Content)))
+	for {
+		if _, err = d.Token(); err != nil {
+			break
+		}
+	}
+	if err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if _, err = NewDaemonService("windows", `C:\nhctl.exe`, `C:\Users\dev`); err == nil {
+		t.Fatal("expect error for windows")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"nocalhost/internal/nhctl/utils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DaemonTokenFile     = "daemon.token"
+	SudoDaemonTokenFile = "daemon-sudo.token"
+
+	// DaemonTokenHeader carries the token of http requests to the daemon
+	DaemonTokenHeader = "X-Nocalhost-Token"
+)
+
+// DaemonTokenPath returns the token file of the daemon, sudo daemon uses another file so that
+// the two daemons do not share a token. The file is created by whichever of the client and
+// the daemon reads it first, usually the client running as the invoking user
+func DaemonTokenPath(isSudoUser bool) string {
+	if isSudoUser {
+		return filepath.Join(nocalhost_path.GetNhctlHomeDir(), SudoDaemonTokenFile)
+	}
+	return filepath.Join(nocalhost_path.GetNhctlHomeDir(), DaemonTokenFile)
+}
+
+// GetOrCreateDaemonToken returns the token every command to the daemon must carry,
+// the token is generated if not exists and is only accessible to its owner
+func GetOrCreateDaemonToken(isSudoUser bool) (string, error) {
+	tokenPath := DaemonTokenPath(isSudoUser)
+	if token, err := readDaemonToken(tokenPath); err != nil || token != "" {
+		return token, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tokenPath), 0700); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	// TempFile creates file with 0600, linking it to the token path
+	// fails if another process has created the token first
+	tmp, err := ioutil.TempFile(filepath.Dir(tokenPath), filepath.Base(tokenPath))
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(hex.EncodeToString(buf))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	if err = os.Link(tmp.Name(), tokenPath); err != nil && !os.IsExist(err) {
+		return "", errors.Wrap(err, "Failed to create daemon token")
+	}
+
+	token, err := readDaemonToken(tokenPath)
+	if err == nil && token == "" {
+		err = errors.New("Daemon token " + tokenPath + " is empty")
+	}
+	return token, err
+}
+
+func readDaemonToken(tokenPath string) (string, error) {
+	bys, err := ioutil.ReadFile(tokenPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "Failed to read daemon token")
+	}
+	// tighten the permission if the file has been changed to be readable by others
+	if info, err := os.Stat(tokenPath); err == nil && info.Mode().Perm()&0077 != 0 && !utils.IsWindows() {
+		if err = os.Chmod(tokenPath, 0600); err != nil {
+			return "", errors.Wrap(err, "")
+		}
+	}
+	return strings.TrimSpace(string(bys)), nil
+}
@@ -0,0 +1,50 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_common
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestGetOrCreateDaemonToken(t *testing.T) {
+	home, err := ioutil.TempDir("", "nhctl-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	defer os.Setenv("SUDO_USER", os.Getenv("SUDO_USER"))
+	_ = os.Setenv("HOME", home)
+	_ = os.Unsetenv("SUDO_USER")
+
+	token, err := GetOrCreateDaemonToken(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 {
+		t.Fatalf("unexpected token %s", token)
+	}
+	if again, err := GetOrCreateDaemonToken(false); err != nil || again != token {
+		t.Fatalf("expect the same token, got %s, %v", again, err)
+	}
+	if sudoToken, err := GetOrCreateDaemonToken(true); err != nil || sudoToken == token {
+		t.Fatalf("expect another token for sudo daemon, got %s, %v", sudoToken, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(DaemonTokenPath(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expect 0600, got %o", info.Mode().Perm())
+	}
+}
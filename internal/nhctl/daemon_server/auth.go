@@ -0,0 +1,146 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"net/url"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/pkg/nhctl/log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// token of the daemon, loaded while daemon starts
+var daemonToken string
+
+// authenticate checks the token and protocol version of a command,
+// GetDaemonServerInfo is always allowed for clients to find out the version of daemon,
+// stopping and restarting daemon only requires the token, so that clients can upgrade the daemon
+func authenticate(base *command.BaseCommand) error {
+	if base.CommandType == command.GetDaemonServerInfo {
+		return nil
+	}
+	if err := checkToken(base); err != nil {
+		return err
+	}
+	if base.CommandType == command.StopDaemonServer || base.CommandType == command.RestartDaemonServer {
+		return nil
+	}
+	return checkProtocolVersion(base)
+}
+
+func checkToken(base *command.BaseCommand) error {
+	if subtle.ConstantTimeCompare([]byte(base.Token), []byte(daemonToken)) != 1 {
+		if base.Token == "" {
+			return errors.New(
+				fmt.Sprintf(
+					"Command %s is rejected for missing token, "+
+						"please update the current nhctl's version up to %s and try again", base.CommandType, version,
+				),
+			)
+		}
+		return errors.New(fmt.Sprintf("Command %s is rejected for invalid token", base.CommandType))
+	}
+	return nil
+}
+
+func checkProtocolVersion(base *command.BaseCommand) error {
+	if base.ProtocolVersion != command.ProtocolVersion {
+		return errors.New(
+			fmt.Sprintf(
+				"Current nhctl speaks protocol v%d but daemon server [%s] speaks v%d, "+
+					"please restart the daemon server with `nhctl daemon restart`",
+				base.ProtocolVersion, startUpPath, command.ProtocolVersion,
+			),
+		)
+	}
+	return nil
+}
+
+// allowedOrigin reports whether a browser request from origin can reach the http server,
+// requests without origin come from local processes rather than web pages
+func allowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, o := range strings.Split(os.Getenv(_const.DaemonAllowedOriginsEnvKey), ",") {
+		if o = strings.TrimSpace(o); o != "" && strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "vscode-webview", "vscode-file":
+		// webviews of IDE plugins
+		return true
+	case "http", "https":
+		return isLoopbackHost(u.Hostname())
+	}
+	return false
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// withOriginCheck rejects requests from web pages not in allowed origins and requests
+// whose host is not loopback which may come from a DNS rebinding page
+func withOriginCheck(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, port, err := net.SplitHostPort(r.Host)
+		if err != nil || !isLoopbackHost(host) || port != strconv.Itoa(daemonHttpPort()) {
+			log.Warnf("Http request with host %s is rejected", r.Host)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		origin := r.Header.Get("Origin")
+		if !allowedOrigin(origin) {
+			log.Warnf("Http request from origin %s is rejected", origin)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		handler(w, r)
+	}
+}
+
+// withTokenCheck rejects http requests without the daemon token in DaemonTokenHeader,
+// preflight requests of browsers are answered without the token since they can not carry it
+func withTokenCheck(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+daemon_common.DaemonTokenHeader)
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		token := r.Header.Get(daemon_common.DaemonTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(daemonToken)) != 1 {
+			log.Warnf("Http request to %s is rejected for missing or invalid token", r.URL.Path)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
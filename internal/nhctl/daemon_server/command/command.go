@@ -27,6 +27,11 @@ const (
 	UpdateApplicationMeta DaemonCommandType = "UpdateApplicationMeta"
 	KubeconfigOperation   DaemonCommandType = "KubeconfigOperationCommand"
 	CheckClusterStatus    DaemonCommandType = "CheckClusterStatus"
+	GetPortForwardStats   DaemonCommandType = "GetPortForwardStats"
+	GetDaemonHealth       DaemonCommandType = "GetDaemonHealth"
+
+	// ProtocolVersion is increased while the commands between nhctl and daemon change incompatibly
+	ProtocolVersion = 1
 
 	PREVIEW_VERSION = 0
 	SUCCESS         = 200
@@ -38,6 +43,10 @@ type BaseCommand struct {
 	CommandType DaemonCommandType
 	ClientStack string
 	ClientPath  string
+
+	// filled by daemon client for every command
+	ProtocolVersion int    `json:",omitempty"`
+	Token           string `json:",omitempty"`
 }
 
 type BaseResponse struct {
@@ -60,6 +69,17 @@ type PortForwardCommand struct {
 	RemotePort  int    `json:"remotePort"`
 	Role        string `json:"role"`
 	Nid         string `json:"nid"`
+	K8sService  string `json:"k8sService,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	BindAddress string `json:"bindAddress,omitempty"`
+	PortPolicy  string `json:"portPolicy,omitempty"`
+}
+
+// PortForwardResponse reports the address a started port-forward listens on,
+// LocalPort differs from the requested one if another port is chosen by the port policy
+type PortForwardResponse struct {
+	BindAddress string `json:"bindAddress"`
+	LocalPort   int    `json:"localPort"`
 }
 
 type GetApplicationMetaCommand struct {
@@ -78,6 +98,16 @@ type CheckClusterStatusCommand struct {
 	KubeConfigContent string `json:"kubeConfig"`
 }
 
+// GetPortForwardStatsCommand lists traffic stats of running port-forwards,
+// filtered by NameSpace and AppName if they are not empty
+type GetPortForwardStatsCommand struct {
+	CommandType DaemonCommandType
+	ClientStack string
+
+	NameSpace string `json:"nameSpace"`
+	AppName   string `json:"appName"`
+}
+
 type GetApplicationMetasCommand struct {
 	CommandType DaemonCommandType
 	ClientStack string
@@ -124,11 +154,24 @@ const (
 	OperationRemove Operation = "remove"
 )
 
-func ParseBaseCommand(bys []byte) (DaemonCommandType, string, error) {
+func ParseBaseCommand(bys []byte) (*BaseCommand, error) {
 	base := &BaseCommand{}
 	err := json.Unmarshal(bys, base)
 	if err != nil {
-		return "", "", errors.Wrap(err, "")
+		return nil, errors.Wrap(err, "")
+	}
+	return base, nil
+}
+
+// SignCommand adds protocol version and token to a marshaled command,
+// so that every command type carries them without declaring
+func SignCommand(bys []byte, token string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(bys, &fields); err != nil {
+		return nil, errors.Wrap(err, "")
 	}
-	return base.CommandType, base.ClientStack, nil
+	fields["ProtocolVersion"], _ = json.Marshal(ProtocolVersion)
+	fields["Token"], _ = json.Marshal(token)
+	signed, err := json.Marshal(fields)
+	return signed, errors.Wrap(err, "")
 }
@@ -15,6 +15,7 @@ import (
 	"nocalhost/internal/nhctl/app"
 	"nocalhost/internal/nhctl/appmeta"
 	"nocalhost/internal/nhctl/appmeta_manager"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/daemon_common"
 	"nocalhost/internal/nhctl/daemon_handler"
 	"nocalhost/internal/nhctl/daemon_server/command"
@@ -22,6 +23,7 @@ import (
 	"nocalhost/internal/nhctl/syncthing/daemon"
 	"nocalhost/internal/nhctl/utils"
 	"nocalhost/pkg/nhctl/log"
+	"os"
 	"runtime/debug"
 	"strings"
 	"time"
@@ -36,6 +38,11 @@ var (
 	daemonCtx, daemonCancelFunc = context.WithCancel(context.Background()) // For exiting current daemon server
 	startUpPath                 string
 	upgrading                   bool
+	startTime                   time.Time
+	// service manager running the daemon, it restarts the daemon exiting with failure
+	managedBy string
+	// daemon exits with failure for being restarted by the service manager
+	restartByManager bool
 )
 
 func init() {
@@ -49,11 +56,24 @@ func daemonListenPort() int {
 	return daemon_common.DefaultDaemonPort
 }
 
+func daemonGrpcPort() int {
+	if isSudo {
+		return daemon_common.SudoDaemonGrpcPort
+	}
+	return daemon_common.DaemonGrpcPort
+}
+
+func daemonHttpPort() int {
+	return daemon_common.DaemonHttpPort
+}
+
 func StartDaemon(isSudoUser bool, v string, c string) error {
 
 	log.UseBulk(true)
 	log.Log("Starting daemon server...")
 	startUpPath, _ = utils.GetNhctlPath()
+	startTime = time.Now()
+	managedBy = os.Getenv(_const.DaemonManagedEnvKey)
 
 	version = v
 	commitId = c
@@ -61,6 +81,11 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 		return errors.New("Failed to start daemon server with sudo")
 	}
 	isSudo = isSudoUser // Mark daemon server if it is run as sudo
+	token, err := daemon_common.GetOrCreateDaemonToken(isSudoUser)
+	if err != nil {
+		return err
+	}
+	daemonToken = token
 	address := fmt.Sprintf("%s:%d", "0.0.0.0", daemonListenPort())
 	listener, err := net.Listen("tcp4", address)
 	if err != nil {
@@ -72,6 +97,9 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 		appmeta_manager.Init()
 		appmeta_manager.RegisterListener(
 			func(pack *appmeta_manager.ApplicationEventPack) error {
+				if !pack.Event.EventType.IsDevEvent() {
+					return nil
+				}
 				//kubeconfig, err := nocalhost.GetKubeConfigFromProfile(pack.Ns, pack.AppName)
 				//if err != nil {
 				//	return nil
@@ -111,7 +139,10 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 				return nil
 			},
 		)
+		appmeta_manager.RegisterListener(appEventHub.consume)
 		appmeta_manager.Start()
+
+		go superviseSyncthing()
 	}
 
 	// update nocalhost-hub
@@ -171,15 +202,17 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 					log.Log("No data read from connection")
 					return
 				}
-				cmdType, clientStack, err := command.ParseBaseCommand(bytes)
+				baseCmd, err := command.ParseBaseCommand(bytes)
 				if err != nil {
 					log.LogE(err)
 					return
 				}
-				log.Tracef("Handling %s command", cmdType)
-				handleCommand(conn, bytes, cmdType, clientStack)
+				log.Tracef("Handling %s command", baseCmd.CommandType)
+				handleCommand(conn, bytes, baseCmd)
 				takes := time.Now().Sub(start).Seconds()
-				log.WriteToEsWithField(map[string]interface{}{"take": takes}, "%s command done", cmdType)
+				log.WriteToEsWithField(
+					map[string]interface{}{"take": takes}, "%s command done", baseCmd.CommandType,
+				)
 			}()
 		}
 	}()
@@ -187,6 +220,8 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 	// Listen http
 	go startHttpServer()
 
+	startGrpcServer()
+
 	go checkClusterStatusCronJob()
 
 	go func() {
@@ -210,12 +245,16 @@ func StartDaemon(isSudoUser bool, v string, c string) error {
 	select {
 	case <-daemonCtx.Done():
 		log.Log("Exit daemon server")
+		if restartByManager {
+			return errors.New(fmt.Sprintf("Daemon server exits for being restarted by %s", managedBy))
+		}
 		return nil
 	}
 }
 
-func handleCommand(conn net.Conn, bys []byte, cmdType command.DaemonCommandType, clientStack string) {
+func handleCommand(conn net.Conn, bys []byte, baseCmd *command.BaseCommand) {
 	var err error
+	cmdType, clientStack := baseCmd.CommandType, baseCmd.ClientStack
 
 	defer func() {
 		if err != nil {
@@ -239,6 +278,16 @@ func handleCommand(conn net.Conn, bys []byte, cmdType command.DaemonCommandType,
 		return
 	}
 
+	if authErr := authenticate(baseCmd); authErr != nil {
+		log.Logf("Rejecting %s command: %s", cmdType, authErr.Error())
+		err = Process(
+			conn, func(conn net.Conn) (interface{}, error) {
+				return nil, authErr
+			},
+		)
+		return
+	}
+
 	switch cmdType {
 	case command.StartPortForward:
 		err = Process(
@@ -247,10 +296,7 @@ func handleCommand(conn net.Conn, bys []byte, cmdType command.DaemonCommandType,
 				if err = json.Unmarshal(bys, startCmd); err != nil {
 					return nil, err
 				}
-				if err = handleStartPortForwardCommand(startCmd); err != nil {
-					return nil, err
-				}
-				return nil, nil
+				return handleStartPortForwardCommand(startCmd)
 			},
 		)
 
@@ -302,6 +348,7 @@ func handleCommand(conn net.Conn, bys []byte, cmdType command.DaemonCommandType,
 			conn, func(conn net.Conn) (interface{}, error) {
 				return &daemon_common.DaemonServerInfo{
 					Version: version, CommitId: commitId, NhctlPath: startUpPath, Upgrading: upgrading,
+					ProtocolVersion: command.ProtocolVersion, GrpcPort: grpcPort,
 				}, nil
 			},
 		)
@@ -315,6 +362,24 @@ func handleCommand(conn net.Conn, bys []byte, cmdType command.DaemonCommandType,
 			},
 		)
 
+	case command.GetDaemonHealth:
+		err = Process(
+			conn, func(conn net.Conn) (interface{}, error) {
+				return handleGetDaemonHealth(), nil
+			},
+		)
+
+	case command.GetPortForwardStats:
+		err = Process(
+			conn, func(conn net.Conn) (interface{}, error) {
+				statsCmd := &command.GetPortForwardStatsCommand{}
+				if err = json.Unmarshal(bys, statsCmd); err != nil {
+					return nil, err
+				}
+				return pfManager.ListPortForwardStats(statsCmd.NameSpace, statsCmd.AppName), nil
+			},
+		)
+
 	case command.GetApplicationMeta:
 		err = Process(
 			conn, func(conn net.Conn) (interface{}, error) {
@@ -448,6 +513,14 @@ func handlerRestartDaemonServerCommand(isSudoUser bool, clientPath string) error
 	} else {
 		upgrading = true
 	}
+	// nhctl of the service is upgraded in place, client restarts daemon only if it runs the same nhctl
+	if managedBy != "" {
+		log.Logf("Daemon server is managed by %s, exit to be restarted", managedBy)
+		restartByManager = true
+		tcpCancelFunc()
+		return nil
+	}
+
 	var nhctlPath string
 	var err error
 
@@ -472,6 +545,9 @@ func handleStopPortForwardCommand(cmd *command.PortForwardCommand) error {
 }
 
 // If a port-forward already exist, skip it(don't do anything), and return an error
-func handleStartPortForwardCommand(startCmd *command.PortForwardCommand) error {
-	return pfManager.StartPortForwardGoRoutine(startCmd, true)
+func handleStartPortForwardCommand(startCmd *command.PortForwardCommand) (*command.PortForwardResponse, error) {
+	if err := pfManager.StartPortForwardGoRoutine(startCmd, true); err != nil {
+		return nil, err
+	}
+	return &command.PortForwardResponse{BindAddress: startCmd.BindAddress, LocalPort: startCmd.LocalPort}, nil
 }
@@ -0,0 +1,256 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net"
+	"nocalhost/internal/nhctl/appmeta_manager"
+	"nocalhost/internal/nhctl/daemon_handler"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	rpcv1 "nocalhost/internal/nhctl/daemon_server/rpc/v1"
+	"nocalhost/internal/nhctl/syncthing/network/req"
+	"nocalhost/pkg/nhctl/log"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// port of grpc server, zero if it fails to listen
+var grpcPort int
+
+// daemonService serves the same handlers as json commands
+type daemonService struct{}
+
+// startGrpcServer listens grpc port and serves in background
+func startGrpcServer() {
+	listener, err := net.Listen("tcp4", fmt.Sprintf("127.0.0.1:%d", daemonGrpcPort()))
+	if err != nil {
+		log.ErrorE(errors.Wrap(err, ""), "Failed to listen grpc port, only json commands are served")
+		return
+	}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(
+			func(
+				ctx context.Context, r interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+			) (interface{}, error) {
+				if err := authenticateGrpc(ctx, info.FullMethod); err != nil {
+					return nil, err
+				}
+				return handler(ctx, r)
+			},
+		),
+		grpc.StreamInterceptor(
+			func(
+				srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+			) error {
+				if err := authenticateGrpc(ss.Context(), info.FullMethod); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			},
+		),
+	)
+	rpcv1.RegisterDaemonServiceServer(server, &daemonService{})
+
+	go func() {
+		<-tcpCtx.Done()
+		log.Log("Stop serving grpc for daemon server")
+		server.Stop()
+	}()
+
+	log.Infof("Starting grpc server on %d", daemonGrpcPort())
+	grpcPort = daemonGrpcPort()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Errorf("Grpc Server occurs panic: %s", string(debug.Stack()))
+			}
+		}()
+		if err := server.Serve(listener); err != nil {
+			log.ErrorE(err, "Grpc Server occur errors")
+		}
+	}()
+}
+
+// authenticateGrpc checks token and protocol version in metadata the same as json commands
+func authenticateGrpc(ctx context.Context, fullMethod string) error {
+	base := &command.BaseCommand{CommandType: command.DaemonCommandType(path.Base(fullMethod))}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(rpcv1.TokenMetadataKey); len(values) > 0 {
+			base.Token = values[0]
+		}
+		if values := md.Get(rpcv1.ProtocolVersionMetadataKey); len(values) > 0 {
+			base.ProtocolVersion, _ = strconv.Atoi(values[0])
+		}
+	}
+	if err := checkToken(base); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err := checkProtocolVersion(base); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+func jsonReply(v interface{}) (*rpcv1.JsonReply, error) {
+	bys, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &rpcv1.JsonReply{Json: bys}, nil
+}
+
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	log.LogE(err)
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func (s *daemonService) StartPortForward(
+	ctx context.Context, r *rpcv1.PortForwardRequest,
+) (*rpcv1.StartPortForwardReply, error) {
+	resp, err := handleStartPortForwardCommand(r.Command(command.StartPortForward))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &rpcv1.StartPortForwardReply{BindAddress: resp.BindAddress, LocalPort: int32(resp.LocalPort)}, nil
+}
+
+func (s *daemonService) StopPortForward(ctx context.Context, r *rpcv1.PortForwardRequest) (*rpcv1.Empty, error) {
+	if err := handleStopPortForwardCommand(r.Command(command.StopPortForward)); err != nil {
+		return nil, grpcError(err)
+	}
+	return &rpcv1.Empty{}, nil
+}
+
+func (s *daemonService) ListPortForwards(
+	ctx context.Context, r *rpcv1.ListPortForwardsRequest,
+) (*rpcv1.ListPortForwardsReply, error) {
+	return rpcv1.NewListPortForwardsReply(pfManager.ListPortForwardStats(r.GetNamespace(), r.GetAppName())), nil
+}
+
+func (s *daemonService) WatchPortForwards(
+	r *rpcv1.WatchPortForwardsRequest, stream rpcv1.DaemonService_WatchPortForwardsServer,
+) error {
+	interval := time.Duration(r.GetIntervalSeconds()) * time.Second
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		reply := rpcv1.NewListPortForwardsReply(pfManager.ListPortForwardStats(r.GetNamespace(), r.GetAppName()))
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *daemonService) GetApplicationMeta(
+	ctx context.Context, r *rpcv1.GetApplicationMetaRequest,
+) (*rpcv1.JsonReply, error) {
+	return jsonReply(appmeta_manager.GetApplicationMeta(r.GetNamespace(), r.GetAppName(), []byte(r.GetKubeconfig())))
+}
+
+func (s *daemonService) GetApplicationMetas(
+	ctx context.Context, r *rpcv1.GetApplicationMetasRequest,
+) (*rpcv1.JsonReply, error) {
+	return jsonReply(appmeta_manager.GetApplicationMetas(r.GetNamespace(), []byte(r.GetKubeconfig())))
+}
+
+func (s *daemonService) GetResourceInfo(
+	ctx context.Context, r *rpcv1.GetResourceInfoRequest,
+) (*rpcv1.JsonReply, error) {
+	return jsonReply(
+		daemon_handler.HandleGetResourceInfoRequest(
+			&command.GetResourceInfoCommand{
+				CommandType:  command.GetResourceInfo,
+				KubeConfig:   r.GetKubeconfig(),
+				Namespace:    r.GetNamespace(),
+				AppName:      r.GetAppName(),
+				Resource:     r.GetResource(),
+				ResourceName: r.GetResourceName(),
+				Label:        r.GetLabel(),
+			},
+		),
+	)
+}
+
+func (s *daemonService) KubeconfigOperation(
+	ctx context.Context, r *rpcv1.KubeconfigOperationRequest,
+) (*rpcv1.Empty, error) {
+	err := daemon_handler.HandleKubeconfigOperationRequest(
+		&command.KubeconfigOperationCommand{
+			CommandType:     command.KubeconfigOperation,
+			KubeConfigBytes: r.GetKubeconfig(),
+			Namespace:       r.GetNamespace(),
+			Operation:       command.Operation(r.GetOperation()),
+		},
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &rpcv1.Empty{}, nil
+}
+
+func (s *daemonService) CheckClusterStatus(
+	ctx context.Context, r *rpcv1.CheckClusterStatusRequest,
+) (*rpcv1.ClusterStatus, error) {
+	ccs, err := HandleCheckClusterStatus(
+		&command.CheckClusterStatusCommand{
+			CommandType: command.CheckClusterStatus, KubeConfigContent: r.GetKubeconfig(),
+		},
+	)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &rpcv1.ClusterStatus{Available: ccs.Available, Info: ccs.Info}, nil
+}
+
+func (s *daemonService) GetSyncStatus(ctx context.Context, r *rpcv1.SyncStatusRequest) (*rpcv1.SyncStatus, error) {
+	return newSyncStatus(HandleGetSyncStatus(r)), nil
+}
+
+func (s *daemonService) WatchSyncStatus(r *rpcv1.SyncStatusRequest, stream rpcv1.DaemonService_WatchSyncStatusServer) error {
+	var last *rpcv1.SyncStatus
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		current := newSyncStatus(HandleGetSyncStatus(r))
+		if last == nil || !proto.Equal(last, current) {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			last = current
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func newSyncStatus(s *req.SyncthingStatus) *rpcv1.SyncStatus {
+	return &rpcv1.SyncStatus{Status: string(s.Status), Msg: s.Msg, Tips: s.Tips, OutOfSync: s.OutOfSync}
+}
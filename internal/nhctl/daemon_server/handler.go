@@ -6,10 +6,15 @@
 package daemon_server
 
 import (
+	"github.com/mitchellh/go-ps"
 	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/app"
+	"nocalhost/internal/nhctl/common/base"
 	"nocalhost/internal/nhctl/daemon_common"
 	"nocalhost/internal/nhctl/daemon_server/command"
+	rpcv1 "nocalhost/internal/nhctl/daemon_server/rpc/v1"
 	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/internal/nhctl/syncthing/network/req"
 	"nocalhost/pkg/nhctl/clientgoutils"
 	"sync"
 	"time"
@@ -89,3 +94,29 @@ func checkClusterStatusCronJob() {
 	}
 
 }
+
+// HandleGetSyncStatus returns the sync status of a workload in dev mode as `nhctl sync-status`
+func HandleGetSyncStatus(r *rpcv1.SyncStatusRequest) *req.SyncthingStatus {
+	kubeconfig := nocalhost.GetOrGenKubeConfigPath(r.GetKubeconfig())
+	nhApp, err := app.NewApplication(r.GetAppName(), r.GetNamespace(), kubeconfig, true)
+	if err != nil {
+		return req.AppNotInstalledTemplate
+	}
+
+	nhSvc := nhApp.Controller(r.GetService(), base.SvcTypeOf(r.GetServiceType()))
+	if !nhSvc.IsInDevMode() {
+		return req.NotInDevModeTemplate
+	}
+	if !nhSvc.IsProcessor() {
+		return req.NotProcessor
+	}
+
+	pid, err := nhSvc.GetSyncThingPid()
+	if err != nil {
+		return req.NotSyncthingProcessFound
+	}
+	if pro, err := ps.FindProcess(pid); err != nil || pro == nil {
+		return req.NotSyncthingProcessFound
+	}
+	return nhSvc.NewSyncthingHttpClient(2).GetSyncthingStatus()
+}
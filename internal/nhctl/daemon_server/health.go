@@ -0,0 +1,77 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"nocalhost/internal/nhctl/appmeta_manager"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/resouce_cache"
+	"os"
+	"runtime"
+)
+
+const (
+	InformerKindResourceCache     = "resource-cache"
+	InformerKindApplicationSecret = "application-secret"
+)
+
+func handleGetDaemonHealth() *daemon_common.DaemonHealth {
+	health := &daemon_common.DaemonHealth{
+		Pid:        os.Getpid(),
+		Sudo:       isSudo,
+		Version:    version,
+		CommitId:   commitId,
+		NhctlPath:  startUpPath,
+		ManagedBy:  managedBy,
+		StartTime:  startTime.Format("2006-01-02 15:04:05"),
+		Goroutines: runtime.NumGoroutine(),
+		Routines:   daemonWatchdog.list(),
+		Informers:  listInformers(),
+	}
+
+	// errors of running port-forwards are recorded by metrics instead of watchdog
+	stats := map[string]*daemon_common.PortForwardProfile{}
+	for _, pf := range pfManager.ListPortForwardStats("", "") {
+		stats[portForwardKey(profileCommand(pf))] = pf
+	}
+	for _, r := range health.Routines {
+		if r.Kind != daemon_common.RoutineKindPortForward || r.State != daemon_common.RoutineStateRunning {
+			continue
+		}
+		if pf, ok := stats[r.Name]; ok && pf.Stats != nil && pf.Stats.LastError != "" {
+			r.LastError, r.LastErrorTime = pf.Stats.LastError, pf.Stats.LastErrorTime
+		}
+	}
+	return health
+}
+
+func listInformers() []*daemon_common.InformerHealth {
+	result := make([]*daemon_common.InformerHealth, 0)
+	for _, s := range resouce_cache.ListSearchers() {
+		informer := &daemon_common.InformerHealth{
+			Kind:            InformerKindResourceCache,
+			Server:          s.Server,
+			Namespace:       s.Namespace,
+			ClusterScope:    s.ClusterScope,
+			Informers:       s.Informers,
+			SyncedInformers: s.SyncedInformers,
+			PodSubscribers:  s.PodSubscribers,
+		}
+		if !s.LastUsedTime.IsZero() {
+			informer.LastUsedTime = s.LastUsedTime.Format("2006-01-02 15:04:05")
+		}
+		result = append(result, informer)
+	}
+	// application secret watcher is added after its informer synced
+	for _, ns := range appmeta_manager.WatchedNamespaces() {
+		result = append(
+			result, &daemon_common.InformerHealth{
+				Kind: InformerKindApplicationSecret, Namespace: ns, Informers: 1, SyncedInformers: 1,
+			},
+		)
+	}
+	return result
+}
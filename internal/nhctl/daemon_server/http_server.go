@@ -11,9 +11,12 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"net/http"
+	_const "nocalhost/internal/nhctl/const"
 	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/daemon_common"
 	"nocalhost/internal/nhctl/profile"
 	"nocalhost/pkg/nhctl/log"
+	"os"
 	"runtime/debug"
 )
 
@@ -39,21 +42,24 @@ func startHttpServer() {
 	}()
 	log.Info("Starting http server")
 
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/health", withOriginCheck(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Nocalhost http-server is working"))
-	})
+	}))
 
-	http.HandleFunc("/config-save", handlingConfigSave)
+	http.HandleFunc("/config-save", withOriginCheck(withTokenCheck(handlingConfigSave)))
 
-	err := http.ListenAndServe("127.0.0.1:30125", nil)
+	if os.Getenv(_const.EnableDaemonMetricsEnvKey) != "" {
+		http.HandleFunc("/metrics", withOriginCheck(handlingMetrics))
+	}
+
+	err := http.ListenAndServe(fmt.Sprintf("127.0.0.1:%d", daemonHttpPort()), nil)
 	if err != nil {
 		log.ErrorE(err, "Http Server occur errors")
 	}
 }
 
 func handlingConfigSave(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Add("Access-Control-Allow-Headers", "Content-Type, "+daemon_common.DaemonTokenHeader)
 	w.Header().Set("content-type", "application/json")
 	w.Header().Set("Access-Control-Allow-Methods", "*")
 	w.Header().Set("Access-Control-Max-Age", "300")
@@ -0,0 +1,92 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"strings"
+)
+
+var portForwardMetricFamilies = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(stats *clientgoutils.PortForwardStats) int64
+}{
+	{
+		"nhctl_port_forward_received_bytes_total", "counter", "Bytes received from the pod.",
+		func(s *clientgoutils.PortForwardStats) int64 { return s.BytesIn },
+	},
+	{
+		"nhctl_port_forward_sent_bytes_total", "counter", "Bytes sent to the pod.",
+		func(s *clientgoutils.PortForwardStats) int64 { return s.BytesOut },
+	},
+	{
+		"nhctl_port_forward_active_connections", "gauge", "Connections being forwarded.",
+		func(s *clientgoutils.PortForwardStats) int64 { return s.ActiveConnections },
+	},
+	{
+		"nhctl_port_forward_connections_total", "counter", "Connections forwarded.",
+		func(s *clientgoutils.PortForwardStats) int64 { return s.TotalConnections },
+	},
+	{
+		"nhctl_port_forward_reconnects_total", "counter", "Times the port-forward reconnected.",
+		func(s *clientgoutils.PortForwardStats) int64 { return s.Reconnects },
+	},
+}
+
+// handlingMetrics writes traffic stats of running port-forwards in prometheus text format
+func handlingMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "text/plain; version=0.0.4")
+	_, _ = w.Write(formatPortForwardMetrics(pfManager.ListPortForwardStats("", "")))
+}
+
+func formatPortForwardMetrics(pfList []*daemon_common.PortForwardProfile) []byte {
+	buf := &bytes.Buffer{}
+	for _, family := range portForwardMetricFamilies {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		for _, pf := range pfList {
+			if pf.Stats == nil {
+				continue
+			}
+			fmt.Fprintf(buf, "%s{%s} %d\n", family.name, metricLabels(pf), family.value(pf.Stats))
+		}
+	}
+	return buf.Bytes()
+}
+
+func metricLabels(pf *daemon_common.PortForwardProfile) string {
+	protocol := pf.Protocol
+	if protocol == "" {
+		protocol = profile.PortForwardProtocolTCP
+	}
+	labels := [][2]string{
+		{"namespace", pf.NameSpace},
+		{"app", pf.AppName},
+		{"service", pf.SvcName},
+		{"service_type", pf.SvcType},
+		{"local_port", fmt.Sprintf("%d", pf.LocalPort)},
+		{"remote_port", fmt.Sprintf("%d", pf.RemotePort)},
+		{"protocol", protocol},
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, l[0], escapeLabelValue(l[1])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// escapeLabelValue escapes backslash, newline and double quote as prometheus text format requires
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
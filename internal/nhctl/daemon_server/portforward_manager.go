@@ -14,15 +14,22 @@ import (
 	"net"
 	"nocalhost/internal/nhctl/app"
 	"nocalhost/internal/nhctl/common/base"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/controller"
 	"nocalhost/internal/nhctl/daemon_common"
 	"nocalhost/internal/nhctl/daemon_server/command"
 	"nocalhost/internal/nhctl/nocalhost"
 	"nocalhost/internal/nhctl/profile"
+	"nocalhost/internal/nhctl/syncthing/ports"
+	"nocalhost/internal/nhctl/tunnel"
+	"nocalhost/pkg/nhctl/clientgoutils"
 	"nocalhost/pkg/nhctl/log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,20 +37,60 @@ import (
 
 type PortForwardManager struct {
 	pfList map[string]*daemon_common.PortForwardProfile
-	lock   sync.Mutex
+	lock   sync.Mutex // guards pfList and port-forward records in db
 }
 
 func NewPortForwardManager() *PortForwardManager {
 	return &PortForwardManager{pfList: map[string]*daemon_common.PortForwardProfile{}}
 }
 
-func (p *PortForwardManager) StopPortForwardGoRoutine(cmd *command.PortForwardCommand) error {
-	key := fmt.Sprintf("%d:%d", cmd.LocalPort, cmd.RemotePort)
+func (p *PortForwardManager) getPortForward(key string) (*daemon_common.PortForwardProfile, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	pfProfile, ok := p.pfList[key]
+	return pfProfile, ok
+}
+
+func (p *PortForwardManager) setPortForward(key string, pfProfile *daemon_common.PortForwardProfile) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pfList[key] = pfProfile
+}
+
+func (p *PortForwardManager) deletePortForward(key string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.pfList, key)
+}
+
+// portForwardKey identifies a port-forward go routine by namespace/app/service/ports,
+// udp port-forward is suffixed with /udp
+func portForwardKey(cmd *command.PortForwardCommand) string {
+	key := fmt.Sprintf("%s/%s/%s/%d:%d", cmd.NameSpace, cmd.AppName, cmd.Service, cmd.LocalPort, cmd.RemotePort)
+	if cmd.Protocol == profile.PortForwardProtocolUDP {
+		key += "/" + profile.PortForwardProtocolUDP
+	}
+	return key
+}
+
+// dialAddress returns the address to dial for reaching a port listened on bindAddress
+func dialAddress(bindAddress string, port int) string {
+	if ip := net.ParseIP(bindAddress); ip == nil || ip.IsUnspecified() {
+		bindAddress = "127.0.0.1"
+	}
+	return net.JoinHostPort(bindAddress, strconv.Itoa(port))
+}
+
+func (p *PortForwardManager) StopPortForwardGoRoutine(cmd *command.PortForwardCommand) error {
+	key := portForwardKey(cmd)
+	// port-forward may be waiting for restarting by watchdog
+	daemonWatchdog.forget(daemon_common.RoutineKindPortForward, key)
+	// the lock is not held while waiting, since the routine updates db under the lock while stopping
+	pfProfile, ok := p.getPortForward(key)
 	if ok {
 		pfProfile.Cancel()
 		err := <-pfProfile.StopCh
-		delete(p.pfList, key)
+		p.deletePortForward(key)
 		return err
 	}
 
@@ -60,18 +107,55 @@ func (p *PortForwardManager) StopPortForwardGoRoutine(cmd *command.PortForwardCo
 		cmd.ServiceType = "deployment"
 	}
 	nhController := nocalhostApp.Controller(cmd.Service, base.SvcType(cmd.ServiceType))
-	return nhController.DeletePortForwardFromDB(cmd.LocalPort, cmd.RemotePort)
+	return nhController.DeletePortForwardFromDB(cmd.LocalPort, cmd.RemotePort, cmd.Protocol)
 }
 
 // ListAllRunningPortForwardGoRoutineProfile
 func (p *PortForwardManager) ListAllRunningPFGoRoutineProfile() []*daemon_common.PortForwardProfile {
 	result := make([]*daemon_common.PortForwardProfile, 0)
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	for _, v := range p.pfList {
 		result = append(result, v)
 	}
 	return result
 }
 
+// ListPortForwardStats returns running port-forwards with their stats, namespace
+// and appName are ignored if they are empty
+func (p *PortForwardManager) ListPortForwardStats(namespace, appName string) []*daemon_common.PortForwardProfile {
+	result := make([]*daemon_common.PortForwardProfile, 0)
+	// copy profiles under the lock, which is shared with routines writing pfList,
+	// stats are read outside of it since metrics are synchronized by themselves
+	p.lock.Lock()
+	for _, v := range p.pfList {
+		if (namespace != "" && v.NameSpace != namespace) || (appName != "" && v.AppName != appName) {
+			continue
+		}
+		pf := *v
+		result = append(result, &pf)
+	}
+	p.lock.Unlock()
+	for _, pf := range result {
+		if pf.Metrics != nil {
+			pf.Stats = pf.Metrics.Stats()
+		}
+	}
+	sort.Slice(
+		result, func(i, j int) bool {
+			return portForwardKey(profileCommand(result[i])) < portForwardKey(profileCommand(result[j]))
+		},
+	)
+	return result
+}
+
+func profileCommand(pf *daemon_common.PortForwardProfile) *command.PortForwardCommand {
+	return &command.PortForwardCommand{
+		NameSpace: pf.NameSpace, AppName: pf.AppName, Service: pf.SvcName,
+		LocalPort: pf.LocalPort, RemotePort: pf.RemotePort, Protocol: pf.Protocol,
+	}
+}
+
 func (p *PortForwardManager) RecoverPortForwardForApplication(ns, appName, nid string) error {
 	profile, err := nocalhost.GetProfileV2(ns, appName, nid)
 	if err != nil {
@@ -109,6 +193,10 @@ func (p *PortForwardManager) RecoverPortForwardForApplication(ns, appName, nid s
 						RemotePort:  pf.RemotePort,
 						Role:        pf.Role,
 						Nid:         nid,
+						K8sService:  pf.K8sService,
+						Protocol:    pf.Protocol,
+						BindAddress: pf.BindAddress,
+						PortPolicy:  pf.PortPolicy,
 					}, false,
 				)
 				if err != nil {
@@ -147,14 +235,64 @@ func (p *PortForwardManager) RecoverAllPortForward() error {
 	return nil
 }
 
+// chooseLocalPort checks if the local port of startCmd can be listened on, another port
+// is chosen and set to startCmd if the port policy is next-free
+func (p *PortForwardManager) chooseLocalPort(startCmd *command.PortForwardCommand) error {
+	isUDP := startCmd.Protocol == profile.PortForwardProtocolUDP
+	protocol := profile.PortForwardProtocolTCP
+	if isUDP {
+		protocol = profile.PortForwardProtocolUDP
+	}
+	// port-forwards which are reconnecting do not listen on their ports
+	usedBy := func(port int) string {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		for _, pf := range p.pfList {
+			if pf.LocalPort == port && (pf.Protocol == profile.PortForwardProtocolUDP) == isUDP {
+				return fmt.Sprintf("%s/%s/%s", pf.NameSpace, pf.AppName, pf.SvcName)
+			}
+		}
+		return ""
+	}
+
+	err := ports.CheckPortAvailable(protocol, startCmd.BindAddress, startCmd.LocalPort)
+	if err == nil {
+		if by := usedBy(startCmd.LocalPort); by != "" {
+			err = errors.New(fmt.Sprintf("used by port-forward of %s", by))
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if startCmd.PortPolicy != profile.PortPolicyNextFree {
+		return errors.New(fmt.Sprintf("Port %d is unavailable: %s", startCmd.LocalPort, err.Error()))
+	}
+
+	port, err := ports.NextAvailablePort(
+		protocol, startCmd.BindAddress, startCmd.LocalPort+1, func(port int) bool { return usedBy(port) != "" },
+	)
+	if err != nil {
+		return err
+	}
+	log.Infof(
+		"Port %d is unavailable, port-forward to %d listens on %d instead", startCmd.LocalPort, startCmd.RemotePort, port,
+	)
+	startCmd.LocalPort = port
+	return nil
+}
+
 // Start a port-forward
 // If saveToDB is true, record it to leveldb
+// The local port of startCmd is updated if another port is chosen by the port policy
 func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortForwardCommand, saveToDB bool) error {
-
-	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
-	key := fmt.Sprintf("%d:%d", localPort, remotePort)
-	if _, ok := p.pfList[key]; ok {
-		log.Logf("Port-forward %d:%d has been running in another go routine, stop it first", localPort, remotePort)
+	if startCmd.BindAddress == "" {
+		startCmd.BindAddress = _const.DefaultPortForwardBindAddress
+	}
+	if _, ok := p.getPortForward(portForwardKey(startCmd)); ok {
+		log.Logf(
+			"Port-forward %d:%d has been running in another go routine, stop it first",
+			startCmd.LocalPort, startCmd.RemotePort,
+		)
 		if err := p.StopPortForwardGoRoutine(startCmd); err != nil {
 			log.LogE(err)
 		}
@@ -169,18 +307,28 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 		return err
 	}
 
-	address := fmt.Sprintf("0.0.0.0:%d", startCmd.LocalPort)
-	listener, err := net.Listen("tcp", address)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Port %d is unavailable: %s", startCmd.LocalPort, err.Error()))
-	}
-	_ = listener.Close()
-
 	nhController := nocalhostApp.Controller(startCmd.Service, base.SvcType(startCmd.ServiceType))
 
+	requestedPort := startCmd.LocalPort
+	if err = p.chooseLocalPort(startCmd); err != nil {
+		return err
+	}
+	if requestedPort != startCmd.LocalPort && !saveToDB {
+		// recovering port-forward listens on another port, replace the record
+		p.lock.Lock()
+		err = nhController.DeletePortForwardFromDB(requestedPort, startCmd.RemotePort, startCmd.Protocol)
+		p.lock.Unlock()
+		if err != nil {
+			log.LogE(err)
+		}
+		saveToDB = true
+	}
+	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
+	key := portForwardKey(startCmd)
+
 	if saveToDB {
 		// Check if port forward already exists
-		if existed, _ := nhController.CheckIfPortForwardExists(localPort, remotePort); existed {
+		if existed, _ := nhController.CheckIfPortForwardExists(localPort, remotePort, startCmd.Protocol); existed {
 			return errors.New(fmt.Sprintf("Port forward %d:%d already exists", localPort, remotePort))
 		}
 		pf := &profile.DevPortForward{
@@ -196,6 +344,10 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 			Sudo:            isSudo,
 			DaemonServerPid: os.Getpid(),
 			ServiceType:     startCmd.ServiceType,
+			K8sService:      startCmd.K8sService,
+			Protocol:        startCmd.Protocol,
+			BindAddress:     startCmd.BindAddress,
+			PortPolicy:      startCmd.PortPolicy,
 		}
 
 		p.lock.Lock()
@@ -208,22 +360,36 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 	}
 
 	ctx, cancel := context.WithCancel(context.TODO())
-	p.pfList[key] = &daemon_common.PortForwardProfile{
-		Cancel:     cancel,
-		StopCh:     make(chan error, 1),
-		NameSpace:  startCmd.NameSpace,
-		SvcName:    startCmd.Service,
-		SvcType:    startCmd.ServiceType,
-		Role:       startCmd.Role,
-		AppName:    startCmd.AppName,
-		LocalPort:  startCmd.LocalPort,
-		RemotePort: startCmd.RemotePort,
-	}
+	metrics := clientgoutils.NewPortForwardMetrics()
+	p.setPortForward(key, &daemon_common.PortForwardProfile{
+		Cancel:      cancel,
+		StopCh:      make(chan error, 1),
+		NameSpace:   startCmd.NameSpace,
+		SvcName:     startCmd.Service,
+		SvcType:     startCmd.ServiceType,
+		Role:        startCmd.Role,
+		AppName:     startCmd.AppName,
+		LocalPort:   startCmd.LocalPort,
+		RemotePort:  startCmd.RemotePort,
+		BindAddress: startCmd.BindAddress,
+		Protocol:    startCmd.Protocol,
+		Metrics:     metrics,
+	})
+	routine := daemonWatchdog.running(daemon_common.RoutineKindPortForward, key)
 	go func() {
+		// stops forwarding of the current connection, for cleaning up after crash
+		stopForwarding := func() {}
 		defer func() {
 			if r := recover(); r != nil {
-				log.Fatalf("DAEMON-RECOVER: %s", string(debug.Stack()))
+				log.Errorf("DAEMON-RECOVER: %s", string(debug.Stack()))
+				stopForwarding()
+				crash := errors.New(fmt.Sprintf("port-forward routine panics: %v", r))
+				metrics.SetLastError(crash)
+				p.portForwardCrashed(startCmd, key, crash)
+				return
 			}
+			// port-forward is stopped or can not be recovered
+			daemonWatchdog.release(routine)
 		}()
 
 		log.Logf("Forwarding %d:%d", localPort, localPort)
@@ -251,6 +417,15 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 			log.LogE(err)
 		}
 
+		if startCmd.K8sService != "" {
+			p.forwardService(ctx, startCmd, nocalhostApp.GetClient(), nhController, key, metrics)
+			return
+		}
+		if startCmd.Protocol == profile.PortForwardProtocolUDP {
+			p.forwardUDP(ctx, startCmd, nocalhostApp.GetClient(), nhController, key, metrics)
+			return
+		}
+
 		handlingStreamCreateFailed := false
 		for {
 			// stopCh control the port forwarding lifecycle. When it gets closed the
@@ -270,6 +445,14 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 				ErrOut: stdout,
 			}
 
+			stopForwarding = func() {
+				select {
+				case <-stopCh:
+				default:
+					close(stopCh)
+				}
+			}
+
 			if !handlingStreamCreateFailed {
 				k8s_runtime.ErrorHandlers = append(
 					k8s_runtime.ErrorHandlers, func(err error) {
@@ -299,7 +482,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Fatalf("DAEMON-RECOVER: %s", string(debug.Stack()))
+						log.Errorf("DAEMON-RECOVER: %s", string(debug.Stack()))
 					}
 				}()
 
@@ -323,9 +506,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 						default:
 							log.Debugf("try to send port-forward heartbeat to %d", localPort)
 							err := nocalhostApp.SendPortForwardTCPHeartBeat(
-								fmt.Sprintf(
-									"%s:%v", "127.0.0.1", localPort,
-								),
+								dialAddress(startCmd.BindAddress, localPort),
 							)
 							if err != nil {
 								log.WarnE(err, "")
@@ -336,7 +517,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 							if lastStatus != currentStatus {
 								lastStatus = currentStatus
 								p.lock.Lock()
-								nhController.UpdatePortForwardStatus(localPort, remotePort, lastStatus, "Heart Beat")
+								nhController.UpdatePortForwardStatus(localPort, remotePort, startCmd.Protocol, lastStatus, "Heart Beat")
 								p.lock.Unlock()
 							}
 							<-time.After(30 * time.Second)
@@ -349,7 +530,9 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 			go func() {
 				defer func() {
 					if r := recover(); r != nil {
-						log.Fatalf("DAEMON-RECOVER: %s", string(debug.Stack()))
+						log.Errorf("DAEMON-RECOVER: %s", string(debug.Stack()))
+						// reconnect as the port-forward fails
+						errCh <- errors.New(fmt.Sprintf("port-forward panics: %v", r))
 					}
 				}()
 
@@ -370,13 +553,16 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 				//		ReadyCh:   readyCh,
 				//	},
 				//):
-				case errCh <- nocalhostApp.PortForward(startCmd.PodName, localPort, remotePort, readyCh, stopCh, stream):
+				case errCh <- nocalhostApp.PortForward(
+					startCmd.PodName, startCmd.BindAddress, localPort, remotePort, readyCh, stopCh, stream, metrics,
+				):
 					log.Logf("Port-forward %d:%d occurs errors", localPort, remotePort)
 				}
 			}()
 
 			select {
 			case err := <-errCh:
+				metrics.SetLastError(err)
 				if err != nil {
 					found, _ := regexp.Match("pods \"(.*?)\" not found", []byte(err.Error()))
 					if strings.Contains(err.Error(), "unable to listen on any of the requested ports") || found {
@@ -386,19 +572,19 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 							log.WarnE(err, fmt.Sprintf("Unable to listen on port %d", localPort))
 						}
 						p.lock.Lock()
-						err2 := nhController.UpdatePortForwardStatus(localPort, remotePort, "DISCONNECTED",
+						err2 := nhController.UpdatePortForwardStatus(localPort, remotePort, startCmd.Protocol, "DISCONNECTED",
 							fmt.Sprintf("Unable to listen on port %d", localPort))
 						p.lock.Unlock()
 						if err2 != nil {
 							log.LogE(err2)
 						}
-						delete(p.pfList, key)
+						p.deletePortForward(key)
 						return
 					}
 					log.WarnE(err, fmt.Sprintf("Port-forward %d:%d failed, reconnecting after 30 seconds...", localPort, remotePort))
 					heartBeatCancel()
 					p.lock.Lock()
-					err = nhController.UpdatePortForwardStatus(localPort, remotePort, "RECONNECTING",
+					err = nhController.UpdatePortForwardStatus(localPort, remotePort, startCmd.Protocol, "RECONNECTING",
 						"Port-forward failed, reconnecting after 30 seconds...")
 					p.lock.Unlock()
 					if err != nil {
@@ -408,7 +594,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 					log.Warn("Reconnecting after 30 seconds...")
 					heartBeatCancel()
 					p.lock.Lock()
-					err = nhController.UpdatePortForwardStatus(localPort, remotePort, "RECONNECTING",
+					err = nhController.UpdatePortForwardStatus(localPort, remotePort, startCmd.Protocol, "RECONNECTING",
 						"Reconnecting after 30 seconds...")
 					p.lock.Unlock()
 					if err != nil {
@@ -417,6 +603,7 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 				}
 				<-time.After(30 * time.Second)
 				log.Info("Reconnecting...")
+				metrics.AddReconnect()
 			case <-ctx.Done():
 				log.Logf("Port-forward %d:%d done", localPort, remotePort)
 				log.Log("Stopping pf routine")
@@ -434,11 +621,11 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 				}
 				//delete(p.pfList, key)
 				log.Logf("Delete port-forward %d:%d record", localPort, remotePort)
-				err = nhController.DeletePortForwardFromDB(localPort, remotePort)
+				err = nhController.DeletePortForwardFromDB(localPort, remotePort, startCmd.Protocol)
 				if err != nil {
 					log.LogE(err)
 				}
-				if pfProfile, ok := p.pfList[key]; ok {
+				if pfProfile, ok := p.getPortForward(key); ok {
 					pfProfile.StopCh <- err
 				}
 				return
@@ -447,3 +634,169 @@ func (p *PortForwardManager) StartPortForwardGoRoutine(startCmd *command.PortFor
 	}()
 	return nil
 }
+
+// portForwardCrashed cleans up the crashed port-forward routine and lets watchdog restart it,
+// the record of port-forward in db is kept for restarting
+func (p *PortForwardManager) portForwardCrashed(startCmd *command.PortForwardCommand, key string, reason error) {
+	if pfProfile, ok := p.getPortForward(key); ok {
+		pfProfile.Cancel()
+		p.deletePortForward(key)
+	}
+	cmd := *startCmd
+	daemonWatchdog.crashed(
+		daemon_common.RoutineKindPortForward, key, reason, func() error {
+			return p.StartPortForwardGoRoutine(&cmd, false)
+		},
+	)
+}
+
+// forwardService keep forwarding to k8s Service until ctx is done, the local listener
+// is kept open while the pods behind the service change
+func (p *PortForwardManager) forwardService(
+	ctx context.Context, startCmd *command.PortForwardCommand, client *clientgoutils.ClientGoUtils,
+	nhController *controller.Controller, key string, metrics *clientgoutils.PortForwardMetrics,
+) {
+	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
+	updateStatus := func(status, reason string) {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if err := nhController.UpdatePortForwardStatus(localPort, remotePort, startCmd.Protocol, status, reason); err != nil {
+			log.LogE(err)
+		}
+	}
+
+	forwarder := client.NewServicePortForwarder(startCmd.K8sService, startCmd.BindAddress, localPort, remotePort)
+	forwarder.Metrics = metrics
+	forwarder.OnRetarget = func(target *clientgoutils.ServiceTarget) {
+		if target == nil {
+			updateStatus("RECONNECTING", fmt.Sprintf("No ready endpoint of service %s", startCmd.K8sService))
+		} else {
+			metrics.AddReconnect()
+			updateStatus("LISTEN", fmt.Sprintf("Re-targeted to pod %s", target.PodName))
+		}
+	}
+
+	for {
+		readyCh := make(chan struct{})
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- forwarder.ForwardPorts(ctx, readyCh)
+		}()
+		go func() {
+			select {
+			case <-readyCh:
+				log.Infof("Port forward %d:%d to service %s is ready", localPort, remotePort, startCmd.K8sService)
+				updateStatus("LISTEN", fmt.Sprintf("Forwarding to service %s", startCmd.K8sService))
+			case <-ctx.Done():
+			}
+		}()
+
+		err := <-errCh
+		select {
+		case <-ctx.Done():
+			log.Logf("Port-forward %d:%d to service %s done", localPort, remotePort, startCmd.K8sService)
+			err = nhController.DeletePortForwardFromDB(localPort, remotePort, startCmd.Protocol)
+			if err != nil {
+				log.LogE(err)
+			}
+			if pfProfile, ok := p.getPortForward(key); ok {
+				pfProfile.StopCh <- err
+			}
+			return
+		default:
+		}
+
+		if err != nil && strings.Contains(err.Error(), "unable to listen") {
+			log.WarnE(err, fmt.Sprintf("Unable to listen on port %d", localPort))
+			updateStatus("DISCONNECTED", fmt.Sprintf("Unable to listen on port %d", localPort))
+			p.deletePortForward(key)
+			return
+		}
+		log.WarnE(err, fmt.Sprintf("Port-forward %d:%d failed, reconnecting after 30 seconds...", localPort, remotePort))
+		metrics.SetLastError(err)
+		updateStatus("RECONNECTING", "Port-forward failed, reconnecting after 30 seconds...")
+		select {
+		case <-time.After(30 * time.Second):
+			metrics.AddReconnect()
+		case <-ctx.Done():
+		}
+	}
+}
+
+// forwardUDP keep forwarding datagrams to the udp relay in the pod until ctx is done,
+// the relay is added to the pod again if it exited
+func (p *PortForwardManager) forwardUDP(
+	ctx context.Context, startCmd *command.PortForwardCommand, client *clientgoutils.ClientGoUtils,
+	nhController *controller.Controller, key string, metrics *clientgoutils.PortForwardMetrics,
+) {
+	localPort, remotePort := startCmd.LocalPort, startCmd.RemotePort
+	updateStatus := func(status, reason string) {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if err := nhController.UpdatePortForwardStatus(localPort, remotePort, startCmd.Protocol, status, reason); err != nil {
+			log.LogE(err)
+		}
+	}
+
+	for {
+		err := p.serveUDP(ctx, startCmd, client, metrics, func() {
+			log.Infof("Port forward %d:%d/udp is ready", localPort, remotePort)
+			updateStatus("LISTEN", "Relaying udp")
+		})
+		select {
+		case <-ctx.Done():
+			log.Logf("Port-forward %d:%d/udp done", localPort, remotePort)
+			err = nhController.DeletePortForwardFromDB(localPort, remotePort, startCmd.Protocol)
+			if err != nil {
+				log.LogE(err)
+			}
+			if pfProfile, ok := p.getPortForward(key); ok {
+				pfProfile.StopCh <- err
+			}
+			return
+		default:
+		}
+
+		if err != nil && strings.Contains(err.Error(), "unable to listen") {
+			log.WarnE(err, fmt.Sprintf("Unable to listen on port %d", localPort))
+			updateStatus("DISCONNECTED", fmt.Sprintf("Unable to listen on port %d", localPort))
+			p.deletePortForward(key)
+			return
+		}
+		log.WarnE(err, fmt.Sprintf("Port-forward %d:%d/udp failed, reconnecting after 30 seconds...", localPort, remotePort))
+		metrics.SetLastError(err)
+		updateStatus("RECONNECTING", "Port-forward failed, reconnecting after 30 seconds...")
+		select {
+		case <-time.After(30 * time.Second):
+			metrics.AddReconnect()
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (p *PortForwardManager) serveUDP(
+	ctx context.Context, startCmd *command.PortForwardCommand, client *clientgoutils.ClientGoUtils,
+	metrics *clientgoutils.PortForwardMetrics, onReady func(),
+) error {
+	relay, err := tunnel.EnsureUDPRelay(client, startCmd.PodName, startCmd.RemotePort)
+	if err != nil {
+		return err
+	}
+	dialer := client.NewPodDialer(startCmd.PodName)
+	dialer.Metrics = metrics
+	defer dialer.Close()
+
+	forwarder := tunnel.NewUDPForwarder(
+		net.JoinHostPort(startCmd.BindAddress, strconv.Itoa(startCmd.LocalPort)), startCmd.RemotePort, relay.Token,
+		func() (net.Conn, error) { return dialer.Dial(relay.Port) },
+	)
+	readyCh := make(chan struct{})
+	go func() {
+		select {
+		case <-readyCh:
+			onReady()
+		case <-ctx.Done():
+		}
+	}()
+	return forwarder.Serve(ctx, readyCh)
+}
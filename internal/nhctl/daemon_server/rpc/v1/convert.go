@@ -0,0 +1,118 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package v1
+
+import (
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/pkg/nhctl/clientgoutils"
+)
+
+//go:generate protoc --go_out=plugins=grpc:. daemon.proto
+
+const (
+	// metadata keys every call carries
+	TokenMetadataKey           = "nh-token"
+	ProtocolVersionMetadataKey = "nh-protocol-version"
+)
+
+func NewPortForwardRequest(cmd *command.PortForwardCommand) *PortForwardRequest {
+	return &PortForwardRequest{
+		Namespace:   cmd.NameSpace,
+		AppName:     cmd.AppName,
+		Service:     cmd.Service,
+		ServiceType: cmd.ServiceType,
+		PodName:     cmd.PodName,
+		LocalPort:   int32(cmd.LocalPort),
+		RemotePort:  int32(cmd.RemotePort),
+		Role:        cmd.Role,
+		Nid:         cmd.Nid,
+		K8SService:  cmd.K8sService,
+		Protocol:    cmd.Protocol,
+		BindAddress: cmd.BindAddress,
+		PortPolicy:  cmd.PortPolicy,
+	}
+}
+
+// Command converts the request to the json command handled by daemon
+func (x *PortForwardRequest) Command(cmdType command.DaemonCommandType) *command.PortForwardCommand {
+	return &command.PortForwardCommand{
+		CommandType: cmdType,
+		NameSpace:   x.GetNamespace(),
+		AppName:     x.GetAppName(),
+		Service:     x.GetService(),
+		ServiceType: x.GetServiceType(),
+		PodName:     x.GetPodName(),
+		LocalPort:   int(x.GetLocalPort()),
+		RemotePort:  int(x.GetRemotePort()),
+		Role:        x.GetRole(),
+		Nid:         x.GetNid(),
+		K8sService:  x.GetK8SService(),
+		Protocol:    x.GetProtocol(),
+		BindAddress: x.GetBindAddress(),
+		PortPolicy:  x.GetPortPolicy(),
+	}
+}
+
+func NewPortForward(pf *daemon_common.PortForwardProfile) *PortForward {
+	result := &PortForward{
+		Namespace:   pf.NameSpace,
+		AppName:     pf.AppName,
+		Service:     pf.SvcName,
+		ServiceType: pf.SvcType,
+		Role:        pf.Role,
+		LocalPort:   int32(pf.LocalPort),
+		RemotePort:  int32(pf.RemotePort),
+		BindAddress: pf.BindAddress,
+		Protocol:    pf.Protocol,
+	}
+	if s := pf.Stats; s != nil {
+		result.Stats = &PortForwardStats{
+			BytesIn:           s.BytesIn,
+			BytesOut:          s.BytesOut,
+			ActiveConnections: s.ActiveConnections,
+			TotalConnections:  s.TotalConnections,
+			Reconnects:        s.Reconnects,
+			LastError:         s.LastError,
+			LastErrorTime:     s.LastErrorTime,
+		}
+	}
+	return result
+}
+
+func (x *PortForward) Profile() *daemon_common.PortForwardProfile {
+	result := &daemon_common.PortForwardProfile{
+		NameSpace:   x.GetNamespace(),
+		AppName:     x.GetAppName(),
+		SvcName:     x.GetService(),
+		SvcType:     x.GetServiceType(),
+		Role:        x.GetRole(),
+		LocalPort:   int(x.GetLocalPort()),
+		RemotePort:  int(x.GetRemotePort()),
+		BindAddress: x.GetBindAddress(),
+		Protocol:    x.GetProtocol(),
+	}
+	if s := x.GetStats(); s != nil {
+		result.Stats = &clientgoutils.PortForwardStats{
+			BytesIn:           s.GetBytesIn(),
+			BytesOut:          s.GetBytesOut(),
+			ActiveConnections: s.GetActiveConnections(),
+			TotalConnections:  s.GetTotalConnections(),
+			Reconnects:        s.GetReconnects(),
+			LastError:         s.GetLastError(),
+			LastErrorTime:     s.GetLastErrorTime(),
+		}
+	}
+	return result
+}
+
+func NewListPortForwardsReply(pfList []*daemon_common.PortForwardProfile) *ListPortForwardsReply {
+	reply := &ListPortForwardsReply{Items: make([]*PortForward, 0, len(pfList))}
+	for _, pf := range pfList {
+		reply.Items = append(reply.Items, NewPortForward(pf))
+	}
+	return reply
+}
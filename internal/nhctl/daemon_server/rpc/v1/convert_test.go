@@ -0,0 +1,59 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/daemon_server/command"
+	"nocalhost/pkg/nhctl/clientgoutils"
+)
+
+func TestPortForwardRequestConversion(t *testing.T) {
+	cmd := &command.PortForwardCommand{
+		CommandType: command.StartPortForward,
+		NameSpace:   "nocalhost", AppName: "bookinfo", Service: "ratings", ServiceType: "deployment",
+		PodName: "ratings-0", LocalPort: 9080, RemotePort: 9080, Role: "manual", Nid: "nid",
+		K8sService: "ratings", Protocol: "udp", BindAddress: "127.0.0.1", PortPolicy: "next-free",
+	}
+	bys, err := proto.Marshal(NewPortForwardRequest(cmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &PortForwardRequest{}
+	if err = proto.Unmarshal(bys, r); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Command(command.StartPortForward); !reflect.DeepEqual(got, cmd) {
+		t.Fatalf("expect %+v, got %+v", cmd, got)
+	}
+}
+
+func TestPortForwardConversion(t *testing.T) {
+	pf := &daemon_common.PortForwardProfile{
+		NameSpace: "nocalhost", AppName: "bookinfo", SvcName: "ratings", SvcType: "deployment", Role: "manual",
+		LocalPort: 9080, RemotePort: 9080, BindAddress: "127.0.0.1",
+		Stats: &clientgoutils.PortForwardStats{BytesIn: 10, BytesOut: 20, TotalConnections: 1, LastError: "EOF"},
+	}
+	reply := NewListPortForwardsReply([]*daemon_common.PortForwardProfile{pf})
+	bys, err := proto.Marshal(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &ListPortForwardsReply{}
+	if err = proto.Unmarshal(bys, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.GetItems()) != 1 {
+		t.Fatalf("expect 1 item, got %d", len(decoded.GetItems()))
+	}
+	if got := decoded.GetItems()[0].Profile(); !reflect.DeepEqual(got, pf) {
+		t.Fatalf("expect %+v, got %+v", pf, got)
+	}
+}
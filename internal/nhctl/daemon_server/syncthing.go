@@ -50,17 +50,9 @@ func recoverSyncthingForApplication(ns, appName, nid string) error {
 		return errors.New(fmt.Sprintf("Profile not found %s-%s", ns, appName))
 	}
 
-	nhctlPath, err := utils.GetNhctlPath()
-	if err != nil {
-		return err
-	}
-
 	for _, svcProfile := range profile.SvcProfile {
 		if svcProfile.Syncing {
-			// nhctl sync bookinfo -d productpage --resume --kubeconfig
-			args := []string{nhctlPath, "sync", appName, "-d", svcProfile.GetName(), "--resume", "-n", ns}
-			log.Logf("Resuming syncthing of %s-%s-%s", ns, appName, svcProfile.GetName())
-			if err = daemon.RunSubProcess(args, nil, false); err != nil {
+			if err = resumeSyncthing(ns, appName, svcProfile.GetType(), svcProfile.GetName()); err != nil {
 				log.LogE(err)
 			}
 		}
@@ -68,3 +60,17 @@ func recoverSyncthingForApplication(ns, appName, nid string) error {
 
 	return nil
 }
+
+func resumeSyncthing(ns, appName, svcType, svcName string) error {
+	nhctlPath, err := utils.GetNhctlPath()
+	if err != nil {
+		return err
+	}
+	// nhctl sync bookinfo -d productpage --resume --kubeconfig
+	args := []string{nhctlPath, "sync", appName, "-d", svcName, "--resume", "-n", ns}
+	if svcType != "" {
+		args = append(args, "-t", svcType)
+	}
+	log.Logf("Resuming syncthing of %s-%s-%s", ns, appName, svcName)
+	return daemon.RunSubProcess(args, nil, false)
+}
@@ -0,0 +1,265 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"nocalhost/internal/nhctl/appmeta"
+	"nocalhost/internal/nhctl/appmeta_manager"
+	_const "nocalhost/internal/nhctl/const"
+	rpcv1 "nocalhost/internal/nhctl/daemon_server/rpc/v1"
+	"nocalhost/internal/nhctl/resouce_cache"
+	"nocalhost/pkg/nhctl/log"
+	"strings"
+	"sync"
+)
+
+const (
+	watchKindApplication = "application"
+	watchKindDev         = "dev"
+	watchKindPod         = "pod"
+)
+
+// watchHub fans out application events to WatchNamespace streams,
+// it is registered as a listener of appmeta_manager once the daemon starts
+type watchHub struct {
+	lock        sync.Mutex
+	subscribers map[*watchSubscriber]struct{}
+}
+
+type watchSubscriber struct {
+	namespace  string
+	kubeconfig []byte
+	ch         chan *rpcv1.WatchEvent
+}
+
+var appEventHub = &watchHub{subscribers: map[*watchSubscriber]struct{}{}}
+
+func (h *watchHub) subscribe(namespace string, kubeconfig []byte) *watchSubscriber {
+	sub := &watchSubscriber{namespace: namespace, kubeconfig: kubeconfig, ch: make(chan *rpcv1.WatchEvent, 256)}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (h *watchHub) unsubscribe(sub *watchSubscriber) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.subscribers, sub)
+}
+
+// consume never blocks the event queue of appmeta_manager, slow streams are unsubscribed
+// and their channels are closed instead of dropping events, so they can watch again
+func (h *watchHub) consume(pack *appmeta_manager.ApplicationEventPack) error {
+	event := newApplicationWatchEvent(pack)
+	if event == nil {
+		return nil
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for sub := range h.subscribers {
+		if sub.namespace != pack.Ns || !bytes.Equal(sub.kubeconfig, pack.KubeConfigBytes) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Warnf("Too many events in namespace %s, stop watching", pack.Ns)
+			delete(h.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+	return nil
+}
+
+func newApplicationWatchEvent(pack *appmeta_manager.ApplicationEventPack) *rpcv1.WatchEvent {
+	event := &rpcv1.WatchEvent{Namespace: pack.Ns, AppName: pack.AppName}
+	switch pack.Event.EventType {
+	case appmeta.APP_ADD, appmeta.APP_UPD:
+		event.Kind, event.Type = watchKindApplication, string(watch.Modified)
+		if pack.Event.EventType == appmeta.APP_ADD {
+			event.Type = string(watch.Added)
+		}
+		if pack.Meta != nil {
+			event.ApplicationMeta, _ = json.Marshal(pack.Meta)
+		}
+	case appmeta.APP_DEL:
+		event.Kind, event.Type = watchKindApplication, string(watch.Deleted)
+	case appmeta.DEV_STA, appmeta.DEV_END:
+		event.Kind, event.Type = watchKindDev, "STARTED"
+		if pack.Event.EventType == appmeta.DEV_END {
+			event.Type = "ENDED"
+		}
+		event.Dev = &rpcv1.DevEvent{
+			ResourceName: pack.Event.ResourceName,
+			DevType:      string(pack.Event.DevType),
+			Identifier:   pack.Event.Identifier,
+		}
+	default:
+		return nil
+	}
+	return event
+}
+
+func (s *daemonService) WatchNamespace(
+	r *rpcv1.WatchNamespaceRequest, stream rpcv1.DaemonService_WatchNamespaceServer,
+) error {
+	if isSudo {
+		return status.Error(codes.Unimplemented, "Watching is only served by daemon without sudo")
+	}
+	if r.GetNamespace() == "" {
+		return status.Error(codes.InvalidArgument, "Namespace can not be empty")
+	}
+	kubeconfig := []byte(r.GetKubeconfig())
+
+	sub := appEventHub.subscribe(r.GetNamespace(), kubeconfig)
+	defer appEventHub.unsubscribe(sub)
+
+	// applications existing, it also makes appmeta_manager watch the namespace, the
+	// informer of it replays them as APP_ADD which are filtered by sentApps
+	sentApps := &sentApplications{metas: map[string][]byte{}}
+	for _, meta := range appmeta_manager.GetApplicationMetas(r.GetNamespace(), kubeconfig) {
+		event := &rpcv1.WatchEvent{
+			Type: string(watch.Added), Kind: watchKindApplication, Namespace: meta.Ns, AppName: meta.Application,
+		}
+		event.ApplicationMeta, _ = json.Marshal(meta)
+		if !sentApps.filter(event) {
+			continue
+		}
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	var podEvents <-chan *resouce_cache.PodEvent
+	if searcher, err := resouce_cache.GetSearcherWithLRU(kubeconfig, r.GetNamespace()); err != nil {
+		log.WarnE(err, fmt.Sprintf("Failed to watch pods in %s, only application events are sent", r.GetNamespace()))
+	} else {
+		var cancel func()
+		podEvents, cancel = searcher.SubscribePods(r.GetNamespace())
+		defer cancel()
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.ch:
+			if !ok {
+				return status.Errorf(codes.Unavailable, "Too many events in %s, watch again", r.GetNamespace())
+			}
+			if !sentApps.filter(event) {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case pe, ok := <-podEvents:
+			if !ok {
+				// the searcher is evicted or expired, or the stream is too slow, client should watch again
+				return status.Errorf(codes.Unavailable, "Watching pods in %s is stopped", r.GetNamespace())
+			}
+			if err := stream.Send(newPodWatchEvent(pe)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sentApplications records application metas sent by a stream, for skipping
+// events replayed by the informer of appmeta_manager
+type sentApplications struct {
+	metas map[string][]byte
+}
+
+// filter reports whether the event should be sent, application events already sent are
+// skipped and ADDED events of applications already sent are turned into MODIFIED
+func (s *sentApplications) filter(event *rpcv1.WatchEvent) bool {
+	if event.Kind != watchKindApplication {
+		return true
+	}
+	if event.Type == string(watch.Deleted) {
+		if _, ok := s.metas[event.AppName]; !ok {
+			return false
+		}
+		delete(s.metas, event.AppName)
+		return true
+	}
+	meta, ok := s.metas[event.AppName]
+	if ok && bytes.Equal(meta, event.ApplicationMeta) {
+		return false
+	}
+	if ok {
+		event.Type = string(watch.Modified)
+	} else {
+		event.Type = string(watch.Added)
+	}
+	s.metas[event.AppName] = event.ApplicationMeta
+	return true
+}
+
+func newPodWatchEvent(pe *resouce_cache.PodEvent) *rpcv1.WatchEvent {
+	pod := pe.Pod
+	ready, restarts := 0, int32(0)
+	reason := pod.Status.Reason
+	for _, c := range pod.Status.ContainerStatuses {
+		restarts += c.RestartCount
+		if c.Ready {
+			ready++
+		} else if reason == "" && c.State.Waiting != nil {
+			reason = c.State.Waiting.Reason
+		} else if reason == "" && c.State.Terminated != nil {
+			reason = c.State.Terminated.Reason
+		}
+	}
+	kind, name := podWorkload(pod)
+	return &rpcv1.WatchEvent{
+		Type:      string(pe.Type),
+		Kind:      watchKindPod,
+		Namespace: pod.Namespace,
+		AppName:   podAppName(pod),
+		Pod: &rpcv1.PodStatus{
+			Name:         pod.Name,
+			Phase:        string(pod.Status.Phase),
+			Ready:        fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)),
+			Restarts:     restarts,
+			WorkloadKind: kind,
+			WorkloadName: name,
+			Reason:       reason,
+		},
+	}
+}
+
+// podAppName returns the application of pod by the annotations nocalhost and helm add,
+// pods without them are not related to any application
+func podAppName(pod *corev1.Pod) string {
+	if name := pod.Annotations[_const.NocalhostApplicationName]; name != "" {
+		return name
+	}
+	return pod.Annotations[_const.HelmReleaseName]
+}
+
+// podWorkload returns the workload controlling the pod, pods of deployments are owned by replica sets
+// named with pod-template-hash suffix
+func podWorkload(pod *corev1.Pod) (string, string) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return "Pod", pod.Name
+	}
+	if hash := pod.Labels["pod-template-hash"]; ref.Kind == "ReplicaSet" && hash != "" &&
+		strings.HasSuffix(ref.Name, "-"+hash) {
+		return "Deployment", strings.TrimSuffix(ref.Name, "-"+hash)
+	}
+	return ref.Kind, ref.Name
+}
@@ -0,0 +1,319 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package daemon_server
+
+import (
+	"fmt"
+	"github.com/mitchellh/go-ps"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/common/base"
+	"nocalhost/internal/nhctl/controller"
+	"nocalhost/internal/nhctl/daemon_common"
+	"nocalhost/internal/nhctl/nocalhost"
+	"nocalhost/pkg/nhctl/log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	watchdogMinBackoff = time.Second
+	watchdogMaxBackoff = 5 * time.Minute
+	// a routine running longer than this is healthy again, its backoff is reset
+	watchdogStableTime = 10 * time.Minute
+	// watchdog gives up after crashing so many times in a row
+	watchdogMaxFailures = 10
+
+	syncCheckInterval = time.Minute
+)
+
+var daemonWatchdog = newWatchdog()
+
+type supervisedRoutine struct {
+	kind          string
+	name          string
+	state         string
+	restarts      int
+	failures      int // crashes in a row, for backoff
+	lastError     string
+	lastErrorTime time.Time
+	startTime     time.Time
+	nextRestart   time.Time
+	timer         *time.Timer
+}
+
+// watchdog restarts crashed port-forward and sync routines with exponential backoff
+type watchdog struct {
+	lock     sync.Mutex
+	routines map[string]*supervisedRoutine
+}
+
+func newWatchdog() *watchdog {
+	return &watchdog{routines: map[string]*supervisedRoutine{}}
+}
+
+func watchdogKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// watchdogBackoff returns the delay before restarting a routine crashed failures times in a row
+func watchdogBackoff(failures int) time.Duration {
+	backoff := watchdogMinBackoff
+	for i := 1; i < failures && backoff < watchdogMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > watchdogMaxBackoff {
+		backoff = watchdogMaxBackoff
+	}
+	return backoff
+}
+
+// running marks the routine (re)started
+func (w *watchdog) running(kind, name string) *supervisedRoutine {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	r, ok := w.routines[watchdogKey(kind, name)]
+	if !ok {
+		r = &supervisedRoutine{kind: kind, name: name}
+		w.routines[watchdogKey(kind, name)] = r
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.state = daemon_common.RoutineStateRunning
+	r.startTime = time.Now()
+	r.nextRestart = time.Time{}
+	return r
+}
+
+// release stops supervising r while the routine exits, unless it is started again with the same name
+func (w *watchdog) release(r *supervisedRoutine) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	key := watchdogKey(r.kind, r.name)
+	if w.routines[key] == r && r.state == daemon_common.RoutineStateRunning {
+		delete(w.routines, key)
+	}
+}
+
+// forget stops supervising the routine, it is called while the routine is stopped on purpose
+func (w *watchdog) forget(kind, name string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if r, ok := w.routines[watchdogKey(kind, name)]; ok {
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		delete(w.routines, watchdogKey(kind, name))
+	}
+}
+
+func (w *watchdog) state(kind, name string) string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if r, ok := w.routines[watchdogKey(kind, name)]; ok {
+		return r.state
+	}
+	return ""
+}
+
+// crashed schedules restarting of the routine after backoff
+func (w *watchdog) crashed(kind, name string, reason error, restart func() error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	key := watchdogKey(kind, name)
+	r, ok := w.routines[key]
+	if !ok {
+		r = &supervisedRoutine{kind: kind, name: name}
+		w.routines[key] = r
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	if !r.startTime.IsZero() && time.Since(r.startTime) > watchdogStableTime {
+		r.failures = 0
+	}
+	r.failures++
+	if reason != nil {
+		r.lastError = reason.Error()
+	}
+	r.lastErrorTime = time.Now()
+
+	if r.failures > watchdogMaxFailures {
+		log.Errorf("%s %s crashed %d times in a row, watchdog gives up restarting it", kind, name, r.failures)
+		r.state = daemon_common.RoutineStateFailed
+		r.nextRestart = time.Time{}
+		return
+	}
+	backoff := watchdogBackoff(r.failures)
+	log.Warnf("%s %s crashed: %s, restarting after %s", kind, name, r.lastError, backoff)
+	r.state = daemon_common.RoutineStateBackoff
+	r.nextRestart = time.Now().Add(backoff)
+	r.timer = time.AfterFunc(
+		backoff, func() {
+			w.restart(kind, name, restart)
+		},
+	)
+}
+
+func (w *watchdog) restart(kind, name string, restart func() error) {
+	w.lock.Lock()
+	r, ok := w.routines[watchdogKey(kind, name)]
+	if !ok || r.state != daemon_common.RoutineStateBackoff {
+		w.lock.Unlock()
+		return
+	}
+	r.restarts++
+	r.timer = nil
+	w.lock.Unlock()
+
+	log.Logf("Watchdog is restarting %s %s", kind, name)
+	if err := restart(); err != nil {
+		w.crashed(kind, name, err, restart)
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	// the routine may be restarted with another name, e.g. port-forward listening on another port
+	key := watchdogKey(kind, name)
+	if w.routines[key] == r && r.state == daemon_common.RoutineStateBackoff && r.timer == nil {
+		delete(w.routines, key)
+	}
+}
+
+func (w *watchdog) list() []*daemon_common.RoutineHealth {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	result := make([]*daemon_common.RoutineHealth, 0, len(w.routines))
+	for _, r := range w.routines {
+		h := &daemon_common.RoutineHealth{
+			Kind:      r.kind,
+			Name:      r.name,
+			State:     r.state,
+			Restarts:  r.restarts,
+			LastError: r.lastError,
+		}
+		if !r.lastErrorTime.IsZero() {
+			h.LastErrorTime = r.lastErrorTime.Format("2006-01-02 15:04:05")
+		}
+		if !r.nextRestart.IsZero() {
+			h.NextRestart = r.nextRestart.Format("2006-01-02 15:04:05")
+		}
+		result = append(result, h)
+	}
+	sort.Slice(
+		result, func(i, j int) bool {
+			return watchdogKey(result[i].Kind, result[i].Name) < watchdogKey(result[j].Kind, result[j].Name)
+		},
+	)
+	return result
+}
+
+// superviseSyncthing checks syncthing of syncing services periodically, syncthing is
+// resumed by the watchdog if its process is found gone in two checks in a row
+func superviseSyncthing() {
+	suspected := map[string]bool{}
+	for {
+		select {
+		case <-daemonCtx.Done():
+			return
+		case <-time.After(syncCheckInterval):
+			suspected = checkSyncthing(suspected)
+		}
+	}
+}
+
+func checkSyncthing(suspected map[string]bool) map[string]bool {
+	appMap, err := nocalhost.GetNsAndApplicationInfo()
+	if err != nil {
+		log.LogE(err)
+		return suspected
+	}
+
+	dead := map[string]bool{}
+	syncing := map[string]bool{}
+	for _, a := range appMap {
+		profile, err := nocalhost.GetProfileV2(a.Namespace, a.Name, a.Nid)
+		if err != nil || profile == nil {
+			continue
+		}
+		for _, svcProfile := range profile.SvcProfile {
+			if !svcProfile.Syncing {
+				continue
+			}
+			ns, appName, nid := a.Namespace, a.Name, a.Nid
+			svcType, svcName := svcProfile.GetType(), svcProfile.GetName()
+			name := fmt.Sprintf("%s/%s/%s/%s", ns, appName, strings.ToLower(svcType), svcName)
+			syncing[name] = true
+
+			state := daemonWatchdog.state(daemon_common.RoutineKindSync, name)
+			if state == daemon_common.RoutineStateBackoff {
+				continue
+			}
+			if err = checkSyncthingProcess(ns, appName, nid, svcType, svcName); err == nil {
+				// syncthing may also be resumed by user after watchdog gives up
+				if state != daemon_common.RoutineStateRunning {
+					daemonWatchdog.running(daemon_common.RoutineKindSync, name)
+				}
+				continue
+			}
+			if state == daemon_common.RoutineStateFailed {
+				continue
+			}
+			// syncthing may be starting by `nhctl dev start` or `nhctl sync`
+			if !suspected[name] {
+				dead[name] = true
+				continue
+			}
+			daemonWatchdog.crashed(
+				daemon_common.RoutineKindSync, name, err, func() error {
+					if err := resumeSyncthing(ns, appName, svcType, svcName); err != nil {
+						return err
+					}
+					daemonWatchdog.running(daemon_common.RoutineKindSync, name)
+					return nil
+				},
+			)
+		}
+	}
+
+	for _, r := range daemonWatchdog.list() {
+		if r.Kind == daemon_common.RoutineKindSync && !syncing[r.Name] {
+			daemonWatchdog.forget(r.Kind, r.Name)
+		}
+	}
+	return dead
+}
+
+func checkSyncthingProcess(ns, appName, nid, svcType, svcName string) error {
+	t := base.SvcType(strings.ToLower(svcType))
+	if t == "" {
+		t = base.Deployment
+	}
+	pidFile := controller.GetSyncThingPidFileOf(ns, appName, nid, t, svcName)
+	bys, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		return errors.Wrap(err, "syncthing pid file not found")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(bys)))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	p, err := ps.FindProcess(pid)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if p == nil {
+		return errors.New(fmt.Sprintf("syncthing process %d is not running", pid))
+	}
+	return nil
+}
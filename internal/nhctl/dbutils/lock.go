@@ -0,0 +1,43 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dbutils
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// IsLevelDBLocked check if the leveldb in path is opened for writing by a process. The
+// LOCK file is taken in shared mode and released right away, a process opening the leveldb
+// during the probe fails to lock it exclusively and retries in OpenLevelDB
+func IsLevelDBLocked(path string) (bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return false, errors.Wrap(err, "")
+	}
+	s, err := storage.OpenFile(path, true)
+	if err != nil {
+		return true, nil
+	}
+	return false, errors.Wrap(s.Close(), "")
+}
+
+// IsLevelDBStuck check the lock of leveldb several times in duration, leveldb is opened and
+// closed by every nhctl operation, so only a lock held all the time is worth reporting
+func IsLevelDBStuck(path string, times int, duration time.Duration) (bool, error) {
+	for i := 0; i < times; i++ {
+		if i > 0 {
+			time.Sleep(duration / time.Duration(times))
+		}
+		locked, err := IsLevelDBLocked(path)
+		if err != nil || !locked {
+			return false, err
+		}
+	}
+	return true, nil
+}
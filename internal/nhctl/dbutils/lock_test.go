@@ -0,0 +1,43 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package dbutils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestIsLevelDBLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	if _, err := IsLevelDBLocked(path); err == nil {
+		t.Fatal("expect error for missing leveldb")
+	}
+
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, err := IsLevelDBStuck(path, 2, 10*time.Millisecond)
+	if err != nil || !locked {
+		t.Fatalf("expect leveldb to be locked, got %v, %v", locked, err)
+	}
+
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	locked, err = IsLevelDBLocked(path)
+	if err != nil || locked {
+		t.Fatalf("expect leveldb to be free, got %v, %v", locked, err)
+	}
+	// checking must not take the lock away from writers
+	if db, err = leveldb.OpenFile(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+}
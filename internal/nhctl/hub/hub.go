@@ -22,6 +22,19 @@ func FindNocalhostSvcConfig(appName, svcName string, svcType base.SvcType, conta
 	if image == "" {
 		return nil, errors.New("Image can not be empty")
 	}
+	log.Log("Finding config in hub index ...")
+	if svcConfig, err := findSvcConfigInIndex(
+		nocalhost_path.GetNocalhostHubDir(), string(svcType), image,
+	); err == nil {
+		// configs in index are matched by image, the name of container may differ
+		if svcConfig.GetContainerConfig(container) == nil && len(svcConfig.ContainerConfigs) == 1 {
+			svcConfig.ContainerConfigs[0].Name = container
+		}
+		if svcConfig.GetContainerConfig(container) != nil {
+			return svcConfig, nil
+		}
+	}
+
 	log.Logf("Finding %s's config.yaml by app name ...", appName)
 	config, err := findNocalhostConfigByAppName(appName)
 	if err != nil {
@@ -0,0 +1,172 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package hub
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const hubTestConfig = `name: reviews
+serviceType: deployment
+containers:
+  - name: reviews
+    dev:
+      image: nocalhost-docker.pkg.coding.net/nocalhost/dev-images/java:latest
+      workDir: /home/nocalhost-dev
+`
+
+func TestIndex(t *testing.T) {
+	index := NewIndex()
+	for _, e := range []*Entry{
+		{Name: "reviews", Version: "1.0.0", Images: []string{"*/bookinfo/reviews"}},
+		{Name: "reviews", Version: "1.10.0", Images: []string{"*/bookinfo/reviews"}},
+		{Name: "reviews", Version: "1.2.0", Images: []string{"*/bookinfo/reviews"}},
+		{Name: "ratings", Version: "0.1.0", Images: []string{"bookinfo/ratings"}, Keywords: []string{"node"}},
+	} {
+		if err := index.Add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Add(&Entry{Name: "reviews", Version: "1.2.0"}); err == nil {
+		t.Errorf("versions should be immutable")
+	}
+	if err := index.Add(&Entry{Name: "reviews", Version: "latest"}); err == nil {
+		t.Errorf("version should be semantic")
+	}
+
+	for version, expected := range map[string]string{"": "1.10.0", "~1.2": "1.2.0", "1.0.0": "1.0.0", "^2": ""} {
+		e := index.Get("reviews", version)
+		if (e == nil && expected != "") || (e != nil && e.Version != expected) {
+			t.Errorf("expected %q of reviews@%s, got %v", expected, version, e)
+		}
+	}
+
+	if result := index.Search("", "nocalhost-docker.pkg.coding.net/nocalhost/bookinfo/reviews:v1"); len(result) != 1 ||
+		result[0].Version != "1.10.0" {
+		t.Errorf("latest reviews should be matched by image, got %v", result)
+	}
+	if result := index.Search("NODE", ""); len(result) != 1 || result[0].Name != "ratings" {
+		t.Errorf("ratings should be matched by keyword, got %v", result)
+	}
+	if result := index.Search("", "nocalhost/bookinfo/details"); len(result) != 0 {
+		t.Errorf("nothing should be matched, got %v", result)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "publisher")
+	keyId, err := GenerateKey(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bys, err := ioutil.ReadFile(name + PublicKeySuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, _ := base64.StdEncoding.DecodeString(string(bys))
+	trusted := map[string]ed25519.PublicKey{keyId: publicKey}
+
+	entry := &Entry{Name: "reviews", Version: "1.0.0"}
+	if err = Sign(entry, []byte(hubTestConfig), name+PrivateKeySuffix); err != nil {
+		t.Fatal(err)
+	}
+	if entry.KeyId != keyId {
+		t.Errorf("expected key id %s, got %s", keyId, entry.KeyId)
+	}
+	if err = Verify(entry, []byte(hubTestConfig), trusted); err != nil {
+		t.Errorf("signed config should be verified, %v", err)
+	}
+	if err = Verify(entry, []byte(hubTestConfig+"\n"), trusted); err == nil {
+		t.Errorf("tampered config should not be verified")
+	}
+	if err = Verify(entry, []byte(hubTestConfig), nil); err == nil {
+		t.Errorf("config signed by untrusted key should not be verified")
+	}
+
+	stripped := *entry
+	stripped.Signature, stripped.KeyId = "", ""
+	if err = Verify(&stripped, []byte(hubTestConfig), trusted); err == nil {
+		t.Errorf("config with signature stripped should not be verified while keys are trusted")
+	}
+	if err = Verify(&stripped, []byte(hubTestConfig), nil); err != nil {
+		t.Errorf("unsigned config should be verified by checksum if no key is trusted, %v", err)
+	}
+}
+
+func TestPublishAndPull(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo := NewRepository(dir, "")
+	entry := &Entry{Name: "reviews", Version: "1.0.0", Images: []string{"bookinfo/reviews"}}
+	if err = Sign(entry, []byte(hubTestConfig), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Publish(entry, []byte(hubTestConfig)); err != nil {
+		t.Fatal(err)
+	}
+
+	pulled, svcConfig, err := Pull(repo, "reviews", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pulled.Url != "reviews/1.0.0/config.yaml" || pulled.Digest != entry.Digest {
+		t.Errorf("unexpected entry %+v", pulled)
+	}
+	if c := svcConfig.GetContainerConfig("reviews"); c == nil || c.Dev.WorkDir != "/home/nocalhost-dev" {
+		t.Errorf("unexpected config %+v", svcConfig)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "reviews/1.0.0/config.yaml"), []byte("name: evil\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Pull(repo, "reviews", "1.0.0", true); err == nil {
+		t.Errorf("checksum should always be verified")
+	}
+}
+
+func TestFetchAbsoluteUrl(t *testing.T) {
+	authorizations := map[string]string{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizations[name] = r.Header.Get("Authorization")
+			_, _ = w.Write([]byte(hubTestConfig))
+		})
+	}
+	hub := httptest.NewServer(handler("hub"))
+	defer hub.Close()
+	other := httptest.NewServer(handler("other"))
+	defer other.Close()
+
+	repo := NewRepository(hub.URL, "token")
+	for _, url := range []string{hub.URL + "/reviews/1.0.0/config.yaml", other.URL + "/reviews/config.yaml"} {
+		if _, err := repo.Fetch(&Entry{Name: "reviews", Version: "1.0.0", Url: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if authorizations["hub"] != "Bearer token" {
+		t.Errorf("token should be sent to the hub, got %q", authorizations["hub"])
+	}
+	if authorizations["other"] != "" {
+		t.Errorf("token should not be sent to other hosts, got %q", authorizations["other"])
+	}
+}
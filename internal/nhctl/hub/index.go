@@ -0,0 +1,201 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package hub
+
+import (
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	IndexFileName   = "index.yaml"
+	IndexApiVersion = "v1"
+	ConfigFileName  = "config.yaml"
+)
+
+// Index is the index of a hub, it lists all versions of the service configs in the hub
+type Index struct {
+	ApiVersion string              `json:"apiVersion" yaml:"apiVersion"`
+	Generated  time.Time           `json:"generated" yaml:"generated"`
+	Entries    map[string][]*Entry `json:"entries" yaml:"entries"`
+}
+
+// Entry is a version of a service config in the hub
+type Entry struct {
+	Name        string   `json:"name" yaml:"name"`
+	Version     string   `json:"version" yaml:"version"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	Maintainers []string `json:"maintainers,omitempty" yaml:"maintainers,omitempty"`
+	// Images are rules matching images of the workloads which the config fits, a rule is
+	// either a glob pattern such as `*/bookinfo/reviews*` or a substring of the image
+	Images      []string `json:"images,omitempty" yaml:"images,omitempty"`
+	ServiceType string   `json:"serviceType,omitempty" yaml:"serviceType,omitempty"`
+	// Digest is the checksum of the config, such as `sha256:<hex>`
+	Digest string `json:"digest" yaml:"digest"`
+	// Signature is the base64 ed25519 signature of the config, signed by the key of KeyId
+	Signature string    `json:"signature,omitempty" yaml:"signature,omitempty"`
+	KeyId     string    `json:"keyId,omitempty" yaml:"keyId,omitempty"`
+	Url       string    `json:"url" yaml:"url"`
+	Created   time.Time `json:"created" yaml:"created"`
+}
+
+func NewIndex() *Index {
+	return &Index{ApiVersion: IndexApiVersion, Generated: time.Now(), Entries: map[string][]*Entry{}}
+}
+
+// Validate checks the name and version of the entry
+func (e *Entry) Validate() error {
+	if e.Name == "" || strings.ContainsAny(e.Name, `/\@ `) {
+		return errors.New(fmt.Sprintf("Invalid hub config name %q", e.Name))
+	}
+	if _, err := semver.StrictNewVersion(e.Version); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Invalid version %q of hub config %s", e.Version, e.Name))
+	}
+	return nil
+}
+
+func (e *Entry) String() string {
+	return e.Name + "@" + e.Version
+}
+
+// MatchImage returns true if any of the image rules matches the image
+func (e *Entry) MatchImage(image string) bool {
+	repository := image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository = repository[:i]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+
+	for _, rule := range e.Images {
+		if rule == "" {
+			continue
+		}
+		if strings.ContainsAny(rule, "*?") {
+			if globMatch(rule, repository) || globMatch(rule, image) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(image, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches s with pattern, `*` matches any characters including `/`
+// and `?` matches a single character
+func globMatch(pattern, s string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+expr+"$", s)
+	return matched
+}
+
+// Add adds the entry to the index, versions of an entry are immutable
+func (i *Index) Add(entry *Entry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if i.Get(entry.Name, entry.Version) != nil {
+		return errors.New(fmt.Sprintf("%s already exists in hub", entry))
+	}
+	if i.Entries == nil {
+		i.Entries = map[string][]*Entry{}
+	}
+	i.Entries[entry.Name] = append(i.Entries[entry.Name], entry)
+	i.SortEntries()
+	return nil
+}
+
+// SortEntries sorts versions of each entry, the latest version comes first
+func (i *Index) SortEntries() {
+	for _, versions := range i.Entries {
+		sort.SliceStable(
+			versions, func(a, b int) bool {
+				va, errA := semver.NewVersion(versions[a].Version)
+				vb, errB := semver.NewVersion(versions[b].Version)
+				if errA != nil || errB != nil {
+					return errB != nil && errA == nil
+				}
+				return va.GreaterThan(vb)
+			},
+		)
+	}
+}
+
+// Get returns the version of the entry, version is either empty for the latest version,
+// an exact version or a constraint such as `^1.2`
+func (i *Index) Get(name, version string) *Entry {
+	versions := i.Entries[name]
+	if len(versions) == 0 {
+		return nil
+	}
+	if version == "" || version == "latest" {
+		return versions[0]
+	}
+
+	constraint, err := semver.NewConstraint(version)
+	for _, e := range versions {
+		if e.Version == version {
+			return e
+		}
+		if err != nil {
+			continue
+		}
+		if v, err := semver.NewVersion(e.Version); err == nil && constraint.Check(v) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Search returns the latest versions of entries matching the keyword and the image,
+// either of them can be empty
+func (i *Index) Search(keyword, image string) []*Entry {
+	keyword = strings.ToLower(keyword)
+	result := make([]*Entry, 0)
+	for _, versions := range i.Entries {
+		for _, e := range versions {
+			if image != "" && !e.MatchImage(image) {
+				continue
+			}
+			if keyword != "" && !e.matchKeyword(keyword) {
+				continue
+			}
+			result = append(result, e)
+			break
+		}
+	}
+	sort.Slice(
+		result, func(a, b int) bool {
+			return result[a].Name < result[b].Name
+		},
+	)
+	return result
+}
+
+func (e *Entry) matchKeyword(keyword string) bool {
+	if strings.Contains(strings.ToLower(e.Name), keyword) ||
+		strings.Contains(strings.ToLower(e.Description), keyword) {
+		return true
+	}
+	for _, k := range e.Keywords {
+		if strings.Contains(strings.ToLower(k), keyword) {
+			return true
+		}
+	}
+	return false
+}
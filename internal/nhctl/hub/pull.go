@@ -0,0 +1,106 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package hub
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"nocalhost/internal/nhctl/profile"
+	"nocalhost/pkg/nhctl/log"
+	"strings"
+)
+
+// ParseReference parses `name@version`, version is empty if not specified
+func ParseReference(ref string) (string, string) {
+	if i := strings.LastIndex(ref, "@"); i > 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// Pull fetches the config of name@version from the repository and verifies it, signatures
+// are not verified if skipVerify, version is either empty, an exact version or a constraint
+func Pull(repo Repository, name, version string, skipVerify bool) (*Entry, *profile.ServiceConfigV2, error) {
+	index, err := repo.Index()
+	if err != nil {
+		return nil, nil, err
+	}
+	entry := index.Get(name, version)
+	if entry == nil {
+		if version == "" {
+			return nil, nil, errors.New(fmt.Sprintf("%s not found in hub", name))
+		}
+		return nil, nil, errors.New(fmt.Sprintf("%s@%s not found in hub", name, version))
+	}
+	return fetchAndVerify(repo, entry, skipVerify)
+}
+
+func fetchAndVerify(repo Repository, entry *Entry, skipVerify bool) (*Entry, *profile.ServiceConfigV2, error) {
+	content, err := repo.Fetch(entry)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verified := entry
+	trustedKeys := map[string]ed25519.PublicKey{}
+	if skipVerify {
+		// checksum is always verified
+		unsigned := *entry
+		unsigned.Signature = ""
+		verified = &unsigned
+	} else if trustedKeys, err = TrustedKeys(); err != nil {
+		return nil, nil, err
+	}
+	if err = Verify(verified, content, trustedKeys); err != nil {
+		return nil, nil, err
+	}
+	if entry.Signature == "" && !skipVerify {
+		log.Warnf("%s is not signed and no key is trusted, only its checksum is verified", entry)
+	}
+
+	svcConfig, err := ParseSvcConfig(content)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("Invalid config of %s", entry))
+	}
+	return entry, svcConfig, nil
+}
+
+// ParseSvcConfig parses the service config in hub, which has at least one container
+func ParseSvcConfig(content []byte) (*profile.ServiceConfigV2, error) {
+	svcConfig := &profile.ServiceConfigV2{}
+	if err := yaml.Unmarshal(content, svcConfig); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if len(svcConfig.ContainerConfigs) == 0 {
+		return nil, errors.New("No container config found")
+	}
+	return svcConfig, nil
+}
+
+// findSvcConfigInIndex finds the latest config in the index of dir which matches the image,
+// configs whose signatures are invalid are skipped
+func findSvcConfigInIndex(dir string, svcType, image string) (*profile.ServiceConfigV2, error) {
+	repo := NewRepository(dir, "")
+	index, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range index.Search("", image) {
+		if entry.ServiceType != "" && !strings.EqualFold(entry.ServiceType, svcType) {
+			continue
+		}
+		_, svcConfig, err := fetchAndVerify(repo, entry, false)
+		if err != nil {
+			log.LogE(err)
+			continue
+		}
+		log.Logf("Config %s in hub matches image %s", entry, image)
+		return svcConfig, nil
+	}
+	return nil, errors.New("Service config not found in hub index")
+}
@@ -0,0 +1,205 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package hub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Repository is where configs of a hub are stored, it is either a local directory,
+// a static http server hosting the directory, or the hub served by nocalhost-api
+type Repository interface {
+	Index() (*Index, error)
+	Fetch(entry *Entry) ([]byte, error)
+	Publish(entry *Entry, content []byte) error
+}
+
+// PublishRequest is the body of publishing a config to the hub served by nocalhost-api
+type PublishRequest struct {
+	Entry   *Entry `json:"entry" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+// NewRepository returns the repository of url, which is either a http(s) url or a directory,
+// token is sent as the bearer token to http repositories
+func NewRepository(url, token string) Repository {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return &httpRepository{
+			url:    strings.TrimSuffix(url, "/"),
+			token:  token,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	return &dirRepository{dir: url}
+}
+
+// EntryUrl returns the url of the config of the entry, relative to the root of the hub
+func EntryUrl(name, version string) string {
+	return path.Join(name, version, ConfigFileName)
+}
+
+func checkEntryUrl(entry *Entry) error {
+	if entry.Url == "" || path.IsAbs(entry.Url) || strings.Contains(entry.Url, "..") {
+		return errors.New(fmt.Sprintf("Invalid url %q of %s", entry.Url, entry))
+	}
+	return nil
+}
+
+type dirRepository struct {
+	dir string
+}
+
+func (d *dirRepository) Index() (*Index, error) {
+	bys, err := ioutil.ReadFile(filepath.Join(d.dir, IndexFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewIndex(), nil
+		}
+		return nil, errors.Wrap(err, "")
+	}
+	return parseIndex(bys)
+}
+
+func (d *dirRepository) Fetch(entry *Entry) ([]byte, error) {
+	if err := checkEntryUrl(entry); err != nil {
+		return nil, err
+	}
+	bys, err := ioutil.ReadFile(filepath.Join(d.dir, filepath.FromSlash(entry.Url)))
+	return bys, errors.Wrap(err, "")
+}
+
+func (d *dirRepository) Publish(entry *Entry, content []byte) error {
+	index, err := d.Index()
+	if err != nil {
+		return err
+	}
+	entry.Url = EntryUrl(entry.Name, entry.Version)
+	entry.Created = time.Now()
+	if err = index.Add(entry); err != nil {
+		return err
+	}
+
+	file := filepath.Join(d.dir, filepath.FromSlash(entry.Url))
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err = ioutil.WriteFile(file, content, 0644); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	index.Generated = time.Now()
+	bys, err := yaml.Marshal(index)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(d.dir, IndexFileName), bys, 0644), "")
+}
+
+type httpRepository struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// apiResponse is the response of nocalhost-api, which is returned while failing
+type apiResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (h *httpRepository) do(method, url string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	// the token of hub is never sent to other hosts, such as absolute urls of entries
+	if h.token != "" && h.isSameHost(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer resp.Body.Close()
+	bys, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("%s %s: %s", method, url, resp.Status))
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		r := apiResponse{}
+		if err = json.Unmarshal(bys, &r); err == nil && r.Code != 0 {
+			return nil, errors.New(fmt.Sprintf("%s %s: %s", method, url, r.Message))
+		}
+	}
+	return bys, nil
+}
+
+func (h *httpRepository) isSameHost(u *neturl.URL) bool {
+	hub, err := neturl.Parse(h.url)
+	if err != nil {
+		return false
+	}
+	return hub.Scheme == u.Scheme && hub.Host == u.Host
+}
+
+func (h *httpRepository) Index() (*Index, error) {
+	bys, err := h.do(http.MethodGet, h.url+"/"+IndexFileName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndex(bys)
+}
+
+func (h *httpRepository) Fetch(entry *Entry) ([]byte, error) {
+	if strings.HasPrefix(entry.Url, "http://") || strings.HasPrefix(entry.Url, "https://") {
+		return h.do(http.MethodGet, entry.Url, nil)
+	}
+	if err := checkEntryUrl(entry); err != nil {
+		return nil, err
+	}
+	return h.do(http.MethodGet, h.url+"/"+entry.Url, nil)
+}
+
+func (h *httpRepository) Publish(entry *Entry, content []byte) error {
+	bys, err := json.Marshal(&PublishRequest{Entry: entry, Content: string(content)})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	_, err = h.do(http.MethodPost, h.url+"/configs", bytes.NewReader(bys))
+	return err
+}
+
+func parseIndex(bys []byte) (*Index, error) {
+	index := &Index{}
+	if err := yaml.Unmarshal(bys, index); err != nil {
+		return nil, errors.Wrap(err, "Invalid hub index")
+	}
+	if index.ApiVersion != IndexApiVersion {
+		return nil, errors.New(fmt.Sprintf("Unsupported hub index version %q", index.ApiVersion))
+	}
+	index.SortEntries()
+	return index, nil
+}
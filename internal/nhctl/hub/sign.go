@@ -0,0 +1,138 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package hub
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"nocalhost/internal/nhctl/nocalhost_path"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	digestPrefix     = "sha256:"
+	PublicKeySuffix  = ".pub"
+	PrivateKeySuffix = ".key"
+)
+
+// Digest returns the checksum of the content
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// KeyId identifies the public key, it is the first 8 bytes of the checksum of the key
+func KeyId(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey generates a key pair for signing configs, the keys are written
+// to <name>.key and <name>.pub as base64
+func GenerateKey(name string) (string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	if err = ioutil.WriteFile(
+		name+PrivateKeySuffix, []byte(base64.StdEncoding.EncodeToString(privateKey)), 0600,
+	); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	if err = ioutil.WriteFile(
+		name+PublicKeySuffix, []byte(base64.StdEncoding.EncodeToString(publicKey)), 0644,
+	); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	return KeyId(publicKey), nil
+}
+
+func readKey(file string, size int) ([]byte, error) {
+	bys, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(bys)))
+	if err != nil || len(key) != size {
+		return nil, errors.New(fmt.Sprintf("%s is not a valid ed25519 key", file))
+	}
+	return key, nil
+}
+
+// Sign fills digest and signature of the entry, the entry is only checksummed if keyFile is empty
+func Sign(entry *Entry, content []byte, keyFile string) error {
+	entry.Digest = Digest(content)
+	entry.Signature, entry.KeyId = "", ""
+	if keyFile == "" {
+		return nil
+	}
+
+	key, err := readKey(keyFile, ed25519.PrivateKeySize)
+	if err != nil {
+		return err
+	}
+	privateKey := ed25519.PrivateKey(key)
+	entry.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, content))
+	entry.KeyId = KeyId(privateKey.Public().(ed25519.PublicKey))
+	return nil
+}
+
+// TrustedKeys returns the public keys under the trusted keys dir, keyed by their ids
+func TrustedKeys() (map[string]ed25519.PublicKey, error) {
+	dir := nocalhost_path.GetNocalhostHubTrustedKeysDir()
+	keys := map[string]ed25519.PublicKey{}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return nil, errors.Wrap(err, "")
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), PublicKeySuffix) {
+			continue
+		}
+		key, err := readKey(filepath.Join(dir, f.Name()), ed25519.PublicKeySize)
+		if err != nil {
+			return nil, err
+		}
+		keys[KeyId(key)] = key
+	}
+	return keys, nil
+}
+
+// Verify verifies the checksum of the content, and the signature of it if the entry is signed,
+// the signing key must be one of trustedKeys. Entries must be signed once any key is trusted,
+// otherwise one could strip the signature to bypass the verification
+func Verify(entry *Entry, content []byte, trustedKeys map[string]ed25519.PublicKey) error {
+	if digest := Digest(content); digest != entry.Digest {
+		return errors.New(fmt.Sprintf("Checksum of %s mismatched, expected %s, got %s", entry, entry.Digest, digest))
+	}
+	if entry.Signature == "" {
+		if len(trustedKeys) > 0 {
+			return errors.New(fmt.Sprintf("%s is not signed while trusted keys are configured", entry))
+		}
+		return nil
+	}
+
+	key, ok := trustedKeys[entry.KeyId]
+	if !ok {
+		return errors.New(fmt.Sprintf("%s is signed by untrusted key %s", entry, entry.KeyId))
+	}
+	signature, err := base64.StdEncoding.DecodeString(entry.Signature)
+	if err != nil || !ed25519.Verify(key, content, signature) {
+		return errors.New(fmt.Sprintf("Signature of %s is invalid", entry))
+	}
+	return nil
+}
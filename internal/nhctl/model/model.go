@@ -5,6 +5,8 @@
 
 package model
 
+import "nocalhost/internal/nhctl/profile"
+
 type NocalHostResource struct {
 	NameSpace   string
 	Nid         string
@@ -12,13 +14,24 @@ type NocalHostResource struct {
 	Service     string
 	ServiceType string
 	PodName     string
+	K8sService  string // forward to k8s Service instead of PodName while specified
+	Protocol    string // protocol of port-forward, tcp if empty
+	BindAddress string // local address port-forward listens on, loopback if empty
+	PortPolicy  string // policy while the local port is unavailable, fixed if empty
 }
 
 type DevStartOptions struct {
 	//WorkDir      string
 	SideCarImage string
-	//DevImage     string
+	// DevImage overrides the image of the dev config, such as the image built by dev.build
+	DevImage  string
 	Container string
+	// ExtraContainers are replaced together with Container, each one is synced from its own local dir
+	ExtraContainers []*profile.ExtraDevContainer
+	// ExtraDevImages override images of the dev config of extra containers, keyed by container
+	ExtraDevImages map[string]string
+	// Mode is replace or ephemeral, the workload is not changed in ephemeral mode
+	Mode string
 
 	// for debug
 	SyncthingVersion string
@@ -272,6 +272,7 @@ func GetApplicationMeta(appName, namespace, kubeConfig string) (*appmeta.Applica
 	if err != nil {
 		return nil, err
 	}
+	defer cli.Close()
 
 	bys, err := ioutil.ReadFile(kubeConfig)
 
@@ -307,6 +308,7 @@ func GetApplicationMetas(namespace, kubeConfig string) (appmeta.ApplicationMetas
 	if err != nil {
 		return nil, err
 	}
+	defer cli.Close()
 
 	bys, err := ioutil.ReadFile(kubeConfig)
 
@@ -75,3 +75,8 @@ func GetNocalhostIncubatorHubDir() string {
 func GetNocalhostStableHubDir() string {
 	return filepath.Join(GetNocalhostHubDir(), "stable")
 }
+
+// GetNocalhostHubTrustedKeysDir is where public keys of trusted publishers of hub configs are
+func GetNocalhostHubTrustedKeysDir() string {
+	return filepath.Join(GetNhctlHomeDir(), "hub", "trusted")
+}
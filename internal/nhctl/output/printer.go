@@ -0,0 +1,94 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	Json  Format = "json"
+	Yaml  Format = "yaml"
+	Table Format = "table"
+)
+
+// ParseFormat parse the value of `-o`
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Json, Yaml, Table:
+		return f, nil
+	}
+	return "", errors.New(fmt.Sprintf("unsupported output format %s, must be %s, %s or %s", s, Json, Yaml, Table))
+}
+
+// Object is implemented by all output schemas
+type Object interface {
+	typeMeta() *TypeMeta
+	kind() string
+}
+
+// Tabular is implemented by output schemas which can be printed as a table,
+// the others are printed as yaml while table format is specified
+type Tabular interface {
+	TableHeader() []string
+	TableRows() [][]string
+}
+
+// Print print obj in the format, apiVersion and kind is filled before printing
+func Print(w io.Writer, format Format, obj Object) error {
+	meta := obj.typeMeta()
+	meta.APIVersion = APIVersion
+	meta.Kind = obj.kind()
+
+	switch format {
+	case Json:
+		bys, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		_, err = fmt.Fprintln(w, string(bys))
+		return errors.Wrap(err, "")
+	case Table:
+		if t, ok := obj.(Tabular); ok {
+			printTable(w, t)
+			return nil
+		}
+	}
+
+	bys, err := yaml.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	_, err = w.Write(bys)
+	return errors.Wrap(err, "")
+}
+
+func printTable(w io.Writer, t Tabular) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(t.TableHeader())
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("   ")
+	table.SetNoWhiteSpace(true)
+	table.AppendBulk(t.TableRows())
+	table.Render()
+}
@@ -0,0 +1,187 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package output
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func testObjects() map[string]Object {
+	return map[string]Object{
+		"application_list": &ApplicationList{
+			Items: []Application{
+				{Name: "bookinfo", Namespace: "nocalhost", State: "INSTALLED", Type: "rawManifestGit"},
+				{Name: "nocalhost.default", Namespace: "nocalhost", State: "INSTALLED", Type: "rawManifestLocal"},
+			},
+		},
+		"service_list": &ServiceList{
+			Application: "bookinfo",
+			Items: []Service{
+				{
+					Name: "ratings", Type: "deployment", Developing: true, Syncing: true,
+					DevPortForwards: []string{"9080:9080"}, SyncPaths: []string{"/home/ratings"},
+					LocalSyncthingGUIPort: 50001,
+				},
+				{Name: "reviews", Type: "deployment", DevPortForwards: []string{}, SyncPaths: []string{}},
+			},
+		},
+		"application_description": &ApplicationDescription{
+			Application: ApplicationDetail{
+				Name: "bookinfo", Namespace: "nocalhost", Type: "rawManifestGit", Installed: true,
+				Services: []ServiceDetail{
+					{
+						Name: "ratings", Type: "deployment", Developing: true, DevelopStatus: "STARTED", Possess: true,
+						DevMode: "replace", Syncing: true, Associate: "/home/dev/bookinfo/ratings",
+						SyncPaths: []string{"/home/dev/bookinfo/ratings"}, DevPortForwards: []string{"9080:9080"},
+						LocalSyncthingGUIPort: 38289,
+					},
+				},
+			},
+		},
+		"service_description": &ServiceDescription{
+			Application: "bookinfo",
+			Service: ServiceDetail{
+				Name: "reviews", Type: "deployment", DevelopStatus: "NONE", SyncPaths: []string{},
+				DevPortForwards: []string{},
+			},
+		},
+		"port_forward_list": &PortForwardList{
+			Application: "bookinfo",
+			Items: []PortForward{
+				{
+					Service: "ratings", ServiceType: "deployment", LocalPort: 9080, RemotePort: 9080,
+					Status: "LISTEN", Role: "manual", DaemonServerPid: 1024, Updated: "2021-08-01 10:00:00",
+				},
+			},
+		},
+		"port_forward_stats": &PortForwardList{
+			Application: "bookinfo",
+			Items: []PortForward{
+				{
+					Service: "ratings", ServiceType: "deployment", LocalPort: 9080, RemotePort: 9080,
+					Status: "LISTEN", Role: "manual", DaemonServerPid: 1024, Updated: "2021-08-01 10:00:00",
+					Stats: &PortForwardStats{
+						BytesIn: 2048, BytesOut: 512, ActiveConnections: 1, TotalConnections: 3, Reconnects: 1,
+						LastError: "error creating forwarding stream", LastErrorTime: "2021-08-01 10:05:00",
+					},
+				},
+				{
+					Service: "productpage", ServiceType: "deployment", LocalPort: 8125, RemotePort: 8125,
+					Protocol: "udp", Status: "DISCONNECTED", Role: "manual", Updated: "2021-08-01 10:00:00",
+				},
+			},
+		},
+		"pvc_list": &PVCList{
+			Items: []PVC{
+				{
+					Name: "nocalhost-pvc-ratings", AppName: "bookinfo", ServiceName: "ratings", Capacity: "10Gi",
+					StorageClass: "cbs", Status: "Bound", MountPath: "/root/.m2",
+				},
+			},
+		},
+		"daemon_doctor_report": &DaemonDoctorReport{
+			Daemons: []DaemonHealth{
+				{
+					Running: true, Pid: 1024, Version: "v0.5.0", ManagedBy: "systemd",
+					StartTime: "2021-08-01 10:00:00", Goroutines: 86,
+					Routines: []DaemonRoutine{
+						{Kind: "port-forward", Name: "nocalhost/bookinfo/ratings/9080:9080", State: "Running"},
+						{
+							Kind: "sync", Name: "nocalhost/bookinfo/deployment/reviews", State: "BackOff",
+							Restarts: 2, LastError: "syncthing process 2048 is not running",
+							LastErrorTime: "2021-08-01 10:05:00", NextRestart: "2021-08-01 10:05:04",
+						},
+					},
+					Informers: []Informer{
+						{
+							Kind: "resource-cache", Server: "https://127.0.0.1:6443", Namespace: "nocalhost",
+							Informers: 12, SyncedInformers: 12, PodSubscribers: 1, LastUsedTime: "2021-08-01 10:04:00",
+						},
+						{Kind: "application-secret", Namespace: "nocalhost", Informers: 1, SyncedInformers: 1},
+					},
+				},
+				{Sudo: true},
+			},
+			LevelDBs: []LevelDBLock{
+				{Path: "/home/dev/.nh/nhctl/ns/nid/bookinfo/db", Locked: true},
+			},
+		},
+		"config_lint_report": &ConfigLintReport{
+			File: "/home/dev/bookinfo/.nocalhost/config.yaml",
+			Issues: []ConfigLintIssue{
+				{
+					File: "/home/dev/bookinfo/.nocalhost/config.yaml", Line: 14, Column: 13,
+					Path: "application.services.0.containers.0.dev.workdir", Severity: "error",
+					Message: "Additional property workdir is not allowed, did you mean workDir?",
+				},
+				{
+					File: "/home/dev/bookinfo/.nocalhost/config.yaml", Line: 7, Column: 17,
+					Path: "application.manifestType", Severity: "warning",
+					Message: "services are only checked against raw manifests, skipped for helmGit application",
+				},
+			},
+		},
+		"hub_config_list": &HubConfigList{
+			Items: []HubConfig{
+				{
+					Name: "bookinfo-reviews", Version: "1.2.0", ServiceType: "deployment",
+					Images: []string{"*/bookinfo/reviews*"}, Description: "reviews of bookinfo, java with maven",
+					KeyId: "3f2a9c1d8e7b6a50", Hub: "https://nocalhost.example.com/v1/hub",
+				},
+				{
+					Name: "golang", Version: "0.1.0", Images: []string{"golang"}, Description: "go service",
+					Hub: "/home/dev/.nh/nhctl/nocalhost-hub",
+				},
+			},
+		},
+		"sync_status": &SyncStatus{Status: "idle", Msg: "Sync completed", Tips: "", OutOfSync: ""},
+		"profile_value": &ProfileValue{
+			Service: "ratings", Container: "ratings", Key: "image", Value: "nocalhost-docker.pkg.coding.net/dev:latest",
+		},
+	}
+}
+
+func TestPrintGolden(t *testing.T) {
+	for name, obj := range testObjects() {
+		for _, format := range []Format{Json, Yaml, Table} {
+			buf := &bytes.Buffer{}
+			if err := Print(buf, format, obj); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", name+"."+string(format)+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run `go test -update` to generate golden files", err)
+			}
+			if !bytes.Equal(expected, buf.Bytes()) {
+				t.Errorf("output of %s in %s mismatch\nexpected:\n%s\ngot:\n%s", name, format, expected, buf.Bytes())
+			}
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSON"); err != nil || f != Json {
+		t.Fatalf("expected json, got %s, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatal("xml should be unsupported")
+	}
+}
@@ -0,0 +1,501 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIVersion is the version of all output schemas, fields can only be added in the same version,
+// renaming or removing a field requires a new version
+const APIVersion = "nhctl.nocalhost.dev/v1"
+
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
+}
+
+func (t *TypeMeta) typeMeta() *TypeMeta {
+	return t
+}
+
+// ApplicationList is the output of `nhctl list`
+type ApplicationList struct {
+	TypeMeta `yaml:",inline"`
+	Items    []Application `json:"items" yaml:"items"`
+}
+
+type Application struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	State     string `json:"state" yaml:"state"`
+	Type      string `json:"type" yaml:"type"`
+}
+
+func (l *ApplicationList) kind() string {
+	return "ApplicationList"
+}
+
+func (l *ApplicationList) TableHeader() []string {
+	return []string{"NAME", "STATE", "NAMESPACE", "TYPE"}
+}
+
+func (l *ApplicationList) TableRows() [][]string {
+	rows := make([][]string, 0, len(l.Items))
+	for _, item := range l.Items {
+		rows = append(rows, []string{item.Name, item.State, item.Namespace, item.Type})
+	}
+	return rows
+}
+
+// ServiceList is the output of `nhctl list [NAME]`
+type ServiceList struct {
+	TypeMeta    `yaml:",inline"`
+	Application string    `json:"application" yaml:"application"`
+	Items       []Service `json:"items" yaml:"items"`
+}
+
+type Service struct {
+	Name                  string   `json:"name" yaml:"name"`
+	Type                  string   `json:"type" yaml:"type"`
+	Developing            bool     `json:"developing" yaml:"developing"`
+	Syncing               bool     `json:"syncing" yaml:"syncing"`
+	DevPortForwards       []string `json:"devPortForwards" yaml:"devPortForwards"`
+	SyncPaths             []string `json:"syncPaths" yaml:"syncPaths"`
+	LocalSyncthingGUIPort int      `json:"localSyncthingGUIPort" yaml:"localSyncthingGUIPort"`
+}
+
+func (l *ServiceList) kind() string {
+	return "ServiceList"
+}
+
+func (l *ServiceList) TableHeader() []string {
+	return []string{"NAME", "TYPE", "DEVELOPING", "SYNCING", "DEV-PORT-FORWARDED", "SYNC-PATH", "LOCAL-SYNCTHING-GUI"}
+}
+
+func (l *ServiceList) TableRows() [][]string {
+	rows := make([][]string, 0, len(l.Items))
+	for _, item := range l.Items {
+		rows = append(
+			rows, []string{
+				item.Name, item.Type, strconv.FormatBool(item.Developing), strconv.FormatBool(item.Syncing),
+				strings.Join(item.DevPortForwards, ","), strings.Join(item.SyncPaths, ","),
+				strconv.Itoa(item.LocalSyncthingGUIPort),
+			},
+		)
+	}
+	return rows
+}
+
+// ApplicationDescription is the output of `nhctl describe [NAME]`
+type ApplicationDescription struct {
+	TypeMeta    `yaml:",inline"`
+	Application ApplicationDetail `json:"application" yaml:"application"`
+}
+
+type ApplicationDetail struct {
+	Name           string          `json:"name" yaml:"name"`
+	Namespace      string          `json:"namespace" yaml:"namespace"`
+	Type           string          `json:"type" yaml:"type"`
+	Installed      bool            `json:"installed" yaml:"installed"`
+	ConfigOverlays []string        `json:"configOverlays,omitempty" yaml:"configOverlays,omitempty"`
+	Services       []ServiceDetail `json:"services" yaml:"services"`
+}
+
+func (d *ApplicationDescription) kind() string {
+	return "ApplicationDescription"
+}
+
+// ServiceDescription is the output of `nhctl describe [NAME] -d [SERVICE]`
+type ServiceDescription struct {
+	TypeMeta    `yaml:",inline"`
+	Application string        `json:"application" yaml:"application"`
+	Service     ServiceDetail `json:"service" yaml:"service"`
+}
+
+type ServiceDetail struct {
+	Name          string `json:"name" yaml:"name"`
+	Type          string `json:"type" yaml:"type"`
+	Developing    bool   `json:"developing" yaml:"developing"`
+	DevelopStatus string `json:"developStatus" yaml:"developStatus"`
+	// Possess is true if the service is developed on this machine
+	Possess               bool     `json:"possess" yaml:"possess"`
+	DevMode               string   `json:"devMode,omitempty" yaml:"devMode,omitempty"`
+	DevContainer          string   `json:"devContainer,omitempty" yaml:"devContainer,omitempty"`
+	Syncing               bool     `json:"syncing" yaml:"syncing"`
+	Associate             string   `json:"associate" yaml:"associate"`
+	SyncPaths             []string `json:"syncPaths" yaml:"syncPaths"`
+	DevPortForwards       []string `json:"devPortForwards" yaml:"devPortForwards"`
+	LocalSyncthingGUIPort int      `json:"localSyncthingGUIPort" yaml:"localSyncthingGUIPort"`
+}
+
+func (d *ServiceDescription) kind() string {
+	return "ServiceDescription"
+}
+
+// PortForwardList is the output of `nhctl port-forward list [NAME]`
+type PortForwardList struct {
+	TypeMeta    `yaml:",inline"`
+	Application string        `json:"application" yaml:"application"`
+	Items       []PortForward `json:"items" yaml:"items"`
+}
+
+type PortForward struct {
+	Service         string `json:"service" yaml:"service"`
+	ServiceType     string `json:"serviceType" yaml:"serviceType"`
+	LocalPort       int    `json:"localPort" yaml:"localPort"`
+	RemotePort      int    `json:"remotePort" yaml:"remotePort"`
+	Protocol        string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	BindAddress     string `json:"bindAddress,omitempty" yaml:"bindAddress,omitempty"`
+	Status          string `json:"status" yaml:"status"`
+	Role            string `json:"role" yaml:"role"`
+	Sudo            bool   `json:"sudo" yaml:"sudo"`
+	DaemonServerPid int    `json:"daemonServerPid" yaml:"daemonServerPid"`
+	Updated         string `json:"updated" yaml:"updated"`
+	Reason          string `json:"reason" yaml:"reason"`
+	// only present with `--stats` while the port-forward is running
+	Stats *PortForwardStats `json:"stats,omitempty" yaml:"stats,omitempty"`
+}
+
+// PortForwardStats is the traffic of a port-forward counted by daemon since it started
+type PortForwardStats struct {
+	BytesIn           int64  `json:"bytesIn" yaml:"bytesIn"`
+	BytesOut          int64  `json:"bytesOut" yaml:"bytesOut"`
+	ActiveConnections int64  `json:"activeConnections" yaml:"activeConnections"`
+	TotalConnections  int64  `json:"totalConnections" yaml:"totalConnections"`
+	Reconnects        int64  `json:"reconnects" yaml:"reconnects"`
+	LastError         string `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	LastErrorTime     string `json:"lastErrorTime,omitempty" yaml:"lastErrorTime,omitempty"`
+}
+
+func (l *PortForwardList) kind() string {
+	return "PortForwardList"
+}
+
+func (l *PortForwardList) hasStats() bool {
+	for _, item := range l.Items {
+		if item.Stats != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *PortForwardList) TableHeader() []string {
+	header := []string{"SERVICE", "TYPE", "PORT", "STATUS", "ROLE", "UPDATED", "REASON"}
+	if l.hasStats() {
+		header = append(header, "BYTES-IN", "BYTES-OUT", "CONNECTIONS", "RECONNECTS", "LAST-ERROR")
+	}
+	return header
+}
+
+func (l *PortForwardList) TableRows() [][]string {
+	rows := make([][]string, 0, len(l.Items))
+	for _, item := range l.Items {
+		port := fmt.Sprintf("%d:%d", item.LocalPort, item.RemotePort)
+		if item.Protocol != "" && item.Protocol != "tcp" {
+			port += "/" + item.Protocol
+		}
+		row := []string{
+			item.Service, item.ServiceType, port,
+			item.Status, item.Role, item.Updated, item.Reason,
+		}
+		if l.hasStats() {
+			if s := item.Stats; s != nil {
+				row = append(
+					row, strconv.FormatInt(s.BytesIn, 10), strconv.FormatInt(s.BytesOut, 10),
+					fmt.Sprintf("%d/%d", s.ActiveConnections, s.TotalConnections),
+					strconv.FormatInt(s.Reconnects, 10), s.LastError,
+				)
+			} else {
+				row = append(row, "", "", "", "", "")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// PVCList is the output of `nhctl pvc list`
+type PVCList struct {
+	TypeMeta `yaml:",inline"`
+	Items    []PVC `json:"items" yaml:"items"`
+}
+
+type PVC struct {
+	Name         string `json:"name" yaml:"name"`
+	AppName      string `json:"appName" yaml:"appName"`
+	ServiceName  string `json:"serviceName" yaml:"serviceName"`
+	Capacity     string `json:"capacity" yaml:"capacity"`
+	StorageClass string `json:"storageClass" yaml:"storageClass"`
+	Status       string `json:"status" yaml:"status"`
+	MountPath    string `json:"mountPath" yaml:"mountPath"`
+}
+
+func (l *PVCList) kind() string {
+	return "PVCList"
+}
+
+func (l *PVCList) TableHeader() []string {
+	return []string{"NAME", "APP", "SERVICE", "CAPACITY", "STORAGE-CLASS", "STATUS", "MOUNT-PATH"}
+}
+
+func (l *PVCList) TableRows() [][]string {
+	rows := make([][]string, 0, len(l.Items))
+	for _, item := range l.Items {
+		rows = append(
+			rows, []string{
+				item.Name, item.AppName, item.ServiceName, item.Capacity, item.StorageClass, item.Status,
+				item.MountPath,
+			},
+		)
+	}
+	return rows
+}
+
+// SyncStatus is the output of `nhctl sync-status [NAME]`
+type SyncStatus struct {
+	TypeMeta  `yaml:",inline"`
+	Status    string `json:"status" yaml:"status"`
+	Msg       string `json:"msg" yaml:"msg"`
+	Tips      string `json:"tips" yaml:"tips"`
+	OutOfSync string `json:"outOfSync" yaml:"outOfSync"`
+}
+
+func (s *SyncStatus) kind() string {
+	return "SyncStatus"
+}
+
+func (s *SyncStatus) TableHeader() []string {
+	return []string{"STATUS", "MESSAGE", "TIPS", "OUT-OF-SYNC"}
+}
+
+func (s *SyncStatus) TableRows() [][]string {
+	return [][]string{{s.Status, s.Msg, s.Tips, s.OutOfSync}}
+}
+
+// ProfileValue is the output of `nhctl profile get`
+type ProfileValue struct {
+	TypeMeta  `yaml:",inline"`
+	Service   string `json:"service" yaml:"service"`
+	Container string `json:"container" yaml:"container"`
+	Key       string `json:"key" yaml:"key"`
+	Value     string `json:"value" yaml:"value"`
+}
+
+func (v *ProfileValue) kind() string {
+	return "ProfileValue"
+}
+
+func (v *ProfileValue) TableHeader() []string {
+	return []string{"SERVICE", "CONTAINER", "KEY", "VALUE"}
+}
+
+func (v *ProfileValue) TableRows() [][]string {
+	return [][]string{{v.Service, v.Container, v.Key, v.Value}}
+}
+
+// DaemonDoctorReport is the output of `nhctl daemon doctor`
+type DaemonDoctorReport struct {
+	TypeMeta `yaml:",inline"`
+	Daemons  []DaemonHealth `json:"daemons" yaml:"daemons"`
+	LevelDBs []LevelDBLock  `json:"levelDBs" yaml:"levelDBs"`
+}
+
+type DaemonHealth struct {
+	Sudo       bool            `json:"sudo" yaml:"sudo"`
+	Running    bool            `json:"running" yaml:"running"`
+	Error      string          `json:"error,omitempty" yaml:"error,omitempty"`
+	Pid        int             `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Version    string          `json:"version,omitempty" yaml:"version,omitempty"`
+	ManagedBy  string          `json:"managedBy,omitempty" yaml:"managedBy,omitempty"`
+	StartTime  string          `json:"startTime,omitempty" yaml:"startTime,omitempty"`
+	Goroutines int             `json:"goroutines,omitempty" yaml:"goroutines,omitempty"`
+	Routines   []DaemonRoutine `json:"routines,omitempty" yaml:"routines,omitempty"`
+	Informers  []Informer      `json:"informers,omitempty" yaml:"informers,omitempty"`
+}
+
+// DaemonRoutine is a port-forward or sync routine supervised by the watchdog of daemon
+type DaemonRoutine struct {
+	Kind          string `json:"kind" yaml:"kind"`
+	Name          string `json:"name" yaml:"name"`
+	State         string `json:"state" yaml:"state"`
+	Restarts      int    `json:"restarts" yaml:"restarts"`
+	LastError     string `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	LastErrorTime string `json:"lastErrorTime,omitempty" yaml:"lastErrorTime,omitempty"`
+	NextRestart   string `json:"nextRestart,omitempty" yaml:"nextRestart,omitempty"`
+}
+
+type Informer struct {
+	Kind            string `json:"kind" yaml:"kind"`
+	Server          string `json:"server,omitempty" yaml:"server,omitempty"`
+	Namespace       string `json:"namespace" yaml:"namespace"`
+	ClusterScope    bool   `json:"clusterScope,omitempty" yaml:"clusterScope,omitempty"`
+	Informers       int    `json:"informers" yaml:"informers"`
+	SyncedInformers int    `json:"syncedInformers" yaml:"syncedInformers"`
+	PodSubscribers  int    `json:"podSubscribers,omitempty" yaml:"podSubscribers,omitempty"`
+	LastUsedTime    string `json:"lastUsedTime,omitempty" yaml:"lastUsedTime,omitempty"`
+}
+
+// LevelDBLock reports if the leveldb of an application is held by a process
+type LevelDBLock struct {
+	Path   string `json:"path" yaml:"path"`
+	Locked bool   `json:"locked" yaml:"locked"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (r *DaemonDoctorReport) kind() string {
+	return "DaemonDoctorReport"
+}
+
+func (r *DaemonDoctorReport) TableHeader() []string {
+	return []string{"CHECK", "STATUS", "DETAIL"}
+}
+
+func (r *DaemonDoctorReport) TableRows() [][]string {
+	rows := make([][]string, 0)
+	for _, d := range r.Daemons {
+		name := "daemon"
+		if d.Sudo {
+			name = "daemon(sudo)"
+		}
+		if !d.Running {
+			rows = append(rows, []string{name, "NOT-RUNNING", d.Error})
+			continue
+		}
+		if d.Error != "" {
+			rows = append(rows, []string{name, "ERROR", d.Error})
+			continue
+		}
+		detail := fmt.Sprintf(
+			"pid %d, version %s, started at %s, %d goroutines", d.Pid, d.Version, d.StartTime, d.Goroutines,
+		)
+		if d.ManagedBy != "" {
+			detail += ", managed by " + d.ManagedBy
+		}
+		rows = append(rows, []string{name, "OK", detail})
+
+		for _, routine := range d.Routines {
+			details := []string{fmt.Sprintf("restarts %d", routine.Restarts)}
+			if routine.NextRestart != "" {
+				details = append(details, "next restart at "+routine.NextRestart)
+			}
+			if routine.LastError != "" {
+				details = append(details, fmt.Sprintf("last error at %s: %s", routine.LastErrorTime, routine.LastError))
+			}
+			rows = append(
+				rows, []string{routine.Kind + " " + routine.Name, routine.State, strings.Join(details, ", ")},
+			)
+		}
+		for _, informer := range d.Informers {
+			scope := informer.Namespace
+			if informer.ClusterScope {
+				scope = "cluster"
+			}
+			status := "SYNCED"
+			if informer.SyncedInformers < informer.Informers {
+				status = fmt.Sprintf("SYNCING(%d/%d)", informer.SyncedInformers, informer.Informers)
+			}
+			details := []string{fmt.Sprintf("%d informers", informer.Informers)}
+			if informer.Server != "" {
+				details = append(details, informer.Server)
+			}
+			if informer.PodSubscribers > 0 {
+				details = append(details, fmt.Sprintf("%d pod watches", informer.PodSubscribers))
+			}
+			if informer.LastUsedTime != "" {
+				details = append(details, "last used at "+informer.LastUsedTime)
+			}
+			rows = append(
+				rows, []string{"informer " + informer.Kind + " " + scope, status, strings.Join(details, ", ")},
+			)
+		}
+	}
+	for _, db := range r.LevelDBs {
+		status := "FREE"
+		if db.Error != "" {
+			status = "ERROR"
+		} else if db.Locked {
+			status = "LOCKED"
+		}
+		rows = append(rows, []string{"leveldb " + db.Path, status, db.Error})
+	}
+	return rows
+}
+
+// ConfigLintReport is the output of `nhctl config lint`
+type ConfigLintReport struct {
+	TypeMeta `yaml:",inline"`
+	File     string            `json:"file" yaml:"file"`
+	Issues   []ConfigLintIssue `json:"issues" yaml:"issues"`
+}
+
+type ConfigLintIssue struct {
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line" yaml:"line"`
+	Column   int    `json:"column" yaml:"column"`
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+func (r *ConfigLintReport) kind() string {
+	return "ConfigLintReport"
+}
+
+func (r *ConfigLintReport) TableHeader() []string {
+	return []string{"POSITION", "SEVERITY", "PATH", "MESSAGE"}
+}
+
+func (r *ConfigLintReport) TableRows() [][]string {
+	rows := make([][]string, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		rows = append(
+			rows, []string{
+				fmt.Sprintf("%s:%d:%d", issue.File, issue.Line, issue.Column), issue.Severity, issue.Path, issue.Message,
+			},
+		)
+	}
+	return rows
+}
+
+// HubConfigList is the output of `nhctl hub search`
+type HubConfigList struct {
+	TypeMeta `yaml:",inline"`
+	Items    []HubConfig `json:"items" yaml:"items"`
+}
+
+type HubConfig struct {
+	Name        string   `json:"name" yaml:"name"`
+	Version     string   `json:"version" yaml:"version"`
+	ServiceType string   `json:"serviceType,omitempty" yaml:"serviceType,omitempty"`
+	Images      []string `json:"images,omitempty" yaml:"images,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	KeyId       string   `json:"keyId,omitempty" yaml:"keyId,omitempty"`
+	Hub         string   `json:"hub" yaml:"hub"`
+}
+
+func (l *HubConfigList) kind() string {
+	return "HubConfigList"
+}
+
+func (l *HubConfigList) TableHeader() []string {
+	return []string{"NAME", "VERSION", "SERVICE-TYPE", "IMAGES", "SIGNED-BY", "DESCRIPTION"}
+}
+
+func (l *HubConfigList) TableRows() [][]string {
+	rows := make([][]string, 0, len(l.Items))
+	for _, item := range l.Items {
+		rows = append(
+			rows, []string{
+				item.Name, item.Version, item.ServiceType, strings.Join(item.Images, ","), item.KeyId, item.Description,
+			},
+		)
+	}
+	return rows
+}
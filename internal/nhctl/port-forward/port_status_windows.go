@@ -15,7 +15,7 @@ import (
 )
 
 func PidPortStatus(pid int, port int) string {
-	result, err := tools.ExecCommand(nil, false, false, "cmd", "/c", fmt.Sprintf("netstat -ano | findstr %d | findstr %d | findstr LISTENING", pid, port))
+	result, err := tools.ExecCommand(nil, false, false, false, "cmd", "/c", fmt.Sprintf("netstat -ano | findstr %d | findstr %d | findstr LISTENING", pid, port))
 	if err != nil {
 		log.Errorf("netstat error %s", err.Error())
 	}
@@ -44,9 +44,18 @@ type ApplicationConfig struct {
 	HelmVersion    string             `json:"helmVersion" yaml:"helmVersion"`
 	Env            []*Env             `json:"env" yaml:"env"`
 	EnvFrom        EnvFrom            `json:"envFrom,omitempty" yaml:"envFrom,omitempty"`
+	DependsOn      []*AppDependency   `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
 	ServiceConfigs []*ServiceConfigV2 `json:"services" yaml:"services,omitempty"`
 }
 
+// AppDependency is an application in the same namespace which must be installed and ready
+// before this one, Config is the config file relative to .nocalhost dir of the same source,
+// which is used to install the dependency by `nhctl install --with-deps`
+type AppDependency struct {
+	Name   string `json:"name" yaml:"name"`
+	Config string `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
 type HubConfig struct {
 	Image string `json:"image" yaml:"image"`
 }
@@ -74,6 +83,39 @@ type ContainerDevConfig struct {
 	EnvFrom               *EnvFrom               `json:"envFrom,omitempty" yaml:"envFrom,omitempty"`
 	PortForward           []string               `json:"portForward" yaml:"portForward"`
 	SidecarImage          string                 `json:"sidecarImage,omitempty" yaml:"sidecarImage,omitempty"`
+	// Build builds the dev image from a Dockerfile in the cluster while dev start, image is ignored if specified
+	Build *DevBuildConfig `json:"build,omitempty" yaml:"build,omitempty"`
+	// Lifecycle hooks are executed in the dev container by `nhctl dev start` and `nhctl dev end`
+	Lifecycle *DevLifecycle `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+}
+
+// DevLifecycle commands are executed in the dev container, the output is streamed to nhctl
+type DevLifecycle struct {
+	// PostStart runs once after the first sync completes, e.g. `go mod download`
+	PostStart []string `json:"postStart,omitempty" yaml:"postStart,omitempty"`
+	// PostSync runs after every sync batch completes
+	PostSync []string `json:"postSync,omitempty" yaml:"postSync,omitempty"`
+	// PreEnd runs before the workload is rolled back, e.g. dumping the database
+	PreEnd []string `json:"preEnd,omitempty" yaml:"preEnd,omitempty"`
+}
+
+// DevBuildConfig builds the dev image by kaniko or BuildKit in the cluster, the local context
+// is streamed through the api server and images are cached by the hash of the context
+type DevBuildConfig struct {
+	// Image is the repository which the built image is pushed to, tagged by the hash of the context
+	Image string `json:"image" yaml:"image"`
+	// Context is the build context relative to the source directory, default is the source directory
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+	// Dockerfile is relative to the context, default is Dockerfile
+	Dockerfile string            `json:"dockerfile,omitempty" yaml:"dockerfile,omitempty"`
+	Target     string            `json:"target,omitempty" yaml:"target,omitempty"`
+	Args       map[string]string `json:"args,omitempty" yaml:"args,omitempty"`
+	// Builder is either kaniko or buildkit, default is kaniko
+	Builder string `json:"builder,omitempty" yaml:"builder,omitempty"`
+	// PushSecret is a secret of type kubernetes.io/dockerconfigjson to push the image
+	PushSecret string `json:"pushSecret,omitempty" yaml:"pushSecret,omitempty"`
+	// Insecure pushes to a registry over http or with a self-signed certificate
+	Insecure bool `json:"insecure,omitempty" yaml:"insecure,omitempty"`
 }
 
 type DevCommands struct {
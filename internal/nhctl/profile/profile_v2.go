@@ -53,6 +53,10 @@ type AppProfileV2 struct {
 	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig,omitempty"`
 	db         *dbutils.LevelDBUtils
 
+	// overlays of config specified while installing, they are applied to the config
+	// loaded from the associated local dir as well
+	ConfigOverlays []string `json:"configOverlays,omitempty" yaml:"configOverlays,omitempty"`
+
 	// for previous version, associate path is stored in profile
 	// and now it store in a standalone db
 	AssociateMigrate bool `json:"associate_migrate" yaml:"associate_migrate"`
@@ -188,6 +192,13 @@ func (a *AppProfileV2) CloseDb() error {
 	return a.db.Close()
 }
 
+// ExtraDevContainer is a container of the workload replaced in the same dev session,
+// LocalSyncDir is synced to the workDir of it
+type ExtraDevContainer struct {
+	Container    string `json:"container" yaml:"container"`
+	LocalSyncDir string `json:"localSyncDir" yaml:"localSyncDir"`
+}
+
 type SvcProfileV2 struct {
 	*ServiceConfigV2 `json:"rawConfig" yaml:"rawConfig"` // deprecated, move to app mate
 	//ContainerProfile []*ContainerProfileV2 `json:"containerProfile" yaml:"containerProfile"`
@@ -211,6 +222,16 @@ type SvcProfileV2 struct {
 	LocalAbsoluteSyncDirFromDevStartPlugin []string          `json:"localAbsoluteSyncDirFromDevStartPlugin" yaml:"localAbsoluteSyncDirFromDevStartPlugin"`
 	DevPortForwardList                     []*DevPortForward `json:"devPortForwardList" yaml:"devPortForwardList"` // combine DevPortList,PortForwardStatusList and PortForwardPidList
 
+	// container developed by `dev start -c`, lifecycle hooks of it are executed while dev end
+	DevContainer string `json:"devContainer,omitempty" yaml:"devContainer,omitempty"`
+	// containers developed together with DevContainer in the same dev session, synced by the same syncthing
+	ExtraDevContainers []*ExtraDevContainer `json:"extraDevContainers,omitempty" yaml:"extraDevContainers,omitempty"`
+	// DevMode is how the dev container is started by `dev start --mode`, replace or ephemeral
+	DevMode string `json:"devMode,omitempty" yaml:"devMode,omitempty"`
+	// the pod which the ephemeral dev container is attached to in ephemeral dev mode
+	EphemeralPod          string `json:"ephemeralPod,omitempty" yaml:"ephemeralPod,omitempty"`
+	EphemeralDevContainer string `json:"ephemeralDevContainer,omitempty" yaml:"ephemeralDevContainer,omitempty"`
+
 	// nocalhost supports config from local dir under "Associate" Path, it's priority is highest
 	LocalConfigLoaded bool `json:"localconfigloaded" yaml:"localconfigloaded"`
 
@@ -253,6 +274,31 @@ type DevPortForward struct {
 	Sudo            bool   `json:"sudo" yaml:"sudo"`
 	DaemonServerPid int    `json:"daemonserverpid" yaml:"daemonserverpid"`
 	ServiceType     string `json:"servicetype" yaml:"servicetype"`
+	K8sService      string `json:"k8sService,omitempty" yaml:"k8sService,omitempty"`   // forward to k8s Service instead of PodName
+	Protocol        string `json:"protocol,omitempty" yaml:"protocol,omitempty"`       // tcp or udp, tcp if empty
+	BindAddress     string `json:"bindAddress,omitempty" yaml:"bindAddress,omitempty"` // loopback if empty
+	PortPolicy      string `json:"portPolicy,omitempty" yaml:"portPolicy,omitempty"`   // fixed if empty
+}
+
+const (
+	PortForwardProtocolTCP = "tcp"
+	PortForwardProtocolUDP = "udp"
+
+	// PortPolicyFixed fails if the local port is unavailable
+	PortPolicyFixed = "fixed"
+	// PortPolicyNextFree listens on the next free port if the local port is unavailable,
+	// the chosen port is recorded as LocalPort
+	PortPolicyNextFree = "next-free"
+)
+
+func (pf *DevPortForward) IsUDP() bool {
+	return pf.Protocol == PortForwardProtocolUDP
+}
+
+// Matches returns true if pf forwards localPort to remotePort by protocol, empty protocol means tcp
+func (pf *DevPortForward) Matches(localPort, remotePort int, protocol string) bool {
+	return pf.LocalPort == localPort && pf.RemotePort == remotePort &&
+		pf.IsUDP() == (protocol == PortForwardProtocolUDP)
 }
 
 // Compatible for v1
@@ -0,0 +1,157 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package profile
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"reflect"
+	"strings"
+)
+
+const (
+	SchemaDraft = "http://json-schema.org/draft-07/schema#"
+	SchemaId    = "https://nocalhost.dev/schema/config.schema.json"
+)
+
+// schemaEnums are the values allowed for some fields, keyed by `TypeName.yamlField`
+var schemaEnums = map[string][]interface{}{
+	"ConfigProperties.version": {"v2"},
+	"ApplicationConfig.manifestType": {
+		"rawManifest", "rawManifestGit", "rawManifestLocal", "helmGit", "helmRepo", "helmLocal",
+		"kustomizeGit", "kustomizeLocal", "jsonnetGit", "jsonnetLocal", "cueLocal",
+	},
+	"ServiceConfigV2.serviceType": {
+		"deployment", "statefulset", "daemonset", "job", "cronjob", "pod",
+		"Deployment", "StatefulSet", "DaemonSet", "Job", "CronJob", "Pod",
+	},
+	"SyncConfig.type":        {"send", "sendReceive"},
+	"DevBuildConfig.builder": {"kaniko", "buildkit"},
+}
+
+// schemaRequired are the fields must be specified, keyed by type name
+var schemaRequired = map[string][]string{
+	"ServiceConfigV2": {"name"},
+	"AppDependency":   {"name"},
+	"Env":             {"name"},
+	"EnvFile":         {"path"},
+	"WeightablePath":  {"path"},
+	"DevBuildConfig":  {"image"},
+}
+
+// ConfigSchema returns the json schema of .nocalhost/config.yaml, it is generated
+// from NocalHostAppConfigV2 so that it never goes out of date
+func ConfigSchema() map[string]interface{} {
+	g := &schemaGenerator{definitions: map[string]interface{}{}}
+	root := g.definitionOf(reflect.TypeOf(NocalHostAppConfigV2{}))
+	// service configs may be kept out of the application config, e.g. in the repo of the service
+	g.definitionOf(reflect.TypeOf(ServiceConfigV2{}))
+
+	return map[string]interface{}{
+		"$schema":     SchemaDraft,
+		"$id":         SchemaId,
+		"title":       "Nocalhost config",
+		"$ref":        root["$ref"],
+		"definitions": g.definitions,
+	}
+}
+
+// ServiceConfigSchema returns the json schema of a file containing service configs only,
+// list indicates if it is an array of service configs or a single one
+func ServiceConfigSchema(list bool) map[string]interface{} {
+	schema := ConfigSchema()
+	ref := map[string]interface{}{"$ref": "#/definitions/ServiceConfigV2"}
+	delete(schema, "$ref")
+	if list {
+		schema["type"] = "array"
+		schema["items"] = ref
+	} else {
+		schema["$ref"] = ref["$ref"]
+	}
+	return schema
+}
+
+// ConfigSchemaJSON returns the indented json of ConfigSchema
+func ConfigSchemaJSON() ([]byte, error) {
+	bys, err := json.MarshalIndent(ConfigSchema(), "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return append(bys, '\n'), nil
+}
+
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return g.definitionOf(t)
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": []string{"boolean", "null"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": []string{"integer", "null"}}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": []string{"number", "null"}}
+	case reflect.String:
+		// yaml scalars such as `cpu: 1` are decoded into strings as well
+		return map[string]interface{}{"type": []string{"string", "number", "boolean", "null"}}
+	}
+	// interface{}, e.g. helmVals
+	return map[string]interface{}{}
+}
+
+// definitionOf adds the struct to definitions and returns a reference to it
+func (g *schemaGenerator) definitionOf(t reflect.Type) map[string]interface{} {
+	ref := map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	if _, ok := g.definitions[t.Name()]; ok {
+		return ref
+	}
+	// placeholder for recursive types
+	g.definitions[t.Name()] = nil
+
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaOf(field.Type)
+		if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+			property = map[string]interface{}{
+				"type": []string{"string", "null"},
+				"enum": append(append([]interface{}{}, enum...), nil),
+			}
+		}
+		properties[name] = property
+	}
+
+	definition := map[string]interface{}{
+		"type":                 []string{"object", "null"},
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[t.Name()]; ok {
+		definition["required"] = required
+	}
+	g.definitions[t.Name()] = definition
+	return ref
+}
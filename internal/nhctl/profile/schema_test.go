@@ -0,0 +1,58 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package profile
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the published schema")
+
+const publishedSchema = "../../../docs/schema/config.schema.json"
+
+func TestPublishedConfigSchema(t *testing.T) {
+	bys, err := ConfigSchemaJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err = ioutil.WriteFile(publishedSchema, bys, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(publishedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, bys) {
+		t.Errorf("%s is out of date, run `go test ./internal/nhctl/profile/ -update` to regenerate it", publishedSchema)
+	}
+}
+
+func TestConfigSchemaDefinitions(t *testing.T) {
+	definitions := ConfigSchema()["definitions"].(map[string]interface{})
+	for _, name := range []string{
+		"NocalHostAppConfigV2", "ApplicationConfig", "ServiceConfigV2", "ContainerConfig", "ContainerDevConfig",
+		"WeightablePath", "ResourceQuota",
+	} {
+		if definitions[name] == nil {
+			t.Errorf("definition %s not found", name)
+		}
+	}
+
+	dev := definitions["ContainerDevConfig"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := dev["workDir"]; !ok {
+		t.Errorf("property workDir of ContainerDevConfig not found")
+	}
+	if _, ok := dev["DevContainerResources"]; ok {
+		t.Errorf("properties should be named by yaml tags")
+	}
+}
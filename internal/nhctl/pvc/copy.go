@@ -0,0 +1,189 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package pvc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/internal/nhctl/utils"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	helperReadyTimeout  = 5 * time.Minute
+	helperContainerName = "pvc-helper"
+	helperMountPath     = "/data"
+	// content copied is extracted here before replacing the original one
+	helperStagingDir = ".nocalhost-pvc-copy"
+	// helper pods exit after a while, in case they are not deleted by nhctl
+	helperLifetimeSeconds = 3600
+)
+
+// PodsUsing returns pods not completed which mount the pvc
+func PodsUsing(client *clientgoutils.ClientGoUtils, pvcName string) ([]corev1.Pod, error) {
+	pods, err := client.ListPodsByLabels(nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]corev1.Pod, 0)
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == pvcName {
+				result = append(result, pod)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkNotInUse returns an error if the pvc is mounted by any pod, its content is going to be replaced
+func checkNotInUse(client *clientgoutils.ClientGoUtils, pvcName string) error {
+	pods, err := PodsUsing(client, pvcName)
+	if err != nil {
+		return err
+	}
+	if len(pods) > 0 {
+		return errors.New(
+			fmt.Sprintf("pvc %s is used by pod %s, please end the dev mode first", pvcName, pods[0].Name),
+		)
+	}
+	return nil
+}
+
+// startHelper creates a pod mounting the pvc at helperMountPath, returns name of the pod. The pod is
+// scheduled to the node of the pod using the pvc, since a ReadWriteOnce volume can only be mounted by one node
+func startHelper(client *clientgoutils.ClientGoUtils, pvcName string, readOnly bool) (string, error) {
+	var nodeName string
+	pods, err := PodsUsing(client, pvcName)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) > 0 {
+		nodeName = pods[0].Spec.NodeName
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "nocalhost-pvc-helper-",
+			Labels: map[string]string{
+				_const.PvcHelperLabel:    "true",
+				_const.AppManagedByLabel: _const.AppManagedByNocalhost,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			NodeName:      nodeName,
+			Containers: []corev1.Container{
+				{
+					Name:            helperContainerName,
+					Image:           _const.DefaultSideCarImage,
+					ImagePullPolicy: _const.DefaultSidecarImagePullPolicy,
+					Command:         []string{"sh", "-c", fmt.Sprintf("sleep %d", helperLifetimeSeconds)},
+					VolumeMounts:    []corev1.VolumeMount{{Name: "data", MountPath: helperMountPath}},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: pvcName,
+							ReadOnly:  readOnly,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	created, err := client.CreatePod(pod)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Waiting for helper %s mounting pvc %s to be ready", created.Name, pvcName)
+	if err = client.WaitPodReady(created.Name, helperReadyTimeout); err != nil {
+		_ = client.DeletePodByName(created.Name, 0)
+		return "", errors.Wrap(err, fmt.Sprintf("helper of pvc %s is not ready", pvcName))
+	}
+	return created.Name, nil
+}
+
+// copyVolume replaces content of the pvc `to` with content of the pvc `from`, it's streamed by tar
+// through nhctl, so the pvcs can reside in different namespaces or clusters
+func copyVolume(
+	fromClient *clientgoutils.ClientGoUtils, from string, toClient *clientgoutils.ClientGoUtils, to string,
+) error {
+	src, err := startHelper(fromClient, from, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.ShouldI(fromClient.DeletePodByName(src, 0), fmt.Sprintf("Failed to delete helper %s", src))
+	}()
+	dst, err := startHelper(toClient, to, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.ShouldI(toClient.DeletePodByName(dst, 0), fmt.Sprintf("Failed to delete helper %s", dst))
+	}()
+
+	log.Infof("Copying content of pvc %s to pvc %s", from, to)
+	reader, writer := io.Pipe()
+	srcStderr := &bytes.Buffer{}
+	srcErr := make(chan error, 1)
+	go func() {
+		err := fromClient.ExecStream(
+			src, helperContainerName, []string{"tar", "cf", "-", "-C", helperMountPath, "."}, nil, writer, srcStderr,
+		)
+		_ = writer.CloseWithError(err)
+		srcErr <- err
+	}()
+
+	dstStderr := &bytes.Buffer{}
+	err = toClient.ExecStream(
+		dst, helperContainerName, []string{"sh", "-c", extractScript(helperMountPath)}, reader, nil, dstStderr,
+	)
+	// unblock the source while the destination fails
+	_ = reader.Close()
+	// the source fails with closed pipe if the destination exits early, which is reported below
+	if e := <-srcErr; e != nil && !errors.Is(e, io.ErrClosedPipe) {
+		return errors.Wrap(e, fmt.Sprintf("fail to archive pvc %s: %s", from, strings.TrimSpace(srcStderr.String())))
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("fail to extract to pvc %s: %s", to, strings.TrimSpace(dstStderr.String())))
+	}
+	return nil
+}
+
+// extractScript extracts the tar from stdin to a staging dir in the volume, the original content
+// is replaced by the staged one only if the extraction succeeds, so a failed copy leaves it untouched
+func extractScript(dir string) string {
+	return fmt.Sprintf(
+		`s=%[1]s/%[2]s
+rm -rf $s && mkdir $s && tar xf - -C $s || { rm -rf $s; exit 1; }
+for f in %[1]s/..?* %[1]s/.[!.]* %[1]s/*; do
+  if [ -e "$f" ] && [ "$f" != $s ]; then rm -rf "$f" || exit 1; fi
+done
+for f in $s/..?* $s/.[!.]* $s/*; do
+  if [ -e "$f" ]; then mv "$f" %[1]s/ || exit 1; fi
+done
+rmdir $s`, dir, helperStagingDir,
+	)
+}
@@ -0,0 +1,69 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package pvc
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractScript(t *testing.T) {
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar is required")
+	}
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	for file, content := range map[string]string{
+		filepath.Join(src, "sub", "new"): "new",
+		filepath.Join(src, ".hidden"):    "hidden",
+		filepath.Join(dst, "old"):        "old",
+	} {
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	extract := exec.Command("sh", "-c", extractScript(dst))
+	extract.Stdin = strings.NewReader("not a tar")
+	if err = extract.Run(); err == nil {
+		t.Fatal("extracting invalid tar should fail")
+	}
+	if _, err = os.Stat(filepath.Join(dst, "old")); err != nil {
+		t.Fatalf("content should be kept if extraction fails, %v", err)
+	}
+
+	archive, err := exec.Command("tar", "cf", "-", "-C", src, ".").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract = exec.Command("sh", "-c", extractScript(dst))
+	extract.Stdin = strings.NewReader(string(archive))
+	if out, err := extract.CombinedOutput(); err != nil {
+		t.Fatalf("%v, %s", err, out)
+	}
+	files, err := ioutil.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if strings.Join(names, ",") != ".hidden,sub" {
+		t.Errorf("content should be replaced, got %v", names)
+	}
+}
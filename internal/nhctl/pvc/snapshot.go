@@ -0,0 +1,277 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package pvc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	snapshotReadyTimeout = 10 * time.Minute
+	defaultCapacity      = "10Gi"
+)
+
+// Snapshot is a point-in-time copy of a pvc, it's a CSI VolumeSnapshot if the storage class
+// of the pvc supports, or a pvc copied through helper pods
+type Snapshot struct {
+	Name   string
+	Source string
+	// CSI is true if the snapshot is a VolumeSnapshot
+	CSI bool
+}
+
+// CreateSnapshot snapshots the pvc, name of the snapshot is generated if not specified.
+// The content is copied by tar if forceCopy or CSI snapshot is not supported
+func CreateSnapshot(client *clientgoutils.ClientGoUtils, pvcName, name string, forceCopy bool) (*Snapshot, error) {
+	source, err := client.GetPvcByName(pvcName)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-snapshot-%d", pvcName, time.Now().Unix())
+	}
+	labels := map[string]string{_const.SnapshotLabel: "true"}
+	annotations := map[string]string{_const.SnapshotSourceAnnotation: pvcName}
+	if dir, ok := source.Annotations[_const.PersistentVolumeDirLabel]; ok {
+		annotations[_const.PersistentVolumeDirLabel] = dir
+	}
+
+	if !forceCopy {
+		version := client.VolumeSnapshotVersion()
+		class, err := client.GetVolumeSnapshotClass(version, storageClassOf(source))
+		if err != nil {
+			log.WarnE(err, "Failed to find VolumeSnapshotClass, falling back to copy")
+		}
+		if class != "" {
+			log.Infof("Creating VolumeSnapshot %s of pvc %s with class %s", name, pvcName, class)
+			if _, err = client.CreateVolumeSnapshot(version, name, pvcName, class, labels, annotations); err != nil {
+				return nil, err
+			}
+			if err = client.WaitVolumeSnapshotReady(version, name, snapshotReadyTimeout); err != nil {
+				return nil, err
+			}
+			return &Snapshot{Name: name, Source: pvcName, CSI: true}, nil
+		}
+		log.Infof("CSI snapshot is not supported by the storage class of pvc %s, falling back to copy", pvcName)
+	}
+
+	// the snapshot pvc has no app and service labels, so it's not mounted by dev mode
+	if _, err = client.CreatePVC(
+		name, labels, annotations, capacityOf(source), source.Spec.StorageClassName,
+	); err != nil {
+		return nil, err
+	}
+	if err = copyVolume(client, pvcName, client, name); err != nil {
+		if e := client.DeletePVC(name); e != nil {
+			log.WarnE(e, fmt.Sprintf("Failed to clean up snapshot %s", name))
+		}
+		return nil, err
+	}
+	return &Snapshot{Name: name, Source: pvcName}, nil
+}
+
+// GetSnapshot returns the snapshot, either a VolumeSnapshot or a snapshot pvc
+func GetSnapshot(client *clientgoutils.ClientGoUtils, name string) (*Snapshot, error) {
+	if version := client.VolumeSnapshotVersion(); version != "" {
+		vs, err := client.GetVolumeSnapshot(version, name)
+		if err != nil {
+			return nil, err
+		}
+		if vs != nil {
+			return &Snapshot{Name: name, Source: vs.GetAnnotations()[_const.SnapshotSourceAnnotation], CSI: true}, nil
+		}
+	}
+	claim, err := client.GetPvcByName(name)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("snapshot %s not found", name))
+	}
+	if claim.Labels[_const.SnapshotLabel] != "true" {
+		return nil, errors.New(fmt.Sprintf("pvc %s is not a snapshot", name))
+	}
+	return &Snapshot{Name: name, Source: claim.Annotations[_const.SnapshotSourceAnnotation]}, nil
+}
+
+// ListSnapshots returns VolumeSnapshots and snapshot pvcs created by nhctl in the namespace of client
+func ListSnapshots(client *clientgoutils.ClientGoUtils) ([]*Snapshot, error) {
+	labels := map[string]string{_const.SnapshotLabel: "true"}
+	result := make([]*Snapshot, 0)
+	if version := client.VolumeSnapshotVersion(); version != "" {
+		list, err := client.ListVolumeSnapshots(version, labels)
+		if err != nil {
+			return nil, err
+		}
+		for _, vs := range list {
+			result = append(
+				result,
+				&Snapshot{Name: vs.GetName(), Source: vs.GetAnnotations()[_const.SnapshotSourceAnnotation], CSI: true},
+			)
+		}
+	}
+	claims, err := client.GetPvcByLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	for _, claim := range claims {
+		result = append(result, &Snapshot{Name: claim.Name, Source: claim.Annotations[_const.SnapshotSourceAnnotation]})
+	}
+	return result, nil
+}
+
+// DeleteSnapshot deletes the VolumeSnapshot or the snapshot pvc
+func DeleteSnapshot(client *clientgoutils.ClientGoUtils, name string) error {
+	snapshot, err := GetSnapshot(client, name)
+	if err != nil {
+		return err
+	}
+	if snapshot.CSI {
+		return client.DeleteVolumeSnapshot(client.VolumeSnapshotVersion(), name)
+	}
+	return client.DeletePVC(name)
+}
+
+// RestoreSnapshot restores the pvc to the snapshot, returns name of the restored pvc. A new pvc with
+// the labels of the original one is created from a VolumeSnapshot and the original one is deleted,
+// or the content of the snapshot pvc is copied to the pvc in place
+func RestoreSnapshot(client *clientgoutils.ClientGoUtils, name, pvcName string) (string, error) {
+	target, err := client.GetPvcByName(pvcName)
+	if err != nil {
+		return "", err
+	}
+	if err = checkNotInUse(client, pvcName); err != nil {
+		return "", err
+	}
+	snapshot, err := GetSnapshot(client, name)
+	if err != nil {
+		return "", err
+	}
+
+	if !snapshot.CSI {
+		return pvcName, copyVolume(client, name, client, pvcName)
+	}
+
+	restored := newPvcName(target.Labels[_const.AppLabel], pvcName)
+	log.Infof("Creating pvc %s from VolumeSnapshot %s", restored, name)
+	if _, err = client.CreatePVCFromSnapshot(
+		restored, target.Labels, restoredAnnotations(target), capacityOf(target), target.Spec.StorageClassName, name,
+	); err != nil {
+		return "", err
+	}
+	// the original pvc is kept until the restored one is usable
+	if err = client.WaitPvcBound(restored, snapshotReadyTimeout); err != nil {
+		_ = client.DeletePVC(restored)
+		return "", err
+	}
+	if err = client.DeletePVC(pvcName); err != nil {
+		_ = client.DeletePVC(restored)
+		return "", err
+	}
+	return restored, nil
+}
+
+// Clone copies the pvc in another namespace or cluster to the pvc of the same persistent volume dir in
+// the namespace of client, app and svc are the labels of the cloned pvc, default is the same as the source.
+// The pvc is created if not exists, returns name of it
+func Clone(
+	fromClient *clientgoutils.ClientGoUtils, from string, client *clientgoutils.ClientGoUtils,
+	app, svc, storageClass string,
+) (string, error) {
+	source, err := fromClient.GetPvcByName(from)
+	if err != nil {
+		return "", err
+	}
+	labels := cloneLabels(source.Labels, app, svc)
+	if len(labels) < 3 {
+		return "", errors.New(
+			fmt.Sprintf("pvc %s is not a persistent volume dir of a service, app and service are required", from),
+		)
+	}
+
+	claims, err := client.GetPvcByLabels(labels)
+	if err != nil {
+		return "", err
+	}
+	var target string
+	switch len(claims) {
+	case 0:
+		var sc *string
+		if storageClass != "" {
+			sc = &storageClass
+		}
+		annotations := map[string]string{
+			_const.PersistentVolumeDirLabel: source.Annotations[_const.PersistentVolumeDirLabel],
+		}
+		target = newPvcName(labels[_const.AppLabel], from)
+		if _, err = client.CreatePVC(target, labels, annotations, capacityOf(source), sc); err != nil {
+			return "", err
+		}
+		log.Infof("Pvc %s is created", target)
+	case 1:
+		target = claims[0].Name
+		if err = checkNotInUse(client, target); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New(fmt.Sprintf("found %d pvcs of the persistent volume dir, expected 1", len(claims)))
+	}
+
+	return target, copyVolume(fromClient, from, client, target)
+}
+
+// cloneLabels returns labels selecting the pvc of a persistent volume dir by dev mode
+func cloneLabels(source map[string]string, app, svc string) map[string]string {
+	labels := map[string]string{}
+	for _, key := range []string{_const.AppLabel, _const.ServiceLabel, _const.PersistentVolumeDirLabel} {
+		if v := source[key]; v != "" {
+			labels[key] = v
+		}
+	}
+	if app != "" {
+		labels[_const.AppLabel] = app
+	}
+	if svc != "" {
+		labels[_const.ServiceLabel] = svc
+	}
+	return labels
+}
+
+// newPvcName is the same as names of pvcs created by dev mode
+func newPvcName(app, fallback string) string {
+	if app == "" {
+		app = fallback
+	}
+	return fmt.Sprintf("%s-%d", app, time.Now().UnixNano())
+}
+
+// restoredAnnotations returns annotations of the pvc restored from the target, annotations
+// set by the pv controller, such as bind-completed, must not be copied to the new claim
+func restoredAnnotations(target *corev1.PersistentVolumeClaim) map[string]string {
+	annotations := map[string]string{}
+	if dir, ok := target.Annotations[_const.PersistentVolumeDirLabel]; ok {
+		annotations[_const.PersistentVolumeDirLabel] = dir
+	}
+	return annotations
+}
+
+func storageClassOf(claim *corev1.PersistentVolumeClaim) string {
+	if claim.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *claim.Spec.StorageClassName
+}
+
+func capacityOf(claim *corev1.PersistentVolumeClaim) string {
+	if q, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		return q.String()
+	}
+	return defaultCapacity
+}
@@ -0,0 +1,63 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package pvc
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	_const "nocalhost/internal/nhctl/const"
+)
+
+func TestCloneLabels(t *testing.T) {
+	source := map[string]string{
+		_const.AppLabel:                 "bookinfo",
+		_const.ServiceLabel:             "reviews",
+		_const.PersistentVolumeDirLabel: "d2c6b2e0",
+		"other":                         "value",
+	}
+	labels := cloneLabels(source, "", "")
+	if len(labels) != 3 || labels[_const.AppLabel] != "bookinfo" || labels["other"] != "" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	labels = cloneLabels(source, "bookinfo-dev", "reviews-v2")
+	if labels[_const.AppLabel] != "bookinfo-dev" || labels[_const.ServiceLabel] != "reviews-v2" ||
+		labels[_const.PersistentVolumeDirLabel] != "d2c6b2e0" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if labels = cloneLabels(map[string]string{}, "bookinfo", "reviews"); len(labels) != 2 {
+		t.Errorf("dir label should not be generated, got %v", labels)
+	}
+}
+
+func TestCapacityOf(t *testing.T) {
+	claim := &corev1.PersistentVolumeClaim{}
+	if c := capacityOf(claim); c != defaultCapacity {
+		t.Errorf("expected %s, got %s", defaultCapacity, c)
+	}
+	claim.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")}
+	if c := capacityOf(claim); c != "20Gi" {
+		t.Errorf("expected 20Gi, got %s", c)
+	}
+}
+
+func TestRestoredAnnotations(t *testing.T) {
+	target := &corev1.PersistentVolumeClaim{}
+	target.Annotations = map[string]string{
+		_const.PersistentVolumeDirLabel:                 "d2c6b2e0",
+		"pv.kubernetes.io/bind-completed":               "yes",
+		"pv.kubernetes.io/bound-by-controller":          "yes",
+		"volume.beta.kubernetes.io/storage-provisioner": "ebs.csi.aws.com",
+	}
+	annotations := restoredAnnotations(target)
+	if len(annotations) != 1 || annotations[_const.PersistentVolumeDirLabel] != "d2c6b2e0" {
+		t.Errorf("unexpected annotations %v", annotations)
+	}
+	if annotations = restoredAnnotations(&corev1.PersistentVolumeClaim{}); len(annotations) != 0 {
+		t.Errorf("unexpected annotations %v", annotations)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package resouce_cache
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"nocalhost/pkg/nhctl/log"
+	"sync"
+)
+
+const podEventBuffer = 256
+
+// PodEvent is a change of pod observed by the informer of Searcher
+type PodEvent struct {
+	Type watch.EventType
+	Pod  *corev1.Pod
+}
+
+type podSubscriber struct {
+	namespace string
+	ch        chan *PodEvent
+	// resource version sent of each pod, for skipping resync and replayed events
+	sent map[types.UID]string
+	lock sync.Mutex
+}
+
+// send returns false if the channel is full, the subscriber is too slow and must be dropped,
+// since skipping an event leaves the pods of the subscriber inconsistent
+func (p *podSubscriber) send(eventType watch.EventType, pod *corev1.Pod) bool {
+	if p.namespace != "" && pod.Namespace != p.namespace {
+		return true
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	rv, ok := p.sent[pod.UID]
+	if eventType == watch.Deleted {
+		if !ok {
+			return true
+		}
+	} else if ok && rv == pod.ResourceVersion {
+		return true
+	} else if ok {
+		eventType = watch.Modified
+	} else {
+		eventType = watch.Added
+	}
+
+	// the pod is recorded only after it's sent
+	select {
+	case p.ch <- &PodEvent{Type: eventType, Pod: pod}:
+	default:
+		log.Warnf("Too many pod events in namespace %s, stop watching", p.namespace)
+		return false
+	}
+	if eventType == watch.Deleted {
+		delete(p.sent, pod.UID)
+	} else {
+		p.sent[pod.UID] = pod.ResourceVersion
+	}
+	return true
+}
+
+// SubscribePods sends changes of pods in namespace until cancel is called,
+// pods already existing are sent as added first. The channel is closed once
+// the searcher is stopped or the subscriber can't keep up with the events,
+// subscribers should subscribe again then
+func (s *Searcher) SubscribePods(namespace string) (events <-chan *PodEvent, cancel func()) {
+	informer := s.informerFactory.Core().V1().Pods().Informer()
+
+	s.podLock.Lock()
+	defer s.podLock.Unlock()
+	// pods existing are replayed while holding the lock, the buffer holds all of them
+	existing := informer.GetStore().List()
+	sub := &podSubscriber{
+		namespace: namespace, ch: make(chan *PodEvent, len(existing)+podEventBuffer), sent: map[types.UID]string{},
+	}
+	if s.podStopped {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	if s.podSubscribers == nil {
+		s.podSubscribers = map[*podSubscriber]struct{}{}
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					s.dispatchPodEvent(watch.Added, obj)
+				},
+				UpdateFunc: func(_, obj interface{}) {
+					s.dispatchPodEvent(watch.Modified, obj)
+				},
+				DeleteFunc: func(obj interface{}) {
+					if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+						obj = tombstone.Obj
+					}
+					s.dispatchPodEvent(watch.Deleted, obj)
+				},
+			},
+		)
+	}
+	s.podSubscribers[sub] = struct{}{}
+
+	for _, obj := range existing {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			sub.send(watch.Added, pod)
+		}
+	}
+
+	return sub.ch, func() {
+		s.podLock.Lock()
+		defer s.podLock.Unlock()
+		if _, ok := s.podSubscribers[sub]; ok {
+			delete(s.podSubscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// hasPodSubscribers reports whether pods of the searcher are still watched
+func (s *Searcher) hasPodSubscribers() bool {
+	s.podLock.Lock()
+	defer s.podLock.Unlock()
+	return len(s.podSubscribers) > 0
+}
+
+// closePodSubscribers closes channels of all subscribers while stopping the searcher
+func (s *Searcher) closePodSubscribers() {
+	s.podLock.Lock()
+	defer s.podLock.Unlock()
+	s.podStopped = true
+	for sub := range s.podSubscribers {
+		close(sub.ch)
+		delete(s.podSubscribers, sub)
+	}
+}
+
+func (s *Searcher) dispatchPodEvent(eventType watch.EventType, obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	s.podLock.Lock()
+	defer s.podLock.Unlock()
+	for sub := range s.podSubscribers {
+		if !sub.send(eventType, pod) {
+			delete(s.podSubscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
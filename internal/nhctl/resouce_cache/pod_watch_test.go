@@ -0,0 +1,83 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package resouce_cache
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodSubscriberSend(t *testing.T) {
+	sub := &podSubscriber{namespace: "nocalhost", ch: make(chan *PodEvent, 10), sent: map[types.UID]string{}}
+	pod := func(ns, rv string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ratings-0", Namespace: ns, UID: "uid", ResourceVersion: rv}}
+	}
+
+	sub.send(watch.Added, pod("default", "1"))
+	sub.send(watch.Modified, pod("nocalhost", "1"))
+	sub.send(watch.Added, pod("nocalhost", "1"))
+	sub.send(watch.Modified, pod("nocalhost", "2"))
+	sub.send(watch.Deleted, pod("nocalhost", "2"))
+	sub.send(watch.Deleted, pod("nocalhost", "2"))
+	close(sub.ch)
+
+	var got []watch.EventType
+	for e := range sub.ch {
+		got = append(got, e.Type)
+	}
+	expected := []watch.EventType{watch.Added, watch.Modified, watch.Deleted}
+	if len(got) != len(expected) {
+		t.Fatalf("expect %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expect %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestClosePodSubscribers(t *testing.T) {
+	s := &Searcher{informerFactory: informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)}
+	events, cancel := s.SubscribePods("nocalhost")
+	defer cancel()
+	if !s.hasPodSubscribers() {
+		t.Fatal("expect pods to be watched")
+	}
+
+	s.closePodSubscribers()
+	if _, ok := <-events; ok {
+		t.Fatal("expect events to be closed after the searcher stopped")
+	}
+	if s.hasPodSubscribers() {
+		t.Fatal("expect no pods to be watched after the searcher stopped")
+	}
+	events, _ = s.SubscribePods("nocalhost")
+	if _, ok := <-events; ok {
+		t.Fatal("expect events of a stopped searcher to be closed")
+	}
+}
+
+func TestPodSubscriberSendFull(t *testing.T) {
+	sub := &podSubscriber{ch: make(chan *PodEvent, 1), sent: map[types.UID]string{}}
+	pod := func(uid types.UID) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: string(uid), UID: uid, ResourceVersion: "1"}}
+	}
+	if !sub.send(watch.Added, pod("a")) {
+		t.Fatal("expect the first event to be sent")
+	}
+	if sub.send(watch.Added, pod("b")) {
+		t.Fatal("expect the subscriber to be too slow while the channel is full")
+	}
+	if _, ok := sub.sent["b"]; ok {
+		t.Fatal("expect the pod not sent to be not recorded")
+	}
+}
@@ -46,6 +46,8 @@ var clusterMapLock sync.Mutex
 
 type Searcher struct {
 	kubeconfig      []byte
+	namespace       string
+	clusterScope    bool
 	informerFactory informers.SharedInformerFactory
 	// [string]*meta.RESTMapping
 	supportSchema *sync.Map
@@ -53,6 +55,10 @@ type Searcher struct {
 	stopChannel   chan struct{}
 	// last used this searcher, for release informer resource
 	lastUsedTime time.Time
+
+	podLock        sync.Mutex
+	podSubscribers map[*podSubscriber]struct{}
+	podStopped     bool
 }
 
 // getSupportedSchema return restMapping of each resource, [string]*meta.RESTMapping
@@ -158,7 +164,8 @@ func initSearcher(kubeconfigBytes []byte, namespace string) (*Searcher, error) {
 
 	var informerFactory informers.SharedInformerFactory
 
-	if isClusterAdmin(clientset) {
+	clusterScope := isClusterAdmin(clientset)
+	if clusterScope {
 		informerFactory = informers.NewSharedInformerFactory(clientset, time.Second*5)
 		clusterMapLock.Lock()
 		clusterMap[string(kubeconfigBytes)] = true
@@ -229,6 +236,8 @@ func initSearcher(kubeconfigBytes []byte, namespace string) (*Searcher, error) {
 
 	newSearcher := &Searcher{
 		kubeconfig:      kubeconfigBytes,
+		namespace:       namespace,
+		clusterScope:    clusterScope,
 		informerFactory: informerFactory,
 		supportSchema:   restMappingList,
 		mapper:          mapper,
@@ -244,6 +253,7 @@ func (s *Searcher) Start() {
 
 // Stop to stop the searcher
 func (s *Searcher) Stop() {
+	s.closePodSubscribers()
 	length := 0
 	s.supportSchema.Range(func(key, value interface{}) bool {
 		length++
@@ -650,7 +660,8 @@ func init() {
 							if get, found := searchMap.Get(key); found && get != nil {
 								if s, ok := get.(*Searcher); ok && s != nil {
 									t := time.Time{}
-									if s.lastUsedTime != t && time.Now().Sub(s.lastUsedTime).Hours() >= 24 {
+									if s.lastUsedTime != t && time.Now().Sub(s.lastUsedTime).Hours() >= 24 &&
+										!s.hasPodSubscribers() {
 										go func() { s.Stop() }()
 										searchMap.Remove(key)
 									}
@@ -0,0 +1,83 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package resouce_cache
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// SearcherInfo describes the informers of a cached Searcher
+type SearcherInfo struct {
+	Server       string
+	Namespace    string
+	ClusterScope bool
+	// informers started and how many of them have synced
+	Informers       int
+	SyncedInformers int
+	PodSubscribers  int
+	LastUsedTime    time.Time
+}
+
+// ListSearchers returns informers of all searchers cached in LRU
+func ListSearchers() []*SearcherInfo {
+	lock.Lock()
+	searchers := make([]*Searcher, 0, searchMap.Len())
+	for _, key := range searchMap.Keys() {
+		if get, found := searchMap.Peek(key); found && get != nil {
+			if s, ok := get.(*Searcher); ok && s != nil {
+				searchers = append(searchers, s)
+			}
+		}
+	}
+	lock.Unlock()
+
+	result := make([]*SearcherInfo, 0, len(searchers))
+	for _, s := range searchers {
+		result = append(result, s.info())
+	}
+	sort.Slice(
+		result, func(i, j int) bool {
+			if result[i].Server != result[j].Server {
+				return result[i].Server < result[j].Server
+			}
+			return result[i].Namespace < result[j].Namespace
+		},
+	)
+	return result
+}
+
+func (s *Searcher) info() *SearcherInfo {
+	info := &SearcherInfo{
+		Namespace:    s.namespace,
+		ClusterScope: s.clusterScope,
+		LastUsedTime: s.lastUsedTime,
+	}
+	if config, err := clientcmd.Load(s.kubeconfig); err == nil {
+		if context, ok := config.Contexts[config.CurrentContext]; ok {
+			if cluster, ok := config.Clusters[context.Cluster]; ok {
+				info.Server = cluster.Server
+			}
+		}
+	}
+
+	// a closed channel makes WaitForCacheSync returning the current state immediately
+	stopCh := make(chan struct{})
+	close(stopCh)
+	for _, synced := range s.informerFactory.WaitForCacheSync(stopCh) {
+		info.Informers++
+		if synced {
+			info.SyncedInformers++
+		}
+	}
+
+	s.podLock.Lock()
+	info.PodSubscribers = len(s.podSubscribers)
+	s.podLock.Unlock()
+	return info
+}
@@ -8,8 +8,10 @@ package ports
 import (
 	"fmt"
 	"github.com/pkg/errors"
+	"io"
 	"math/rand"
 	"net"
+	"strconv"
 	"time"
 
 	"nocalhost/pkg/nhctl/log"
@@ -74,3 +76,38 @@ func IsTCP4PortAvailable(iface string, port int) bool {
 	defer listener.Close()
 	return true
 }
+
+// maxNextPortAttempts is how many ports are tried while looking for the next free port
+const maxNextPortAttempts = 100
+
+// CheckPortAvailable returns an error if the tcp or udp port can not be listened on address
+func CheckPortAvailable(protocol, address string, port int) error {
+	hostPort := net.JoinHostPort(address, strconv.Itoa(port))
+	var (
+		l   io.Closer
+		err error
+	)
+	if protocol == "udp" {
+		l, err = net.ListenPacket("udp", hostPort)
+	} else {
+		l, err = net.Listen("tcp", hostPort)
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("port %d is unavailable", port))
+	}
+	return l.Close()
+}
+
+// NextAvailablePort returns the first port available on address starting from port,
+// ports excluded are skipped even if they are available
+func NextAvailablePort(protocol, address string, port int, excluded func(int) bool) (int, error) {
+	for p := port; p < port+maxNextPortAttempts && p <= 65535; p++ {
+		if excluded != nil && excluded(p) {
+			continue
+		}
+		if CheckPortAvailable(protocol, address, p) == nil {
+			return p, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("no free port found from %d on %s", port, address))
+}
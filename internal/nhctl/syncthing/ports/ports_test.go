@@ -0,0 +1,40 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package ports
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNextAvailablePort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	taken := l.Addr().(*net.TCPAddr).Port
+
+	if err = CheckPortAvailable("tcp", "127.0.0.1", taken); err == nil {
+		t.Fatalf("expect port %d to be unavailable", taken)
+	}
+	port, err := NextAvailablePort("tcp", "127.0.0.1", taken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port <= taken {
+		t.Fatalf("expect a port after %d, got %d", taken, port)
+	}
+
+	// excluded ports are skipped even if they are free
+	next, err := NextAvailablePort("tcp", "127.0.0.1", port, func(p int) bool { return p == port })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next <= port {
+		t.Fatalf("expect a port after %d, got %d", port, next)
+	}
+}
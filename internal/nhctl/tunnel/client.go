@@ -0,0 +1,119 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// Client runs on the developer's machine, it dials the proxy with DialTunnel
+// and connects every intercepted connection to Targets[port], port is the
+// port which the proxy accepted the connection on
+type Client struct {
+	DialTunnel func() (net.Conn, error)
+	Targets    map[int]string
+	// OnConnected is called every time the control connection is established
+	OnConnected func()
+}
+
+// Run keeps the control connection alive until ctx is done
+func (c *Client) Run(ctx context.Context) error {
+	backoff := minReconnectBackoff
+	for {
+		start := time.Now()
+		err := c.serve(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(start) > maxReconnectBackoff {
+			backoff = minReconnectBackoff
+		}
+		log.WarnE(err, "Tunnel disconnected, reconnecting in "+backoff.String())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+func (c *Client) serve(ctx context.Context) error {
+	control, err := c.DialTunnel()
+	if err != nil {
+		return err
+	}
+	defer control.Close()
+	go func() {
+		<-ctx.Done()
+		_ = control.Close()
+	}()
+
+	if err = writeLine(control, cmdControl); err != nil {
+		return err
+	}
+	if c.OnConnected != nil {
+		c.OnConnected()
+	}
+
+	reader := bufio.NewReader(control)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		line = strings.TrimSpace(line)
+		if line == cmdPing {
+			continue
+		}
+		id, port, err := parseConnect(line)
+		if err != nil {
+			return err
+		}
+		go c.handleConnect(id, port)
+	}
+}
+
+func (c *Client) handleConnect(id uint64, port int) {
+	target, ok := c.Targets[port]
+	if !ok {
+		log.Warnf("No local target for intercepted port %d", port)
+		return
+	}
+
+	remote, err := c.DialTunnel()
+	if err != nil {
+		log.WarnE(err, "Failed to open tunnel data connection")
+		return
+	}
+	if err = writeLine(remote, "%s %d", cmdData, id); err != nil {
+		_ = remote.Close()
+		return
+	}
+
+	local, err := net.DialTimeout("tcp", target, dataConnTimeout)
+	if err != nil {
+		// closing the data connection makes the proxy close the inbound connection as well
+		log.WarnE(errors.Wrap(err, ""), "Failed to connect local target "+target)
+		_ = remote.Close()
+		return
+	}
+	pipe(local, remote)
+}
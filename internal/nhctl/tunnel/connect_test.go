@@ -0,0 +1,167 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/proxy"
+)
+
+// startConnect starts a DialServer and a ConnectProxy in front of it, returns address of the proxy
+func startConnect(t *testing.T, ctx context.Context) (string, *RemoteDialer) {
+	dialPort := freePort(t)
+	server := NewDialServer(dialPort, 0)
+	server.Address = "127.0.0.1"
+	go func() { _ = server.Serve(ctx) }()
+
+	dialer := &RemoteDialer{
+		DialTunnel: func() (net.Conn, error) {
+			return net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(dialPort)))
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = (&ConnectProxy{Dialer: dialer}).Serve(ctx, l) }()
+
+	// wait for the dial server
+	for i := 0; i < 50; i++ {
+		if conn, err := dialer.DialTunnel(); err == nil {
+			_ = conn.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return l.Addr().String(), dialer
+}
+
+func TestConnectProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello from cluster"))
+	}))
+	defer backend.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proxyAddress, _ := startConnect(t, ctx)
+
+	socks, err := proxy.SOCKS5("tcp", proxyAddress, nil, proxy.Direct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyUrl, _ := url.Parse("http://" + proxyAddress)
+	clients := map[string]*http.Client{
+		"socks5": {Transport: &http.Transport{Dial: socks.Dial}},
+		"http":   {Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}},
+	}
+	for name, client := range clients {
+		resp, err := client.Get(backend.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != "hello from cluster" {
+			t.Fatalf("%s: unexpected body %q", name, body)
+		}
+	}
+
+	// unreachable address is reported as an error by the proxy
+	if _, err = socks.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(freePort(t)))); err == nil {
+		t.Fatal("expect error while dialing a closed port")
+	}
+}
+
+func TestDNSAnswer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, dialer := startConnect(t, ctx)
+	server := &DNSServer{Resolver: dialer}
+
+	query := func(name string, qtype dnsmessage.Type) dnsmessage.Message {
+		msg := dnsmessage.Message{
+			Header: dnsmessage.Header{ID: 7, RecursionDesired: true},
+			Questions: []dnsmessage.Question{
+				{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET},
+			},
+		}
+		packed, err := msg.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, err := server.answer(packed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result dnsmessage.Message
+		if err = result.Unpack(reply); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	reply := query("localhost.", dnsmessage.TypeA)
+	if reply.ID != 7 || !reply.Response || reply.RCode != dnsmessage.RCodeSuccess {
+		t.Fatalf("unexpected reply header %+v", reply.Header)
+	}
+	found := false
+	for _, answer := range reply.Answers {
+		if a, ok := answer.Body.(*dnsmessage.AResource); ok && net.IP(a.A[:]).IsLoopback() {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expect loopback address in answers %v", reply.Answers)
+	}
+
+	reply = query("nocalhost-not-exist.invalid.", dnsmessage.TypeA)
+	if reply.RCode != dnsmessage.RCodeNameError {
+		t.Fatalf("expect NXDOMAIN, got %v", reply.RCode)
+	}
+}
+
+func TestDialServerKeepAlive(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialPort := freePort(t)
+	server := NewDialServer(dialPort, 1500*time.Millisecond)
+	server.Address = "127.0.0.1"
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx) }()
+
+	dialer := &RemoteDialer{
+		DialTunnel: func() (net.Conn, error) {
+			return net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(dialPort)))
+		},
+	}
+	keepAliveCtx, stopKeepAlive := context.WithCancel(ctx)
+	go dialer.KeepAlive(keepAliveCtx, 500*time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("dial server should not exit by idle timeout while keeping alive")
+	case <-time.After(4 * time.Second):
+	}
+
+	stopKeepAlive()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dial server should exit by idle timeout after keepalive stops")
+	}
+}
@@ -0,0 +1,232 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"nocalhost/pkg/nhctl/log"
+)
+
+// A connection to DialServer starts with one of the requests, and is piped
+// to the dialed connection if it's a DIAL request:
+//
+//	local  -> helper: DIAL <host:port> | RESOLVE <name>
+//	helper -> local:  OK [ip,ip...] | ERR <message>
+const (
+	DefaultDialPort = 10801
+
+	cmdDial    = "DIAL"
+	cmdResolve = "RESOLVE"
+	replyOK    = "OK"
+	replyErr   = "ERR"
+
+	dialTimeout = 10 * time.Second
+)
+
+// DialServer runs inside the cluster, it dials or resolves addresses on behalf of
+// the local side, so cluster dns names are resolved by the dns of the cluster
+type DialServer struct {
+	// Address defaults to loopback, the local side connects through port-forward
+	Address string
+	Port    int
+	// Serve returns after no connection is active for IdleTimeout, zero means never
+	IdleTimeout time.Duration
+
+	lock         sync.Mutex
+	active       int
+	lastActivity time.Time
+}
+
+func NewDialServer(port int, idleTimeout time.Duration) *DialServer {
+	return &DialServer{Address: loopbackAddress, Port: port, IdleTimeout: idleTimeout}
+}
+
+// Serve blocks until ctx is done, the listener fails or idle timeout
+func (s *DialServer) Serve(ctx context.Context) error {
+	l, err := net.Listen("tcp", net.JoinHostPort(s.Address, strconv.Itoa(s.Port)))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer l.Close()
+	log.Infof("Waiting for dial requests on port %d", s.Port)
+
+	s.touch(0)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				errCh <- errors.Wrap(err, "")
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err = <-errCh:
+			return err
+		case <-ticker.C:
+			if s.idle() {
+				log.Infof("No connection for %s, exiting", s.IdleTimeout)
+				return nil
+			}
+		}
+	}
+}
+
+func (s *DialServer) touch(delta int) {
+	s.lock.Lock()
+	s.active += delta
+	s.lastActivity = time.Now()
+	s.lock.Unlock()
+}
+
+func (s *DialServer) idle() bool {
+	if s.IdleTimeout <= 0 {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.active == 0 && time.Since(s.lastActivity) > s.IdleTimeout
+}
+
+func (s *DialServer) handle(conn net.Conn) {
+	s.touch(1)
+	defer s.touch(-1)
+
+	_ = conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	line, err := readLine(conn)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 2 && fields[0] == cmdDial:
+		target, err := net.DialTimeout("tcp", fields[1], dialTimeout)
+		if err != nil {
+			_ = writeLine(conn, "%s %s", replyErr, oneLine(err))
+			_ = conn.Close()
+			return
+		}
+		if err = writeLine(conn, replyOK); err != nil {
+			_ = target.Close()
+			_ = conn.Close()
+			return
+		}
+		pipe(conn, target)
+	case len(fields) == 2 && fields[0] == cmdResolve:
+		ips, err := net.DefaultResolver.LookupIPAddr(context.Background(), fields[1])
+		if err != nil {
+			_ = writeLine(conn, "%s %s", replyErr, oneLine(err))
+		} else {
+			addresses := make([]string, 0, len(ips))
+			for _, ip := range ips {
+				addresses = append(addresses, ip.IP.String())
+			}
+			_ = writeLine(conn, "%s %s", replyOK, strings.Join(addresses, ","))
+		}
+		_ = conn.Close()
+	default:
+		_ = writeLine(conn, "%s unknown request", replyErr)
+		_ = conn.Close()
+	}
+}
+
+func oneLine(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", " ")
+}
+
+// RemoteDialer is the local side of DialServer, DialTunnel opens a new connection to
+// the DialServer, usually through a port-forward
+type RemoteDialer struct {
+	DialTunnel func() (net.Conn, error)
+}
+
+// Dial connects to address from inside the cluster
+func (d *RemoteDialer) Dial(address string) (net.Conn, error) {
+	conn, err := d.DialTunnel()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = d.request(conn, cmdDial, address); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Resolve looks up the name by the dns of cluster
+func (d *RemoteDialer) Resolve(name string) ([]net.IP, error) {
+	conn, err := d.DialTunnel()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	reply, err := d.request(conn, cmdResolve, name)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0)
+	for _, address := range strings.Split(reply, ",") {
+		if ip := net.ParseIP(address); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// KeepAlive sends a request to the dial server every interval until ctx is done,
+// so that the dial server does not exit by idle timeout
+func (d *RemoteDialer) KeepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.Resolve("localhost"); err != nil {
+				log.Debugf("Keepalive of dial server failed: %v", err)
+			}
+		}
+	}
+}
+
+func (d *RemoteDialer) request(conn net.Conn, cmd, arg string) (string, error) {
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout + 5*time.Second))
+	defer conn.SetDeadline(time.Time{})
+	if err := writeLine(conn, "%s %s", cmd, arg); err != nil {
+		return "", err
+	}
+	reply, err := readLine(conn)
+	if err != nil {
+		return "", err
+	}
+	if reply == replyOK {
+		return "", nil
+	}
+	if strings.HasPrefix(reply, replyOK+" ") {
+		return strings.TrimPrefix(reply, replyOK+" "), nil
+	}
+	return "", errors.New(fmt.Sprintf("%s %s: %s", strings.ToLower(cmd), arg, strings.TrimPrefix(reply, replyErr+" ")))
+}
@@ -0,0 +1,104 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/dns/dnsmessage"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const dnsTTL = 5
+
+// DNSServer answers A and AAAA queries over udp by resolving names inside the cluster
+type DNSServer struct {
+	Address  string
+	Resolver *RemoteDialer
+}
+
+// Serve blocks until ctx is done or the listener fails
+func (s *DNSServer) Serve(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", s.Address)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "")
+		}
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		go func() {
+			reply, err := s.answer(query)
+			if err != nil {
+				log.Debugf("Failed to answer dns query: %v", err)
+				return
+			}
+			_, _ = conn.WriteTo(reply, addr)
+		}()
+	}
+}
+
+func (s *DNSServer) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	reply := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 header.ID,
+			Response:           true,
+			RecursionDesired:   header.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: []dnsmessage.Question{question},
+	}
+	// other types are answered with no records
+	if question.Type != dnsmessage.TypeA && question.Type != dnsmessage.TypeAAAA {
+		return reply.Pack()
+	}
+
+	ips, err := s.Resolver.Resolve(strings.TrimSuffix(question.Name.String(), "."))
+	if err != nil {
+		reply.RCode = dnsmessage.RCodeNameError
+		return reply.Pack()
+	}
+	resourceHeader := dnsmessage.ResourceHeader{
+		Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: dnsTTL,
+	}
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil && question.Type == dnsmessage.TypeA {
+			resource := &dnsmessage.AResource{}
+			copy(resource.A[:], ip4)
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: resourceHeader, Body: resource})
+		} else if ip4 == nil && question.Type == dnsmessage.TypeAAAA {
+			resource := &dnsmessage.AAAAResource{}
+			copy(resource.AAAA[:], ip.To16())
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: resourceHeader, Body: resource})
+		}
+	}
+	return reply.Pack()
+}
@@ -0,0 +1,212 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/pkg/nhctl/clientgoutils"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	helperReadyTimeout = 3 * time.Minute
+	// connect helper exits after idle for a while, in case it's not deleted by nhctl
+	connectHelperIdleTimeout = 10 * time.Minute
+	// ConnectHelperKeepAlive is how often `nhctl connect` keeps the helper from exiting by idle timeout
+	ConnectHelperKeepAlive = connectHelperIdleTimeout / 4
+
+	udpRelayContainerName = "nocalhost-udp-relay"
+)
+
+// HelperPod returns a pod running `nhctl tunnel-proxy` with args, it's ready while probePort of loopback is listening
+func HelperPod(name string, labels, annotations map[string]string, args []string,
+	ports []corev1.ContainerPort, probePort int) *corev1.Pod {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[_const.AppManagedByLabel] = _const.AppManagedByNocalhost
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:            "tunnel",
+					Image:           _const.DefaultTunnelImage,
+					ImagePullPolicy: _const.DefaultSidecarImagePullPolicy,
+					Args:            args,
+					Ports:           ports,
+					ReadinessProbe: &corev1.Probe{
+						Handler: corev1.Handler{
+							Exec: &corev1.ExecAction{
+								Command: []string{
+									"/usr/local/bin/nhctl", "tunnel-proxy", "--probe-port", strconv.Itoa(probePort),
+								},
+							},
+						},
+						PeriodSeconds: 2,
+					},
+				},
+			},
+		},
+	}
+}
+
+// StartConnectHelper creates a helper pod dialing connections for `nhctl connect`, and
+// cleans up helpers exited before. Returns name of the pod
+func StartConnectHelper(client *clientgoutils.ClientGoUtils) (string, error) {
+	pods, err := client.ListPodsByLabels(map[string]string{_const.ConnectLabel: "true"})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			log.Debugf("Deleting exited connect helper %s", pod.Name)
+			_ = client.DeletePodByName(pod.Name, 0)
+		}
+	}
+
+	pod := HelperPod(
+		"", map[string]string{_const.ConnectLabel: "true"}, nil,
+		[]string{
+			"--dial-port", strconv.Itoa(DefaultDialPort),
+			"--idle-timeout", connectHelperIdleTimeout.String(),
+		},
+		[]corev1.ContainerPort{{Name: "dial", ContainerPort: DefaultDialPort}},
+		DefaultDialPort,
+	)
+	pod.GenerateName = "nocalhost-connect-"
+	pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	created, err := client.CreatePod(pod)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Waiting for connect helper %s to be ready", created.Name)
+	if err = client.WaitPodReady(created.Name, helperReadyTimeout); err != nil {
+		_ = client.DeletePodByName(created.Name, 0)
+		return "", errors.Wrap(err, "connect helper is not ready")
+	}
+	return created.Name, nil
+}
+
+// IsHelperTerminated returns true if the helper pod exited, is being deleted or not found,
+// which never serves again since it's not restarted
+func IsHelperTerminated(client *clientgoutils.ClientGoUtils, podName string) bool {
+	pod, err := client.GetPod(podName)
+	if err != nil {
+		return k8serrors.IsNotFound(errors.Cause(err))
+	}
+	return pod.DeletionTimestamp != nil ||
+		pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// UDPRelayEndpoint is where the udp relay of a pod listens on, and the token it requires
+type UDPRelayEndpoint struct {
+	Port  int
+	Token string
+}
+
+// EnsureUDPRelay makes sure an udp relay of udpPort is running in the network namespace of the pod,
+// an ephemeral container is added to the pod if there is none
+func EnsureUDPRelay(client *clientgoutils.ClientGoUtils, podName string, udpPort int) (*UDPRelayEndpoint, error) {
+	pod, err := client.GetPod(podName)
+	if err != nil {
+		return nil, err
+	}
+	terminated := map[string]bool{}
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		terminated[status.Name] = status.State.Terminated != nil
+	}
+	relays := 0
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if !strings.HasPrefix(ec.Name, udpRelayContainerName) {
+			continue
+		}
+		relays++
+		endpoint, udpPorts := parseUDPRelayArgs(ec.Args)
+		if terminated[ec.Name] || endpoint.Token == "" || !containsPort(udpPorts, udpPort) {
+			continue
+		}
+		// the relay may be still waiting for its image
+		if err = client.WaitEphemeralContainerRunning(podName, ec.Name, helperReadyTimeout); err != nil {
+			return nil, err
+		}
+		return endpoint, nil
+	}
+
+	// ephemeral containers can not be removed or restarted, add a new one if the previous one exited.
+	// Relays share the network namespace, so every relay listens on its own port
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := &UDPRelayEndpoint{Port: DefaultUDPRelayPort + relays, Token: token}
+	name := udpRelayContainerName
+	if relays > 0 {
+		name = fmt.Sprintf("%s-%d", udpRelayContainerName, relays)
+	}
+	log.Infof("Adding udp relay %s to pod %s", name, podName)
+	if err = client.AddEphemeralContainer(
+		podName, corev1.EphemeralContainer{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+				Name:            name,
+				Image:           _const.DefaultTunnelImage,
+				ImagePullPolicy: _const.DefaultSidecarImagePullPolicy,
+				Args:            udpRelayArgs(endpoint, udpPort),
+			},
+		},
+	); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func udpRelayArgs(endpoint *UDPRelayEndpoint, udpPort int) []string {
+	return []string{
+		"--udp-relay-port", strconv.Itoa(endpoint.Port),
+		"--udp-ports", strconv.Itoa(udpPort),
+		"--token", endpoint.Token,
+	}
+}
+
+// parseUDPRelayArgs parses args generated by udpRelayArgs
+func parseUDPRelayArgs(args []string) (*UDPRelayEndpoint, []int) {
+	endpoint := &UDPRelayEndpoint{}
+	udpPorts := make([]int, 0)
+	for i := 0; i+1 < len(args); i += 2 {
+		switch args[i] {
+		case "--udp-relay-port":
+			endpoint.Port, _ = strconv.Atoi(args[i+1])
+		case "--udp-ports":
+			for _, p := range strings.Split(args[i+1], ",") {
+				if port, err := strconv.Atoi(p); err == nil {
+					udpPorts = append(udpPorts, port)
+				}
+			}
+		case "--token":
+			endpoint.Token = args[i+1]
+		}
+	}
+	return endpoint, udpPorts
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+// Package tunnel routes connections accepted by an in-cluster proxy back to
+// processes on the developer's machine. The local side keeps a control
+// connection to the proxy (usually over a port-forward), the proxy asks for a
+// data connection on the control connection for every connection it accepts,
+// and the two connections are piped together:
+//
+//	local  -> proxy: CONTROL
+//	proxy  -> local: CONNECT <id> <port>
+//	local  -> proxy: DATA <id>     (new connection)
+package tunnel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTunnelPort = 10800
+
+	// servers reached by port-forward only listen on loopback of the pod, so
+	// they can't be accessed by other pods of the cluster
+	loopbackAddress = "127.0.0.1"
+
+	cmdControl = "CONTROL"
+	cmdData    = "DATA"
+	cmdConnect = "CONNECT"
+	cmdPing    = "PING"
+
+	maxLineLength = 4096
+)
+
+func writeLine(w io.Writer, format string, a ...interface{}) error {
+	_, err := fmt.Fprintf(w, format+"\n", a...)
+	return errors.Wrap(err, "")
+}
+
+// readLine reads a single line byte by byte, so nothing after the line is
+// consumed and the connection can be used as a raw stream afterwards
+func readLine(r io.Reader) (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for sb.Len() < maxLineLength {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", errors.Wrap(err, "")
+		}
+		if buf[0] == '\n' {
+			return strings.TrimSpace(sb.String()), nil
+		}
+		sb.WriteByte(buf[0])
+	}
+	return "", errors.New("tunnel handshake line too long")
+}
+
+func parseConnect(line string) (uint64, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != cmdConnect {
+		return 0, 0, errors.New(fmt.Sprintf("unexpected tunnel command %q", line))
+	}
+	id, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "")
+	}
+	port, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "")
+	}
+	return id, port, nil
+}
+
+// newToken returns a random token authenticating the local side
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Probe returns nil if the port of loopback is listening, it's the readiness probe of helper pods
+// since their servers don't listen on the pod ip
+func Probe(port int) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(loopbackAddress, strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	return conn.Close()
+}
+
+// pipe copies data between the two connections until either side is closed
+func pipe(a, b net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		_ = a.Close()
+		_ = b.Close()
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(a, b)
+		once.Do(closeBoth)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(b, a)
+		once.Do(closeBoth)
+	}()
+	wg.Wait()
+}
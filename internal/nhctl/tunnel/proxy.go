@@ -0,0 +1,218 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	dataConnTimeout = 10 * time.Second
+	pingInterval    = 15 * time.Second
+)
+
+// Proxy runs inside the cluster, it accepts connections on Ports and hands
+// them over to the local side connected to TunnelPort
+type Proxy struct {
+	Address string
+	// TunnelAddress is the address TunnelPort listens on, defaults to loopback
+	// since the local side connects through port-forward
+	TunnelAddress string
+	TunnelPort    int
+	Ports         []int
+
+	lock      sync.Mutex
+	writeLock sync.Mutex
+	control   net.Conn
+	nextID    uint64
+	pending   map[uint64]chan net.Conn
+}
+
+func NewProxy(tunnelPort int, ports []int) *Proxy {
+	return &Proxy{
+		TunnelAddress: loopbackAddress, TunnelPort: tunnelPort, Ports: ports, pending: map[uint64]chan net.Conn{},
+	}
+}
+
+// Serve blocks until ctx is done or one of the listeners fails
+func (p *Proxy) Serve(ctx context.Context) error {
+	listeners := make([]net.Listener, 0, len(p.Ports)+1)
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+
+	tunnelListener, err := net.Listen("tcp", net.JoinHostPort(p.TunnelAddress, strconv.Itoa(p.TunnelPort)))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	listeners = append(listeners, tunnelListener)
+
+	errCh := make(chan error, len(p.Ports)+1)
+	go func() { errCh <- p.acceptLoop(tunnelListener, p.handleTunnelConn) }()
+
+	for _, port := range p.Ports {
+		l, err := net.Listen("tcp", net.JoinHostPort(p.Address, strconv.Itoa(port)))
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		listeners = append(listeners, l)
+		port := port
+		go func() {
+			errCh <- p.acceptLoop(l, func(conn net.Conn) { p.handleInbound(conn, port) })
+		}()
+		log.Infof("Intercepting port %d", port)
+	}
+	log.Infof("Waiting for tunnel on port %d", p.TunnelPort)
+
+	go p.keepAlive(ctx)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err = <-errCh:
+		return err
+	}
+}
+
+func (p *Proxy) acceptLoop(l net.Listener, handle func(net.Conn)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		go handle(conn)
+	}
+}
+
+func (p *Proxy) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.lock.Lock()
+			control := p.control
+			p.lock.Unlock()
+			if control != nil {
+				p.sendControl(control, cmdPing)
+			}
+		}
+	}
+}
+
+func (p *Proxy) handleTunnelConn(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(dataConnTimeout))
+	line, err := readLine(conn)
+	if err != nil {
+		// the readiness probe closes the connection without handshake
+		if errors.Cause(err) != io.EOF {
+			log.WarnE(err, "Failed to read tunnel handshake")
+		}
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 1 && fields[0] == cmdControl:
+		p.lock.Lock()
+		old := p.control
+		p.control = conn
+		p.lock.Unlock()
+		if old != nil {
+			_ = old.Close()
+		}
+		log.Info("Tunnel connected")
+		// control connection is write only for proxy, reading just detects disconnection
+		buf := make([]byte, 1)
+		for {
+			if _, err = conn.Read(buf); err != nil {
+				p.dropControl(conn)
+				log.Info("Tunnel disconnected")
+				return
+			}
+		}
+	case len(fields) == 2 && fields[0] == cmdData:
+		id, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			_ = conn.Close()
+			return
+		}
+		p.lock.Lock()
+		ch, ok := p.pending[id]
+		delete(p.pending, id)
+		p.lock.Unlock()
+		if !ok {
+			_ = conn.Close()
+			return
+		}
+		ch <- conn
+	default:
+		log.Warnf("Unexpected tunnel handshake %q", line)
+		_ = conn.Close()
+	}
+}
+
+func (p *Proxy) sendControl(control net.Conn, format string, a ...interface{}) {
+	p.writeLock.Lock()
+	err := writeLine(control, format, a...)
+	p.writeLock.Unlock()
+	if err != nil {
+		p.dropControl(control)
+	}
+}
+
+func (p *Proxy) dropControl(conn net.Conn) {
+	p.lock.Lock()
+	if p.control == conn {
+		p.control = nil
+	}
+	p.lock.Unlock()
+	_ = conn.Close()
+}
+
+func (p *Proxy) handleInbound(conn net.Conn, port int) {
+	p.lock.Lock()
+	control := p.control
+	if control == nil {
+		p.lock.Unlock()
+		log.Warnf("No tunnel connected, refusing connection from %s", conn.RemoteAddr())
+		_ = conn.Close()
+		return
+	}
+	p.nextID++
+	id := p.nextID
+	ch := make(chan net.Conn, 1)
+	p.pending[id] = ch
+	p.lock.Unlock()
+
+	p.sendControl(control, "%s %d %d", cmdConnect, id, port)
+
+	select {
+	case dataConn := <-ch:
+		pipe(conn, dataConn)
+	case <-time.After(dataConnTimeout):
+		p.lock.Lock()
+		delete(p.pending, id)
+		p.lock.Unlock()
+		log.Warnf("Timeout waiting for tunnel data connection of port %d", port)
+		_ = conn.Close()
+	}
+}
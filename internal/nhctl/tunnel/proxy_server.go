@@ -0,0 +1,192 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	socks5Version     = 0x05
+	socks5Connect     = 0x01
+	socks5AtypIPv4    = 0x01
+	socks5AtypDomain  = 0x03
+	socks5AtypIPv6    = 0x04
+	socks5Succeeded   = 0x00
+	socks5HostUnreach = 0x04
+	socks5CmdNotSupp  = 0x07
+)
+
+// ConnectProxy serves SOCKS5 and HTTP proxy on the same listener, the protocol is
+// detected by the first byte, connections are dialed from inside the cluster by Dialer
+type ConnectProxy struct {
+	Dialer *RemoteDialer
+}
+
+// Serve blocks until ctx is done or the listener fails
+func (p *ConnectProxy) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "")
+		}
+		go p.handle(conn)
+	}
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (p *ConnectProxy) handle(conn net.Conn) {
+	bc := &bufferedConn{Conn: conn, reader: bufio.NewReader(conn)}
+	first, err := bc.reader.Peek(1)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	if first[0] == socks5Version {
+		err = p.handleSocks5(bc)
+	} else {
+		err = p.handleHttp(bc)
+	}
+	if err != nil {
+		log.Debugf("Proxy connection from %s failed: %v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+	}
+}
+
+func (p *ConnectProxy) handleSocks5(conn *bufferedConn) error {
+	// greeting: VER NMETHODS METHODS, only no authentication is supported
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if _, err := conn.Write([]byte{socks5Version, 0x00}); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// request: VER CMD RSV ATYP DST.ADDR DST.PORT
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return errors.Wrap(err, "")
+	}
+	var host string
+	switch request[3] {
+	case socks5AtypIPv4, socks5AtypIPv6:
+		size := net.IPv4len
+		if request[3] == socks5AtypIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return errors.Wrap(err, "")
+		}
+		host = net.IP(ip).String()
+	case socks5AtypDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return errors.Wrap(err, "")
+		}
+		domain := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return errors.Wrap(err, "")
+		}
+		host = string(domain)
+	default:
+		return errors.New(fmt.Sprintf("unsupported socks5 address type %d", request[3]))
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return errors.Wrap(err, "")
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	if request[1] != socks5Connect {
+		_, _ = conn.Write(socks5Reply(socks5CmdNotSupp))
+		return errors.New(fmt.Sprintf("unsupported socks5 command %d", request[1]))
+	}
+	remote, err := p.Dialer.Dial(address)
+	if err != nil {
+		_, _ = conn.Write(socks5Reply(socks5HostUnreach))
+		return err
+	}
+	if _, err = conn.Write(socks5Reply(socks5Succeeded)); err != nil {
+		_ = remote.Close()
+		return errors.Wrap(err, "")
+	}
+	pipe(conn, remote)
+	return nil
+}
+
+func socks5Reply(status byte) []byte {
+	return []byte{socks5Version, status, 0x00, socks5AtypIPv4, 0, 0, 0, 0, 0, 0}
+}
+
+func (p *ConnectProxy) handleHttp(conn *bufferedConn) error {
+	req, err := http.ReadRequest(conn.reader)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	address := req.Host
+	if _, _, err = net.SplitHostPort(address); err != nil {
+		if req.Method == http.MethodConnect || req.URL.Scheme == "https" {
+			address = net.JoinHostPort(address, "443")
+		} else {
+			address = net.JoinHostPort(address, "80")
+		}
+	}
+
+	remote, err := p.Dialer.Dial(address)
+	if err != nil {
+		_, _ = fmt.Fprintf(conn, "HTTP/1.1 502 Bad Gateway\r\nConnection: close\r\n\r\n%s\n", err.Error())
+		return err
+	}
+
+	if req.Method == http.MethodConnect {
+		if _, err = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+			_ = remote.Close()
+			return errors.Wrap(err, "")
+		}
+	} else {
+		// the next request on this connection may go to another host, so close after one request
+		req.Header.Del("Proxy-Connection")
+		req.Header.Del("Proxy-Authorization")
+		req.Close = true
+		if err = req.Write(remote); err != nil {
+			_ = remote.Close()
+			return errors.Wrap(err, "")
+		}
+	}
+	pipe(conn, remote)
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestReadLine(t *testing.T) {
+	r := bytes.NewBufferString("DATA 12\nrest")
+	line, err := readLine(r)
+	if err != nil || line != "DATA 12" {
+		t.Fatalf("got %q, %v", line, err)
+	}
+	if r.String() != "rest" {
+		t.Fatalf("readLine consumed data after line: %q", r.String())
+	}
+}
+
+func TestParseConnect(t *testing.T) {
+	id, port, err := parseConnect("CONNECT 3 8080")
+	if err != nil || id != 3 || port != 8080 {
+		t.Fatalf("got %d %d %v", id, port, err)
+	}
+	if _, _, err = parseConnect("CONNECT x 8080"); err == nil {
+		t.Fatal("expect error for invalid id")
+	}
+	if _, _, err = parseConnect("HELLO"); err == nil {
+		t.Fatal("expect error for unknown command")
+	}
+}
+
+func TestTunnel(t *testing.T) {
+	// local echo server which plays the service running on the laptop
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tunnelPort, servicePort := freePort(t), freePort(t)
+	proxy := NewProxy(tunnelPort, []int{servicePort})
+	proxy.Address = "127.0.0.1"
+	go func() { _ = proxy.Serve(ctx) }()
+
+	connected := make(chan struct{}, 1)
+	client := &Client{
+		DialTunnel: func() (net.Conn, error) {
+			return net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(tunnelPort)))
+		},
+		Targets:     map[int]string{servicePort: echo.Addr().String()},
+		OnConnected: func() { connected <- struct{}{} },
+	}
+	go func() {
+		// proxy may not listen yet at the first dial
+		for ctx.Err() == nil {
+			_ = client.serve(ctx)
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tunnel not connected")
+	}
+	// wait for the proxy to register the control connection
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(servicePort)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := "hello " + strconv.Itoa(i) + "\n"
+		if _, err = conn.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		reply, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply != msg {
+			t.Fatalf("expect %q, got %q", msg, reply)
+		}
+		_ = conn.Close()
+	}
+}
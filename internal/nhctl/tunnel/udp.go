@@ -0,0 +1,312 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"nocalhost/pkg/nhctl/log"
+)
+
+// Datagrams are encapsulated over a stream connection, each client address of the
+// local udp port gets its own stream which starts with a handshake line, then
+// datagrams are framed with a 2 bytes big endian length in both directions.
+// Token is generated while the relay is added to the pod, and the port must be
+// one of the ports the relay is started for:
+//
+//	local -> relay: UDP <port> <token>
+//	both:           <length><datagram>...
+const (
+	DefaultUDPRelayPort = 10802
+
+	cmdUDP = "UDP"
+
+	maxDatagramSize       = 65535
+	defaultUDPIdleTimeout = 2 * time.Minute
+)
+
+func writeFrame(w io.Writer, payload []byte) error {
+	if len(payload) > maxDatagramSize {
+		return errors.New(fmt.Sprintf("datagram of %d bytes is too large", len(payload)))
+	}
+	frame := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(frame, uint16(len(payload)))
+	copy(frame[2:], payload)
+	_, err := w.Write(frame)
+	return errors.Wrap(err, "")
+}
+
+func readFrame(r io.Reader, buf []byte) ([]byte, error) {
+	if _, err := io.ReadFull(r, buf[:2]); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	size := int(binary.BigEndian.Uint16(buf[:2]))
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return buf[:size], nil
+}
+
+// UDPRelay runs in the network namespace of the target pod, it sends datagrams
+// received from streams to the udp port of localhost and streams the replies back
+type UDPRelay struct {
+	// Address defaults to loopback, the local side connects through port-forward
+	Address string
+	Port    int
+	// the address datagrams are sent to, defaults to 127.0.0.1
+	TargetHost string
+	// Token must be presented in the handshake, and only datagrams to UDPPorts are relayed
+	Token    string
+	UDPPorts []int
+}
+
+func NewUDPRelay(port int, token string, udpPorts []int) *UDPRelay {
+	return &UDPRelay{
+		Address: loopbackAddress, Port: port, TargetHost: "127.0.0.1", Token: token, UDPPorts: udpPorts,
+	}
+}
+
+// Serve blocks until ctx is done or the listener fails
+func (r *UDPRelay) Serve(ctx context.Context) error {
+	l, err := net.Listen("tcp", net.JoinHostPort(r.Address, strconv.Itoa(r.Port)))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	log.Infof("Relaying udp on port %d", r.Port)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "")
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *UDPRelay) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	line, err := readLine(conn)
+	if err != nil {
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != cmdUDP {
+		log.Warnf("Unexpected udp relay handshake from %s", conn.RemoteAddr())
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(fields[2]), []byte(r.Token)) != 1 {
+		log.Warnf("Invalid udp relay token from %s", conn.RemoteAddr())
+		return
+	}
+	if port, err := strconv.Atoi(fields[1]); err != nil || !containsPort(r.UDPPorts, port) {
+		log.Warnf("Udp port %s is not allowed to relay", fields[1])
+		return
+	}
+
+	target, err := net.Dial("udp", net.JoinHostPort(r.TargetHost, fields[1]))
+	if err != nil {
+		log.WarnE(errors.Wrap(err, ""), "Failed to dial udp port "+fields[1])
+		return
+	}
+	defer target.Close()
+
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, err := target.Read(buf)
+			if err != nil {
+				if isClosed(err) {
+					return
+				}
+				// ICMP port unreachable is reported as error of the connected udp socket, keep reading
+				continue
+			}
+			if err = writeFrame(conn, buf[:n]); err != nil {
+				_ = target.Close()
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		payload, err := readFrame(conn, buf)
+		if err != nil {
+			return
+		}
+		_, _ = target.Write(payload)
+	}
+}
+
+func isClosed(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// UDPForwarder listens on a local udp address and forwards datagrams to RemotePort
+// of the target pod through streams opened by DialTunnel
+type UDPForwarder struct {
+	LocalAddress string
+	RemotePort   int
+	Token        string
+	DialTunnel   func() (net.Conn, error)
+	// a stream is closed after no datagram for IdleTimeout
+	IdleTimeout time.Duration
+
+	lock     sync.Mutex
+	sessions map[string]*udpSession
+}
+
+type udpSession struct {
+	conn       net.Conn
+	lastActive time.Time
+}
+
+func NewUDPForwarder(
+	localAddress string, remotePort int, token string, dial func() (net.Conn, error),
+) *UDPForwarder {
+	return &UDPForwarder{
+		LocalAddress: localAddress,
+		RemotePort:   remotePort,
+		Token:        token,
+		DialTunnel:   dial,
+		IdleTimeout:  defaultUDPIdleTimeout,
+		sessions:     map[string]*udpSession{},
+	}
+}
+
+// Serve blocks until ctx is done or the local listener fails, readyCh is
+// closed once the local address is listening
+func (f *UDPForwarder) Serve(ctx context.Context, readyCh chan struct{}) error {
+	pc, err := net.ListenPacket("udp", f.LocalAddress)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("unable to listen on %s/udp", f.LocalAddress))
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		_ = pc.Close()
+		f.closeSessions(0)
+	}()
+	go func() {
+		ticker := time.NewTicker(time.Second * 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.closeSessions(f.IdleTimeout)
+			}
+		}
+	}()
+	if readyCh != nil {
+		close(readyCh)
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "")
+		}
+		session, err := f.session(pc, addr)
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Failed to forward datagram from %s", addr))
+			continue
+		}
+		if err = writeFrame(session.conn, buf[:n]); err != nil {
+			f.dropSession(addr.String(), session)
+		}
+	}
+}
+
+func (f *UDPForwarder) session(pc net.PacketConn, addr net.Addr) (*udpSession, error) {
+	key := addr.String()
+	f.lock.Lock()
+	session, ok := f.sessions[key]
+	if ok {
+		session.lastActive = time.Now()
+		f.lock.Unlock()
+		return session, nil
+	}
+	f.lock.Unlock()
+
+	conn, err := f.DialTunnel()
+	if err != nil {
+		return nil, err
+	}
+	if err = writeLine(conn, "%s %d %s", cmdUDP, f.RemotePort, f.Token); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	session = &udpSession{conn: conn, lastActive: time.Now()}
+	f.lock.Lock()
+	f.sessions[key] = session
+	f.lock.Unlock()
+
+	go func() {
+		defer f.dropSession(key, session)
+		buf := make([]byte, maxDatagramSize)
+		for {
+			payload, err := readFrame(conn, buf)
+			if err != nil {
+				return
+			}
+			if _, err = pc.WriteTo(payload, addr); err != nil {
+				return
+			}
+			f.lock.Lock()
+			session.lastActive = time.Now()
+			f.lock.Unlock()
+		}
+	}()
+	return session, nil
+}
+
+func (f *UDPForwarder) dropSession(key string, session *udpSession) {
+	f.lock.Lock()
+	if f.sessions[key] == session {
+		delete(f.sessions, key)
+	}
+	f.lock.Unlock()
+	_ = session.conn.Close()
+}
+
+// closeSessions closes sessions idle longer than idle, all sessions if idle is zero
+func (f *UDPForwarder) closeSessions(idle time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for key, session := range f.sessions {
+		if idle == 0 || time.Since(session.lastActive) > idle {
+			_ = session.conn.Close()
+			delete(f.sessions, key)
+		}
+	}
+}
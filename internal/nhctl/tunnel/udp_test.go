@@ -0,0 +1,147 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+	for _, payload := range [][]byte{[]byte("metric:1|c"), {}} {
+		if err := writeFrame(&buf, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := make([]byte, maxDatagramSize)
+	for _, expected := range []string{"metric:1|c", ""} {
+		payload, err := readFrame(&buf, read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(payload) != expected {
+			t.Fatalf("expect %q, got %q", expected, payload)
+		}
+	}
+	if err := writeFrame(&buf, make([]byte, maxDatagramSize+1)); err == nil {
+		t.Fatal("expect error for a too large datagram")
+	}
+}
+
+func TestUDPForward(t *testing.T) {
+	// udp echo server which plays the service in the pod
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteTo(append([]byte("echo "), buf[:n]...), addr)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	relayPort := freePort(t)
+	echoPort := echo.LocalAddr().(*net.UDPAddr).Port
+	relay := NewUDPRelay(relayPort, "token", []int{echoPort})
+	go func() { _ = relay.Serve(ctx) }()
+
+	localPort := freePort(t)
+	forwarder := NewUDPForwarder(
+		net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)), echoPort, "token",
+		func() (net.Conn, error) {
+			return net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(relayPort)))
+		},
+	)
+	readyCh := make(chan struct{})
+	go func() { _ = forwarder.Serve(ctx, readyCh) }()
+	<-readyCh
+	time.Sleep(100 * time.Millisecond)
+
+	// two clients get their own sessions
+	for i := 0; i < 2; i++ {
+		client, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 3; j++ {
+			msg := "datagram " + strconv.Itoa(i) + strconv.Itoa(j)
+			if _, err = client.Write([]byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+			_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+			buf := make([]byte, 1500)
+			n, err := client.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(buf[:n]) != "echo "+msg {
+				t.Fatalf("expect %q, got %q", "echo "+msg, buf[:n])
+			}
+		}
+		_ = client.Close()
+	}
+
+	forwarder.lock.Lock()
+	sessions := len(forwarder.sessions)
+	forwarder.lock.Unlock()
+	if sessions != 2 {
+		t.Fatalf("expect 2 sessions, got %d", sessions)
+	}
+	forwarder.closeSessions(0)
+}
+
+func TestUDPRelayHandshake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	relayPort := freePort(t)
+	relay := NewUDPRelay(relayPort, "token", []int{53})
+	go func() { _ = relay.Serve(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, handshake := range []string{"UDP 53", "UDP 53 invalid", "UDP 22 token"} {
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(relayPort)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = writeLine(conn, handshake); err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err = conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Fatalf("expect handshake %q to be rejected, got %v", handshake, err)
+		}
+		_ = conn.Close()
+	}
+}
+
+func TestParseUDPRelayArgs(t *testing.T) {
+	endpoint := &UDPRelayEndpoint{Port: 10803, Token: "token"}
+	parsed, udpPorts := parseUDPRelayArgs(udpRelayArgs(endpoint, 53))
+	if !reflect.DeepEqual(parsed, endpoint) || !reflect.DeepEqual(udpPorts, []int{53}) {
+		t.Fatalf("expect %v %v, got %v %v", endpoint, []int{53}, parsed, udpPorts)
+	}
+	// relays added before tokens are required
+	parsed, _ = parseUDPRelayArgs([]string{"--udp-relay-port", "10802"})
+	if parsed.Token != "" {
+		t.Fatalf("expect no token, got %q", parsed.Token)
+	}
+}
@@ -50,7 +50,7 @@ func Reverse(account *Account, sshEndpoint, remoteEndpoint, localEndpoint string
 	for {
 		remoteConn, err := listener.Accept()
 		if err != nil {
-			log.Error("error: %s", err)
+			log.Errorf("error: %s", err)
 			return err
 		}
 		localConn, err := net.Dial("tcp", localEndpoint)
@@ -66,13 +66,13 @@ func copyStream(remoteConn net.Conn, localConn net.Conn) {
 	cancel, cancelFunc := context.WithCancel(context.Background())
 	go func() {
 		if _, err := io.Copy(localConn, remoteConn); err != nil {
-			log.Error("error occurs while copy local -> remote: %s", err)
+			log.Errorf("error occurs while copy local -> remote: %s", err)
 		}
 		cancelFunc()
 	}()
 	go func() {
 		if _, err := io.Copy(remoteConn, localConn); err != nil {
-			log.Error("error occurs while copy remote -> local: %s", err)
+			log.Errorf("error occurs while copy remote -> local: %s", err)
 		}
 		cancelFunc()
 	}()
@@ -0,0 +1,44 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+*/
+
+package model
+
+import (
+	"time"
+
+	validator "github.com/go-playground/validator/v10"
+)
+
+// HubConfigModel is a version of a service config published to the hub,
+// list fields are stored as json arrays
+type HubConfigModel struct {
+	ID          uint64     `gorm:"primary_key;AUTO_INCREMENT;column:id" json:"id"`
+	Name        string     `gorm:"column:name;UNIQUE_INDEX:uidx_name_version;not null" json:"name"`
+	Version     string     `gorm:"column:version;UNIQUE_INDEX:uidx_name_version;not null" json:"version"`
+	Description string     `gorm:"column:description" json:"description"`
+	Keywords    string     `gorm:"column:keywords" json:"keywords"`
+	Maintainers string     `gorm:"column:maintainers" json:"maintainers"`
+	Images      string     `gorm:"column:images" json:"images"`
+	ServiceType string     `gorm:"column:service_type" json:"service_type"`
+	Digest      string     `gorm:"column:digest;not null" json:"digest"`
+	Signature   string     `gorm:"column:signature" json:"signature"`
+	KeyId       string     `gorm:"column:key_id" json:"key_id"`
+	Content     string     `gorm:"column:content;type:text;not null" json:"-"`
+	UserId      uint64     `gorm:"column:user_id;not null" json:"user_id"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"-"`
+	DeletedAt   *time.Time `gorm:"column:deleted_at" json:"-"`
+}
+
+// Validate the fields.
+func (u *HubConfigModel) Validate() error {
+	validate := validator.New()
+	return validate.Struct(u)
+}
+
+// TableName
+func (u *HubConfigModel) TableName() string {
+	return "hub_configs"
+}
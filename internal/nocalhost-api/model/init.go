@@ -77,6 +77,6 @@ func GetDB() *gorm.DB {
 func MigrateDB() {
 	DB.AutoMigrate(
 		&ApplicationModel{}, &ClusterModel{}, &ClusterUserModel{}, &PrePullModel{}, &UserBaseModel{},
-		&ApplicationUserModel{},
+		&ApplicationUserModel{}, &HubConfigModel{},
 	)
 }
@@ -0,0 +1,61 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+*/
+
+package hub_config
+
+import (
+	"context"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"nocalhost/internal/nocalhost-api/model"
+)
+
+type HubConfigRepo interface {
+	Create(ctx context.Context, config model.HubConfigModel) (model.HubConfigModel, error)
+	Get(ctx context.Context, name, version string) (model.HubConfigModel, error)
+	GetList(ctx context.Context) ([]*model.HubConfigModel, error)
+	Close()
+}
+
+type hubConfigRepo struct {
+	db *gorm.DB
+}
+
+func NewHubConfigRepo(db *gorm.DB) HubConfigRepo {
+	return &hubConfigRepo{
+		db: db,
+	}
+}
+
+func (repo *hubConfigRepo) Create(ctx context.Context, config model.HubConfigModel) (model.HubConfigModel, error) {
+	if err := repo.db.Create(&config).Error; err != nil {
+		return config, errors.Wrap(err, "[hub_config_repo] create hub config err")
+	}
+	return config, nil
+}
+
+func (repo *hubConfigRepo) Get(ctx context.Context, name, version string) (model.HubConfigModel, error) {
+	config := model.HubConfigModel{}
+	if err := repo.db.Where("name = ? and version = ?", name, version).First(&config).Error; err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// GetList lists all configs without their contents
+func (repo *hubConfigRepo) GetList(ctx context.Context) ([]*model.HubConfigModel, error) {
+	result := make([]*model.HubConfigModel, 0)
+	if err := repo.db.Select(
+		"id, name, version, description, keywords, maintainers, images, service_type, digest, signature, " +
+			"key_id, user_id, created_at",
+	).Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (repo *hubConfigRepo) Close() {
+	repo.db.Close()
+}
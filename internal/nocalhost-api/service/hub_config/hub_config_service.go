@@ -0,0 +1,122 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+*/
+
+package hub_config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"nocalhost/internal/nhctl/hub"
+	"nocalhost/internal/nocalhost-api/model"
+	"nocalhost/internal/nocalhost-api/repository/hub_config"
+)
+
+type HubConfigService interface {
+	Publish(ctx context.Context, entry *hub.Entry, content []byte, userId uint64) error
+	Index(ctx context.Context) (*hub.Index, error)
+	GetContent(ctx context.Context, name, version string) ([]byte, error)
+	Close()
+}
+
+type hubConfigService struct {
+	hubConfigRepo hub_config.HubConfigRepo
+}
+
+func NewHubConfigService() HubConfigService {
+	db := model.GetDB()
+	return &hubConfigService{hubConfigRepo: hub_config.NewHubConfigRepo(db)}
+}
+
+// Publish stores the config, its checksum is verified but its signature is verified by
+// consumers with keys they trust
+func (srv *hubConfigService) Publish(ctx context.Context, entry *hub.Entry, content []byte, userId uint64) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if digest := hub.Digest(content); digest != entry.Digest {
+		return errors.New(fmt.Sprintf("checksum mismatched, expected %s, got %s", entry.Digest, digest))
+	}
+	if _, err := hub.ParseSvcConfig(content); err != nil {
+		return err
+	}
+	if _, err := srv.hubConfigRepo.Get(ctx, entry.Name, entry.Version); err == nil {
+		return errors.New(fmt.Sprintf("%s already exists in hub", entry))
+	}
+
+	_, err := srv.hubConfigRepo.Create(
+		ctx, model.HubConfigModel{
+			Name:        entry.Name,
+			Version:     entry.Version,
+			Description: entry.Description,
+			Keywords:    marshalList(entry.Keywords),
+			Maintainers: marshalList(entry.Maintainers),
+			Images:      marshalList(entry.Images),
+			ServiceType: entry.ServiceType,
+			Digest:      entry.Digest,
+			Signature:   entry.Signature,
+			KeyId:       entry.KeyId,
+			Content:     string(content),
+			UserId:      userId,
+		},
+	)
+	return err
+}
+
+// Index generates the hub index of all configs
+func (srv *hubConfigService) Index(ctx context.Context) (*hub.Index, error) {
+	configs, err := srv.hubConfigRepo.GetList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := hub.NewIndex()
+	for _, c := range configs {
+		entry := &hub.Entry{
+			Name:        c.Name,
+			Version:     c.Version,
+			Description: c.Description,
+			Keywords:    unmarshalList(c.Keywords),
+			Maintainers: unmarshalList(c.Maintainers),
+			Images:      unmarshalList(c.Images),
+			ServiceType: c.ServiceType,
+			Digest:      c.Digest,
+			Signature:   c.Signature,
+			KeyId:       c.KeyId,
+			Url:         fmt.Sprintf("configs/%s/%s", c.Name, c.Version),
+			Created:     c.CreatedAt,
+		}
+		index.Entries[c.Name] = append(index.Entries[c.Name], entry)
+	}
+	index.SortEntries()
+	return index, nil
+}
+
+func (srv *hubConfigService) GetContent(ctx context.Context, name, version string) ([]byte, error) {
+	config, err := srv.hubConfigRepo.Get(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(config.Content), nil
+}
+
+func (srv *hubConfigService) Close() {
+	srv.hubConfigRepo.Close()
+}
+
+func marshalList(list []string) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+	bys, _ := json.Marshal(list)
+	return string(bys)
+}
+
+func unmarshalList(s string) []string {
+	list := make([]string, 0)
+	_ = json.Unmarshal([]byte(s), &list)
+	return list
+}
@@ -18,6 +18,7 @@ import (
 	"nocalhost/internal/nocalhost-api/service/application_user"
 	"nocalhost/internal/nocalhost-api/service/cluster"
 	"nocalhost/internal/nocalhost-api/service/cluster_user"
+	"nocalhost/internal/nocalhost-api/service/hub_config"
 	"nocalhost/internal/nocalhost-api/service/pre_pull"
 	"nocalhost/internal/nocalhost-api/service/user"
 	"nocalhost/pkg/nocalhost-api/pkg/clientgo"
@@ -42,6 +43,7 @@ type Service struct {
 	clusterUserSvc        cluster_user.ClusterUserService
 	prePullSvc            pre_pull.PrePullService
 	applicationUserSvc    application_user.ApplicationUserService
+	hubConfigSvc          hub_config.HubConfigService
 }
 
 // New init service
@@ -54,6 +56,7 @@ func New() (s *Service) {
 		clusterUserSvc:        cluster_user.NewClusterUserService(),
 		prePullSvc:            pre_pull.NewPrePullService(),
 		applicationUserSvc:    application_user.NewApplicationUserService(),
+		hubConfigSvc:          hub_config.NewHubConfigService(),
 	}
 
 	if global.ServiceInitial == "true" {
@@ -95,6 +98,10 @@ func (s *Service) ApplicationUser() application_user.ApplicationUserService {
 	return s.applicationUserSvc
 }
 
+func (s *Service) HubConfig() hub_config.HubConfigService {
+	return s.hubConfigSvc
+}
+
 // Ping service
 func (s *Service) Ping() error {
 	return nil
@@ -174,7 +174,7 @@ func (c *ClientGoUtils) GetResourceInfoFromReader(reader io.Reader, continueOnEr
 	validate, err := f.Validator(true)
 	if err != nil {
 		if continueOnError {
-			log.Warnf("Build validator err:", err.Error())
+			log.Warnf("Build validator err: %s", err.Error())
 		} else {
 			return nil, errors.Wrap(err, "")
 		}
@@ -24,6 +24,11 @@ func (c *ClientGoUtils) ListConfigMaps() ([]v1.ConfigMap, error) {
 	return result, errors.Wrap(err, "")
 }
 
+func (c *ClientGoUtils) CreateConfigMap(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+	cm2, err := c.ClientSet.CoreV1().ConfigMaps(c.namespace).Create(c.ctx, cm, metav1.CreateOptions{})
+	return cm2, errors.Wrap(err, "")
+}
+
 func (c *ClientGoUtils) UpdateConfigMaps(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
 	cm2, err := c.ClientSet.CoreV1().ConfigMaps(c.namespace).Update(c.ctx, cm, metav1.UpdateOptions{})
 	return cm2, errors.Wrap(err, "")
@@ -146,3 +146,25 @@ func Execute(
 		},
 	)
 }
+
+// ExecStream executes the command in the container without tty, stdin of the command is closed
+// once stdin is drained, stdin can be nil
+func (c *ClientGoUtils) ExecStream(
+	podName, containerName string, command []string, stdin io.Reader, stdout, stderr io.Writer,
+) error {
+	req := c.ClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(c.namespace).
+		SubResource("exec")
+	req.VersionedParams(
+		&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    stderr != nil,
+		}, scheme.ParameterCodec,
+	)
+	return errors.Wrap(Execute("POST", req.URL(), c.restConfig, stdin, stdout, stderr, false, nil), "")
+}
@@ -0,0 +1,263 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	HelmDefaultTimeout = 60 * time.Minute
+	// same as the default driver of helm cli
+	helmDriver = "secret"
+)
+
+// HelmError is returned by all helm operations, so the caller can
+// know which operation against which release failed without parsing output
+type HelmError struct {
+	Op      string
+	Release string
+	Err     error
+}
+
+func (e *HelmError) Error() string {
+	return fmt.Sprintf("helm %s release %s failed: %v", e.Op, e.Release, e.Err)
+}
+
+func (e *HelmError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HelmError) Cause() error {
+	return e.Err
+}
+
+// HelmFlags describe a helm install or upgrade, chart can be a local chart dir,
+// a 'repoName/chartName' reference or a chart name while RepoUrl is specified
+type HelmFlags struct {
+	ReleaseName string
+	Chart       string
+	RepoUrl     string
+	Username    string
+	Password    string
+	Version     string
+	ValueFiles  []string
+	Set         []string
+	Wait        bool
+	Atomic      bool
+	Timeout     time.Duration
+	Debug       bool
+}
+
+func (c *ClientGoUtils) newHelmSettings(debug bool) *cli.EnvSettings {
+	settings := cli.New()
+	settings.KubeConfig = c.kubeConfigFilePath
+	settings.Debug = debug
+	return settings
+}
+
+// NewHelmActionConfig init helm action configuration with the kubeconfig and namespace of this client
+func (c *ClientGoUtils) NewHelmActionConfig(debug bool) (*action.Configuration, error) {
+	actionConfig := &action.Configuration{}
+	debugLog := func(format string, v ...interface{}) {
+		if debug {
+			log.Infof(format, v...)
+		} else {
+			log.Debugf(format, v...)
+		}
+	}
+	if err := actionConfig.Init(c.NewConfigFlags(), c.namespace, helmDriver, debugLog); err != nil {
+		return nil, errors.Wrap(err, "fail to init helm action config")
+	}
+	return actionConfig, nil
+}
+
+// HelmRepoUpdate refresh index of all helm repo configured locally, same as `helm repo update`
+func (c *ClientGoUtils) HelmRepoUpdate() error {
+	settings := c.newHelmSettings(false)
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil
+		}
+		return &HelmError{Op: "repo update", Err: err}
+	}
+
+	for _, entry := range repoFile.Repositories {
+		chartRepo, err := repo.NewChartRepository(entry, getter.All(settings))
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Fail to init helm repo %s", entry.Name))
+			continue
+		}
+		chartRepo.CachePath = settings.RepositoryCache
+		if _, err = chartRepo.DownloadIndexFile(); err != nil {
+			log.WarnE(err, fmt.Sprintf("Fail to update helm repo %s", entry.Name))
+		}
+	}
+	return nil
+}
+
+// HelmRepoNameByUrl find the name of local helm repo which url contains repoUrl
+func (c *ClientGoUtils) HelmRepoNameByUrl(repoUrl string) string {
+	settings := c.newHelmSettings(false)
+	repoFile, err := repo.LoadFile(settings.RepositoryConfig)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range repoFile.Repositories {
+		if strings.Contains(entry.URL, repoUrl) {
+			return entry.Name
+		}
+	}
+	return ""
+}
+
+// HelmDependencyBuild same as `helm dependency build`
+func (c *ClientGoUtils) HelmDependencyBuild(chartPath string, debug bool) error {
+	settings := c.newHelmSettings(debug)
+	manager := &downloader.Manager{
+		Out:              os.Stdout,
+		ChartPath:        chartPath,
+		Debug:            debug,
+		Getters:          getter.All(settings),
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+		SkipUpdate:       true,
+	}
+	if err := manager.Build(); err != nil {
+		return &HelmError{Op: "dependency build", Release: chartPath, Err: err}
+	}
+	return nil
+}
+
+func (c *ClientGoUtils) loadHelmChart(
+	settings *cli.EnvSettings, pathOptions *action.ChartPathOptions, flags *HelmFlags,
+) (*chart.Chart, map[string]interface{}, error) {
+	chartPath, err := pathOptions.LocateChart(flags.Chart, settings)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("fail to locate chart %s", flags.Chart))
+	}
+
+	chartRequested, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("fail to load chart %s", chartPath))
+	}
+	if chartRequested.Metadata.Deprecated {
+		log.Warnf("Chart %s is deprecated", chartRequested.Name())
+	}
+	if chartRequested.Metadata.Dependencies != nil {
+		if err = action.CheckDependencies(chartRequested, chartRequested.Metadata.Dependencies); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	valueOpts := &values.Options{ValueFiles: flags.ValueFiles, Values: flags.Set}
+	vals, err := valueOpts.MergeValues(getter.All(settings))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "fail to merge helm values")
+	}
+	return chartRequested, vals, nil
+}
+
+func helmChartPathOptions(flags *HelmFlags) action.ChartPathOptions {
+	return action.ChartPathOptions{
+		RepoURL:  flags.RepoUrl,
+		Username: flags.Username,
+		Password: flags.Password,
+		Version:  flags.Version,
+	}
+}
+
+func helmTimeout(flags *HelmFlags) time.Duration {
+	if flags.Timeout > 0 {
+		return flags.Timeout
+	}
+	return HelmDefaultTimeout
+}
+
+// HelmInstall same as `helm install`
+func (c *ClientGoUtils) HelmInstall(flags *HelmFlags) (*release.Release, error) {
+	actionConfig, err := c.NewHelmActionConfig(flags.Debug)
+	if err != nil {
+		return nil, &HelmError{Op: "install", Release: flags.ReleaseName, Err: err}
+	}
+
+	install := action.NewInstall(actionConfig)
+	install.ReleaseName = flags.ReleaseName
+	install.Namespace = c.namespace
+	install.Wait = flags.Wait || flags.Atomic
+	install.Atomic = flags.Atomic
+	install.Timeout = helmTimeout(flags)
+	install.ChartPathOptions = helmChartPathOptions(flags)
+
+	chartRequested, vals, err := c.loadHelmChart(c.newHelmSettings(flags.Debug), &install.ChartPathOptions, flags)
+	if err != nil {
+		return nil, &HelmError{Op: "install", Release: flags.ReleaseName, Err: err}
+	}
+
+	rel, err := install.Run(chartRequested, vals)
+	if err != nil {
+		return rel, &HelmError{Op: "install", Release: flags.ReleaseName, Err: err}
+	}
+	return rel, nil
+}
+
+// HelmUpgrade same as `helm upgrade`
+func (c *ClientGoUtils) HelmUpgrade(flags *HelmFlags) (*release.Release, error) {
+	actionConfig, err := c.NewHelmActionConfig(flags.Debug)
+	if err != nil {
+		return nil, &HelmError{Op: "upgrade", Release: flags.ReleaseName, Err: err}
+	}
+
+	upgrade := action.NewUpgrade(actionConfig)
+	upgrade.Namespace = c.namespace
+	upgrade.Wait = flags.Wait || flags.Atomic
+	upgrade.Atomic = flags.Atomic
+	upgrade.Timeout = helmTimeout(flags)
+	upgrade.ChartPathOptions = helmChartPathOptions(flags)
+
+	chartRequested, vals, err := c.loadHelmChart(c.newHelmSettings(flags.Debug), &upgrade.ChartPathOptions, flags)
+	if err != nil {
+		return nil, &HelmError{Op: "upgrade", Release: flags.ReleaseName, Err: err}
+	}
+
+	rel, err := upgrade.Run(flags.ReleaseName, chartRequested, vals)
+	if err != nil {
+		return rel, &HelmError{Op: "upgrade", Release: flags.ReleaseName, Err: err}
+	}
+	return rel, nil
+}
+
+// HelmUninstall same as `helm uninstall`
+func (c *ClientGoUtils) HelmUninstall(releaseName string, debug bool) error {
+	actionConfig, err := c.NewHelmActionConfig(debug)
+	if err != nil {
+		return &HelmError{Op: "uninstall", Release: releaseName, Err: err}
+	}
+
+	uninstall := action.NewUninstall(actionConfig)
+	uninstall.Timeout = HelmDefaultTimeout
+	if _, err = uninstall.Run(releaseName); err != nil {
+		return &HelmError{Op: "uninstall", Release: releaseName, Err: err}
+	}
+	return nil
+}
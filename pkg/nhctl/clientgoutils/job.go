@@ -6,11 +6,15 @@
 package clientgoutils
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 func (c *ClientGoUtils) CreateJob(job *batchv1.Job) (*batchv1.Job, error) {
@@ -39,3 +43,32 @@ func (c *ClientGoUtils) ListPodsByJob(name string) (*corev1.PodList, error) {
 	}
 	return pods, nil
 }
+
+// WaitJobComplete waits until the job succeeds, returns an error if the job fails
+func (c *ClientGoUtils) WaitJobComplete(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultWaitReadyTimeout
+	}
+	return errors.Wrap(
+		wait.PollImmediate(
+			waitReadyInterval, timeout, func() (bool, error) {
+				job, err := c.ClientSet.BatchV1().Jobs(c.namespace).Get(c.ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+				for _, condition := range job.Status.Conditions {
+					if condition.Status != corev1.ConditionTrue {
+						continue
+					}
+					switch condition.Type {
+					case batchv1.JobComplete:
+						return true, nil
+					case batchv1.JobFailed:
+						return false, errors.New(fmt.Sprintf("job %s failed: %s", name, condition.Message))
+					}
+				}
+				return false, nil
+			},
+		), "",
+	)
+}
@@ -6,10 +6,15 @@
 package clientgoutils
 
 import (
+	"fmt"
+	"io"
+	"time"
+
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 // ListPodsByDeployment This method can not list pods whose deployment is already deleted.
@@ -79,3 +84,58 @@ func (c *ClientGoUtils) UpdatePod(pod *corev1.Pod) (*corev1.Pod, error) {
 	pod2, err := c.GetPodClient().Update(c.ctx, pod, metav1.UpdateOptions{})
 	return pod2, errors.Wrap(err, "")
 }
+
+// AddEphemeralContainer adds the container to ephemeral containers of the pod, and waits until it's running
+func (c *ClientGoUtils) AddEphemeralContainer(podName string, container corev1.EphemeralContainer) error {
+	podClient := c.GetPodClient()
+	ecs, err := podClient.GetEphemeralContainers(c.ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	ecs.EphemeralContainers = append(ecs.EphemeralContainers, container)
+	if _, err = podClient.UpdateEphemeralContainers(c.ctx, podName, ecs, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("fail to add ephemeral container to pod %s", podName))
+	}
+	return c.WaitEphemeralContainerRunning(podName, container.Name, DefaultWaitReadyTimeout)
+}
+
+// WaitEphemeralContainerRunning waits until the ephemeral container of pod is running
+func (c *ClientGoUtils) WaitEphemeralContainerRunning(podName, container string, timeout time.Duration) error {
+	return errors.Wrap(
+		wait.PollImmediate(
+			waitReadyInterval, timeout, func() (bool, error) {
+				pod, err := c.GetPod(podName)
+				if err != nil {
+					return false, err
+				}
+				for _, status := range pod.Status.EphemeralContainerStatuses {
+					if status.Name != container {
+						continue
+					}
+					if status.State.Terminated != nil {
+						return false, errors.New(
+							fmt.Sprintf(
+								"ephemeral container %s terminated: %s", container, status.State.Terminated.Reason,
+							),
+						)
+					}
+					return status.State.Running != nil, nil
+				}
+				return false, nil
+			},
+		), fmt.Sprintf("ephemeral container %s of pod %s is not running", container, podName),
+	)
+}
+
+// FollowContainerLogs copies logs of the container to out until the container exits
+func (c *ClientGoUtils) FollowContainerLogs(podName, container string, out io.Writer) error {
+	stream, err := c.ClientSet.CoreV1().Pods(c.namespace).GetLogs(
+		podName, &corev1.PodLogOptions{Container: container, Follow: true},
+	).Stream(c.ctx)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer stream.Close()
+	_, err = io.Copy(out, stream)
+	return errors.Wrap(err, "")
+}
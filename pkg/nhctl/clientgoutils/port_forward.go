@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"io/ioutil"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"net/http"
+	_const "nocalhost/internal/nhctl/const"
 
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
@@ -21,12 +23,14 @@ type ForwardPort struct {
 	RemotePort int
 }
 
-func (c *ClientGoUtils) Forward(pod string, localPort, remotePort int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams) error {
+// Forward forwards localPort listened on address to remotePort of the pod, traffic is
+// counted by metrics if it's not nil
+func (c *ClientGoUtils) Forward(pod, address string, localPort, remotePort int, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams, metrics *PortForwardMetrics) error {
 	pf := ForwardPort{
 		LocalPort:  localPort,
 		RemotePort: remotePort,
 	}
-	fd, err := c.CreatePortForwarder(pod, []*ForwardPort{&pf}, readyChan, stopChan, g)
+	fd, err := c.createPortForwarder(pod, address, []*ForwardPort{&pf}, readyChan, stopChan, g, metrics)
 	if err != nil {
 		return err
 	}
@@ -34,6 +38,14 @@ func (c *ClientGoUtils) Forward(pod string, localPort, remotePort int, readyChan
 }
 
 func (c *ClientGoUtils) CreatePortForwarder(pod string, fps []*ForwardPort, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams) (*portforward.PortForwarder, error) {
+	return c.CreatePortForwarderOnAddress(pod, _const.DefaultPortForwardBindAddress, fps, readyChan, stopChan, g)
+}
+
+func (c *ClientGoUtils) CreatePortForwarderOnAddress(pod, address string, fps []*ForwardPort, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams) (*portforward.PortForwarder, error) {
+	return c.createPortForwarder(pod, address, fps, readyChan, stopChan, g, nil)
+}
+
+func (c *ClientGoUtils) createPortForwarder(pod, address string, fps []*ForwardPort, readyChan, stopChan chan struct{}, g genericclioptions.IOStreams, metrics *PortForwardMetrics) (*portforward.PortForwarder, error) {
 	if fps == nil || len(fps) < 1 {
 		return nil, errors.New("forward ports can not be nil")
 	}
@@ -48,7 +60,10 @@ func (c *ClientGoUtils) CreatePortForwarder(pod string, fps []*ForwardPort, read
 	if err != nil {
 		return nil, err
 	}
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+	var dialer httpstream.Dialer = spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+	if metrics != nil {
+		dialer = &meteredDialer{Dialer: dialer, metrics: metrics}
+	}
 
 	if readyChan == nil {
 		readyChan = make(chan struct{})
@@ -65,7 +80,7 @@ func (c *ClientGoUtils) CreatePortForwarder(pod string, fps []*ForwardPort, read
 
 	pf, err := portforward.NewOnAddresses(
 		dialer,
-		[]string{"0.0.0.0"},
+		[]string{address},
 		ports,
 		stopChan,
 		readyChan,
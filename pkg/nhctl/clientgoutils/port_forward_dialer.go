@@ -0,0 +1,88 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"net"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+
+	"nocalhost/pkg/nhctl/log"
+)
+
+// PodDialer opens connections to ports of a pod, all connections are streams
+// of a single port-forward connection which is re-dialed once it's closed
+type PodDialer struct {
+	client *ClientGoUtils
+	pod    string
+	// Metrics counts traffic of the dialer if it's not nil
+	Metrics *PortForwardMetrics
+
+	lock       sync.Mutex
+	streamConn httpstream.Connection
+	requestID  int
+}
+
+func (c *ClientGoUtils) NewPodDialer(pod string) *PodDialer {
+	return &PodDialer{client: c, pod: pod}
+}
+
+func (d *PodDialer) connection() (httpstream.Connection, int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.requestID++
+	if d.streamConn != nil {
+		return d.streamConn, d.requestID, nil
+	}
+	streamConn, err := d.client.dialPortForward(d.pod)
+	if err != nil {
+		return nil, 0, err
+	}
+	streamConn = d.Metrics.WrapConnection(streamConn)
+	d.streamConn = streamConn
+	go func() {
+		<-streamConn.CloseChan()
+		d.lock.Lock()
+		if d.streamConn == streamConn {
+			d.streamConn = nil
+		}
+		d.lock.Unlock()
+	}()
+	return streamConn, d.requestID, nil
+}
+
+// Dial returns a connection to port of the pod
+func (d *PodDialer) Dial(port int) (net.Conn, error) {
+	streamConn, requestID, err := d.connection()
+	if err != nil {
+		return nil, err
+	}
+	local, remote := net.Pipe()
+	go func() {
+		defer remote.Close()
+		created, err := forwardStreams(streamConn, remote, port, requestID)
+		if err != nil {
+			if !created {
+				// the port-forward connection may be broken, dial a new one next time
+				d.Close()
+			}
+			log.Debugf("Stream to %s:%d closed: %v", d.pod, port, err)
+		}
+	}()
+	return local, nil
+}
+
+// Close closes the underlying port-forward connection and all streams
+func (d *PodDialer) Close() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.streamConn != nil {
+		_ = d.streamConn.Close()
+		d.streamConn = nil
+	}
+}
@@ -0,0 +1,163 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+)
+
+// PortForwardStats is a snapshot of PortForwardMetrics
+type PortForwardStats struct {
+	BytesIn           int64  `json:"bytesIn" yaml:"bytesIn"`   // bytes received from the pod
+	BytesOut          int64  `json:"bytesOut" yaml:"bytesOut"` // bytes sent to the pod
+	ActiveConnections int64  `json:"activeConnections" yaml:"activeConnections"`
+	TotalConnections  int64  `json:"totalConnections" yaml:"totalConnections"`
+	Reconnects        int64  `json:"reconnects" yaml:"reconnects"`
+	LastError         string `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	LastErrorTime     string `json:"lastErrorTime,omitempty" yaml:"lastErrorTime,omitempty"`
+}
+
+// PortForwardMetrics counts traffic of a port-forward, every data stream of the
+// port-forward connection is a forwarded connection
+type PortForwardMetrics struct {
+	bytesIn           int64
+	bytesOut          int64
+	activeConnections int64
+	totalConnections  int64
+	reconnects        int64
+
+	lock          sync.Mutex
+	lastError     string
+	lastErrorTime time.Time
+}
+
+func NewPortForwardMetrics() *PortForwardMetrics {
+	return &PortForwardMetrics{}
+}
+
+func (m *PortForwardMetrics) AddReconnect() {
+	atomic.AddInt64(&m.reconnects, 1)
+}
+
+func (m *PortForwardMetrics) SetLastError(err error) {
+	if err == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.lastError = err.Error()
+	m.lastErrorTime = time.Now()
+}
+
+func (m *PortForwardMetrics) Stats() *PortForwardStats {
+	stats := &PortForwardStats{
+		BytesIn:           atomic.LoadInt64(&m.bytesIn),
+		BytesOut:          atomic.LoadInt64(&m.bytesOut),
+		ActiveConnections: atomic.LoadInt64(&m.activeConnections),
+		TotalConnections:  atomic.LoadInt64(&m.totalConnections),
+		Reconnects:        atomic.LoadInt64(&m.reconnects),
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.lastError != "" {
+		stats.LastError = m.lastError
+		stats.LastErrorTime = m.lastErrorTime.Format("2006-01-02 15:04:05")
+	}
+	return stats
+}
+
+// WrapConnection returns a port-forward connection counted by m
+func (m *PortForwardMetrics) WrapConnection(conn httpstream.Connection) httpstream.Connection {
+	if m == nil {
+		return conn
+	}
+	return &meteredConnection{Connection: conn, metrics: m}
+}
+
+type meteredDialer struct {
+	httpstream.Dialer
+	metrics *PortForwardMetrics
+}
+
+func (d *meteredDialer) Dial(protocols ...string) (httpstream.Connection, string, error) {
+	conn, protocol, err := d.Dialer.Dial(protocols...)
+	if err != nil {
+		d.metrics.SetLastError(err)
+		return nil, protocol, err
+	}
+	return d.metrics.WrapConnection(conn), protocol, nil
+}
+
+type meteredConnection struct {
+	httpstream.Connection
+	metrics *PortForwardMetrics
+}
+
+func (c *meteredConnection) CreateStream(headers http.Header) (httpstream.Stream, error) {
+	stream, err := c.Connection.CreateStream(headers)
+	if err != nil {
+		c.metrics.SetLastError(err)
+		return nil, err
+	}
+	if headers.Get(corev1.StreamType) != corev1.StreamTypeData {
+		return stream, nil
+	}
+	atomic.AddInt64(&c.metrics.activeConnections, 1)
+	atomic.AddInt64(&c.metrics.totalConnections, 1)
+	s := &meteredStream{Stream: stream, metrics: c.metrics, doneCh: make(chan struct{})}
+	// streams are reset while the connection is closed
+	go func() {
+		select {
+		case <-c.Connection.CloseChan():
+			s.done()
+		case <-s.doneCh:
+		}
+	}()
+	return s, nil
+}
+
+type meteredStream struct {
+	httpstream.Stream
+	metrics  *PortForwardMetrics
+	doneOnce sync.Once
+	doneCh   chan struct{}
+}
+
+// done is called once the remote side finishes or the stream is reset
+func (s *meteredStream) done() {
+	s.doneOnce.Do(
+		func() {
+			atomic.AddInt64(&s.metrics.activeConnections, -1)
+			close(s.doneCh)
+		},
+	)
+}
+
+func (s *meteredStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	atomic.AddInt64(&s.metrics.bytesIn, int64(n))
+	if err != nil {
+		s.done()
+	}
+	return n, err
+}
+
+func (s *meteredStream) Write(p []byte) (int, error) {
+	n, err := s.Stream.Write(p)
+	atomic.AddInt64(&s.metrics.bytesOut, int64(n))
+	return n, err
+}
+
+func (s *meteredStream) Reset() error {
+	s.done()
+	return s.Stream.Reset()
+}
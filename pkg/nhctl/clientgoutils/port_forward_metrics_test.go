@@ -0,0 +1,87 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+)
+
+type fakeStream struct {
+	httpstream.Stream
+	data []byte
+}
+
+func (s *fakeStream) Read(p []byte) (int, error) {
+	if len(s.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, s.data)
+	s.data = s.data[n:]
+	return n, nil
+}
+
+func (s *fakeStream) Write(p []byte) (int, error) { return len(p), nil }
+
+func (s *fakeStream) Reset() error { return nil }
+
+type fakeConnection struct {
+	httpstream.Connection
+	closeCh chan bool
+}
+
+func (c *fakeConnection) CreateStream(http.Header) (httpstream.Stream, error) {
+	return &fakeStream{data: []byte("pong")}, nil
+}
+
+func (c *fakeConnection) CloseChan() <-chan bool { return c.closeCh }
+
+func TestPortForwardMetrics(t *testing.T) {
+	metrics := NewPortForwardMetrics()
+	conn := metrics.WrapConnection(&fakeConnection{closeCh: make(chan bool)})
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	if _, err := conn.CreateStream(headers); err != nil {
+		t.Fatal(err)
+	}
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	stream, err := conn.CreateStream(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Write([]byte("ping!")); err != nil {
+		t.Fatal(err)
+	}
+	if stats := metrics.Stats(); stats.ActiveConnections != 1 || stats.TotalConnections != 1 {
+		t.Fatalf("expect one active data stream, got %+v", stats)
+	}
+
+	if _, err = io.ReadAll(stream); err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.Reset()
+	metrics.AddReconnect()
+	metrics.SetLastError(errors.New("lost connection to pod"))
+
+	stats := metrics.Stats()
+	if stats.BytesIn != 4 || stats.BytesOut != 5 || stats.ActiveConnections != 0 ||
+		stats.TotalConnections != 1 || stats.Reconnects != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.LastError != "lost connection to pod" {
+		t.Fatalf("unexpected last error %s", stats.LastError)
+	}
+	if _, err = time.Parse("2006-01-02 15:04:05", stats.LastErrorTime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,488 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	_const "nocalhost/internal/nhctl/const"
+	"nocalhost/pkg/nhctl/log"
+)
+
+const endpointSliceServiceLabel = "kubernetes.io/service-name"
+
+// ServiceTarget is a ready endpoint of a k8s Service
+type ServiceTarget struct {
+	PodName string
+	Port    int
+}
+
+// ServicePortForwarder forward a local port to a port of k8s Service. The local listener
+// keeps open while pods behind the service change, every new connection is forwarded
+// to a ready endpoint watched from EndpointSlices (or Endpoints on old clusters)
+type ServicePortForwarder struct {
+	client       *ClientGoUtils
+	service      string
+	servicePort  int
+	localAddress string
+	localPort    int
+
+	lock       sync.Mutex
+	targets    []ServiceTarget
+	target     *ServiceTarget
+	streamConn httpstream.Connection
+	requestID  int
+
+	// OnRetarget is called while the forwarder switches to another pod, target is nil if no endpoint is ready
+	OnRetarget func(target *ServiceTarget)
+	// Metrics counts traffic of the forwarder if it's not nil
+	Metrics *PortForwardMetrics
+}
+
+func (c *ClientGoUtils) NewServicePortForwarder(
+	service string, localAddress string, localPort, servicePort int,
+) *ServicePortForwarder {
+	if localAddress == "" {
+		localAddress = _const.DefaultPortForwardBindAddress
+	}
+	return &ServicePortForwarder{
+		client:       c,
+		service:      service,
+		servicePort:  servicePort,
+		localAddress: localAddress,
+		localPort:    localPort,
+	}
+}
+
+// Target return the pod which new connections are forwarded to currently
+func (f *ServicePortForwarder) Target() *ServiceTarget {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.target != nil {
+		t := *f.target
+		return &t
+	}
+	if len(f.targets) > 0 {
+		t := f.targets[0]
+		return &t
+	}
+	return nil
+}
+
+// ForwardPorts listen on local port and forward connections until ctx is done,
+// readyCh is closed once the listener is open and endpoints of service are synced
+func (f *ServicePortForwarder) ForwardPorts(ctx context.Context, readyCh chan struct{}) error {
+	svc, err := f.client.GetService(f.service)
+	if err != nil {
+		return err
+	}
+	portName, found := "", false
+	for _, port := range svc.Spec.Ports {
+		if int(port.Port) == f.servicePort && port.Protocol != corev1.ProtocolUDP {
+			portName, found = port.Name, true
+			break
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("service %s has no tcp port %d", f.service, f.servicePort))
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(f.localAddress, strconv.Itoa(f.localPort)))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("unable to listen on port %d", f.localPort))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+		f.closeStreamConn()
+	}()
+
+	if err = f.watchEndpoints(ctx, portName); err != nil {
+		return err
+	}
+	if readyCh != nil {
+		close(readyCh)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				return errors.Wrap(err, "")
+			}
+		}
+		go f.handleConnection(conn)
+	}
+}
+
+func (f *ServicePortForwarder) watchEndpoints(ctx context.Context, portName string) error {
+	useSlices := true
+	if _, err := f.client.ClientSet.Discovery().ServerResourcesForGroupVersion(
+		discoveryv1beta1.SchemeGroupVersion.String(),
+	); err != nil {
+		log.Debugf("EndpointSlice is not supported, watching Endpoints instead: %v", err)
+		useSlices = false
+	}
+
+	var informer cache.SharedIndexInformer
+	var refresh func()
+	if useSlices {
+		factory := informers.NewSharedInformerFactoryWithOptions(
+			f.client.ClientSet, 0, informers.WithNamespace(f.client.namespace),
+			informers.WithTweakListOptions(
+				func(options *metav1.ListOptions) {
+					options.LabelSelector = fmt.Sprintf("%s=%s", endpointSliceServiceLabel, f.service)
+				},
+			),
+		)
+		informer = factory.Discovery().V1beta1().EndpointSlices().Informer()
+		refresh = func() {
+			slices := make([]*discoveryv1beta1.EndpointSlice, 0)
+			for _, obj := range informer.GetStore().List() {
+				if slice, ok := obj.(*discoveryv1beta1.EndpointSlice); ok {
+					slices = append(slices, slice)
+				}
+			}
+			f.updateTargets(readyTargetsFromSlices(slices, portName))
+		}
+	} else {
+		factory := informers.NewSharedInformerFactoryWithOptions(
+			f.client.ClientSet, 0, informers.WithNamespace(f.client.namespace),
+			informers.WithTweakListOptions(
+				func(options *metav1.ListOptions) {
+					options.FieldSelector = fmt.Sprintf("metadata.name=%s", f.service)
+				},
+			),
+		)
+		informer = factory.Core().V1().Endpoints().Informer()
+		refresh = func() {
+			targets := make([]ServiceTarget, 0)
+			for _, obj := range informer.GetStore().List() {
+				if endpoints, ok := obj.(*corev1.Endpoints); ok {
+					targets = append(targets, readyTargetsFromEndpoints(endpoints, portName)...)
+				}
+			}
+			f.updateTargets(targets)
+		}
+	}
+
+	informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { refresh() },
+			UpdateFunc: func(oldObj, newObj interface{}) { refresh() },
+			DeleteFunc: func(obj interface{}) { refresh() },
+		},
+	)
+	go informer.Run(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return errors.New(fmt.Sprintf("fail to sync endpoints of service %s", f.service))
+	}
+	refresh()
+	return nil
+}
+
+// readyTargetsFromSlices return ready pods of service port sorted by pod name
+func readyTargetsFromSlices(slices []*discoveryv1beta1.EndpointSlice, portName string) []ServiceTarget {
+	targets := make([]ServiceTarget, 0)
+	for _, slice := range slices {
+		port := -1
+		for _, p := range slice.Ports {
+			name := ""
+			if p.Name != nil {
+				name = *p.Name
+			}
+			if name == portName && p.Port != nil {
+				port = int(*p.Port)
+				break
+			}
+		}
+		if port < 0 {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+				continue
+			}
+			targets = append(targets, ServiceTarget{PodName: endpoint.TargetRef.Name, Port: port})
+		}
+	}
+	sortTargets(targets)
+	return targets
+}
+
+// readyTargetsFromEndpoints return ready pods of service port sorted by pod name
+func readyTargetsFromEndpoints(endpoints *corev1.Endpoints, portName string) []ServiceTarget {
+	targets := make([]ServiceTarget, 0)
+	for _, subset := range endpoints.Subsets {
+		port := -1
+		for _, p := range subset.Ports {
+			if p.Name == portName {
+				port = int(p.Port)
+				break
+			}
+		}
+		if port < 0 {
+			continue
+		}
+		for _, address := range subset.Addresses {
+			if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+				continue
+			}
+			targets = append(targets, ServiceTarget{PodName: address.TargetRef.Name, Port: port})
+		}
+	}
+	sortTargets(targets)
+	return targets
+}
+
+func sortTargets(targets []ServiceTarget) {
+	sort.Slice(
+		targets, func(i, j int) bool {
+			return targets[i].PodName < targets[j].PodName
+		},
+	)
+}
+
+// updateTargets drop the connection to current pod if it is not ready any more,
+// so the next connection will be forwarded to another ready pod
+func (f *ServicePortForwarder) updateTargets(targets []ServiceTarget) {
+	f.lock.Lock()
+	f.targets = targets
+	if f.target == nil || containsTarget(targets, *f.target) {
+		f.lock.Unlock()
+		return
+	}
+
+	log.Infof("Pod %s of service %s is not ready any more", f.target.PodName, f.service)
+	f.target = nil
+	if f.streamConn != nil {
+		_ = f.streamConn.Close()
+		f.streamConn = nil
+	}
+	var next *ServiceTarget
+	if len(targets) > 0 {
+		next = &targets[0]
+		log.Infof("Re-targeting port-forward %d to pod %s", f.localPort, next.PodName)
+	}
+	onRetarget := f.OnRetarget
+	f.lock.Unlock()
+
+	if onRetarget != nil {
+		onRetarget(next)
+	}
+}
+
+func containsTarget(targets []ServiceTarget, target ServiceTarget) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ServicePortForwarder) closeStreamConn() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.streamConn != nil {
+		_ = f.streamConn.Close()
+		f.streamConn = nil
+	}
+}
+
+// connection return the spdy connection to current pod, dial a ready pod if there is none
+func (f *ServicePortForwarder) connection() (httpstream.Connection, ServiceTarget, int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requestID++
+	if f.streamConn != nil && f.target != nil {
+		return f.streamConn, *f.target, f.requestID, nil
+	}
+	if len(f.targets) == 0 {
+		return nil, ServiceTarget{}, 0, errors.New(fmt.Sprintf("no ready endpoint of service %s", f.service))
+	}
+
+	target := f.targets[0]
+	streamConn, err := f.client.dialPortForward(target.PodName)
+	if err != nil {
+		return nil, target, 0, err
+	}
+	streamConn = f.Metrics.WrapConnection(streamConn)
+	f.target, f.streamConn = &target, streamConn
+	log.Infof("Port-forward %d is forwarding to pod %s:%d", f.localPort, target.PodName, target.Port)
+
+	go func() {
+		<-streamConn.CloseChan()
+		f.lock.Lock()
+		if f.streamConn == streamConn {
+			f.streamConn = nil
+		}
+		f.lock.Unlock()
+	}()
+	return streamConn, target, f.requestID, nil
+}
+
+// dropTarget is called while the pod can not be forwarded, it will be tried at last next time
+func (f *ServicePortForwarder) dropTarget(target ServiceTarget) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.target != nil && *f.target == target {
+		f.target = nil
+		if f.streamConn != nil {
+			_ = f.streamConn.Close()
+			f.streamConn = nil
+		}
+	}
+	for i, t := range f.targets {
+		if t == target {
+			f.targets = append(append(f.targets[:i:i], f.targets[i+1:]...), t)
+			break
+		}
+	}
+}
+
+func (c *ClientGoUtils) dialPortForward(pod string) (httpstream.Connection, error) {
+	url := c.ClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(c.namespace).
+		Name(pod).
+		SubResource("portforward").URL()
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("fail to dial port-forward of pod %s", pod))
+	}
+	return streamConn, nil
+}
+
+func (f *ServicePortForwarder) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	// retry with other pods if the stream can not be created
+	for i := 0; i < 3; i++ {
+		streamConn, target, requestID, err := f.connection()
+		if err != nil {
+			f.setLastError(err)
+			log.WarnE(err, fmt.Sprintf("Port-forward %d failed to connect", f.localPort))
+			if target.PodName == "" {
+				return
+			}
+			f.dropTarget(target)
+			continue
+		}
+
+		created, err := forwardStreams(streamConn, conn, target.Port, requestID)
+		if err == nil {
+			return
+		}
+		f.setLastError(err)
+		if created {
+			log.Debugf("Port-forward %d to pod %s: %v", f.localPort, target.PodName, err)
+			return
+		}
+		log.WarnE(err, fmt.Sprintf("Port-forward %d failed to create stream to %s", f.localPort, target.PodName))
+		f.dropTarget(target)
+		<-time.After(time.Second)
+	}
+}
+
+func (f *ServicePortForwarder) setLastError(err error) {
+	if f.Metrics != nil {
+		f.Metrics.SetLastError(err)
+	}
+}
+
+// forwardStreams copy data between conn and a new stream of streamConn, created is false
+// if the stream fails to be created, which means the pod may not be available
+func forwardStreams(streamConn httpstream.Connection, conn net.Conn, port, requestID int) (created bool, err error) {
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.Itoa(requestID))
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return false, errors.Wrap(err, "error creating error stream")
+	}
+	// we're not writing to this stream
+	_ = errorStream.Close()
+
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorChan <- errors.Wrap(err, "error reading from error stream")
+		case len(message) > 0:
+			errorChan <- errors.New(fmt.Sprintf("an error occurred forwarding: %s", string(message)))
+		}
+		close(errorChan)
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		return false, errors.Wrap(err, "error creating forwarding stream")
+	}
+
+	localError := make(chan struct{})
+	remoteDone := make(chan struct{})
+
+	go func() {
+		// inform the select below that the remote copy is done
+		defer close(remoteDone)
+		_, _ = io.Copy(conn, dataStream)
+	}()
+
+	go func() {
+		// inform server we're not sending any more data after copy unblocks
+		defer dataStream.Close()
+		if _, err := io.Copy(dataStream, conn); err != nil {
+			close(localError)
+		}
+	}()
+
+	select {
+	case <-remoteDone:
+	case <-localError:
+	}
+
+	return true, <-errorChan
+}
@@ -0,0 +1,89 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+)
+
+func TestReadyTargetsFromSlices(t *testing.T) {
+	http, metrics := "http", "metrics"
+	httpPort, metricsPort := int32(8080), int32(9090)
+	ready, notReady := true, false
+
+	slices := []*discoveryv1beta1.EndpointSlice{
+		{
+			Ports: []discoveryv1beta1.EndpointPort{
+				{Name: &metrics, Port: &metricsPort},
+				{Name: &http, Port: &httpPort},
+			},
+			Endpoints: []discoveryv1beta1.Endpoint{
+				{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-b"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready}},
+				{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-c"}, Conditions: discoveryv1beta1.EndpointConditions{Ready: &notReady}},
+				{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-a"}},
+			},
+		},
+	}
+
+	expected := []ServiceTarget{{PodName: "web-a", Port: 8080}, {PodName: "web-b", Port: 8080}}
+	if targets := readyTargetsFromSlices(slices, "http"); !reflect.DeepEqual(targets, expected) {
+		t.Fatalf("expected %v, got %v", expected, targets)
+	}
+	if targets := readyTargetsFromSlices(slices, "grpc"); len(targets) != 0 {
+		t.Fatalf("expected no target, got %v", targets)
+	}
+}
+
+func TestReadyTargetsFromEndpoints(t *testing.T) {
+	endpoints := &corev1.Endpoints{
+		Subsets: []corev1.EndpointSubset{
+			{
+				Ports: []corev1.EndpointPort{{Port: 8080}},
+				Addresses: []corev1.EndpointAddress{
+					{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-b"}},
+					{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-a"}},
+				},
+				NotReadyAddresses: []corev1.EndpointAddress{
+					{TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "web-c"}},
+				},
+			},
+		},
+	}
+
+	expected := []ServiceTarget{{PodName: "web-a", Port: 8080}, {PodName: "web-b", Port: 8080}}
+	if targets := readyTargetsFromEndpoints(endpoints, ""); !reflect.DeepEqual(targets, expected) {
+		t.Fatalf("expected %v, got %v", expected, targets)
+	}
+}
+
+func TestServicePortForwarderRetarget(t *testing.T) {
+	f := &ServicePortForwarder{service: "web"}
+	var retargeted *ServiceTarget
+	f.OnRetarget = func(target *ServiceTarget) {
+		retargeted = target
+	}
+
+	f.updateTargets([]ServiceTarget{{PodName: "web-a", Port: 80}, {PodName: "web-b", Port: 80}})
+	f.target = &ServiceTarget{PodName: "web-a", Port: 80}
+
+	f.updateTargets([]ServiceTarget{{PodName: "web-b", Port: 80}})
+	if retargeted == nil || retargeted.PodName != "web-b" {
+		t.Fatalf("expected to re-target to web-b, got %v", retargeted)
+	}
+	if f.target != nil {
+		t.Fatal("current target should be reset")
+	}
+
+	f.updateTargets([]ServiceTarget{{PodName: "web-a", Port: 80}, {PodName: "web-b", Port: 80}})
+	f.dropTarget(ServiceTarget{PodName: "web-a", Port: 80})
+	if target := f.Target(); target == nil || target.PodName != "web-b" {
+		t.Fatalf("dropped target should be tried at last, got %v", target)
+	}
+}
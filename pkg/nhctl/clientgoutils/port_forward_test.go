@@ -2,7 +2,9 @@ package clientgoutils
 
 import (
 	"fmt"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"nocalhost/internal/nhctl/utils"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -17,7 +19,10 @@ func TestClientGoUtils_PortForward(t *testing.T) {
 
 	fps := []*ForwardPort{{LocalPort: 11223, RemotePort: 22}}
 
-	pf, err := client.CreatePortForwarder("details-77cc4f49fd-nl7kn", fps)
+	pf, err := client.CreatePortForwarder(
+		"details-77cc4f49fd-nl7kn", fps, make(chan struct{}), make(chan struct{}),
+		genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr},
+	)
 	if err != nil {
 		panic(err)
 	}
@@ -8,10 +8,13 @@ package clientgoutils
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -20,6 +23,25 @@ import (
 // storageClassName: nil to use default storageClassName
 func (c *ClientGoUtils) CreatePVC(
 	name string, labels map[string]string, annotations map[string]string, quantityStr string, storageClassName *string,
+) (*v1.PersistentVolumeClaim, error) {
+	return c.createPVC(name, labels, annotations, quantityStr, storageClassName, nil)
+}
+
+// CreatePVCFromSnapshot creates a pvc restored from the CSI VolumeSnapshot
+func (c *ClientGoUtils) CreatePVCFromSnapshot(
+	name string, labels map[string]string, annotations map[string]string, quantityStr string, storageClassName *string,
+	snapshot string,
+) (*v1.PersistentVolumeClaim, error) {
+	apiGroup := VolumeSnapshotGroup
+	return c.createPVC(
+		name, labels, annotations, quantityStr, storageClassName,
+		&v1.TypedLocalObjectReference{APIGroup: &apiGroup, Kind: VolumeSnapshotKind, Name: snapshot},
+	)
+}
+
+func (c *ClientGoUtils) createPVC(
+	name string, labels map[string]string, annotations map[string]string, quantityStr string, storageClassName *string,
+	dataSource *v1.TypedLocalObjectReference,
 ) (*v1.PersistentVolumeClaim, error) {
 	q, err := resource.ParseQuantity(quantityStr)
 	if err != nil {
@@ -36,15 +58,81 @@ func (c *ClientGoUtils) CreatePVC(
 				Requests: map[v1.ResourceName]resource.Quantity{v1.ResourceStorage: q},
 			},
 			StorageClassName: storageClassName,
+			DataSource:       dataSource,
 		},
 	}
 	persistentVolumeClaim.Name = name
 	persistentVolumeClaim.Labels = labels
 	persistentVolumeClaim.Annotations = annotations
 
-	return c.ClientSet.CoreV1().PersistentVolumeClaims(c.namespace).Create(
+	pvc, err := c.ClientSet.CoreV1().PersistentVolumeClaims(c.namespace).Create(
 		c.ctx, persistentVolumeClaim, metav1.CreateOptions{},
 	)
+	return pvc, errors.Wrap(err, "")
+}
+
+// WaitPvcBound waits until the pvc is bound, a pending pvc is accepted as well if its storage class
+// binds volumes for the first consumer. Returns error if the pvc is lost
+func (c *ClientGoUtils) WaitPvcBound(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultWaitReadyTimeout
+	}
+	pvc, err := c.GetPvcByName(name)
+	if err != nil {
+		return err
+	}
+	var storageClassName string
+	if pvc.Spec.StorageClassName != nil {
+		storageClassName = *pvc.Spec.StorageClassName
+	}
+	waitForConsumer, err := c.bindsForFirstConsumer(storageClassName)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(
+		wait.PollImmediate(
+			waitReadyInterval, timeout, func() (bool, error) {
+				pvc, err := c.GetPvcByName(name)
+				if err != nil {
+					return false, err
+				}
+				switch pvc.Status.Phase {
+				case v1.ClaimBound:
+					return true, nil
+				case v1.ClaimLost:
+					return false, errors.New(fmt.Sprintf("pvc %s is lost", name))
+				}
+				return waitForConsumer, nil
+			},
+		), fmt.Sprintf("pvc %s is not bound", name),
+	)
+}
+
+// bindsForFirstConsumer returns true if the storage class, or the default storage class if name is empty,
+// delays binding until a pod uses the pvc
+func (c *ClientGoUtils) bindsForFirstConsumer(storageClassName string) (bool, error) {
+	var sc *storagev1.StorageClass
+	if storageClassName != "" {
+		found, err := c.ClientSet.StorageV1().StorageClasses().Get(c.ctx, storageClassName, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		sc = found
+	} else {
+		list, err := c.ClientSet.StorageV1().StorageClasses().List(c.ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		for i := range list.Items {
+			if list.Items[i].Annotations[defaultStorageClassAnnotation] == "true" {
+				sc = &list.Items[i]
+			}
+		}
+	}
+	if sc == nil || sc.VolumeBindingMode == nil {
+		return false, nil
+	}
+	return *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer, nil
 }
 
 func (c *ClientGoUtils) DeletePVC(name string) error {
@@ -0,0 +1,121 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/load"
+	"github.com/pkg/errors"
+)
+
+var cueTagRegexp = regexp.MustCompile(`@tag\(\s*([A-Za-z_$][\w$-]*)`)
+
+// == local file visitor -- Cue
+
+// NewCueResourceReader evaluate cue packages (dir) or files in-process, tags will be
+// injected into fields declared with '@tag(name)', tags not declared are ignored
+func NewCueResourceReader(paths []string, tags map[string]string) *cueResourceReader {
+	return &cueResourceReader{
+		paths: paths,
+		tags:  tags,
+	}
+}
+
+type cueResourceReader struct {
+	paths []string
+	tags  map[string]string
+}
+
+func (crr *cueResourceReader) LoadResource() (*Resource, error) {
+	manifests := make([]string, 0)
+	for _, path := range crr.paths {
+		rendered, err := crr.evaluate(path)
+		if err != nil {
+			return &Resource{}, err
+		}
+		manifests = append(manifests, rendered...)
+	}
+	return &Resource{manifests}, nil
+}
+
+func (crr *cueResourceReader) evaluate(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("the path %q cannot be accessed", path))
+	}
+
+	dir, args := path, []string{"."}
+	if !fi.IsDir() {
+		dir, args = filepath.Dir(path), []string{filepath.Base(path)}
+	}
+
+	declared := declaredCueTags(dir)
+	tags := make([]string, 0)
+	for k, v := range crr.tags {
+		if declared[k] {
+			tags = append(tags, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	sort.Strings(tags)
+
+	instances := load.Instances(args, &load.Config{Dir: dir, Tags: tags})
+	if len(instances) == 0 {
+		return nil, errors.New(fmt.Sprintf("no cue instance found in %s", path))
+	}
+	if err = instances[0].Err; err != nil {
+		return nil, errors.New(fmt.Sprintf("fail to load cue %s: %s", path, cueErrorDetails(err)))
+	}
+
+	value := cuecontext.New().BuildInstance(instances[0])
+	if err = value.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("fail to build cue %s: %s", path, cueErrorDetails(err)))
+	}
+	if err = value.Validate(cue.Concrete(true)); err != nil {
+		return nil, errors.New(fmt.Sprintf("cue %s is not concrete: %s", path, cueErrorDetails(err)))
+	}
+
+	var decoded interface{}
+	if err = value.Decode(&decoded); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("fail to decode cue %s", path))
+	}
+	return extractManifests(decoded)
+}
+
+// declaredCueTags find all the tag declared by '@tag(name)' in cue files under dir
+func declaredCueTags(dir string) map[string]bool {
+	declared := make(map[string]bool)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return declared
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".cue") {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		for _, match := range cueTagRegexp.FindAllStringSubmatch(string(content), -1) {
+			declared[match[1]] = true
+		}
+	}
+	return declared
+}
+
+func cueErrorDetails(err error) string {
+	return strings.TrimSpace(cueerrors.Details(err, nil))
+}
@@ -0,0 +1,140 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// entrypoint of a jsonnet environment if resource path is a dir, same as tanka
+	JsonnetMainFile = "main.jsonnet"
+)
+
+// == local file visitor -- Jsonnet
+
+// NewJsonnetResourceReader evaluate jsonnet files in-process, extVars can be accessed
+// by std.extVar() in jsonnet, the dir of each file and its 'lib' and 'vendor' sub dir
+// are always added to the import paths
+func NewJsonnetResourceReader(paths []string, extVars map[string]string, jPaths ...string) *jsonnetResourceReader {
+	return &jsonnetResourceReader{
+		paths:   paths,
+		extVars: extVars,
+		jPaths:  jPaths,
+	}
+}
+
+type jsonnetResourceReader struct {
+	paths   []string
+	extVars map[string]string
+	jPaths  []string
+}
+
+func (jrr *jsonnetResourceReader) LoadResource() (*Resource, error) {
+	manifests := make([]string, 0)
+	for _, path := range jrr.paths {
+		rendered, err := jrr.evaluate(path)
+		if err != nil {
+			return &Resource{}, err
+		}
+		manifests = append(manifests, rendered...)
+	}
+	return &Resource{manifests}, nil
+}
+
+func (jrr *jsonnetResourceReader) evaluate(path string) ([]string, error) {
+	file := path
+	if fi, err := os.Stat(path); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("the path %q cannot be accessed", path))
+	} else if fi.IsDir() {
+		file = filepath.Join(path, JsonnetMainFile)
+	}
+
+	dir := filepath.Dir(file)
+	vm := jsonnet.MakeVM()
+	vm.Importer(
+		&jsonnet.FileImporter{
+			JPaths: append([]string{dir, filepath.Join(dir, "lib"), filepath.Join(dir, "vendor")}, jrr.jPaths...),
+		},
+	)
+
+	keys := make([]string, 0, len(jrr.extVars))
+	for k := range jrr.extVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vm.ExtVar(k, jrr.extVars[k])
+	}
+
+	out, err := vm.EvaluateFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("fail to evaluate jsonnet %s", file))
+	}
+
+	var value interface{}
+	if err = json.Unmarshal([]byte(out), &value); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("fail to parse output of jsonnet %s", file))
+	}
+	return extractManifests(value)
+}
+
+// extractManifests walk through an evaluated json value, every object that has both
+// apiVersion and kind is treated as a k8s manifest, 'List' kind is flattened,
+// other objects and arrays are walked recursively (keys are visited in order)
+func extractManifests(value interface{}) ([]string, error) {
+	manifests := make([]string, 0)
+	var walk func(v interface{}) error
+	walk = func(v interface{}) error {
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				if err := walk(item); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}:
+			_, hasApiVersion := val["apiVersion"]
+			kind, hasKind := val["kind"]
+			if hasApiVersion && hasKind {
+				if kind == "List" {
+					return walk(val["items"])
+				}
+				b, err := yaml.Marshal(val)
+				if err != nil {
+					return errors.Wrap(err, "")
+				}
+				manifests = append(manifests, string(b))
+				return nil
+			}
+
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if err := walk(val[k]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(value); err != nil {
+		return nil, err
+	}
+	return manifests, nil
+}
@@ -22,11 +22,16 @@ import (
 	"strings"
 )
 
-func (c *ClientGoUtils) NewFactory() cmdutil.Factory {
+// NewConfigFlags returns the kubectl config flags bound to the kubeconfig and namespace of this client
+func (c *ClientGoUtils) NewConfigFlags() *genericclioptions.ConfigFlags {
 	kubeConfigFlags := genericclioptions.NewConfigFlags(true).WithDeprecatedPasswordFlag()
 	kubeConfigFlags.KubeConfig = &c.kubeConfigFilePath
 	kubeConfigFlags.Namespace = &c.namespace
-	matchVersionKubeConfigFlags := cmdutil.NewMatchVersionFlags(kubeConfigFlags)
+	return kubeConfigFlags
+}
+
+func (c *ClientGoUtils) NewFactory() cmdutil.Factory {
+	matchVersionKubeConfigFlags := cmdutil.NewMatchVersionFlags(c.NewConfigFlags())
 	f := cmdutil.NewFactory(matchVersionKubeConfigFlags)
 	return f
 }
@@ -87,6 +92,22 @@ func (c *ClientGoUtils) Apply(files []string, continueOnError bool, flags *Apply
 	)
 }
 
+// ApplyReader is similar to Apply, but the resource is rendered by the reader, such as jsonnet or cue,
+// continueOnError only works while applying, the resource must be rendered successfully
+func (c *ClientGoUtils) ApplyReader(reader ResourceReader, continueOnError bool, flags *ApplyFlags) error {
+	loadResource, err := reader.LoadResource()
+	if err != nil {
+		return err
+	}
+
+	return c.resourceAndThen(
+		loadResource, continueOnError, flags,
+		func(c *ClientGoUtils, r *resource.Info) error {
+			return c.ApplyResourceInfo(r, flags)
+		},
+	)
+}
+
 // useless temporally
 func (c *ClientGoUtils) Delete(files []string, continueOnError bool, flags *ApplyFlags, kustomize string) error {
 
@@ -120,6 +141,13 @@ func (c *ClientGoUtils) renderManifestAndThen(
 		return err
 	}
 
+	return c.resourceAndThen(loadResource, continueOnError, flags, doForResourceInfo)
+}
+
+func (c *ClientGoUtils) resourceAndThen(
+	loadResource *Resource, continueOnError bool, flags *ApplyFlags,
+	doForResourceInfo func(c *ClientGoUtils, r *resource.Info) error,
+) error {
 	//goland:noinspection GoNilness
 	infos, err := loadResource.GetResourceInfo(c, continueOnError)
 	if err != nil {
@@ -0,0 +1,90 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRenderTestFile(t *testing.T, dir, name, content string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJsonnetResourceReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeRenderTestFile(t, dir, "lib.libsonnet", `{ name(app):: app + "-svc" }`)
+	writeRenderTestFile(
+		t, dir, JsonnetMainFile, `
+local lib = import "lib.libsonnet";
+{
+  app: {
+    deployment: { apiVersion: "apps/v1", kind: "Deployment", metadata: { name: std.extVar("APP") } },
+    service: { apiVersion: "v1", kind: "Service", metadata: { name: lib.name(std.extVar("APP")) } },
+  },
+  notManifest: { foo: "bar" },
+}`,
+	)
+
+	resource, err := NewJsonnetResourceReader([]string{dir}, map[string]string{"APP": "productpage"}).LoadResource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resource.arr()) != 2 {
+		t.Fatalf("expect 2 manifests, but got %d: %s", len(resource.arr()), resource.String())
+	}
+	if !strings.Contains(resource.arr()[0], "name: productpage") ||
+		!strings.Contains(resource.arr()[1], "name: productpage-svc") {
+		t.Fatalf("unexpected manifests: %s", resource.String())
+	}
+}
+
+func TestCueResourceReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeRenderTestFile(
+		t, dir, "app.cue", `
+package app
+
+app: string @tag(APP)
+
+objects: [{
+	apiVersion: "v1"
+	kind:       "List"
+	items: [{apiVersion: "v1", kind: "ConfigMap", metadata: name: app}]
+}]
+`,
+	)
+
+	// UNUSED is not declared, it should be ignored
+	resource, err := NewCueResourceReader(
+		[]string{dir}, map[string]string{"APP": "reviews", "UNUSED": "x"},
+	).LoadResource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resource.arr()) != 1 || !strings.Contains(resource.arr()[0], "name: reviews") {
+		t.Fatalf("unexpected manifests: %s", resource.String())
+	}
+
+	if _, err = NewCueResourceReader([]string{dir}, map[string]string{}).LoadResource(); err == nil {
+		t.Fatal("expect error while tag APP is missing")
+	}
+}
@@ -0,0 +1,26 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *ClientGoUtils) ListServices() ([]v1.Service, error) {
+	var result []v1.Service
+	list, err := c.ClientSet.CoreV1().Services(c.namespace).List(c.ctx, metav1.ListOptions{})
+	if list != nil {
+		result = list.Items
+	}
+	return result, errors.Wrap(err, "")
+}
+
+func (c *ClientGoUtils) UpdateService(service *v1.Service) (*v1.Service, error) {
+	s, err := c.ClientSet.CoreV1().Services(c.namespace).Update(c.ctx, service, metav1.UpdateOptions{})
+	return s, errors.Wrap(err, "")
+}
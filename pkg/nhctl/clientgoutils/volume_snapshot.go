@@ -0,0 +1,179 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	VolumeSnapshotGroup = "snapshot.storage.k8s.io"
+	VolumeSnapshotKind  = "VolumeSnapshot"
+
+	defaultStorageClassAnnotation        = "storageclass.kubernetes.io/is-default-class"
+	defaultVolumeSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+)
+
+// volumeSnapshotVersions are versions of the CSI snapshot api, preferred first
+var volumeSnapshotVersions = []string{"v1", "v1beta1"}
+
+// VolumeSnapshotVersion returns the version of the CSI snapshot api served by the cluster,
+// empty if the snapshot controller is not installed
+func (c *ClientGoUtils) VolumeSnapshotVersion() string {
+	for _, version := range volumeSnapshotVersions {
+		resources, err := c.ClientSet.Discovery().ServerResourcesForGroupVersion(VolumeSnapshotGroup + "/" + version)
+		if err != nil {
+			continue
+		}
+		for _, r := range resources.APIResources {
+			if r.Kind == VolumeSnapshotKind {
+				return version
+			}
+		}
+	}
+	return ""
+}
+
+func volumeSnapshotGVR(version, resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: VolumeSnapshotGroup, Version: version, Resource: resource}
+}
+
+// GetVolumeSnapshotClass returns the VolumeSnapshotClass whose driver is the provisioner of the storage class,
+// the default one is preferred. Empty if the storage class doesn't support CSI snapshots
+func (c *ClientGoUtils) GetVolumeSnapshotClass(version, storageClassName string) (string, error) {
+	if version == "" {
+		return "", nil
+	}
+	provisioner, err := c.getProvisioner(storageClassName)
+	if err != nil || provisioner == "" {
+		return "", err
+	}
+
+	list, err := c.dynamicClient.Resource(volumeSnapshotGVR(version, "volumesnapshotclasses")).
+		List(c.ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	var class string
+	for _, item := range list.Items {
+		if driver, _, _ := unstructured.NestedString(item.Object, "driver"); driver != provisioner {
+			continue
+		}
+		if item.GetAnnotations()[defaultVolumeSnapshotClassAnnotation] == "true" {
+			return item.GetName(), nil
+		}
+		if class == "" {
+			class = item.GetName()
+		}
+	}
+	return class, nil
+}
+
+// getProvisioner returns the provisioner of the storage class, or the default storage class if name is empty
+func (c *ClientGoUtils) getProvisioner(storageClassName string) (string, error) {
+	if storageClassName != "" {
+		sc, err := c.ClientSet.StorageV1().StorageClasses().Get(c.ctx, storageClassName, metav1.GetOptions{})
+		if err != nil {
+			return "", errors.Wrap(err, "")
+		}
+		return sc.Provisioner, nil
+	}
+	list, err := c.ClientSet.StorageV1().StorageClasses().List(c.ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	for _, sc := range list.Items {
+		if sc.Annotations[defaultStorageClassAnnotation] == "true" {
+			return sc.Provisioner, nil
+		}
+	}
+	return "", nil
+}
+
+// CreateVolumeSnapshot creates a CSI VolumeSnapshot of the pvc with the VolumeSnapshotClass
+func (c *ClientGoUtils) CreateVolumeSnapshot(
+	version, name, pvcName, snapshotClass string, labels, annotations map[string]string,
+) (*unstructured.Unstructured, error) {
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": VolumeSnapshotGroup + "/" + version,
+			"kind":       VolumeSnapshotKind,
+			"spec": map[string]interface{}{
+				"volumeSnapshotClassName": snapshotClass,
+				"source": map[string]interface{}{
+					"persistentVolumeClaimName": pvcName,
+				},
+			},
+		},
+	}
+	snapshot.SetName(name)
+	snapshot.SetLabels(labels)
+	snapshot.SetAnnotations(annotations)
+
+	created, err := c.dynamicClient.Resource(volumeSnapshotGVR(version, "volumesnapshots")).Namespace(c.namespace).
+		Create(c.ctx, snapshot, metav1.CreateOptions{})
+	return created, errors.Wrap(err, "")
+}
+
+// GetVolumeSnapshot returns nil if the VolumeSnapshot is not found
+func (c *ClientGoUtils) GetVolumeSnapshot(version, name string) (*unstructured.Unstructured, error) {
+	snapshot, err := c.dynamicClient.Resource(volumeSnapshotGVR(version, "volumesnapshots")).Namespace(c.namespace).
+		Get(c.ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return snapshot, errors.Wrap(err, "")
+}
+
+func (c *ClientGoUtils) ListVolumeSnapshots(version string, labelMap map[string]string) ([]unstructured.Unstructured, error) {
+	list, err := c.dynamicClient.Resource(volumeSnapshotGVR(version, "volumesnapshots")).Namespace(c.namespace).
+		List(c.ctx, metav1.ListOptions{LabelSelector: labels.Set(labelMap).AsSelector().String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return list.Items, nil
+}
+
+func (c *ClientGoUtils) DeleteVolumeSnapshot(version, name string) error {
+	return errors.Wrap(
+		c.dynamicClient.Resource(volumeSnapshotGVR(version, "volumesnapshots")).Namespace(c.namespace).
+			Delete(c.ctx, name, metav1.DeleteOptions{}), "",
+	)
+}
+
+// WaitVolumeSnapshotReady waits until the VolumeSnapshot is ready to use
+func (c *ClientGoUtils) WaitVolumeSnapshotReady(version, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultWaitReadyTimeout
+	}
+	return errors.Wrap(
+		wait.PollImmediate(
+			waitReadyInterval, timeout, func() (bool, error) {
+				snapshot, err := c.GetVolumeSnapshot(version, name)
+				if err != nil {
+					return false, err
+				}
+				if snapshot == nil {
+					return false, errors.New(fmt.Sprintf("volume snapshot %s not found", name))
+				}
+				if msg, found, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); found {
+					return false, errors.New(fmt.Sprintf("volume snapshot %s failed: %s", name, msg))
+				}
+				ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+				return ready, nil
+			},
+		), fmt.Sprintf("volume snapshot %s is not ready", name),
+	)
+}
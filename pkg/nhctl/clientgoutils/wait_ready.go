@@ -0,0 +1,348 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"nocalhost/pkg/nhctl/log"
+)
+
+const (
+	// DefaultWaitReadyTimeout is used while no timeout is specified
+	DefaultWaitReadyTimeout = 5 * time.Minute
+	waitReadyInterval       = 2 * time.Second
+	// how many pods, events and log lines of a not ready workload will be reported
+	reportPodsLimit   = 3
+	reportEventsLimit = 5
+	reportLogLines    = int64(20)
+)
+
+// WaitManifestReady wait until all workloads in manifest are ready
+func (c *ClientGoUtils) WaitManifestReady(manifest string, timeout time.Duration) error {
+	if strings.TrimSpace(manifest) == "" {
+		return nil
+	}
+	infos, err := NewResourceFromStr(manifest).GetResourceInfo(c, true)
+	if err != nil {
+		return err
+	}
+	return c.WaitResourceInfosReady(infos, timeout)
+}
+
+// WaitResourceInfosReady wait until all workloads in infos are ready or completed,
+// resources other than Deployment, StatefulSet, DaemonSet and Job are ignored.
+// If any workload fails or is not ready before timeout, its failing pods will be
+// reported with their last events and container logs
+func (c *ClientGoUtils) WaitResourceInfosReady(infos []*resource.Info, timeout time.Duration) error {
+	pending := make([]*resource.Info, 0)
+	for _, info := range infos {
+		switch workloadKind(info) {
+		case "deployment", "statefulset", "daemonset", "job":
+			pending = append(pending, info)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultWaitReadyTimeout
+	}
+
+	var failed error
+	err := wait.PollImmediate(waitReadyInterval, timeout, func() (bool, error) {
+		notReady := make([]*resource.Info, 0)
+		for _, info := range pending {
+			ready, err := c.isWorkloadReady(info)
+			if err != nil {
+				failed = err
+				pending = []*resource.Info{info}
+				return true, nil
+			}
+			if !ready {
+				notReady = append(notReady, info)
+			}
+		}
+		pending = notReady
+		if len(pending) > 0 {
+			log.Debugf("Waiting for %s to be ready", workloadNames(pending))
+		}
+		return len(pending) == 0, nil
+	})
+
+	if err == nil && failed == nil {
+		return nil
+	}
+
+	c.reportNotReadyWorkloads(pending)
+	if failed != nil {
+		return failed
+	}
+	if err == wait.ErrWaitTimeout {
+		return errors.New(
+			fmt.Sprintf("timed out after %s waiting for %s to be ready", timeout, workloadNames(pending)),
+		)
+	}
+	return errors.WithStack(err)
+}
+
+func workloadKind(info *resource.Info) string {
+	return strings.ToLower(info.Object.GetObjectKind().GroupVersionKind().Kind)
+}
+
+func workloadNames(infos []*resource.Info) string {
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, fmt.Sprintf("%s/%s", workloadKind(info), info.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// isWorkloadReady return error only if the workload will never be ready, such as a failed job
+func (c *ClientGoUtils) isWorkloadReady(info *resource.Info) (bool, error) {
+	appsV1 := c.ClientSet.AppsV1()
+
+	switch workloadKind(info) {
+	case "deployment":
+		d, err := appsV1.Deployments(c.namespace).Get(c.ctx, info.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Debugf("Fail to get deployment %s: %v", info.Name, err)
+			return false, nil
+		}
+		return deploymentReady(d), nil
+	case "statefulset":
+		s, err := appsV1.StatefulSets(c.namespace).Get(c.ctx, info.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Debugf("Fail to get statefulset %s: %v", info.Name, err)
+			return false, nil
+		}
+		return statefulSetReady(s), nil
+	case "daemonset":
+		d, err := appsV1.DaemonSets(c.namespace).Get(c.ctx, info.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Debugf("Fail to get daemonset %s: %v", info.Name, err)
+			return false, nil
+		}
+		return daemonSetReady(d), nil
+	case "job":
+		j, err := c.ClientSet.BatchV1().Jobs(c.namespace).Get(c.ctx, info.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Debugf("Fail to get job %s: %v", info.Name, err)
+			return false, nil
+		}
+		return jobCompleted(j)
+	}
+	return true, nil
+}
+
+func deploymentReady(d *v1.Deployment) bool {
+	if d.Generation > d.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.UpdatedReplicas >= replicas && d.Status.AvailableReplicas >= replicas
+}
+
+func statefulSetReady(s *v1.StatefulSet) bool {
+	if s.Generation > s.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	return s.Status.ReadyReplicas >= replicas
+}
+
+func daemonSetReady(d *v1.DaemonSet) bool {
+	if d.Generation > d.Status.ObservedGeneration {
+		return false
+	}
+	return d.Status.UpdatedNumberScheduled >= d.Status.DesiredNumberScheduled &&
+		d.Status.NumberReady >= d.Status.DesiredNumberScheduled
+}
+
+func jobCompleted(j *batchv1.Job) (bool, error) {
+	for _, condition := range j.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return false, errors.New(fmt.Sprintf("job %s failed: %s", j.Name, condition.Message))
+		}
+	}
+	return false, nil
+}
+
+func (c *ClientGoUtils) listPodsOfWorkload(info *resource.Info) ([]corev1.Pod, error) {
+	switch workloadKind(info) {
+	case "deployment":
+		pods, err := c.ListPodsByDeployment(info.Name)
+		if err != nil {
+			return nil, err
+		}
+		return pods.Items, nil
+	case "statefulset":
+		pods, err := c.ListPodsByStatefulSet(info.Name)
+		if err != nil {
+			return nil, err
+		}
+		return pods.Items, nil
+	case "daemonset":
+		return c.ListPodsByDaemonSet(info.Name)
+	case "job":
+		pods, err := c.ListPodsByJob(info.Name)
+		if err != nil {
+			return nil, err
+		}
+		return pods.Items, nil
+	}
+	return []corev1.Pod{}, nil
+}
+
+// WaitPodReady wait until the pod is ready, pod fails to start is reported in the same way as workloads
+func (c *ClientGoUtils) WaitPodReady(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultWaitReadyTimeout
+	}
+	var pod *corev1.Pod
+	err := wait.PollImmediate(waitReadyInterval, timeout, func() (bool, error) {
+		var err error
+		if pod, err = c.GetPod(name); err != nil {
+			return false, err
+		}
+		if pod.Status.Phase == corev1.PodFailed {
+			return false, errors.New(fmt.Sprintf("pod %s failed", name))
+		}
+		return podReady(pod), nil
+	})
+	if err != nil && pod != nil {
+		events, _ := c.ListEvents()
+		c.reportNotReadyPod(pod, events)
+	}
+	return errors.Wrap(err, "")
+}
+
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (c *ClientGoUtils) reportNotReadyWorkloads(infos []*resource.Info) {
+	events, err := c.ListEvents()
+	if err != nil {
+		log.WarnE(err, "Fail to list events")
+	}
+
+	for _, info := range infos {
+		log.Errorf("%s/%s is not ready", workloadKind(info), info.Name)
+		pods, err := c.listPodsOfWorkload(info)
+		if err != nil {
+			log.WarnE(err, fmt.Sprintf("Fail to list pods of %s/%s", workloadKind(info), info.Name))
+			continue
+		}
+
+		reported := 0
+		for i := range pods {
+			if reported >= reportPodsLimit {
+				break
+			}
+			if podReady(&pods[i]) {
+				continue
+			}
+			reported++
+			c.reportNotReadyPod(&pods[i], events)
+		}
+	}
+}
+
+func (c *ClientGoUtils) reportNotReadyPod(pod *corev1.Pod, events []corev1.Event) {
+	log.Errorf("  Pod %s is %s", pod.Name, pod.Status.Phase)
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil {
+			log.Errorf(
+				"    Container %s is waiting: %s %s (restarts: %d)", status.Name,
+				status.State.Waiting.Reason, status.State.Waiting.Message, status.RestartCount,
+			)
+		} else if status.State.Terminated != nil {
+			log.Errorf(
+				"    Container %s terminated: %s, exit code %d (restarts: %d)", status.Name,
+				status.State.Terminated.Reason, status.State.Terminated.ExitCode, status.RestartCount,
+			)
+		}
+	}
+
+	podEvents := make([]corev1.Event, 0)
+	for _, event := range events {
+		if event.InvolvedObject.Kind == "Pod" && event.InvolvedObject.Name == pod.Name {
+			podEvents = append(podEvents, event)
+		}
+	}
+	sort.Slice(
+		podEvents, func(i, j int) bool {
+			return podEvents[i].LastTimestamp.Before(&podEvents[j].LastTimestamp)
+		},
+	)
+	if len(podEvents) > reportEventsLimit {
+		podEvents = podEvents[len(podEvents)-reportEventsLimit:]
+	}
+	if len(podEvents) > 0 {
+		log.Errorf("    Last events:")
+	}
+	for _, event := range podEvents {
+		log.Errorf("      [%s] %s: %s", event.Type, event.Reason, strings.TrimSpace(event.Message))
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Ready {
+			continue
+		}
+		logs, err := c.tailContainerLogs(pod.Name, status.Name, status.RestartCount > 0)
+		if err != nil || strings.TrimSpace(logs) == "" {
+			continue
+		}
+		log.Errorf("    Last logs of container %s:", status.Name)
+		for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+			log.Errorf("      %s", line)
+		}
+	}
+}
+
+func (c *ClientGoUtils) tailContainerLogs(podName, container string, previous bool) (string, error) {
+	tailLines := reportLogLines
+	bys, err := c.ClientSet.CoreV1().Pods(c.namespace).GetLogs(
+		podName, &corev1.PodLogOptions{Container: container, TailLines: &tailLines, Previous: previous},
+	).DoRaw(c.ctx)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(bys), nil
+}
@@ -0,0 +1,55 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+ */
+
+package clientgoutils
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDeploymentReady(t *testing.T) {
+	replicas := int32(2)
+	d := &v1.Deployment{}
+	d.Generation = 2
+	d.Spec.Replicas = &replicas
+	d.Status.ObservedGeneration = 1
+	d.Status.UpdatedReplicas = 2
+	d.Status.AvailableReplicas = 2
+	if deploymentReady(d) {
+		t.Fatal("deployment not observed should not be ready")
+	}
+
+	d.Status.ObservedGeneration = 2
+	d.Status.AvailableReplicas = 1
+	if deploymentReady(d) {
+		t.Fatal("deployment with unavailable replicas should not be ready")
+	}
+
+	d.Status.AvailableReplicas = 2
+	if !deploymentReady(d) {
+		t.Fatal("deployment should be ready")
+	}
+}
+
+func TestJobCompleted(t *testing.T) {
+	j := &batchv1.Job{}
+	if completed, err := jobCompleted(j); completed || err != nil {
+		t.Fatal("running job should not be completed")
+	}
+
+	j.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if _, err := jobCompleted(j); err == nil {
+		t.Fatal("failed job should return error")
+	}
+
+	j.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if completed, err := jobCompleted(j); !completed || err != nil {
+		t.Fatal("job should be completed")
+	}
+}
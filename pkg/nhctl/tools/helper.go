@@ -104,22 +104,16 @@ func copyAndCapture(w io.Writer, r io.Reader, isDisplay bool) ([]byte, error) {
 }
 
 
-// check kubectl and helm
+// check kubectl, helm is no longer needed since helm sdk is used
 func CheckThirdPartyCLI() (string, error) {
 	kubectl := "kubectl"
-	helm := "helm"
 	if runtime.GOOS == "windows" {
 		kubectl = "kubectl.exe"
-		helm = "helm.exe"
 	}
 	_, err := exec.LookPath(kubectl)
 	if err != nil {
 		return "", err
 	}
-	_, err = exec.LookPath(helm)
-	if err != nil {
-		return "", err
-	}
 	return kubectl, nil
 }
 
@@ -0,0 +1,93 @@
+/*
+* Copyright (C) 2021 THL A29 Limited, a Tencent company.  All rights reserved.
+* This source code is licensed under the Apache License Version 2.0.
+*/
+
+package hub
+
+import (
+	"net/http"
+
+	"github.com/ghodss/yaml"
+	"github.com/gin-gonic/gin"
+	"nocalhost/internal/nhctl/hub"
+	"nocalhost/internal/nocalhost-api/service"
+	"nocalhost/pkg/nocalhost-api/app/api"
+	"nocalhost/pkg/nocalhost-api/app/router/ginbase"
+	"nocalhost/pkg/nocalhost-api/pkg/errno"
+	"nocalhost/pkg/nocalhost-api/pkg/log"
+)
+
+// Index Get the index of hub
+// @Summary Get the index of hub
+// @Description Get the index of all service configs in hub, which is used by nhctl hub
+// @Tags Hub
+// @Accept  json
+// @Produce  application/x-yaml
+// @param Authorization header string true "Authorization"
+// @Success 200 {object} hub.Index
+// @Router /v1/hub/index.yaml [get]
+func Index(c *gin.Context) {
+	index, err := service.Svc.HubConfig().Index(c)
+	if err != nil {
+		log.Errorf("Failed to generate hub index: %v", err)
+		api.SendResponse(c, errno.ErrHubIndex, nil)
+		return
+	}
+	bys, err := yaml.Marshal(index)
+	if err != nil {
+		api.SendResponse(c, errno.ErrHubIndex, nil)
+		return
+	}
+	c.Data(http.StatusOK, "application/x-yaml", bys)
+}
+
+// GetConfig Get a service config in hub
+// @Summary Get a service config in hub
+// @Description Get the content of a version of service config in hub
+// @Tags Hub
+// @Accept  json
+// @Produce  application/x-yaml
+// @param Authorization header string true "Authorization"
+// @Param name path string true "Config name"
+// @Param version path string true "Config version"
+// @Success 200 {string} string "config content"
+// @Router /v1/hub/configs/{name}/{version} [get]
+func GetConfig(c *gin.Context) {
+	content, err := service.Svc.HubConfig().GetContent(c, c.Param("name"), c.Param("version"))
+	if err != nil {
+		api.SendResponse(c, errno.ErrHubConfigNotFound, nil)
+		return
+	}
+	c.Data(http.StatusOK, "application/x-yaml", content)
+}
+
+// Publish Publish a service config to hub
+// @Summary Publish a service config to hub
+// @Description Publish a version of service config to hub, versions are immutable
+// @Tags Hub
+// @Accept  json
+// @Produce  json
+// @param Authorization header string true "Authorization"
+// @Param PublishRequest body hub.PublishRequest true "The config and its entry"
+// @Success 200 {object} api.Response "{"code":0,"message":"OK","data":null}"
+// @Router /v1/hub/configs [post]
+func Publish(c *gin.Context) {
+	var req hub.PublishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warnf("publish hub config bind params err: %v", err)
+		api.SendResponse(c, errno.ErrBind, nil)
+		return
+	}
+	userId, err := ginbase.LoginUser(c)
+	if err != nil {
+		api.SendResponse(c, errno.ErrPermissionDenied, nil)
+		return
+	}
+	if err = service.Svc.HubConfig().Publish(c, req.Entry, []byte(req.Content), userId); err != nil {
+		log.Warnf("Failed to publish hub config: %v", err)
+		api.SendResponse(c, &errno.Errno{Code: errno.ErrHubConfigPublish.Code, Message: err.Error()}, nil)
+		return
+	}
+	api.SendResponse(c, errno.OK, nil)
+}
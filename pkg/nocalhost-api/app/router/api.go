@@ -11,6 +11,7 @@ import (
 	"nocalhost/pkg/nocalhost-api/app/api/v1/applications"
 	"nocalhost/pkg/nocalhost-api/app/api/v1/cluster"
 	"nocalhost/pkg/nocalhost-api/app/api/v1/cluster_user"
+	"nocalhost/pkg/nocalhost-api/app/api/v1/hub"
 	"nocalhost/pkg/nocalhost-api/app/api/v1/service_account"
 	"nocalhost/pkg/nocalhost-api/app/api/v1/version"
 	"nocalhost/pkg/nocalhost-api/napp"
@@ -135,6 +136,15 @@ func Load(g *gin.Engine, mw ...gin.HandlerFunc) *gin.Engine {
 		n.GET("/templates", applications.GetNocalhostConfigTemplate)
 	}
 
+	// Hub of service configs, served as a hub of nhctl hub
+	h := g.Group("/v1/hub")
+	h.Use(middleware.AuthMiddleware())
+	{
+		h.GET("/index.yaml", hub.Index)
+		h.GET("/configs/:name/:version", hub.GetConfig)
+		h.POST("/configs", hub.Publish)
+	}
+
 	dv2 := g.Group("/v2/dev_space")
 	dv2.Use(middleware.AuthMiddleware(), middleware.PermissionMiddleware())
 	{
@@ -219,4 +219,9 @@ var (
 	ErrRoleBindingDelete = &Errno{
 		Code: 70007, Message: "Failed to remove role binding, please check your cluster and try again",
 	}
+
+	// hub for hub module request
+	ErrHubIndex          = &Errno{Code: 80000, Message: "Failed to generate hub index"}
+	ErrHubConfigNotFound = &Errno{Code: 80001, Message: "Hub config not found"}
+	ErrHubConfigPublish  = &Errno{Code: 80002, Message: "Failed to publish hub config"}
 )